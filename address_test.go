@@ -945,3 +945,23 @@ func TestMultiAddressSyntacticalValidation(t *testing.T) {
 		runOutputsSyntacticalValidationTest(t, testAPI, tt)
 	}
 }
+
+func TestMultiAddressClone(t *testing.T) {
+	addr := &iotago.MultiAddress{
+		Addresses: iotago.AddressesWithWeight{
+			{Address: tpkg.RandEd25519Address(), Weight: 1},
+			{Address: tpkg.RandAccountAddress(), Weight: 2},
+		},
+		Threshold: 2,
+	}
+
+	//nolint:forcetypeassert // the clone of a MultiAddress is a MultiAddress
+	cpy := addr.Clone().(*iotago.MultiAddress)
+	require.True(t, addr.Equal(cpy))
+
+	// the clone must not share the addresses with the original
+	cpy.Addresses[0].Weight = 2
+	cpy.Addresses[1].Address = tpkg.RandEd25519Address()
+	require.EqualValues(t, 1, addr.Addresses[0].Weight)
+	require.IsType(t, &iotago.AccountAddress{}, addr.Addresses[1].Address)
+}
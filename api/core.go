@@ -546,6 +546,8 @@ func unwrapErrors(err error, errList []error) []error {
 func DetermineTransactionFailureReason(err error) TransactionFailureReason {
 	errorList := make([]error, 0)
 	errorList = unwrapErrors(err, errorList)
+	// the given error itself is the least-detailed one, it matches if it is a sentinel error
+	errorList = append(errorList, err)
 
 	// Map the error to the transaction failure reason.
 	// The strategy is to map the first failure reason that exists in order of most-detailed to least-detailed error.
@@ -559,6 +561,36 @@ func DetermineTransactionFailureReason(err error) TransactionFailureReason {
 	return TxFailureSemanticValidationFailed
 }
 
+// ErrTxSemanticValidationFailed is the error that corresponds to TxFailureSemanticValidationFailed,
+// the failure reason of transactions that failed for a reason without a more specific error.
+var ErrTxSemanticValidationFailed = ierrors.New("semantic validation of the transaction failed")
+
+// txFailureReasonErrorsMap is the inverse of txErrorsFailureReasonMap.
+var txFailureReasonErrorsMap = func() map[TransactionFailureReason]error {
+	failureReasonErrors := make(map[TransactionFailureReason]error, len(txErrorsFailureReasonMap)+1)
+	for err, txFailureReason := range txErrorsFailureReasonMap {
+		failureReasonErrors[txFailureReason] = err
+	}
+	failureReasonErrors[TxFailureSemanticValidationFailed] = ErrTxSemanticValidationFailed
+
+	return failureReasonErrors
+}()
+
+// TransactionFailureReasonError decodes the given failure reason reported by a node into the corresponding error,
+// so that it can be checked with ierrors.Is against the errors of the iotago package.
+// It returns nil for TxFailureNone.
+func TransactionFailureReasonError(txFailureReason TransactionFailureReason) error {
+	if txFailureReason == TxFailureNone {
+		return nil
+	}
+
+	if err, exists := txFailureReasonErrorsMap[txFailureReason]; exists {
+		return err
+	}
+
+	return ierrors.Errorf("unknown transaction failure reason: %d", txFailureReason)
+}
+
 type (
 	// InfoResponse defines the response of a GET info REST API call.
 	InfoResponse struct {
@@ -871,6 +871,16 @@ func TestTransactionFailureReasonDetermination(t *testing.T) {
 			}(),
 			expected: api.TxFailureRewardInputReferenceInvalid,
 		},
+		{
+			name:     "sentinel error that is not wrapped",
+			err:      iotago.ErrInputOutputManaMismatch,
+			expected: api.TxFailureInputOutputManaMismatch,
+		},
+		{
+			name:     "unknown error",
+			err:      ierrors.New("unknown"),
+			expected: api.TxFailureSemanticValidationFailed,
+		},
 	}
 
 	for _, test := range tests {
@@ -884,3 +894,16 @@ func TestTransactionFailureReasonDetermination(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionFailureReasonError(t *testing.T) {
+	require.NoError(t, api.TransactionFailureReasonError(api.TxFailureNone))
+	require.ErrorIs(t, api.TransactionFailureReasonError(api.TxFailureOrphaned), iotago.ErrTxOrphaned)
+	require.ErrorIs(t, api.TransactionFailureReasonError(api.TxFailureSemanticValidationFailed), api.ErrTxSemanticValidationFailed)
+	require.Error(t, api.TransactionFailureReasonError(254))
+
+	// the decoded error determines the same failure reason again
+	for txFailureReason := api.TxFailureConflictRejected; txFailureReason <= api.TxFailureCapabilitiesNFTDestructionNotAllowed; txFailureReason++ {
+		err := api.TransactionFailureReasonError(txFailureReason)
+		require.Equal(t, txFailureReason, api.DetermineTransactionFailureReason(ierrors.WithMessage(err, "message")))
+	}
+}
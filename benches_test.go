@@ -161,6 +161,75 @@ func BenchmarkVerifyEd25519OneIOTxEssence(b *testing.B) {
 	}
 }
 
+func BenchmarkVerifyEd25519Signatures(b *testing.B) {
+	const signatureCount = 128
+
+	messages := make([][]byte, 0, signatureCount)
+	sigs := make([]*iotago.Ed25519Signature, 0, signatureCount)
+	for range signatureCount {
+		prvKey := tpkg.RandEd25519PrivateKey()
+		message := tpkg.RandBytes(64)
+
+		sig := &iotago.Ed25519Signature{}
+		copy(sig.PublicKey[:], prvKey.Public().(ed25519.PublicKey))
+		copy(sig.Signature[:], ed25519.Sign(prvKey, message))
+
+		messages = append(messages, message)
+		sigs = append(sigs, sig)
+	}
+
+	b.Run("individual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j, sig := range sigs {
+				hiveEd25519.Verify(sig.PublicKey[:], messages[j], sig.Signature[:])
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			verifier := iotago.NewEd25519BatchVerifier()
+			for j, sig := range sigs {
+				verifier.Add(sig, messages[j])
+			}
+			verifier.Verify()
+		}
+	})
+}
+
+func BenchmarkVerifyBlockSignatures(b *testing.B) {
+	const blockCount = 128
+
+	blocks := make([]*iotago.Block, 0, blockCount)
+	for range blockCount {
+		block := tpkg.RandBlock(tpkg.RandBasicBlockBody(tpkg.ZeroCostTestAPI, iotago.PayloadTaggedData), tpkg.ZeroCostTestAPI, 0)
+		prvKey := tpkg.RandEd25519PrivateKey()
+		signingMessage, err := block.SigningMessage()
+		tpkg.Must(err)
+
+		sig := &iotago.Ed25519Signature{}
+		copy(sig.PublicKey[:], prvKey.Public().(ed25519.PublicKey))
+		copy(sig.Signature[:], ed25519.Sign(prvKey, signingMessage))
+		block.Signature = sig
+
+		blocks = append(blocks, block)
+	}
+
+	b.Run("individual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, block := range blocks {
+				_, _ = block.VerifySignature()
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = iotago.VerifyBlockSignatures(blocks)
+		}
+	})
+}
+
 func BenchmarkSerializeAndHashBlockWithTransactionPayload(b *testing.B) {
 	txPayload := tpkg.OneInputOutputTransaction()
 
@@ -160,21 +160,54 @@ func (b *Block) Sign(signer AddressSigner, addr Address) (Signature, error) {
 
 // VerifySignature verifies the Signature of the block.
 func (b *Block) VerifySignature() (valid bool, err error) {
-	signingMessage, err := b.SigningMessage()
+	edSig, signingMessage, err := b.signatureToVerify()
 	if err != nil {
 		return false, err
 	}
 
+	return hiveEd25519.Verify(edSig.PublicKey[:], signingMessage, edSig.Signature[:]), nil
+}
+
+// VerifyBlockSignatures verifies the Signatures of the given blocks in a batch and returns whether they are valid,
+// in the order of the blocks. It is considerably faster than calling VerifySignature for every block.
+func VerifyBlockSignatures(blocks []*Block) (valid []bool, err error) {
+	verifier := NewEd25519BatchVerifier()
+	for i, block := range blocks {
+		edSig, signingMessage, err := block.signatureToVerify()
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "block %d", i)
+		}
+		verifier.Add(edSig, signingMessage)
+	}
+
+	valid = make([]bool, len(blocks))
+	for i := range valid {
+		valid[i] = true
+	}
+	for _, invalidIndex := range verifier.Verify() {
+		valid[invalidIndex] = false
+	}
+
+	return valid, nil
+}
+
+// signatureToVerify returns the Ed25519Signature of the block and the message it signs.
+func (b *Block) signatureToVerify() (*Ed25519Signature, []byte, error) {
+	signingMessage, err := b.SigningMessage()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	edSig, isEdSig := b.Signature.(*Ed25519Signature)
 	if !isEdSig {
-		return false, ierrors.Errorf("only ed2519 signatures supported, got %s", b.Signature.Type())
+		return nil, nil, ierrors.Errorf("only ed2519 signatures supported, got %s", b.Signature.Type())
 	}
 
 	if edSig.PublicKey == [ed25519.PublicKeySize]byte{} {
-		return false, ierrors.New("ed25519 public key must not be empty")
+		return nil, nil, ierrors.New("ed25519 public key must not be empty")
 	}
 
-	return hiveEd25519.Verify(edSig.PublicKey[:], signingMessage, edSig.Signature[:]), nil
+	return edSig, signingMessage, nil
 }
 
 // Slot returns the SlotIndex of the Block.
@@ -693,3 +693,35 @@ func TestBlockJSONMarshalling(t *testing.T) {
 // TODO: add tests
 //  - parents parameters basic block
 //  - parents parameters validator block
+
+func TestVerifyBlockSignatures(t *testing.T) {
+	blocks := make([]*iotago.Block, 0, 16)
+	for range 16 {
+		block, err := builder.NewBasicBlockBuilder(tpkg.ZeroCostTestAPI).
+			StrongParents(tpkg.SortedRandBlockIDs(2)).
+			Sign(tpkg.RandAccountID(), tpkg.RandEd25519PrivateKey()).
+			Build()
+		require.NoError(t, err)
+		blocks = append(blocks, block)
+	}
+
+	valid, err := iotago.VerifyBlockSignatures(blocks)
+	require.NoError(t, err)
+	require.Len(t, valid, len(blocks))
+	require.NotContains(t, valid, false)
+
+	// the signature of another block is invalid for this block
+	blocks[3].Signature = blocks[4].Signature
+	valid, err = iotago.VerifyBlockSignatures(blocks)
+	require.NoError(t, err)
+	for i, blockValid := range valid {
+		singleValid, err := blocks[i].VerifySignature()
+		require.NoError(t, err)
+		require.Equal(t, singleValid, blockValid)
+		require.Equal(t, i != 3, blockValid)
+	}
+
+	blocks[5].Signature = &iotago.Ed25519Signature{}
+	_, err = iotago.VerifyBlockSignatures(blocks)
+	require.ErrorContains(t, err, "block 5")
+}
@@ -0,0 +1,253 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// ErrBatchPaymentNoPayments gets returned if a batch without payments is built.
+var ErrBatchPaymentNoPayments = ierrors.New("batch contains no payments")
+
+// Payment is a single payment of a batch, which is sent in its own BasicOutput.
+type Payment struct {
+	// The address the payment is sent to.
+	Address iotago.Address
+	// The amount of base tokens to send. If zero, the minimum storage deposit of the output is sent.
+	Amount iotago.BaseToken
+	// The optional native token to send.
+	NativeToken *iotago.NativeTokenFeature
+	// The optional tag of the output.
+	Tag []byte
+	// The optional metadata of the output.
+	Metadata iotago.MetadataFeatureEntries
+}
+
+// BatchTransaction is a transaction of a BatchPaymentPlan.
+type BatchTransaction struct {
+	// The signed transaction.
+	SignedTransaction *iotago.SignedTransaction
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The indexes of the payments of the batch that are sent by the transaction, in the order of its outputs.
+	PaymentIndexes []int
+	// The result of the input selection of the transaction.
+	Selection *InputSelectionResult
+	// The remainder outputs of the transaction, which are available to the following transactions of the plan.
+	Remainders []*TxInput
+}
+
+// BatchPaymentPlan is the outcome of a BatchPaymentBuilder.
+// The transactions need to be issued in order, since a transaction may consume the remainder outputs
+// of the transactions before it. All transactions have the same creation slot, so they can be issued within one slot.
+type BatchPaymentPlan struct {
+	Transactions []*BatchTransaction
+	// The candidates that were not consumed by the transactions, including the remainder outputs of the last transaction.
+	Unspent []*TxInput
+}
+
+// NewBatchPaymentBuilder creates a new BatchPaymentBuilder which sends the remainders to the given address.
+// The inputs are selected with the LargestFirst strategy, unless another strategy is set.
+func NewBatchPaymentBuilder(api iotago.API, signer iotago.AddressSigner, remainderAddress iotago.Address) *BatchPaymentBuilder {
+	maxWorkScore, err := api.ProtocolParameters().WorkScoreParameters().MaxBlockWork()
+
+	return &BatchPaymentBuilder{
+		api:              api,
+		signer:           signer,
+		remainderAddress: remainderAddress,
+		strategy:         NewLargestFirstStrategy(),
+		maxWorkScore:     maxWorkScore,
+		occurredBuildErr: err,
+	}
+}
+
+// BatchPaymentBuilder splits many payments into as few transactions as the protocol limits allow.
+// A transaction is limited by the maximum amount of inputs and outputs, by the maximum payload size of a block
+// and by the maximum work score, which can be lowered to limit the mana that needs to be burned per block.
+type BatchPaymentBuilder struct {
+	api              iotago.API
+	signer           iotago.AddressSigner
+	remainderAddress iotago.Address
+	strategy         InputSelectionStrategy
+	maxWorkScore     iotago.WorkScore
+	payments         []*Payment
+	occurredBuildErr error
+
+	// the block issuer account the mana for the block issuance is allotted to, if set.
+	blockIssuerAccountID iotago.AccountID
+	rmc                  iotago.Mana
+	allotToBlockIssuer   bool
+}
+
+// AddPayment adds the given payment to the batch.
+func (b *BatchPaymentBuilder) AddPayment(payment *Payment) *BatchPaymentBuilder {
+	b.payments = append(b.payments, payment)
+
+	return b
+}
+
+// Strategy sets the strategy used to select the inputs of every transaction.
+func (b *BatchPaymentBuilder) Strategy(strategy InputSelectionStrategy) *BatchPaymentBuilder {
+	b.strategy = strategy
+
+	return b
+}
+
+// MaxWorkScore sets the maximum work score of a transaction, which defaults to the maximum work score of a block.
+// The work score of the transaction includes the offset for the block it is issued in, see iotago.Block.WorkScore.
+func (b *BatchPaymentBuilder) MaxWorkScore(maxWorkScore iotago.WorkScore) *BatchPaymentBuilder {
+	b.maxWorkScore = maxWorkScore
+
+	return b
+}
+
+// AllotToBlockIssuer lets every transaction allot the mana that is required to issue it with the given RMC
+// to the given block issuer account, see TransactionBuilder.AllotMinRequiredManaAndStoreRemainingManaInOutput.
+// This needs to be part of the plan, since the allotment changes the IDs of the remainder outputs
+// that are consumed by the following transactions.
+func (b *BatchPaymentBuilder) AllotToBlockIssuer(blockIssuerAccountID iotago.AccountID, rmc iotago.Mana) *BatchPaymentBuilder {
+	b.blockIssuerAccountID = blockIssuerAccountID
+	b.rmc = rmc
+	b.allotToBlockIssuer = true
+
+	return b
+}
+
+// Build splits the payments into transactions which are funded by the given candidates and returns the plan.
+// The payments are kept in order, every transaction sends as many of the following payments as the limits allow.
+// The target slot is the slot of the commitment the transactions are going to reference and is used as their creation slot.
+// The remaining mana of every transaction is stored in its first remainder output.
+func (b *BatchPaymentBuilder) Build(targetSlot iotago.SlotIndex, candidates []*TxInput) (*BatchPaymentPlan, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	if len(b.payments) == 0 {
+		return nil, ErrBatchPaymentNoPayments
+	}
+
+	outputs := make([]iotago.Output, len(b.payments))
+	for i, payment := range b.payments {
+		output, err := b.paymentOutput(payment)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to build the output of payment %d", i)
+		}
+		outputs[i] = output
+	}
+
+	plan := &BatchPaymentPlan{Unspent: candidates}
+	for start := 0; start < len(outputs); {
+		// one output is reserved for the remainder
+		maxCount := min(len(outputs)-start, iotago.MaxOutputsCount-1)
+
+		transaction, err := buildLargestFitting(maxCount, func(count int) (*BatchTransaction, error) {
+			return b.buildTransaction(targetSlot, outputs, start, count, plan.Unspent)
+		})
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to build a transaction for payment %d", start)
+		}
+
+		plan.Transactions = append(plan.Transactions, transaction)
+		plan.Unspent = remainingCandidates(plan.Unspent, transaction.Selection.Selected, transaction.Remainders)
+		start += len(transaction.PaymentIndexes)
+	}
+
+	return plan, nil
+}
+
+// paymentOutput returns the BasicOutput of the given payment.
+func (b *BatchPaymentBuilder) paymentOutput(payment *Payment) (*iotago.BasicOutput, error) {
+	outputBuilder := NewBasicOutputBuilder(payment.Address, payment.Amount)
+	if payment.NativeToken != nil {
+		outputBuilder.NativeToken(payment.NativeToken)
+	}
+	if len(payment.Tag) > 0 {
+		outputBuilder.Tag(payment.Tag)
+	}
+	if len(payment.Metadata) > 0 {
+		outputBuilder.Metadata(payment.Metadata)
+	}
+
+	output, err := outputBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Amount == 0 {
+		if output.Amount, err = b.api.StorageScoreStructure().MinDeposit(output); err != nil {
+			return nil, ierrors.Wrap(err, "failed to calculate the minimum storage deposit")
+		}
+	}
+
+	return output, nil
+}
+
+// buildTransaction builds a transaction for count outputs starting at the given index and checks the protocol limits.
+func (b *BatchPaymentBuilder) buildTransaction(targetSlot iotago.SlotIndex, outputs []iotago.Output, start int, count int, candidates []*TxInput) (*BatchTransaction, error) {
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+
+	// every build uses copies of the outputs, so a failed build can't modify the outputs of the following ones
+	paymentIndexes := make([]int, 0, count)
+	for i := start; i < start+count; i++ {
+		txBuilder.AddOutput(outputs[i].Clone())
+		paymentIndexes = append(paymentIndexes, i)
+	}
+
+	selection, err := txBuilder.SelectInputs(targetSlot, b.strategy, 0, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.allotToBlockIssuer {
+		txBuilder.AddRemainderOutputsAndAllotMinRequiredMana(targetSlot, b.remainderAddress, b.rmc, b.blockIssuerAccountID)
+	} else {
+		txBuilder.AddRemainderOutputsAndStoreRemainingMana(targetSlot, b.remainderAddress)
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTransactionLimits(b.api, signedTx, b.maxWorkScore); err != nil {
+		return nil, err
+	}
+
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	remainders := make([]*TxInput, 0, len(txBuilder.RemainderOutputIndexes()))
+	for _, index := range txBuilder.RemainderOutputIndexes() {
+		remainders = append(remainders, &TxInput{
+			UnlockTarget: b.remainderAddress,
+			InputID:      iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:        signedTx.Transaction.Outputs[index],
+		})
+	}
+
+	return &BatchTransaction{
+		SignedTransaction: signedTx,
+		TransactionID:     transactionID,
+		PaymentIndexes:    paymentIndexes,
+		Selection:         selection,
+		Remainders:        remainders,
+	}, nil
+}
+
+// remainingCandidates returns the candidates that were not consumed by a transaction, followed by its remainders.
+func remainingCandidates(candidates []*TxInput, selected []*SelectedInput, remainders []*TxInput) []*TxInput {
+	consumed := make(map[iotago.OutputID]struct{}, len(selected))
+	for _, selectedInput := range selected {
+		consumed[selectedInput.InputID] = struct{}{}
+	}
+
+	remaining := make([]*TxInput, 0, len(candidates)+len(remainders))
+	for _, candidate := range candidates {
+		if _, isConsumed := consumed[candidate.InputID]; !isConsumed {
+			remaining = append(remaining, candidate)
+		}
+	}
+
+	return append(remaining, remainders...)
+}
@@ -0,0 +1,171 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+func TestBatchPaymentBuilder(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	targetSlot := iotago.SlotIndex(100)
+
+	candidates := []*builder.TxInput{
+		{UnlockTarget: addr, InputID: tpkg.RandOutputIDWithCreationSlot(10, 0), Input: builder.NewBasicOutputBuilder(addr, 200_000_000).Mana(1_000_000_000).MustBuild()},
+		{UnlockTarget: addr, InputID: tpkg.RandOutputIDWithCreationSlot(10, 1), Input: builder.NewBasicOutputBuilder(addr, 150_000_000).MustBuild()},
+	}
+
+	newBatch := func(paymentCount int) (*builder.BatchPaymentBuilder, []*builder.Payment) {
+		batch := builder.NewBatchPaymentBuilder(api, signer, addr)
+		payments := make([]*builder.Payment, paymentCount)
+		for i := range payments {
+			payments[i] = &builder.Payment{
+				Address:  tpkg.RandEd25519Address(),
+				Amount:   1_000_000,
+				Tag:      []byte(fmt.Sprintf("payout-%d", i)),
+				Metadata: iotago.MetadataFeatureEntries{"id": []byte{byte(i)}},
+			}
+			batch.AddPayment(payments[i])
+		}
+
+		return batch, payments
+	}
+
+	// validateChain checks that the transactions send the payments in order and unlock their inputs,
+	// which may be the remainders of the transactions before.
+	validateChain := func(t *testing.T, plan *builder.BatchPaymentPlan, payments []*builder.Payment) {
+		t.Helper()
+
+		inputSet := vm.InputSet{}
+		for _, candidate := range candidates {
+			inputSet[candidate.InputID] = candidate.Input
+		}
+
+		var paymentIndex int
+		for _, transaction := range plan.Transactions {
+			signedTx := transaction.SignedTransaction
+			require.Equal(t, targetSlot, signedTx.Transaction.CreationSlot)
+			require.LessOrEqual(t, len(signedTx.Transaction.Outputs), iotago.MaxOutputsCount)
+
+			for i, index := range transaction.PaymentIndexes {
+				require.Equal(t, paymentIndex, index)
+				paymentIndex++
+
+				output := signedTx.Transaction.Outputs[i].(*iotago.BasicOutput)
+				require.True(t, payments[index].Address.Equal(output.UnlockConditionSet().Address().Address))
+				require.Equal(t, payments[index].Amount, output.Amount)
+				require.Equal(t, payments[index].Tag, output.FeatureSet().Tag().Tag)
+			}
+
+			_, err := vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: inputSet})
+			require.NoError(t, err)
+
+			for _, remainder := range transaction.Remainders {
+				inputSet[remainder.InputID] = remainder.Input
+			}
+		}
+		require.Equal(t, len(payments), paymentIndex)
+	}
+
+	t.Run("ok - split by max outputs", func(t *testing.T) {
+		batch, payments := newBatch(300)
+
+		plan, err := batch.Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Transactions, 3)
+		require.Len(t, plan.Transactions[0].PaymentIndexes, iotago.MaxOutputsCount-1)
+		require.Len(t, plan.Transactions[1].PaymentIndexes, iotago.MaxOutputsCount-1)
+		validateChain(t, plan, payments)
+
+		// the transactions consume the largest candidates, the last one chains the remainder of the first one
+		require.Equal(t, candidates[0].InputID, plan.Transactions[0].Selection.Selected[0].InputID)
+		require.Equal(t, candidates[1].InputID, plan.Transactions[1].Selection.Selected[0].InputID)
+		require.Equal(t, plan.Transactions[0].Remainders[0].InputID, plan.Transactions[2].Selection.Selected[0].InputID)
+
+		// the remainders of the second and the last transaction remain unspent
+		require.Len(t, plan.Unspent, 2)
+	})
+
+	t.Run("ok - split by max work score", func(t *testing.T) {
+		batch, payments := newBatch(50)
+
+		// a work score that allows to send only a part of the payments per transaction
+		single, err := builder.NewBatchPaymentBuilder(api, signer, addr).AddPayment(payments[0]).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		singleWorkScore, err := single.Transactions[0].SignedTransaction.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+		require.NoError(t, err)
+		maxWorkScore := singleWorkScore * 10
+
+		plan, err := batch.MaxWorkScore(maxWorkScore).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Greater(t, len(plan.Transactions), 1)
+		validateChain(t, plan, payments)
+
+		for _, transaction := range plan.Transactions {
+			workScore, err := transaction.SignedTransaction.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+			require.NoError(t, err)
+			require.LessOrEqual(t, workScore, maxWorkScore)
+
+			// the block the transaction is issued in has the same work score
+			block, err := builder.NewBasicBlockBuilder(api).Payload(transaction.SignedTransaction).Build()
+			require.NoError(t, err)
+			blockWorkScore, err := block.WorkScore()
+			require.NoError(t, err)
+			require.Equal(t, workScore, blockWorkScore)
+		}
+	})
+
+	t.Run("ok - payment to the remainder address", func(t *testing.T) {
+		batch, payments := newBatch(50)
+		payments[0].Address = addr
+
+		// a work score that forces several builds with fewer payments
+		single, err := builder.NewBatchPaymentBuilder(api, signer, addr).AddPayment(payments[1]).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		singleWorkScore, err := single.Transactions[0].SignedTransaction.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+		require.NoError(t, err)
+
+		plan, err := batch.MaxWorkScore(singleWorkScore*10).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Greater(t, len(plan.Transactions), 1)
+
+		// the payment to the remainder address only holds its own amount
+		validateChain(t, plan, payments)
+	})
+
+	t.Run("ok - allot to block issuer", func(t *testing.T) {
+		batch, payments := newBatch(10)
+		blockIssuerAccountID := tpkg.RandAccountID()
+
+		plan, err := batch.AllotToBlockIssuer(blockIssuerAccountID, 100).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Transactions, 1)
+		validateChain(t, plan, payments)
+
+		allotments := plan.Transactions[0].SignedTransaction.Transaction.Allotments
+		require.Len(t, allotments, 1)
+		require.Equal(t, blockIssuerAccountID, allotments[0].AccountID)
+		require.NotZero(t, allotments[0].Mana)
+	})
+
+	t.Run("fail - not enough base tokens", func(t *testing.T) {
+		batch, _ := newBatch(400)
+
+		_, err := batch.Build(targetSlot, candidates)
+		require.ErrorIs(t, err, builder.ErrInputSelectionNotEnoughBaseTokens)
+	})
+
+	t.Run("fail - no payments", func(t *testing.T) {
+		_, err := builder.NewBatchPaymentBuilder(api, signer, addr).Build(targetSlot, candidates)
+		require.ErrorIs(t, err, builder.ErrBatchPaymentNoPayments)
+	})
+}
@@ -0,0 +1,197 @@
+package builder
+
+import (
+	"slices"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// ErrClaimNothingToClaim gets returned if none of the outputs can be claimed.
+var ErrClaimNothingToClaim = ierrors.New("no output can be claimed")
+
+// ClaimableOutput is an output with a storage deposit return or an expiration unlock condition
+// that can be claimed by an address.
+type ClaimableOutput struct {
+	*TxInput
+	// The address the storage deposit needs to be returned to, nil if nothing needs to be returned.
+	ReturnAddress iotago.Address
+	// The amount of base tokens that needs to be returned to the return address.
+	ReturnAmount iotago.BaseToken
+	// Whether the output expired and is reclaimed by the expiration return address.
+	Expired bool
+}
+
+// ClaimableOutputs is the outcome of ClaimBuilder.Find.
+type ClaimableOutputs struct {
+	// The outputs that can be claimed at the target slot.
+	Claimable []*ClaimableOutput
+	// The outputs that can not be claimed (yet) at the target slot.
+	NotClaimable []*SkippedInput
+}
+
+// ClaimPlan is the outcome of a ClaimBuilder.
+// The transactions need to be issued in order, since every transaction consumes the claimed outputs
+// of the transaction before it.
+type ClaimPlan struct {
+	Transactions []*ConsolidationTransaction
+	// The outputs that are claimed by the transactions.
+	Claimed []*ClaimableOutput
+	// The outputs that can not be claimed (yet) at the target slot.
+	NotClaimable []*SkippedInput
+}
+
+// NewClaimBuilder creates a new ClaimBuilder which claims the outputs for the given address.
+func NewClaimBuilder(api iotago.API, signer iotago.AddressSigner, address iotago.Address) *ClaimBuilder {
+	return &ClaimBuilder{
+		api:           api,
+		address:       address,
+		consolidation: NewConsolidationBuilder(api, signer, address).IncludeNativeTokens(true),
+	}
+}
+
+// ClaimBuilder claims the outputs with a storage deposit return or an expiration unlock condition for an address.
+//
+// An address can claim an output it owns as long as the output didn't expire, in which case the storage deposit
+// needs to be returned to the return address. After the expiration, the output can only be reclaimed
+// by the expiration return address. Whether an output expired is decided with the past and future bounded slots
+// of a transaction that references the commitment of the target slot, so there is a range of slots around
+// the expiration slot in which the output can't be claimed by anyone.
+// The claimed outputs are merged into the fewest possible outputs on the address, see ConsolidationBuilder.
+type ClaimBuilder struct {
+	api           iotago.API
+	address       iotago.Address
+	consolidation *ConsolidationBuilder
+}
+
+// CommitmentInput sets the commitment input of every transaction, which references the commitment of the target slot.
+// Outputs with a timelock or an expiration unlock condition can only be claimed if a commitment input is set.
+func (b *ClaimBuilder) CommitmentInput(commitmentInput *iotago.CommitmentInput) *ClaimBuilder {
+	b.consolidation.CommitmentInput(commitmentInput)
+
+	return b
+}
+
+// MaxInputs sets the maximum amount of inputs of a transaction.
+func (b *ClaimBuilder) MaxInputs(maxInputs int) *ClaimBuilder {
+	b.consolidation.MaxInputs(maxInputs)
+
+	return b
+}
+
+// AllotToBlockIssuer lets every transaction allot the mana that is required to issue it with the given RMC
+// to the given block issuer account, see TransactionBuilder.AllotMinRequiredManaAndStoreRemainingManaInOutput.
+func (b *ClaimBuilder) AllotToBlockIssuer(blockIssuerAccountID iotago.AccountID, rmc iotago.Mana) *ClaimBuilder {
+	b.consolidation.AllotToBlockIssuer(blockIssuerAccountID, rmc)
+
+	return b
+}
+
+// Find returns which of the given outputs can be claimed by the address at the target slot and why the others can't.
+// Outputs without a storage deposit return or an expiration unlock condition have nothing to claim and are ignored.
+func (b *ClaimBuilder) Find(targetSlot iotago.SlotIndex, outputs iotago.OutputSet) *ClaimableOutputs {
+	selector := NewInputSelector(b.api, targetSlot, nil)
+	pastBoundedSlot, futureBoundedSlot := selector.pastBoundedSlot(), selector.futureBoundedSlot()
+
+	outputIDs := make(iotago.OutputIDs, 0, len(outputs))
+	for outputID := range outputs {
+		outputIDs = append(outputIDs, outputID)
+	}
+	slices.SortFunc(outputIDs, func(a iotago.OutputID, b iotago.OutputID) int {
+		return a.Compare(b)
+	})
+
+	result := &ClaimableOutputs{}
+	for _, outputID := range outputIDs {
+		output := outputs[outputID]
+		unlockConditions := output.UnlockConditionSet()
+		storageDepositReturn := unlockConditions.StorageDepositReturn()
+		if storageDepositReturn == nil && !unlockConditions.HasExpirationCondition() {
+			continue
+		}
+
+		txInput := &TxInput{UnlockTarget: b.address, InputID: outputID, Input: output}
+		if err := b.checkClaimable(txInput, pastBoundedSlot, futureBoundedSlot); err != nil {
+			result.NotClaimable = append(result.NotClaimable, &SkippedInput{TxInput: txInput, Reason: err})
+
+			continue
+		}
+
+		expired, _ := unlockConditions.ReturnAddressCanUnlock(futureBoundedSlot)
+		claimable := &ClaimableOutput{TxInput: txInput, Expired: expired}
+
+		// the storage deposit doesn't need to be returned if the output is unlocked by the return address
+		if storageDepositReturn != nil && !storageDepositReturn.ReturnAddress.Equal(b.address) {
+			claimable.ReturnAddress = storageDepositReturn.ReturnAddress
+			claimable.ReturnAmount = storageDepositReturn.Amount
+		}
+
+		result.Claimable = append(result.Claimable, claimable)
+	}
+
+	return result
+}
+
+// checkClaimable returns the reason why the given output can't be claimed by the address or nil.
+func (b *ClaimBuilder) checkClaimable(txInput *TxInput, pastBoundedSlot iotago.SlotIndex, futureBoundedSlot iotago.SlotIndex) error {
+	basicOutput, isBasicOutput := txInput.Input.(*iotago.BasicOutput)
+	if !isBasicOutput {
+		return ierrors.WithMessagef(ErrInputSelectionCandidateNotSupported, "output type %s", txInput.Input.Type())
+	}
+
+	unlockConditions := basicOutput.UnlockConditionSet()
+	if err := unlockConditions.TimelocksExpired(futureBoundedSlot); err != nil {
+		return ierrors.WithMessagef(err, "output is timelocked until slot %d", unlockConditions.Timelock().Slot)
+	}
+
+	returnAddress, err := unlockConditions.CheckExpirationCondition(futureBoundedSlot, pastBoundedSlot)
+	if err != nil {
+		return ierrors.WithMessagef(err, "expiration slot %d is too close to the target slot", unlockConditions.Expiration().Slot)
+	}
+
+	if basicOutput.UnlockableBy(b.address, pastBoundedSlot, futureBoundedSlot) {
+		return nil
+	}
+
+	hrp := b.api.ProtocolParameters().Bech32HRP()
+	switch expiration := unlockConditions.Expiration(); {
+	case returnAddress != nil:
+		return ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "output expired and can only be reclaimed by the return address %s", returnAddress.Bech32(hrp))
+	case expiration != nil && expiration.ReturnAddress.Equal(b.address):
+		return ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "output can be reclaimed after it expires at slot %d", expiration.Slot)
+	default:
+		return ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "output is owned by %s", basicOutput.Owner().Bech32(hrp))
+	}
+}
+
+// Build claims the outputs that can be claimed by the address at the target slot and returns the plan.
+// The storage deposits are returned in the same transactions.
+// The target slot is the slot of the commitment the transactions are going to reference and is used as their creation slot.
+func (b *ClaimBuilder) Build(targetSlot iotago.SlotIndex, outputs iotago.OutputSet) (*ClaimPlan, error) {
+	claimableOutputs := b.Find(targetSlot, outputs)
+
+	plan := &ClaimPlan{NotClaimable: claimableOutputs.NotClaimable}
+	inputs := make([]*TxInput, 0, len(claimableOutputs.Claimable))
+	for _, claimable := range claimableOutputs.Claimable {
+		if err := b.consolidation.checkCommitmentInput(claimable.TxInput); err != nil {
+			plan.NotClaimable = append(plan.NotClaimable, &SkippedInput{TxInput: claimable.TxInput, Reason: err})
+
+			continue
+		}
+
+		plan.Claimed = append(plan.Claimed, claimable)
+		inputs = append(inputs, claimable.TxInput)
+	}
+
+	if len(inputs) == 0 {
+		return nil, ierrors.WithMessagef(ErrClaimNothingToClaim, "%d outputs can not be claimed", len(plan.NotClaimable))
+	}
+
+	transactions, err := b.consolidation.buildChain(targetSlot, inputs)
+	if err != nil {
+		return nil, err
+	}
+	plan.Transactions = transactions
+
+	return plan, nil
+}
@@ -0,0 +1,114 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestClaimBuilder(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	sender := tpkg.RandEd25519Address()
+	targetSlot := iotago.SlotIndex(1000)
+	commitmentInput := &iotago.CommitmentInput{CommitmentID: iotago.NewCommitmentID(targetSlot, tpkg.Rand32ByteArray())}
+
+	// the future bounded slot is 1010 and the past bounded slot is 1020
+	outputIDs := make(iotago.OutputIDs, 9)
+	for i := range outputIDs {
+		outputIDs[i] = tpkg.RandOutputIDWithCreationSlot(10, uint16(i))
+	}
+	outputs := iotago.OutputSet{
+		// claimable by the owner before the expiration
+		outputIDs[0]: builder.NewBasicOutputBuilder(addr, 2_000_000).StorageDepositReturn(sender, 500_000).Expiration(sender, 5000).MustBuild(),
+		outputIDs[1]: builder.NewBasicOutputBuilder(addr, 1_000_000).StorageDepositReturn(sender, 300_000).MustBuild(),
+		// expired, so it is reclaimed by the sender, which is the return address as well
+		outputIDs[2]: builder.NewBasicOutputBuilder(sender, 3_000_000).StorageDepositReturn(addr, 1_000_000).Expiration(addr, 500).MustBuild(),
+		// not expired yet, so it can only be claimed by the owner
+		outputIDs[3]: builder.NewBasicOutputBuilder(sender, 1_000_000).Expiration(addr, 5000).MustBuild(),
+		// expired, so it can only be reclaimed by the sender
+		outputIDs[4]: builder.NewBasicOutputBuilder(addr, 1_000_000).Expiration(sender, 500).MustBuild(),
+		// expires between the future and the past bounded slot
+		outputIDs[5]: builder.NewBasicOutputBuilder(addr, 1_000_000).Expiration(sender, 1015).MustBuild(),
+		outputIDs[6]: builder.NewBasicOutputBuilder(addr, 1_000_000).StorageDepositReturn(sender, 300_000).Timelock(5000).MustBuild(),
+		outputIDs[7]: builder.NewNFTOutputBuilder(addr, 1_000_000).StorageDepositReturn(sender, 300_000).MustBuild(),
+		// nothing to claim
+		outputIDs[8]: builder.NewBasicOutputBuilder(addr, 1_000_000).MustBuild(),
+	}
+
+	t.Run("ok - find", func(t *testing.T) {
+		result := builder.NewClaimBuilder(api, signer, addr).Find(targetSlot, outputs)
+
+		claimable := make(map[iotago.OutputID]*builder.ClaimableOutput)
+		for _, output := range result.Claimable {
+			claimable[output.InputID] = output
+		}
+		require.Len(t, claimable, 3)
+
+		require.True(t, sender.Equal(claimable[outputIDs[0]].ReturnAddress))
+		require.EqualValues(t, 500_000, claimable[outputIDs[0]].ReturnAmount)
+		require.False(t, claimable[outputIDs[0]].Expired)
+		require.EqualValues(t, 300_000, claimable[outputIDs[1]].ReturnAmount)
+		require.Nil(t, claimable[outputIDs[2]].ReturnAddress)
+		require.True(t, claimable[outputIDs[2]].Expired)
+
+		reasons := make(map[iotago.OutputID]error)
+		for _, output := range result.NotClaimable {
+			reasons[output.InputID] = output.Reason
+		}
+		require.Len(t, reasons, 5)
+		require.ErrorIs(t, reasons[outputIDs[3]], builder.ErrInputSelectionCandidateNotUnlockable)
+		require.ErrorIs(t, reasons[outputIDs[4]], builder.ErrInputSelectionCandidateNotUnlockable)
+		require.ErrorIs(t, reasons[outputIDs[5]], iotago.ErrExpirationNotUnlockable)
+		require.ErrorIs(t, reasons[outputIDs[6]], iotago.ErrTimelockNotExpired)
+		require.ErrorIs(t, reasons[outputIDs[7]], builder.ErrInputSelectionCandidateNotSupported)
+	})
+
+	t.Run("ok - build", func(t *testing.T) {
+		plan, err := builder.NewClaimBuilder(api, signer, addr).CommitmentInput(commitmentInput).Build(targetSlot, outputs)
+		require.NoError(t, err)
+		require.Len(t, plan.Claimed, 3)
+		require.Len(t, plan.NotClaimable, 5)
+		require.Len(t, plan.Transactions, 1)
+
+		transaction := plan.Transactions[0]
+		inputSet := vm.InputSet{}
+		for _, input := range transaction.Inputs {
+			inputSet[input.InputID] = input.Input
+		}
+
+		novaVM := nova.NewVirtualMachine()
+		resolvedInputs := vm.ResolvedInputs{InputSet: inputSet, CommitmentInput: &iotago.Commitment{Slot: targetSlot}}
+		unlockedAddrs, err := novaVM.ValidateUnlocks(transaction.SignedTransaction, resolvedInputs)
+		require.NoError(t, err)
+		require.NoError(t, lo.Return2(novaVM.Execute(transaction.SignedTransaction.Transaction, resolvedInputs, unlockedAddrs)))
+
+		// the storage deposits are returned to the sender, the rest is claimed by the address
+		require.Equal(t, map[string]iotago.BaseToken{sender.Key(): 800_000}, transaction.Returned)
+		require.Len(t, transaction.Consolidated, 1)
+		require.EqualValues(t, 6_000_000-800_000, transaction.Consolidated[0].Input.BaseTokenAmount())
+		require.True(t, addr.Equal(transaction.Consolidated[0].Input.(*iotago.BasicOutput).Owner()))
+	})
+
+	t.Run("ok - build without commitment input", func(t *testing.T) {
+		plan, err := builder.NewClaimBuilder(api, signer, addr).Build(targetSlot, outputs)
+		require.NoError(t, err)
+		require.Len(t, plan.Claimed, 1)
+		require.Equal(t, outputIDs[1], plan.Claimed[0].InputID)
+		require.Len(t, plan.NotClaimable, 7)
+	})
+
+	t.Run("fail - nothing to claim", func(t *testing.T) {
+		_, err := builder.NewClaimBuilder(api, signer, addr).Build(targetSlot, iotago.OutputSet{outputIDs[3]: outputs[outputIDs[3]], outputIDs[8]: outputs[outputIDs[8]]})
+		require.ErrorIs(t, err, builder.ErrClaimNothingToClaim)
+	})
+}
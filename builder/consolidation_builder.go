@@ -0,0 +1,350 @@
+package builder
+
+import (
+	"math/big"
+	"slices"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// ErrConsolidationNotNeeded gets returned if less than two of the candidates can be consolidated.
+var ErrConsolidationNotNeeded = ierrors.New("less than two outputs can be consolidated")
+
+// ConsolidationTransaction is a transaction of a ConsolidationPlan.
+type ConsolidationTransaction struct {
+	// The signed transaction.
+	SignedTransaction *iotago.SignedTransaction
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The inputs consumed by the transaction, which may include the consolidated outputs of the transaction before.
+	Inputs []*TxInput
+	// The consolidated outputs of the transaction, which are consumed by the following transaction of the plan.
+	Consolidated []*TxInput
+	// The storage deposits returned by the transaction, mapped by the key of the return address.
+	Returned map[string]iotago.BaseToken
+}
+
+// ConsolidationPlan is the outcome of a ConsolidationBuilder.
+// The transactions need to be issued in order, since every transaction consumes the consolidated outputs
+// of the transaction before it. All transactions have the same creation slot, so they can be issued within one slot.
+type ConsolidationPlan struct {
+	Transactions []*ConsolidationTransaction
+	// The candidates that can not be consolidated.
+	Skipped []*SkippedInput
+}
+
+// Consolidated returns the consolidated outputs of the last transaction of the plan.
+func (p *ConsolidationPlan) Consolidated() []*TxInput {
+	if len(p.Transactions) == 0 {
+		return nil
+	}
+
+	return p.Transactions[len(p.Transactions)-1].Consolidated
+}
+
+// NewConsolidationBuilder creates a new ConsolidationBuilder which consolidates the outputs on the given address.
+func NewConsolidationBuilder(api iotago.API, signer iotago.AddressSigner, targetAddress iotago.Address) *ConsolidationBuilder {
+	return &ConsolidationBuilder{
+		api:           api,
+		signer:        signer,
+		targetAddress: targetAddress,
+		maxInputs:     iotago.MaxInputsCount,
+	}
+}
+
+// ConsolidationBuilder merges many basic outputs into the fewest possible outputs.
+//
+// Outputs with a storage deposit return unlock condition are consolidated as well, the return amount is sent back
+// to the return address unless the output is unlocked by the return address. Outputs with an expiration unlock condition
+// are consolidated if they can be unlocked by their unlock target, either before or after the expiration.
+// All mana of the inputs, including the potential mana, is stored in the first consolidated output.
+type ConsolidationBuilder struct {
+	api                 iotago.API
+	signer              iotago.AddressSigner
+	targetAddress       iotago.Address
+	includeNativeTokens bool
+	maxInputs           int
+	commitmentInput     *iotago.CommitmentInput
+
+	// the block issuer account the mana for the block issuance is allotted to, if set.
+	blockIssuerAccountID iotago.AccountID
+	rmc                  iotago.Mana
+	allotToBlockIssuer   bool
+}
+
+// IncludeNativeTokens sets whether outputs holding native tokens are consolidated.
+// The native tokens are merged into one output per native token.
+func (b *ConsolidationBuilder) IncludeNativeTokens(includeNativeTokens bool) *ConsolidationBuilder {
+	b.includeNativeTokens = includeNativeTokens
+
+	return b
+}
+
+// MaxInputs sets the maximum amount of inputs of a transaction, e.g. to limit the work score of the transactions.
+func (b *ConsolidationBuilder) MaxInputs(maxInputs int) *ConsolidationBuilder {
+	b.maxInputs = min(maxInputs, iotago.MaxInputsCount)
+
+	return b
+}
+
+// CommitmentInput sets the commitment input of every transaction, which references the commitment of the target slot.
+// Outputs with a timelock or an expiration unlock condition can only be consolidated if a commitment input is set.
+func (b *ConsolidationBuilder) CommitmentInput(commitmentInput *iotago.CommitmentInput) *ConsolidationBuilder {
+	b.commitmentInput = commitmentInput
+
+	return b
+}
+
+// AllotToBlockIssuer lets every transaction allot the mana that is required to issue it with the given RMC
+// to the given block issuer account, see TransactionBuilder.AllotMinRequiredManaAndStoreRemainingManaInOutput.
+func (b *ConsolidationBuilder) AllotToBlockIssuer(blockIssuerAccountID iotago.AccountID, rmc iotago.Mana) *ConsolidationBuilder {
+	b.blockIssuerAccountID = blockIssuerAccountID
+	b.rmc = rmc
+	b.allotToBlockIssuer = true
+
+	return b
+}
+
+// Build consolidates the candidates that can be unlocked at the target slot and returns the plan.
+// If the candidates exceed the maximum amount of inputs, they are consolidated in a chain of transactions.
+// The target slot is the slot of the commitment the transactions are going to reference and is used as their creation slot.
+func (b *ConsolidationBuilder) Build(targetSlot iotago.SlotIndex, candidates []*TxInput) (*ConsolidationPlan, error) {
+	selector := NewInputSelector(b.api, targetSlot, nil)
+
+	plan := &ConsolidationPlan{}
+	eligible := make([]*TxInput, 0, len(candidates))
+	seen := make(map[iotago.OutputID]struct{}, len(candidates))
+	for _, candidate := range candidates {
+		if _, isDuplicate := seen[candidate.InputID]; isDuplicate {
+			continue
+		}
+		seen[candidate.InputID] = struct{}{}
+
+		if _, err := selector.checkUnlockable(candidate); err != nil {
+			plan.Skipped = append(plan.Skipped, &SkippedInput{TxInput: candidate, Reason: err})
+
+			continue
+		}
+
+		if err := b.checkCommitmentInput(candidate); err != nil {
+			plan.Skipped = append(plan.Skipped, &SkippedInput{TxInput: candidate, Reason: err})
+
+			continue
+		}
+
+		if nativeToken := candidate.Input.FeatureSet().NativeToken(); nativeToken != nil && !b.includeNativeTokens {
+			plan.Skipped = append(plan.Skipped, &SkippedInput{
+				TxInput: candidate,
+				Reason:  ierrors.WithMessagef(ErrInputSelectionCandidateNotSupported, "holds native token %s", nativeToken.ID.ToHex()),
+			})
+
+			continue
+		}
+
+		eligible = append(eligible, candidate)
+	}
+
+	if len(eligible) < 2 {
+		return nil, ierrors.WithMessagef(ErrConsolidationNotNeeded, "%d of %d candidates can be consolidated", len(eligible), len(candidates))
+	}
+
+	slices.SortStableFunc(eligible, func(a *TxInput, b *TxInput) int {
+		return a.InputID.Compare(b.InputID)
+	})
+
+	transactions, err := b.buildChain(targetSlot, eligible)
+	if err != nil {
+		return nil, err
+	}
+	plan.Transactions = transactions
+
+	return plan, nil
+}
+
+// buildChain builds the transactions that consolidate the given inputs, where every transaction
+// consumes the consolidated outputs of the transaction before.
+func (b *ConsolidationBuilder) buildChain(targetSlot iotago.SlotIndex, eligible []*TxInput) ([]*ConsolidationTransaction, error) {
+	var transactions []*ConsolidationTransaction
+	var consolidated []*TxInput
+	for start := 0; start < len(eligible); {
+		count := min(len(eligible)-start, b.maxInputs-len(consolidated))
+		if count <= 0 {
+			return nil, ierrors.WithMessagef(ErrTransactionBuilder, "the %d consolidated outputs exceed the maximum amount of inputs %d", len(consolidated), b.maxInputs)
+		}
+
+		inputs := append(slices.Clone(consolidated), eligible[start:start+count]...)
+		transaction, err := b.buildTransaction(targetSlot, inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, transaction)
+		consolidated = transaction.Consolidated
+		start += count
+	}
+
+	return transactions, nil
+}
+
+// checkCommitmentInput returns an error if the given candidate can't be consumed without a commitment input.
+func (b *ConsolidationBuilder) checkCommitmentInput(candidate *TxInput) error {
+	if b.commitmentInput != nil {
+		return nil
+	}
+
+	unlockConditions := candidate.Input.UnlockConditionSet()
+	if unlockConditions.HasTimelockCondition() {
+		return iotago.ErrTimelockCommitmentInputMissing
+	}
+	if unlockConditions.HasExpirationCondition() {
+		return iotago.ErrExpirationCommitmentInputMissing
+	}
+
+	return nil
+}
+
+// buildTransaction builds a transaction that consolidates the given inputs.
+func (b *ConsolidationBuilder) buildTransaction(targetSlot iotago.SlotIndex, inputs []*TxInput) (*ConsolidationTransaction, error) {
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+	if b.commitmentInput != nil {
+		txBuilder.AddCommitmentInput(b.commitmentInput)
+	}
+
+	var baseTokens iotago.BaseToken
+	nativeTokens := make(iotago.NativeTokenSum)
+	returnAddresses := make(map[string]iotago.Address)
+	returned := make(map[string]iotago.BaseToken)
+	for _, input := range inputs {
+		txBuilder.AddInput(input)
+
+		var err error
+		if baseTokens, err = safemath.SafeAdd(baseTokens, input.Input.BaseTokenAmount()); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the base tokens of the input")
+		}
+
+		if nativeToken := input.Input.FeatureSet().NativeToken(); nativeToken != nil {
+			addNativeToken(nativeTokens, nativeToken.ID, nativeToken.Amount)
+		}
+
+		// the storage deposit doesn't need to be returned if the output is unlocked by the return address
+		storageDepositReturn := input.Input.UnlockConditionSet().StorageDepositReturn()
+		if storageDepositReturn == nil || storageDepositReturn.ReturnAddress.Equal(input.UnlockTarget) {
+			continue
+		}
+
+		returnAddressKey := storageDepositReturn.ReturnAddress.Key()
+		returnAddresses[returnAddressKey] = storageDepositReturn.ReturnAddress
+		if returned[returnAddressKey], err = safemath.SafeAdd(returned[returnAddressKey], storageDepositReturn.Amount); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the storage deposit return of the input")
+		}
+	}
+
+	// the deposits are returned in simple transfers, which are accumulated per address by the VM
+	returnAddressKeys := make([]string, 0, len(returned))
+	for returnAddressKey := range returned {
+		returnAddressKeys = append(returnAddressKeys, returnAddressKey)
+	}
+	slices.Sort(returnAddressKeys)
+
+	returnOutputs := make([]*iotago.BasicOutput, 0, len(returned))
+	for _, returnAddressKey := range returnAddressKeys {
+		amount := returned[returnAddressKey]
+		if amount > baseTokens {
+			return nil, ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered, "the inputs don't cover the return amounts")
+		}
+		baseTokens -= amount
+
+		returnOutputs = append(returnOutputs, NewBasicOutputBuilder(returnAddresses[returnAddressKey], amount).MustBuild())
+	}
+
+	consolidatedOutputs, err := b.consolidatedOutputs(baseTokens, nativeTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	// the consolidated outputs come first, so that the mana is stored in the first output
+	for _, output := range consolidatedOutputs {
+		txBuilder.AddOutput(output)
+	}
+	for _, output := range returnOutputs {
+		txBuilder.AddOutput(output)
+	}
+
+	if b.allotToBlockIssuer {
+		txBuilder.AllotMinRequiredManaAndStoreRemainingManaInOutput(targetSlot, b.rmc, b.blockIssuerAccountID, 0)
+	} else {
+		txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, 0)
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	consolidated := make([]*TxInput, 0, len(consolidatedOutputs))
+	for index := range consolidatedOutputs {
+		consolidated = append(consolidated, &TxInput{
+			UnlockTarget: b.targetAddress,
+			InputID:      iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:        signedTx.Transaction.Outputs[index],
+		})
+	}
+
+	return &ConsolidationTransaction{
+		SignedTransaction: signedTx,
+		TransactionID:     transactionID,
+		Inputs:            inputs,
+		Consolidated:      consolidated,
+		Returned:          returned,
+	}, nil
+}
+
+// consolidatedOutputs returns the outputs holding the given base tokens and native tokens on the target address.
+// Every native token is put into its own output that holds its minimum storage deposit,
+// the remaining base tokens are added to the first output.
+func (b *ConsolidationBuilder) consolidatedOutputs(baseTokens iotago.BaseToken, nativeTokens iotago.NativeTokenSum) ([]*iotago.BasicOutput, error) {
+	storageScoreStructure := b.api.StorageScoreStructure()
+
+	outputs := make([]*iotago.BasicOutput, 0, max(1, len(nativeTokens)))
+	for _, nativeTokenID := range sortedNativeTokenIDs(nativeTokens) {
+		outputs = append(outputs, NewBasicOutputBuilder(b.targetAddress, 0).
+			NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: new(big.Int).Set(nativeTokens[nativeTokenID])}).
+			MustBuild())
+	}
+	if len(outputs) == 0 {
+		outputs = append(outputs, NewBasicOutputBuilder(b.targetAddress, 0).MustBuild())
+	}
+
+	// the deposits of all but the first output, which receives the remaining base tokens
+	for _, output := range outputs[1:] {
+		minDeposit, err := storageScoreStructure.MinDeposit(output)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to calculate the minimum deposit of the consolidated output")
+		}
+
+		if minDeposit > baseTokens {
+			return nil, ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered, "the inputs don't cover the minimum deposit of the %d consolidated outputs", len(outputs))
+		}
+		output.Amount = minDeposit
+		baseTokens -= minDeposit
+	}
+	outputs[0].Amount = baseTokens
+
+	minDeposit, err := storageScoreStructure.MinDeposit(outputs[0])
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the minimum deposit of the consolidated output")
+	}
+
+	if baseTokens < minDeposit {
+		return nil, ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered, "the remaining %d base tokens don't cover the minimum deposit of %d", baseTokens, minDeposit)
+	}
+
+	return outputs, nil
+}
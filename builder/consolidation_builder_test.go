@@ -0,0 +1,172 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestConsolidationBuilder(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	returnAddr := tpkg.RandEd25519Address()
+	otherAddr := tpkg.RandEd25519Address()
+	targetSlot := iotago.SlotIndex(1000)
+	commitmentInput := &iotago.CommitmentInput{CommitmentID: iotago.NewCommitmentID(targetSlot, tpkg.Rand32ByteArray())}
+	nativeToken := tpkg.RandNativeTokenFeature()
+
+	var outputIndex uint16
+	newInput := func(unlockTarget iotago.Address, output *iotago.BasicOutput) *builder.TxInput {
+		outputIndex++
+
+		return &builder.TxInput{UnlockTarget: unlockTarget, InputID: tpkg.RandOutputIDWithCreationSlot(10, outputIndex), Input: output}
+	}
+
+	simple := func(count int) []*builder.TxInput {
+		inputs := make([]*builder.TxInput, count)
+		for i := range inputs {
+			inputs[i] = newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).Mana(1_000).MustBuild())
+		}
+
+		return inputs
+	}
+
+	// validate executes the transactions of the plan in order against the nova VM.
+	validate := func(t *testing.T, plan *builder.ConsolidationPlan) {
+		t.Helper()
+
+		novaVM := nova.NewVirtualMachine()
+		for _, transaction := range plan.Transactions {
+			inputSet := vm.InputSet{}
+			for _, input := range transaction.Inputs {
+				inputSet[input.InputID] = input.Input
+			}
+
+			resolvedInputs := vm.ResolvedInputs{InputSet: inputSet, CommitmentInput: &iotago.Commitment{Slot: targetSlot}}
+			unlockedAddrs, err := novaVM.ValidateUnlocks(transaction.SignedTransaction, resolvedInputs)
+			require.NoError(t, err)
+			require.NoError(t, lo.Return2(novaVM.Execute(transaction.SignedTransaction.Transaction, resolvedInputs, unlockedAddrs)))
+		}
+	}
+
+	t.Run("ok - storage deposit return and expiration", func(t *testing.T) {
+		candidates := append(simple(3),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 2_000_000).StorageDepositReturn(returnAddr, 500_000).MustBuild()),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 3_000_000).StorageDepositReturn(returnAddr, 700_000).MustBuild()),
+			// expired, so it can be unlocked by the return address without returning the storage deposit
+			newInput(addr, builder.NewBasicOutputBuilder(otherAddr, 4_000_000).StorageDepositReturn(addr, 1_000_000).Expiration(addr, 10).MustBuild()),
+			// not expired yet, so it can still be unlocked by its owner
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 5_000_000).Expiration(otherAddr, 10_000).MustBuild()),
+		)
+
+		plan, err := builder.NewConsolidationBuilder(api, signer, addr).CommitmentInput(commitmentInput).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Transactions, 1)
+		require.Empty(t, plan.Skipped)
+		validate(t, plan)
+
+		outputs := plan.Transactions[0].SignedTransaction.Transaction.Outputs
+		require.Len(t, outputs, 2)
+		require.EqualValues(t, 3_000_000+2_000_000+3_000_000+4_000_000+5_000_000-1_200_000, outputs[0].BaseTokenAmount())
+		require.True(t, returnAddr.Equal(outputs[1].(*iotago.BasicOutput).Owner()))
+		require.EqualValues(t, 1_200_000, outputs[1].BaseTokenAmount())
+		require.Equal(t, map[string]iotago.BaseToken{returnAddr.Key(): 1_200_000}, plan.Transactions[0].Returned)
+
+		require.Len(t, plan.Consolidated(), 1)
+		require.True(t, addr.Equal(plan.Consolidated()[0].Input.(*iotago.BasicOutput).Owner()))
+		require.NotZero(t, plan.Consolidated()[0].Input.StoredMana())
+	})
+
+	t.Run("ok - chained by max inputs", func(t *testing.T) {
+		candidates := simple(12)
+
+		plan, err := builder.NewConsolidationBuilder(api, signer, addr).MaxInputs(5).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Transactions, 3)
+		validate(t, plan)
+
+		// every transaction consumes the consolidated output of the transaction before
+		for i, transaction := range plan.Transactions {
+			require.LessOrEqual(t, len(transaction.Inputs), 5)
+			if i > 0 {
+				require.Equal(t, plan.Transactions[i-1].Consolidated[0].InputID, transaction.Inputs[0].InputID)
+			}
+		}
+		require.EqualValues(t, 12_000_000, plan.Consolidated()[0].Input.BaseTokenAmount())
+	})
+
+	t.Run("ok - native tokens", func(t *testing.T) {
+		candidates := append(simple(2),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).NativeToken(&iotago.NativeTokenFeature{ID: nativeToken.ID, Amount: big.NewInt(10)}).MustBuild()),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).NativeToken(&iotago.NativeTokenFeature{ID: nativeToken.ID, Amount: big.NewInt(20)}).MustBuild()),
+		)
+
+		plan, err := builder.NewConsolidationBuilder(api, signer, addr).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Skipped, 2)
+		require.ErrorIs(t, plan.Skipped[0].Reason, builder.ErrInputSelectionCandidateNotSupported)
+		validate(t, plan)
+
+		plan, err = builder.NewConsolidationBuilder(api, signer, addr).IncludeNativeTokens(true).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Empty(t, plan.Skipped)
+		validate(t, plan)
+
+		consolidated := plan.Consolidated()
+		require.Len(t, consolidated, 1)
+		require.EqualValues(t, 4_000_000, consolidated[0].Input.BaseTokenAmount())
+		require.Equal(t, nativeToken.ID, consolidated[0].Input.FeatureSet().NativeToken().ID)
+		require.EqualValues(t, 30, consolidated[0].Input.FeatureSet().NativeToken().Amount.Int64())
+	})
+
+	t.Run("ok - skip candidates", func(t *testing.T) {
+		candidates := append(simple(2),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).Timelock(targetSlot+100).MustBuild()),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).Expiration(otherAddr, 10).MustBuild()),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).Timelock(10).MustBuild()),
+		)
+
+		plan, err := builder.NewConsolidationBuilder(api, signer, addr).CommitmentInput(commitmentInput).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Skipped, 2)
+		require.ErrorIs(t, plan.Skipped[0].Reason, iotago.ErrTimelockNotExpired)
+		require.ErrorIs(t, plan.Skipped[1].Reason, builder.ErrInputSelectionCandidateNotUnlockable)
+		require.Len(t, plan.Transactions[0].Inputs, 3)
+		validate(t, plan)
+
+		// the expired timelock can't be consolidated without a commitment input
+		plan, err = builder.NewConsolidationBuilder(api, signer, addr).Build(targetSlot, candidates)
+		require.NoError(t, err)
+		require.Len(t, plan.Skipped, 3)
+		require.ErrorIs(t, plan.Skipped[2].Reason, iotago.ErrTimelockCommitmentInputMissing)
+	})
+
+	t.Run("fail - storage deposit return overflow", func(t *testing.T) {
+		candidates := []*builder.TxInput{
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).StorageDepositReturn(returnAddr, math.MaxUint64/2+1).MustBuild()),
+			newInput(addr, builder.NewBasicOutputBuilder(addr, 1_000_000).StorageDepositReturn(returnAddr, math.MaxUint64/2+1).MustBuild()),
+		}
+
+		_, err := builder.NewConsolidationBuilder(api, signer, addr).CommitmentInput(commitmentInput).Build(targetSlot, candidates)
+		require.ErrorIs(t, err, safemath.ErrIntegerOverflow)
+	})
+
+	t.Run("fail - consolidation not needed", func(t *testing.T) {
+		candidates := append(simple(1), newInput(addr, builder.NewBasicOutputBuilder(otherAddr, 1_000_000).MustBuild()))
+
+		_, err := builder.NewConsolidationBuilder(api, signer, addr).Build(targetSlot, candidates)
+		require.ErrorIs(t, err, builder.ErrConsolidationNotNeeded)
+	})
+}
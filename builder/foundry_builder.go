@@ -0,0 +1,390 @@
+package builder
+
+import (
+	"math/big"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+var (
+	// ErrFoundryAccountMismatch gets returned if a foundry is not controlled by the account of the FoundryBuilder.
+	ErrFoundryAccountMismatch = ierrors.New("foundry is not controlled by the account")
+	// ErrFoundryTransitionInvalid gets returned if a transaction built by the FoundryBuilder violates the foundry state transition rules.
+	ErrFoundryTransitionInvalid = ierrors.New("invalid foundry transition")
+)
+
+// MintRecipient is a recipient of native tokens minted by a FoundryBuilder.
+type MintRecipient struct {
+	// The address the native tokens are sent to.
+	Address iotago.Address
+	// The amount of native tokens to mint.
+	Amount *big.Int
+}
+
+// FoundryTransaction is a transaction built by a FoundryBuilder.
+type FoundryTransaction struct {
+	// The signed transaction.
+	SignedTransaction *iotago.SignedTransaction
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The next state of the account, nil if the account was not transitioned.
+	Account *TxInput
+	// The next state of the foundry, nil if no foundry was transitioned or if it was destroyed.
+	Foundry *TxInput
+	// The remainder outputs of the transaction.
+	Remainders []*TxInput
+}
+
+// NewFoundryBuilder creates a new FoundryBuilder for the foundries controlled by the given account,
+// which sends the remainders to the given address.
+// The inputs funding the transactions are selected with the LargestFirst strategy, unless another strategy is set.
+func NewFoundryBuilder(api iotago.API, signer iotago.AddressSigner, account *TxInput, remainderAddress iotago.Address) *FoundryBuilder {
+	return &FoundryBuilder{
+		api:              api,
+		signer:           signer,
+		account:          account,
+		remainderAddress: remainderAddress,
+		strategy:         NewLargestFirstStrategy(),
+	}
+}
+
+// FoundryBuilder builds the transactions of the lifecycle of native tokens: creating a foundry with a SimpleTokenScheme,
+// minting, melting and burning native tokens and destroying a foundry.
+//
+// Every transition of a foundry requires the transition of its controlling account, which is added automatically.
+// The builder keeps track of the account, so the transactions can be built in a row and need to be issued in that order.
+// The mana of the inputs is stored in the account output. Every transaction is checked against the foundry state
+// transition rules of the VM before it is returned.
+type FoundryBuilder struct {
+	api              iotago.API
+	signer           iotago.AddressSigner
+	account          *TxInput
+	remainderAddress iotago.Address
+	strategy         InputSelectionStrategy
+}
+
+// Strategy sets the strategy used to select the inputs funding the transactions.
+func (b *FoundryBuilder) Strategy(strategy InputSelectionStrategy) *FoundryBuilder {
+	b.strategy = strategy
+
+	return b
+}
+
+// Account returns the current state of the account controlling the foundries.
+func (b *FoundryBuilder) Account() *TxInput {
+	return b.account
+}
+
+// CreateFoundry creates a new foundry with a SimpleTokenScheme and the given maximum supply.
+// The storage deposit of the foundry is funded by the given candidates.
+func (b *FoundryBuilder) CreateFoundry(targetSlot iotago.SlotIndex, maximumSupply *big.Int, candidates []*TxInput) (*FoundryTransaction, error) {
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+
+	nextAccount, err := b.transitionAccount(txBuilder, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	foundryOutput := NewFoundryOutputBuilder(nextAccount.AccountID.ToAddress().(*iotago.AccountAddress), 0, nextAccount.FoundryCounter, &iotago.SimpleTokenScheme{
+		MintedTokens:  big.NewInt(0),
+		MeltedTokens:  big.NewInt(0),
+		MaximumSupply: new(big.Int).Set(maximumSupply),
+	}).MustBuild()
+
+	if foundryOutput.Amount, err = b.api.StorageScoreStructure().MinDeposit(foundryOutput); err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the minimum deposit of the foundry")
+	}
+	txBuilder.AddOutput(foundryOutput)
+
+	return b.build(targetSlot, txBuilder, candidates)
+}
+
+// Mint mints native tokens of the given foundry and sends them to the recipients.
+// The storage deposits of the outputs holding the native tokens are funded by the given candidates.
+func (b *FoundryBuilder) Mint(targetSlot iotago.SlotIndex, foundry *TxInput, recipients []*MintRecipient, candidates []*TxInput) (*FoundryTransaction, error) {
+	minted := new(big.Int)
+	for _, recipient := range recipients {
+		minted.Add(minted, recipient.Amount)
+	}
+
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+	nextFoundry, err := b.transitionFoundry(txBuilder, foundry, func(tokenScheme *iotago.SimpleTokenScheme) {
+		tokenScheme.MintedTokens.Add(tokenScheme.MintedTokens, minted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nativeTokenID := nextFoundry.MustNativeTokenID()
+	for _, recipient := range recipients {
+		output := NewBasicOutputBuilder(recipient.Address, 0).
+			NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: new(big.Int).Set(recipient.Amount)}).
+			MustBuild()
+
+		if output.Amount, err = b.api.StorageScoreStructure().MinDeposit(output); err != nil {
+			return nil, ierrors.Wrap(err, "failed to calculate the minimum deposit of the recipient output")
+		}
+		txBuilder.AddOutput(output)
+	}
+
+	return b.build(targetSlot, txBuilder, candidates)
+}
+
+// Melt melts the given amount of native tokens of the given foundry, which are taken from the given candidates.
+func (b *FoundryBuilder) Melt(targetSlot iotago.SlotIndex, foundry *TxInput, amount *big.Int, candidates []*TxInput) (*FoundryTransaction, error) {
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+	if _, err := b.transitionFoundry(txBuilder, foundry, func(tokenScheme *iotago.SimpleTokenScheme) {
+		tokenScheme.MeltedTokens.Add(tokenScheme.MeltedTokens, amount)
+	}); err != nil {
+		return nil, err
+	}
+
+	return b.build(targetSlot, txBuilder, candidates)
+}
+
+// Burn burns the given amount of native tokens, which are taken from the given candidates.
+// Burning doesn't transition the foundry, so the burned tokens still count to its circulating supply.
+// Use Melt instead to reduce the circulating supply, which is required to destroy a foundry.
+func (b *FoundryBuilder) Burn(targetSlot iotago.SlotIndex, nativeTokenID iotago.NativeTokenID, amount *big.Int, candidates []*TxInput) (*FoundryTransaction, error) {
+	txBuilder := NewTransactionBuilder(b.api, b.signer).
+		SetCreationSlot(targetSlot).
+		WithTransactionCapabilities(iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanBurnNativeTokens(true))).
+		BurnNativeToken(nativeTokenID, amount)
+
+	return b.build(targetSlot, txBuilder, candidates)
+}
+
+// DestroyFoundry destroys the given foundry, which requires all of its minted native tokens to be melted.
+// The storage deposit of the foundry is sent to the remainder address.
+func (b *FoundryBuilder) DestroyFoundry(targetSlot iotago.SlotIndex, foundry *TxInput, candidates []*TxInput) (*FoundryTransaction, error) {
+	if err := b.checkFoundry(foundry); err != nil {
+		return nil, err
+	}
+
+	txBuilder := NewTransactionBuilder(b.api, b.signer).
+		SetCreationSlot(targetSlot).
+		WithTransactionCapabilities(iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDestroyFoundryOutputs(true)))
+
+	if _, err := b.transitionAccount(txBuilder, 0); err != nil {
+		return nil, err
+	}
+	txBuilder.AddInput(foundry)
+
+	return b.build(targetSlot, txBuilder, candidates)
+}
+
+// accountID returns the ID of the account, which is derived from its output ID if the account was just created.
+func (b *FoundryBuilder) accountID() (iotago.AccountID, error) {
+	accountOutput, isAccount := b.account.Input.(*iotago.AccountOutput)
+	if !isAccount {
+		return iotago.EmptyAccountID, ierrors.WithMessagef(ErrTransactionBuilder, "controlling output is of type %s instead of an account", b.account.Input.Type())
+	}
+
+	if accountOutput.AccountID.Empty() {
+		return iotago.AccountIDFromOutputID(b.account.InputID), nil
+	}
+
+	return accountOutput.AccountID, nil
+}
+
+// transitionAccount adds the account as the first input and its next state as the first output of the transaction.
+// The mana of the next state is set later on, when the remaining mana of the transaction is stored in it.
+func (b *FoundryBuilder) transitionAccount(txBuilder *TransactionBuilder, foundriesToGenerate uint32) (*iotago.AccountOutput, error) {
+	accountID, err := b.accountID()
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert // the type was checked by accountID
+	nextAccount, err := NewAccountOutputBuilderFromPrevious(b.account.Input.(*iotago.AccountOutput)).
+		AccountID(accountID).
+		Mana(0).
+		FoundriesToGenerate(foundriesToGenerate).
+		Build()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to build the next state of the account")
+	}
+
+	txBuilder.AddInput(b.account)
+	txBuilder.AddOutput(nextAccount)
+
+	return nextAccount, nil
+}
+
+// checkFoundry returns an error if the given foundry is not controlled by the account.
+func (b *FoundryBuilder) checkFoundry(foundry *TxInput) error {
+	foundryOutput, isFoundry := foundry.Input.(*iotago.FoundryOutput)
+	if !isFoundry {
+		return ierrors.WithMessagef(ErrTransactionBuilder, "output of type %s is not a foundry", foundry.Input.Type())
+	}
+
+	accountID, err := b.accountID()
+	if err != nil {
+		return err
+	}
+
+	if !foundryOutput.Owner().Equal(accountID.ToAddress()) {
+		return ierrors.WithMessagef(ErrFoundryAccountMismatch, "foundry %s is controlled by %s", foundryOutput.MustFoundryID().ToHex(), foundryOutput.Owner().Bech32(b.api.ProtocolParameters().Bech32HRP()))
+	}
+
+	return nil
+}
+
+// transitionFoundry adds the account and the foundry as inputs and their next states as outputs of the transaction.
+// The given function modifies the token scheme of the next state of the foundry.
+func (b *FoundryBuilder) transitionFoundry(txBuilder *TransactionBuilder, foundry *TxInput, modifyTokenScheme func(tokenScheme *iotago.SimpleTokenScheme)) (*iotago.FoundryOutput, error) {
+	if err := b.checkFoundry(foundry); err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert // the type was checked by checkFoundry
+	foundryOutput := foundry.Input.(*iotago.FoundryOutput)
+	tokenScheme, isSimple := foundryOutput.TokenScheme.(*iotago.SimpleTokenScheme)
+	if !isSimple {
+		return nil, ierrors.WithMessagef(ErrTransactionBuilder, "token scheme of type %d is not supported", foundryOutput.TokenScheme.Type())
+	}
+
+	if _, err := b.transitionAccount(txBuilder, 0); err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert // the token scheme is a copy of a SimpleTokenScheme
+	nextTokenScheme := tokenScheme.Clone().(*iotago.SimpleTokenScheme)
+	modifyTokenScheme(nextTokenScheme)
+
+	// the maximum supply is not part of the state transition rules, but of the syntactic validation
+	if err := nextTokenScheme.SyntacticalValidation(); err != nil {
+		return nil, ierrors.Join(ErrFoundryTransitionInvalid, err)
+	}
+
+	nextFoundry, err := NewFoundryOutputBuilderFromPrevious(foundryOutput).TokenScheme(nextTokenScheme).Build()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to build the next state of the foundry")
+	}
+
+	txBuilder.AddInput(foundry)
+	txBuilder.AddOutput(nextFoundry)
+
+	return nextFoundry, nil
+}
+
+// build funds the transaction with the given candidates, adds the remainder outputs, stores the remaining mana
+// and checks the foundry transitions of the transaction.
+func (b *FoundryBuilder) build(targetSlot iotago.SlotIndex, txBuilder *TransactionBuilder, candidates []*TxInput) (*FoundryTransaction, error) {
+	if _, err := txBuilder.SelectInputs(targetSlot, b.strategy, 0, candidates); err != nil {
+		return nil, err
+	}
+
+	// the account is always the first output if it is transitioned
+	if transitionsAccount := len(txBuilder.transaction.Outputs) > 0 && txBuilder.transaction.Outputs[0].Type() == iotago.OutputAccount; transitionsAccount {
+		txBuilder.AddRemainderOutputs(b.remainderAddress).
+			StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, 0)
+	} else {
+		txBuilder.AddRemainderOutputsAndStoreRemainingMana(targetSlot, b.remainderAddress)
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyFoundryTransitions(b.api, signedTx, txBuilder.inputs); err != nil {
+		return nil, ierrors.Join(ErrFoundryTransitionInvalid, err)
+	}
+
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	transaction := &FoundryTransaction{
+		SignedTransaction: signedTx,
+		TransactionID:     transactionID,
+	}
+
+	for index, output := range signedTx.Transaction.Outputs {
+		txInput := &TxInput{
+			InputID: iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:   output,
+		}
+
+		switch output := output.(type) {
+		case *iotago.AccountOutput:
+			txInput.UnlockTarget = output.Owner()
+			transaction.Account = txInput
+		case *iotago.FoundryOutput:
+			txInput.UnlockTarget = output.Owner()
+			transaction.Foundry = txInput
+		}
+	}
+
+	for _, index := range txBuilder.RemainderOutputIndexes() {
+		transaction.Remainders = append(transaction.Remainders, &TxInput{
+			UnlockTarget: b.remainderAddress,
+			InputID:      iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:        signedTx.Transaction.Outputs[index],
+		})
+	}
+
+	if transaction.Account != nil {
+		b.account = transaction.Account
+	}
+
+	return transaction, nil
+}
+
+// verifyFoundryTransitions executes the native token balance checks and the state transition validation functions
+// of the foundries transitioned by the given transaction.
+func verifyFoundryTransitions(api iotago.API, signedTx *iotago.SignedTransaction, inputs iotago.OutputSet) error {
+	inputSet := make(vm.InputSet, len(inputs))
+	for outputID, input := range inputs {
+		inputSet[outputID] = input
+	}
+
+	workingSet, err := nova.NewVMParamsWorkingSet(api, signedTx.Transaction, vm.ResolvedInputs{InputSet: inputSet})
+	if err != nil {
+		return err
+	}
+
+	return vm.RunVMFuncs(nova.NewVirtualMachine(), &vm.Params{API: api, WorkingSet: workingSet}, vm.ExecFuncBalancedNativeTokens(), execFuncFoundryTransitions())
+}
+
+// execFuncFoundryTransitions executes the state transition validation functions of the foundries.
+func execFuncFoundryTransitions() vm.ExecFunc {
+	return func(virtualMachine vm.VirtualMachine, vmParams *vm.Params) error {
+		for chainID, input := range vmParams.WorkingSet.InChains {
+			if _, isFoundry := input.Output.(*iotago.FoundryOutput); !isFoundry {
+				continue
+			}
+
+			next, isTransitioned := vmParams.WorkingSet.OutChains[chainID]
+			transitionType := iotago.ChainTransitionTypeStateChange
+			if !isTransitioned {
+				transitionType = iotago.ChainTransitionTypeDestroy
+			}
+
+			if err := virtualMachine.ChainSTVF(vmParams, transitionType, input, next); err != nil {
+				return ierrors.Wrapf(err, "foundry %s", chainID.ToHex())
+			}
+		}
+
+		for chainID, next := range vmParams.WorkingSet.OutChains {
+			if _, isFoundry := next.(*iotago.FoundryOutput); !isFoundry {
+				continue
+			}
+
+			if _, isTransitioned := vmParams.WorkingSet.InChains[chainID]; isTransitioned {
+				continue
+			}
+
+			if err := virtualMachine.ChainSTVF(vmParams, iotago.ChainTransitionTypeGenesis, nil, next); err != nil {
+				return ierrors.Wrapf(err, "new foundry %s", chainID.ToHex())
+			}
+		}
+
+		return nil
+	}
+}
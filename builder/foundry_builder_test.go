@@ -0,0 +1,157 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestFoundryBuilder(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	recipient := tpkg.RandEd25519Address()
+	targetSlot := iotago.SlotIndex(100)
+
+	accountID := tpkg.RandAccountID()
+	account := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      tpkg.RandOutputIDWithCreationSlot(10, 0),
+		Input:        builder.NewAccountOutputBuilder(addr, 10_000_000).AccountID(accountID).Mana(1_000).MustBuild(),
+	}
+	funding := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      tpkg.RandOutputIDWithCreationSlot(10, 1),
+		Input:        builder.NewBasicOutputBuilder(addr, 100_000_000).Mana(1_000_000).MustBuild(),
+	}
+
+	// ledger holds all outputs that were created so far, to resolve the inputs of the transactions,
+	// unspent holds the unspent outputs on the address that can fund the transactions
+	ledger := vm.InputSet{account.InputID: account.Input, funding.InputID: funding.Input}
+	unspent := map[iotago.OutputID]*builder.TxInput{funding.InputID: funding}
+	candidates := func() []*builder.TxInput {
+		txInputs := make([]*builder.TxInput, 0, len(unspent))
+		for _, txInput := range unspent {
+			txInputs = append(txInputs, txInput)
+		}
+
+		return txInputs
+	}
+	novaVM := nova.NewVirtualMachine()
+	execute := func(t *testing.T, transaction *builder.FoundryTransaction) {
+		t.Helper()
+
+		inputSet := vm.InputSet{}
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			inputSet[input.OutputID()] = ledger[input.OutputID()]
+		}
+
+		resolvedInputs := vm.ResolvedInputs{InputSet: inputSet}
+		unlockedAddrs, err := novaVM.ValidateUnlocks(transaction.SignedTransaction, resolvedInputs)
+		require.NoError(t, err)
+		require.NoError(t, lo.Return2(novaVM.Execute(transaction.SignedTransaction.Transaction, resolvedInputs, unlockedAddrs)))
+
+		for index, output := range transaction.SignedTransaction.Transaction.Outputs {
+			ledger[iotago.OutputIDFromTransactionIDAndIndex(transaction.TransactionID, uint16(index))] = output
+		}
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			delete(unspent, input.OutputID())
+		}
+		for _, remainder := range transaction.Remainders {
+			unspent[remainder.InputID] = remainder
+		}
+	}
+
+	foundryBuilder := builder.NewFoundryBuilder(api, signer, account, addr)
+
+	created, err := foundryBuilder.CreateFoundry(targetSlot, big.NewInt(1_000), candidates())
+	require.NoError(t, err)
+	execute(t, created)
+	require.Equal(t, uint32(1), created.Account.Input.(*iotago.AccountOutput).FoundryCounter)
+	require.Equal(t, created.Account, foundryBuilder.Account())
+	foundry := created.Foundry
+	nativeTokenID := foundry.Input.(*iotago.FoundryOutput).MustNativeTokenID()
+
+	minted, err := foundryBuilder.Mint(targetSlot, foundry, []*builder.MintRecipient{
+		{Address: addr, Amount: big.NewInt(600)},
+		{Address: recipient, Amount: big.NewInt(400)},
+	}, candidates())
+	require.NoError(t, err)
+	execute(t, minted)
+	require.EqualValues(t, 1_000, minted.Foundry.Input.(*iotago.FoundryOutput).TokenScheme.(*iotago.SimpleTokenScheme).MintedTokens.Int64())
+
+	tokenOutput := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      iotago.OutputIDFromTransactionIDAndIndex(minted.TransactionID, 2),
+		Input:        minted.SignedTransaction.Transaction.Outputs[2],
+	}
+	require.EqualValues(t, 600, tokenOutput.Input.FeatureSet().NativeToken().Amount.Int64())
+	unspent[tokenOutput.InputID] = tokenOutput
+
+	t.Run("fail - exceeds maximum supply", func(t *testing.T) {
+		_, err := builder.NewFoundryBuilder(api, signer, foundryBuilder.Account(), addr).
+			Mint(targetSlot, minted.Foundry, []*builder.MintRecipient{{Address: addr, Amount: big.NewInt(1)}}, candidates())
+		require.ErrorIs(t, err, builder.ErrFoundryTransitionInvalid)
+	})
+
+	t.Run("fail - foundry of another account", func(t *testing.T) {
+		otherAccount := &builder.TxInput{
+			UnlockTarget: addr,
+			InputID:      tpkg.RandOutputIDWithCreationSlot(10, 0),
+			Input:        builder.NewAccountOutputBuilder(addr, 10_000_000).AccountID(tpkg.RandAccountID()).MustBuild(),
+		}
+
+		_, err := builder.NewFoundryBuilder(api, signer, otherAccount, addr).Melt(targetSlot, minted.Foundry, big.NewInt(1), candidates())
+		require.ErrorIs(t, err, builder.ErrFoundryAccountMismatch)
+	})
+
+	melted, err := foundryBuilder.Melt(targetSlot, minted.Foundry, big.NewInt(500), candidates())
+	require.NoError(t, err)
+	execute(t, melted)
+	require.EqualValues(t, 500, melted.Foundry.Input.(*iotago.FoundryOutput).TokenScheme.(*iotago.SimpleTokenScheme).MeltedTokens.Int64())
+
+	// the tokens that were not melted are kept in a remainder output
+	var tokenRemainders []*builder.TxInput
+	for _, remainder := range melted.Remainders {
+		if nativeToken := remainder.Input.FeatureSet().NativeToken(); nativeToken != nil {
+			require.Equal(t, nativeTokenID, nativeToken.ID)
+			require.EqualValues(t, 100, nativeToken.Amount.Int64())
+			tokenRemainders = append(tokenRemainders, remainder)
+		}
+	}
+	require.Len(t, tokenRemainders, 1)
+
+	burned, err := foundryBuilder.Burn(targetSlot, nativeTokenID, big.NewInt(100), candidates())
+	require.NoError(t, err)
+	execute(t, burned)
+	require.Nil(t, burned.Account)
+	for _, output := range burned.SignedTransaction.Transaction.Outputs {
+		require.Nil(t, output.FeatureSet().NativeToken())
+	}
+
+	t.Run("fail - destroy with circulating supply", func(t *testing.T) {
+		_, err := builder.NewFoundryBuilder(api, signer, foundryBuilder.Account(), addr).DestroyFoundry(targetSlot, melted.Foundry, candidates())
+		require.ErrorIs(t, err, builder.ErrFoundryTransitionInvalid)
+	})
+
+	// a foundry without circulating supply can be destroyed
+	createdSecond, err := foundryBuilder.CreateFoundry(targetSlot, big.NewInt(1_000), candidates())
+	require.NoError(t, err)
+	execute(t, createdSecond)
+	require.Equal(t, uint32(2), createdSecond.Account.Input.(*iotago.AccountOutput).FoundryCounter)
+
+	destroyed, err := foundryBuilder.DestroyFoundry(targetSlot, createdSecond.Foundry, candidates())
+	require.NoError(t, err)
+	execute(t, destroyed)
+	require.Nil(t, destroyed.Foundry)
+	require.Equal(t, uint32(2), destroyed.Account.Input.(*iotago.AccountOutput).FoundryCounter)
+}
@@ -0,0 +1,799 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrInputSelection defines a generic error occurring during the input selection.
+	ErrInputSelection = ierrors.New("input selection error")
+	// ErrInputSelectionNotEnoughBaseTokens gets returned if the candidates do not hold enough base tokens to cover the requirements.
+	ErrInputSelectionNotEnoughBaseTokens = ierrors.New("not enough base tokens available")
+	// ErrInputSelectionNotEnoughNativeTokens gets returned if the candidates do not hold enough native tokens to cover the requirements.
+	ErrInputSelectionNotEnoughNativeTokens = ierrors.New("not enough native tokens available")
+	// ErrInputSelectionNotEnoughMana gets returned if the candidates do not hold enough mana to cover the requirements.
+	ErrInputSelectionNotEnoughMana = ierrors.New("not enough mana available")
+	// ErrInputSelectionRemainderDepositNotCovered gets returned if the remaining base tokens of the selection
+	// do not cover the minimum storage deposit of the needed remainder outputs.
+	ErrInputSelectionRemainderDepositNotCovered = ierrors.New("remainder does not cover the minimum storage deposit")
+	// ErrInputSelectionCandidateNotSupported gets returned if a candidate can not be consumed by the input selection.
+	ErrInputSelectionCandidateNotSupported = ierrors.New("candidate is not supported by the input selection")
+	// ErrInputSelectionCandidateNotUnlockable gets returned if a candidate can not be unlocked by its unlock target at the target slot.
+	ErrInputSelectionCandidateNotUnlockable = ierrors.New("candidate is not unlockable by the unlock target")
+)
+
+// InputSelectionRequirements defines what the selected inputs need to cover.
+type InputSelectionRequirements struct {
+	// The amount of base tokens the selected inputs need to provide.
+	BaseTokens iotago.BaseToken
+	// The native tokens the selected inputs need to provide.
+	NativeTokens iotago.NativeTokenSum
+	// The amount of mana the selected inputs need to provide at the target slot.
+	Mana iotago.Mana
+	// The address the remainder outputs will be sent to.
+	// It is used to calculate the minimum storage deposit of the remainder.
+	// If nil, the unlock target of the first selected input is used.
+	RemainderAddress iotago.Address
+	// The inputs which are consumed in any case, e.g. because they were already added to a TransactionBuilder.
+	Preselected []*TxInput
+	// Additional mana which is available on top of the inputs, e.g. rewards.
+	AdditionalMana iotago.Mana
+}
+
+// NewInputSelectionRequirements returns the InputSelectionRequirements to fund the given outputs and allotments.
+func NewInputSelectionRequirements(outputs iotago.TxEssenceOutputs, allotments iotago.Allotments) (*InputSelectionRequirements, error) {
+	requirements := &InputSelectionRequirements{
+		NativeTokens: make(iotago.NativeTokenSum),
+	}
+
+	for _, output := range outputs {
+		if err := requirements.addOutput(output); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, allotment := range allotments {
+		mana, err := safemath.SafeAdd(requirements.Mana, allotment.Mana)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to add allotted mana to the requirements")
+		}
+		requirements.Mana = mana
+	}
+
+	return requirements, nil
+}
+
+func (r *InputSelectionRequirements) addOutput(output iotago.Output) error {
+	baseTokens, err := safemath.SafeAdd(r.BaseTokens, output.BaseTokenAmount())
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add base tokens to the requirements")
+	}
+	r.BaseTokens = baseTokens
+
+	mana, err := safemath.SafeAdd(r.Mana, output.StoredMana())
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add stored mana to the requirements")
+	}
+	r.Mana = mana
+
+	if nativeTokenFeature := output.FeatureSet().NativeToken(); nativeTokenFeature != nil {
+		addNativeToken(r.NativeTokens, nativeTokenFeature.ID, nativeTokenFeature.Amount)
+	}
+
+	return nil
+}
+
+// InputCandidate is a TxInput which can be picked by an InputSelectionStrategy.
+type InputCandidate struct {
+	*TxInput
+	// The mana (decayed stored mana and potential mana) the input holds at the target slot.
+	Mana iotago.Mana
+}
+
+// BaseTokens returns the amount of base tokens held by the candidate.
+func (c *InputCandidate) BaseTokens() iotago.BaseToken {
+	return c.Input.BaseTokenAmount()
+}
+
+// NativeToken returns the native token held by the candidate or nil.
+func (c *InputCandidate) NativeToken() *iotago.NativeTokenFeature {
+	return c.Input.FeatureSet().NativeToken()
+}
+
+// InputSelectionTarget is the target an InputSelectionStrategy needs to reach.
+// The native token requirements are already covered by the time a strategy is invoked.
+type InputSelectionTarget struct {
+	// The amount of base tokens required in total.
+	RequiredBaseTokens iotago.BaseToken
+	// The amount of mana required in total.
+	RequiredMana iotago.Mana
+	// The amount of base tokens already provided by the selected inputs.
+	AvailableBaseTokens iotago.BaseToken
+	// The amount of mana already provided by the selected inputs.
+	AvailableMana iotago.Mana
+	// The minimum storage deposit the remainder outputs need, in case there are remaining base tokens.
+	RemainderDeposit iotago.BaseToken
+	// Whether a remainder output needs to be created in any case, e.g. because of leftover native tokens.
+	RemainderRequired bool
+}
+
+// CoveredBy tells whether the target is reached if the given candidates are selected in addition to the available funds.
+// An error is returned if the funds overflow.
+func (t *InputSelectionTarget) CoveredBy(candidates []*InputCandidate) (bool, error) {
+	baseTokens, mana, err := t.fundsWith(candidates)
+	if err != nil {
+		return false, err
+	}
+
+	return t.covered(baseTokens, mana), nil
+}
+
+// fundsWith returns the base tokens and mana available if the given candidates are selected in addition to the available funds.
+func (t *InputSelectionTarget) fundsWith(candidates []*InputCandidate) (iotago.BaseToken, iotago.Mana, error) {
+	baseTokens, mana := t.AvailableBaseTokens, t.AvailableMana
+	for _, candidate := range candidates {
+		var err error
+		if baseTokens, err = safemath.SafeAdd(baseTokens, candidate.BaseTokens()); err != nil {
+			return 0, 0, ierrors.Wrapf(err, "failed to add base tokens of input %s", candidate.InputID.ToHex())
+		}
+
+		if mana, err = safemath.SafeAdd(mana, candidate.Mana); err != nil {
+			return 0, 0, ierrors.Wrapf(err, "failed to add mana of input %s", candidate.InputID.ToHex())
+		}
+	}
+
+	return baseTokens, mana, nil
+}
+
+func (t *InputSelectionTarget) covered(baseTokens iotago.BaseToken, mana iotago.Mana) bool {
+	if baseTokens < t.RequiredBaseTokens || mana < t.RequiredMana {
+		return false
+	}
+
+	remainder := baseTokens - t.RequiredBaseTokens
+	if remainder == 0 && !t.RemainderRequired {
+		return true
+	}
+
+	return remainder >= t.RemainderDeposit
+}
+
+// InputSelectionStrategy picks the inputs needed to cover the base token and mana requirements out of a set of candidates.
+type InputSelectionStrategy interface {
+	// Name returns the name of the strategy.
+	Name() string
+	// Select returns the candidates which need to be consumed in addition to the already selected inputs to reach the target.
+	// The candidates are sorted by their OutputID and the result must be deterministic for the same input.
+	Select(target *InputSelectionTarget, candidates []*InputCandidate) ([]*InputCandidate, error)
+}
+
+// NewLargestFirstStrategy creates a strategy which consumes the candidates with the highest base token amount first.
+// This results in the lowest possible number of inputs.
+func NewLargestFirstStrategy() InputSelectionStrategy {
+	return &accumulatingStrategy{
+		name: "largest-first",
+		less: func(a *InputCandidate, b *InputCandidate) bool {
+			return a.BaseTokens() > b.BaseTokens()
+		},
+	}
+}
+
+// NewMinimizeDustStrategy creates a strategy which consumes the candidates with the lowest base token amount first.
+// This consolidates small outputs and reduces the amount of dust held by the address over time.
+func NewMinimizeDustStrategy() InputSelectionStrategy {
+	return &accumulatingStrategy{
+		name: "minimize-dust",
+		less: func(a *InputCandidate, b *InputCandidate) bool {
+			return a.BaseTokens() < b.BaseTokens()
+		},
+	}
+}
+
+// accumulatingStrategy consumes the candidates in the order defined by "less" until the target is reached.
+type accumulatingStrategy struct {
+	name string
+	less func(a *InputCandidate, b *InputCandidate) bool
+}
+
+func (s *accumulatingStrategy) Name() string {
+	return s.name
+}
+
+func (s *accumulatingStrategy) Select(target *InputSelectionTarget, candidates []*InputCandidate) ([]*InputCandidate, error) {
+	sorted := slices.Clone(candidates)
+	slices.SortStableFunc(sorted, func(a *InputCandidate, b *InputCandidate) int {
+		switch {
+		case s.less(a, b):
+			return -1
+		case s.less(b, a):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return accumulate(target, sorted)
+}
+
+// accumulate picks the given candidates in order until the target is reached.
+func accumulate(target *InputSelectionTarget, candidates []*InputCandidate) ([]*InputCandidate, error) {
+	selected := make([]*InputCandidate, 0)
+	if covered, err := target.CoveredBy(selected); err != nil {
+		return nil, err
+	} else if covered {
+		return selected, nil
+	}
+
+	for _, candidate := range candidates {
+		selected = append(selected, candidate)
+		if covered, err := target.CoveredBy(selected); err != nil {
+			return nil, err
+		} else if covered {
+			return selected, nil
+		}
+	}
+
+	return nil, targetNotCoveredError(target, candidates)
+}
+
+// targetNotCoveredError returns the error describing why the target can't be reached with all the given candidates.
+func targetNotCoveredError(target *InputSelectionTarget, candidates []*InputCandidate) error {
+	baseTokens, mana, err := target.fundsWith(candidates)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case baseTokens < target.RequiredBaseTokens:
+		return ierrors.WithMessagef(ErrInputSelectionNotEnoughBaseTokens, "required %d, available %d, shortfall %d", target.RequiredBaseTokens, baseTokens, target.RequiredBaseTokens-baseTokens)
+	case mana < target.RequiredMana:
+		return ierrors.WithMessagef(ErrInputSelectionNotEnoughMana, "required %d, available %d, shortfall %d", target.RequiredMana, mana, target.RequiredMana-mana)
+	default:
+		remainder := baseTokens - target.RequiredBaseTokens
+
+		return ierrors.WithMessagef(ErrInputSelectionRemainderDepositNotCovered, "remainder %d, minimum deposit %d, shortfall %d", remainder, target.RemainderDeposit, target.RemainderDeposit-remainder)
+	}
+}
+
+// NewBranchAndBoundStrategy creates a strategy which searches for a combination of candidates that exactly matches
+// the required base tokens, so that no remainder output needs to be created.
+// The search is bounded by maxTries, if no exact match is found the strategy falls back to largest-first.
+func NewBranchAndBoundStrategy(maxTries int) InputSelectionStrategy {
+	return &branchAndBoundStrategy{
+		maxTries: maxTries,
+		fallback: NewLargestFirstStrategy(),
+	}
+}
+
+type branchAndBoundStrategy struct {
+	maxTries int
+	fallback InputSelectionStrategy
+}
+
+func (s *branchAndBoundStrategy) Name() string {
+	return "branch-and-bound"
+}
+
+func (s *branchAndBoundStrategy) Select(target *InputSelectionTarget, candidates []*InputCandidate) ([]*InputCandidate, error) {
+	if covered, err := target.CoveredBy(nil); err != nil {
+		return nil, err
+	} else if covered {
+		return make([]*InputCandidate, 0), nil
+	}
+
+	// an exact match is only useful if no remainder is needed anyway
+	if target.RemainderRequired || target.AvailableBaseTokens > target.RequiredBaseTokens {
+		return s.fallback.Select(target, candidates)
+	}
+
+	sorted := slices.Clone(candidates)
+	slices.SortStableFunc(sorted, func(a *InputCandidate, b *InputCandidate) int {
+		return -compareBaseTokens(a.BaseTokens(), b.BaseTokens())
+	})
+
+	// remaining[i] holds the sum of the base tokens of all candidates starting at index i
+	remaining := make([]iotago.BaseToken, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		var err error
+		if remaining[i], err = safemath.SafeAdd(remaining[i+1], sorted[i].BaseTokens()); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add base tokens of the candidates")
+		}
+	}
+
+	needed := target.RequiredBaseTokens - target.AvailableBaseTokens
+	tries := 0
+
+	var best []*InputCandidate
+	var searchErr error
+	var search func(index int, sum iotago.BaseToken, picked []*InputCandidate)
+	search = func(index int, sum iotago.BaseToken, picked []*InputCandidate) {
+		tries++
+		if tries > s.maxTries || searchErr != nil {
+			return
+		}
+
+		if sum == needed {
+			covered, err := target.CoveredBy(picked)
+			if err != nil {
+				searchErr = err
+
+				return
+			}
+
+			if covered && (best == nil || len(picked) < len(best)) {
+				best = slices.Clone(picked)
+			}
+
+			return
+		}
+
+		// bound: not being able to reach the target anymore (the sum never exceeds the needed base tokens)
+		if index == len(sorted) || remaining[index] < needed-sum {
+			return
+		}
+
+		// bound: the current branch can't improve the best solution anymore
+		if best != nil && len(picked)+1 >= len(best) {
+			return
+		}
+
+		// branch: include the candidate, unless it overshoots the target
+		if sorted[index].BaseTokens() <= needed-sum {
+			search(index+1, sum+sorted[index].BaseTokens(), append(picked, sorted[index]))
+		}
+
+		// branch: exclude the candidate
+		search(index+1, sum, picked)
+	}
+	search(0, 0, make([]*InputCandidate, 0))
+
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	if best != nil {
+		return best, nil
+	}
+
+	return s.fallback.Select(target, candidates)
+}
+
+func compareBaseTokens(a iotago.BaseToken, b iotago.BaseToken) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SelectedInput is an input that was picked by the input selection.
+type SelectedInput struct {
+	*InputCandidate
+	// The reason why the input was selected.
+	Reason string
+}
+
+// SkippedInput is a candidate that was not considered by the input selection.
+type SkippedInput struct {
+	*TxInput
+	// The reason why the candidate was skipped.
+	Reason error
+}
+
+// InputSelectionResult is the outcome of an input selection.
+type InputSelectionResult struct {
+	// The name of the strategy which was used.
+	Strategy string
+	// The target slot the selection was made for.
+	TargetSlot iotago.SlotIndex
+	// The selected inputs in the order they were picked (including the preselected ones).
+	Selected []*SelectedInput
+	// The candidates which were not considered, sorted by their OutputID.
+	Skipped []*SkippedInput
+	// The base tokens that remain after covering the requirements.
+	RemainderBaseTokens iotago.BaseToken
+	// The native tokens that remain after covering the requirements.
+	RemainderNativeTokens iotago.NativeTokenSum
+	// The mana that remains after covering the requirements.
+	RemainderMana iotago.Mana
+	// The address used to calculate the remainder storage deposit.
+	RemainderAddress iotago.Address
+	// The minimum storage deposit of the remainder outputs.
+	RemainderDeposit iotago.BaseToken
+}
+
+// TxInputs returns the selected TxInputs.
+func (r *InputSelectionResult) TxInputs() []*TxInput {
+	txInputs := make([]*TxInput, 0, len(r.Selected))
+	for _, selected := range r.Selected {
+		txInputs = append(txInputs, selected.TxInput)
+	}
+
+	return txInputs
+}
+
+// InputSelector selects inputs out of a set of candidates to fund a transaction.
+type InputSelector struct {
+	api        iotago.API
+	targetSlot iotago.SlotIndex
+	strategy   InputSelectionStrategy
+}
+
+// NewInputSelector creates a new InputSelector.
+// The target slot is the slot of the commitment the transaction is going to reference. It is used to check
+// timelock and expiration unlock conditions and to calculate the mana of the inputs.
+func NewInputSelector(api iotago.API, targetSlot iotago.SlotIndex, strategy InputSelectionStrategy) *InputSelector {
+	return &InputSelector{
+		api:        api,
+		targetSlot: targetSlot,
+		strategy:   strategy,
+	}
+}
+
+// pastBoundedSlot returns the past bounded slot of a transaction referencing a commitment of the target slot.
+func (s *InputSelector) pastBoundedSlot() iotago.SlotIndex {
+	return s.targetSlot + s.api.ProtocolParameters().MaxCommittableAge()
+}
+
+// futureBoundedSlot returns the future bounded slot of a transaction referencing a commitment of the target slot.
+func (s *InputSelector) futureBoundedSlot() iotago.SlotIndex {
+	return s.targetSlot + s.api.ProtocolParameters().MinCommittableAge()
+}
+
+// Select picks the inputs out of the candidates that cover the given requirements.
+func (s *InputSelector) Select(requirements *InputSelectionRequirements, candidates []*TxInput) (*InputSelectionResult, error) {
+	if s.strategy == nil {
+		return nil, ierrors.WithMessage(ErrInputSelection, "must supply a strategy")
+	}
+
+	result := &InputSelectionResult{
+		Strategy:   s.strategy.Name(),
+		TargetSlot: s.targetSlot,
+		Selected:   make([]*SelectedInput, 0),
+		Skipped:    make([]*SkippedInput, 0),
+	}
+
+	state := &inputSelectionState{
+		nativeTokens: make(iotago.NativeTokenSum),
+		mana:         requirements.AdditionalMana,
+		selectedIDs:  make(map[iotago.OutputID]struct{}),
+	}
+
+	selectInput := func(candidate *InputCandidate, reason string) error {
+		if err := state.add(candidate); err != nil {
+			return err
+		}
+		result.Selected = append(result.Selected, &SelectedInput{InputCandidate: candidate, Reason: reason})
+
+		return nil
+	}
+
+	for _, txInput := range requirements.Preselected {
+		mana, err := s.availableMana(txInput)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := selectInput(&InputCandidate{TxInput: txInput, Mana: mana}, "preselected"); err != nil {
+			return nil, err
+		}
+	}
+
+	eligible, err := s.eligibleCandidates(candidates, state, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// first cover the native tokens, since only few candidates can provide them
+	nativeTokenIDs := sortedNativeTokenIDs(requirements.NativeTokens)
+	for _, nativeTokenID := range nativeTokenIDs {
+		required := requirements.NativeTokens[nativeTokenID]
+		holders := make([]*InputCandidate, 0)
+		for _, candidate := range eligible {
+			if nativeToken := candidate.NativeToken(); nativeToken != nil && nativeToken.ID == nativeTokenID {
+				holders = append(holders, candidate)
+			}
+		}
+		slices.SortStableFunc(holders, func(a *InputCandidate, b *InputCandidate) int {
+			return b.NativeToken().Amount.Cmp(a.NativeToken().Amount)
+		})
+
+		for _, holder := range holders {
+			if state.nativeTokens.ValueOrBigInt0(nativeTokenID).Cmp(required) >= 0 {
+				break
+			}
+
+			if err := selectInput(holder, fmt.Sprintf("native token %s", nativeTokenID.ToHex())); err != nil {
+				return nil, err
+			}
+		}
+
+		if available := state.nativeTokens.ValueOrBigInt0(nativeTokenID); available.Cmp(required) < 0 {
+			return nil, ierrors.WithMessagef(ErrInputSelectionNotEnoughNativeTokens, "native token %s, required %s, available %s, shortfall %s", nativeTokenID.ToHex(), required, available, new(big.Int).Sub(required, available))
+		}
+	}
+
+	remainderNativeTokens := subNativeTokens(state.nativeTokens, requirements.NativeTokens)
+
+	result.RemainderAddress = requirements.RemainderAddress
+	if result.RemainderAddress == nil {
+		switch {
+		case len(result.Selected) > 0:
+			result.RemainderAddress = result.Selected[0].UnlockTarget
+		case len(eligible) > 0:
+			result.RemainderAddress = eligible[0].UnlockTarget
+		default:
+			result.RemainderAddress = &iotago.Ed25519Address{}
+		}
+	}
+
+	remainderDeposit, err := RemainderDeposit(s.api, result.RemainderAddress, remainderNativeTokens)
+	if err != nil {
+		return nil, err
+	}
+	result.RemainderDeposit = remainderDeposit
+
+	// candidates holding native tokens are only considered by the strategy if they don't require an additional remainder output
+	strategyCandidates := make([]*InputCandidate, 0)
+	for _, candidate := range eligible {
+		if _, selected := state.selectedIDs[candidate.InputID]; selected {
+			continue
+		}
+
+		if nativeToken := candidate.NativeToken(); nativeToken != nil {
+			if _, isRemainder := remainderNativeTokens[nativeToken.ID]; !isRemainder {
+				result.Skipped = append(result.Skipped, &SkippedInput{
+					TxInput: candidate.TxInput,
+					Reason:  ierrors.WithMessagef(ErrInputSelectionCandidateNotSupported, "holds native token %s which is not required", nativeToken.ID.ToHex()),
+				})
+
+				continue
+			}
+		}
+
+		strategyCandidates = append(strategyCandidates, candidate)
+	}
+
+	target := &InputSelectionTarget{
+		RequiredBaseTokens:  requirements.BaseTokens,
+		RequiredMana:        requirements.Mana,
+		AvailableBaseTokens: state.baseTokens,
+		AvailableMana:       state.mana,
+		RemainderDeposit:    remainderDeposit,
+		RemainderRequired:   len(remainderNativeTokens) > 0,
+	}
+
+	picked, err := s.strategy.Select(target, strategyCandidates)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "strategy %s failed", s.strategy.Name())
+	}
+
+	for _, candidate := range picked {
+		if err := selectInput(candidate, fmt.Sprintf("base tokens and mana (%s)", s.strategy.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	if !target.covered(state.baseTokens, state.mana) {
+		return nil, ierrors.Wrapf(targetNotCoveredError(target, picked), "strategy %s returned an insufficient selection", s.strategy.Name())
+	}
+
+	result.RemainderBaseTokens = state.baseTokens - requirements.BaseTokens
+	result.RemainderMana = state.mana - requirements.Mana
+	result.RemainderNativeTokens = subNativeTokens(state.nativeTokens, requirements.NativeTokens)
+
+	return result, nil
+}
+
+// eligibleCandidates returns the candidates which can be consumed at the target slot, sorted by their OutputID.
+// All other candidates are added to the skipped inputs of the result.
+func (s *InputSelector) eligibleCandidates(candidates []*TxInput, state *inputSelectionState, result *InputSelectionResult) ([]*InputCandidate, error) {
+	sorted := slices.Clone(candidates)
+	slices.SortStableFunc(sorted, func(a *TxInput, b *TxInput) int {
+		return a.InputID.Compare(b.InputID)
+	})
+
+	seen := make(map[iotago.OutputID]struct{})
+	eligible := make([]*InputCandidate, 0, len(sorted))
+	for _, txInput := range sorted {
+		if _, isDuplicate := seen[txInput.InputID]; isDuplicate {
+			continue
+		}
+		seen[txInput.InputID] = struct{}{}
+
+		if _, isSelected := state.selectedIDs[txInput.InputID]; isSelected {
+			continue
+		}
+
+		if reason := s.checkCandidate(txInput); reason != nil {
+			result.Skipped = append(result.Skipped, &SkippedInput{TxInput: txInput, Reason: reason})
+
+			continue
+		}
+
+		mana, err := s.availableMana(txInput)
+		if err != nil {
+			return nil, err
+		}
+
+		eligible = append(eligible, &InputCandidate{TxInput: txInput, Mana: mana})
+	}
+
+	return eligible, nil
+}
+
+// checkCandidate returns the reason why the given candidate can't be consumed or nil.
+func (s *InputSelector) checkCandidate(txInput *TxInput) error {
+	// outputs created after the target slot, e.g. ones that are newer than the latest commitment, can't be consumed yet
+	if creationSlot := txInput.InputID.CreationSlot(); creationSlot > s.targetSlot {
+		return ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "output was created in slot %d after the target slot %d", creationSlot, s.targetSlot)
+	}
+
+	returnAddress, err := s.checkUnlockable(txInput)
+	if err != nil {
+		return err
+	}
+
+	// the storage deposit only needs to be returned if the output is not unlocked by the return address
+	if txInput.Input.UnlockConditionSet().HasStorageDepositReturnCondition() && returnAddress == nil {
+		return ierrors.WithMessage(ErrInputSelectionCandidateNotSupported, "output requires a storage deposit return")
+	}
+
+	return nil
+}
+
+// checkUnlockable returns the reason why the given basic output can't be unlocked by its unlock target or nil.
+// If the output expired, the expiration return address is returned, which is then the only address that can unlock it.
+func (s *InputSelector) checkUnlockable(txInput *TxInput) (iotago.Address, error) {
+	basicOutput, isBasicOutput := txInput.Input.(*iotago.BasicOutput)
+	if !isBasicOutput {
+		return nil, ierrors.WithMessagef(ErrInputSelectionCandidateNotSupported, "output type %s", txInput.Input.Type())
+	}
+
+	if basicOutput.Owner().Type() == iotago.AddressImplicitAccountCreation {
+		return nil, ierrors.WithMessage(ErrInputSelectionCandidateNotSupported, "output is an implicit account")
+	}
+
+	unlockConditions := basicOutput.UnlockConditionSet()
+	if err := unlockConditions.TimelocksExpired(s.futureBoundedSlot()); err != nil {
+		return nil, err
+	}
+
+	returnAddress, err := unlockConditions.CheckExpirationCondition(s.futureBoundedSlot(), s.pastBoundedSlot())
+	if err != nil {
+		return nil, err
+	}
+
+	if !basicOutput.UnlockableBy(txInput.UnlockTarget, s.pastBoundedSlot(), s.futureBoundedSlot()) {
+		if returnAddress != nil {
+			return nil, ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "output expired and can only be unlocked by the return address %s", returnAddress.Bech32(s.api.ProtocolParameters().Bech32HRP()))
+		}
+
+		return nil, ierrors.WithMessagef(ErrInputSelectionCandidateNotUnlockable, "unlock target %s", txInput.UnlockTarget.Bech32(s.api.ProtocolParameters().Bech32HRP()))
+	}
+
+	return returnAddress, nil
+}
+
+// availableMana returns the mana held by the given input at the target slot.
+func (s *InputSelector) availableMana(txInput *TxInput) (iotago.Mana, error) {
+	creationSlot := txInput.InputID.CreationSlot()
+	if creationSlot > s.targetSlot {
+		return 0, ierrors.WithMessagef(ErrInputSelection, "input %s was created after the target slot %d", txInput.InputID.ToHex(), s.targetSlot)
+	}
+
+	potentialMana, err := iotago.PotentialMana(s.api.ManaDecayProvider(), s.api.StorageScoreStructure(), txInput.Input, creationSlot, s.targetSlot)
+	if err != nil {
+		return 0, ierrors.Wrapf(err, "failed to calculate potential mana of input %s", txInput.InputID.ToHex())
+	}
+
+	storedMana, err := s.api.ManaDecayProvider().DecayManaBySlots(txInput.Input.StoredMana(), creationSlot, s.targetSlot)
+	if err != nil {
+		return 0, ierrors.Wrapf(err, "failed to calculate stored mana decay of input %s", txInput.InputID.ToHex())
+	}
+
+	return safemath.SafeAdd(potentialMana, storedMana)
+}
+
+// inputSelectionState accumulates the funds of the selected inputs.
+type inputSelectionState struct {
+	baseTokens   iotago.BaseToken
+	nativeTokens iotago.NativeTokenSum
+	mana         iotago.Mana
+	selectedIDs  map[iotago.OutputID]struct{}
+}
+
+func (s *inputSelectionState) add(candidate *InputCandidate) error {
+	if _, isSelected := s.selectedIDs[candidate.InputID]; isSelected {
+		return ierrors.WithMessagef(ErrInputSelection, "input %s was selected twice", candidate.InputID.ToHex())
+	}
+	s.selectedIDs[candidate.InputID] = struct{}{}
+
+	baseTokens, err := safemath.SafeAdd(s.baseTokens, candidate.BaseTokens())
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add base tokens of the selected input")
+	}
+	s.baseTokens = baseTokens
+
+	mana, err := safemath.SafeAdd(s.mana, candidate.Mana)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add mana of the selected input")
+	}
+	s.mana = mana
+
+	if nativeToken := candidate.NativeToken(); nativeToken != nil {
+		addNativeToken(s.nativeTokens, nativeToken.ID, nativeToken.Amount)
+	}
+
+	return nil
+}
+
+// RemainderDeposit returns the minimum storage deposit needed to create the remainder outputs on the given address.
+// Every native token needs its own remainder output, if there are no native tokens a single remainder output is needed.
+func RemainderDeposit(api iotago.API, remainderAddress iotago.Address, nativeTokens iotago.NativeTokenSum) (iotago.BaseToken, error) {
+	if len(nativeTokens) == 0 {
+		return api.StorageScoreStructure().MinDeposit(NewBasicOutputBuilder(remainderAddress, 0).MustBuild())
+	}
+
+	var deposit iotago.BaseToken
+	for _, nativeTokenID := range sortedNativeTokenIDs(nativeTokens) {
+		minDeposit, err := api.StorageScoreStructure().MinDeposit(NewBasicOutputBuilder(remainderAddress, 0).
+			NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: nativeTokens[nativeTokenID]}).
+			MustBuild())
+		if err != nil {
+			return 0, ierrors.Wrap(err, "failed to calculate the minimum deposit of the remainder")
+		}
+
+		if deposit, err = safemath.SafeAdd(deposit, minDeposit); err != nil {
+			return 0, ierrors.Wrap(err, "failed to add the minimum deposit of the remainder")
+		}
+	}
+
+	return deposit, nil
+}
+
+// addNativeToken adds the given amount of the native token to the sum.
+func addNativeToken(sum iotago.NativeTokenSum, nativeTokenID iotago.NativeTokenID, amount *big.Int) {
+	val, has := sum[nativeTokenID]
+	if !has {
+		val = new(big.Int)
+		sum[nativeTokenID] = val
+	}
+	val.Add(val, amount)
+}
+
+// subNativeTokens returns the positive differences of a minus b.
+func subNativeTokens(a iotago.NativeTokenSum, b iotago.NativeTokenSum) iotago.NativeTokenSum {
+	diff := make(iotago.NativeTokenSum)
+	for nativeTokenID, amount := range a {
+		remainder := new(big.Int).Sub(amount, b.ValueOrBigInt0(nativeTokenID))
+		if remainder.Sign() > 0 {
+			diff[nativeTokenID] = remainder
+		}
+	}
+
+	return diff
+}
+
+// sortedNativeTokenIDs returns the native token IDs of the sum in lexical order.
+func sortedNativeTokenIDs(sum iotago.NativeTokenSum) []iotago.NativeTokenID {
+	nativeTokenIDs := make([]iotago.NativeTokenID, 0, len(sum))
+	for nativeTokenID := range sum {
+		nativeTokenIDs = append(nativeTokenIDs, nativeTokenID)
+	}
+	slices.SortFunc(nativeTokenIDs, func(a iotago.NativeTokenID, b iotago.NativeTokenID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+
+	return nativeTokenIDs
+}
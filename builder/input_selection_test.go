@@ -0,0 +1,258 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"crypto/ed25519"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+const inputSelectionTestSlot = iotago.SlotIndex(100)
+
+func inputSelectionCandidate(addr iotago.Address, index uint16, output *iotago.BasicOutput) *builder.TxInput {
+	txID := iotago.NewTransactionID(inputSelectionTestSlot, tpkg.RandIdentifier())
+
+	return &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      iotago.OutputIDFromTransactionIDAndIndex(txID, index),
+		Input:        output,
+	}
+}
+
+func TestInputSelector(t *testing.T) {
+	addr := tpkg.RandEd25519Address()
+	nativeTokenID := tpkg.RandNativeTokenFeature().ID
+
+	basic := func(amount iotago.BaseToken, mana iotago.Mana) *iotago.BasicOutput {
+		return builder.NewBasicOutputBuilder(addr, amount).Mana(mana).MustBuild()
+	}
+
+	candidates := []*builder.TxInput{
+		inputSelectionCandidate(addr, 0, basic(100, 0)),
+		inputSelectionCandidate(addr, 1, basic(300, 10)),
+		inputSelectionCandidate(addr, 2, basic(50, 0)),
+		inputSelectionCandidate(addr, 3, basic(250, 0)),
+		inputSelectionCandidate(addr, 4, builder.NewBasicOutputBuilder(addr, 20).NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(40)}).MustBuild()),
+		inputSelectionCandidate(addr, 5, builder.NewBasicOutputBuilder(addr, 1000).Timelock(inputSelectionTestSlot+1000).MustBuild()),
+		inputSelectionCandidate(tpkg.RandEd25519Address(), 6, basic(1000, 0)),
+	}
+
+	selectedAmounts := func(result *builder.InputSelectionResult) []iotago.BaseToken {
+		amounts := make([]iotago.BaseToken, 0)
+		for _, selected := range result.Selected {
+			amounts = append(amounts, selected.BaseTokens())
+		}
+
+		return amounts
+	}
+
+	type test struct {
+		name            string
+		strategy        builder.InputSelectionStrategy
+		requirements    *builder.InputSelectionRequirements
+		expectedAmounts []iotago.BaseToken
+		expectedErr     error
+	}
+
+	tests := []*test{
+		{
+			name:            "ok - largest-first",
+			strategy:        builder.NewLargestFirstStrategy(),
+			requirements:    &builder.InputSelectionRequirements{BaseTokens: 400},
+			expectedAmounts: []iotago.BaseToken{300, 250},
+		},
+		{
+			name:            "ok - minimize-dust",
+			strategy:        builder.NewMinimizeDustStrategy(),
+			requirements:    &builder.InputSelectionRequirements{BaseTokens: 120},
+			expectedAmounts: []iotago.BaseToken{50, 100},
+		},
+		{
+			name:            "ok - branch-and-bound exact match",
+			strategy:        builder.NewBranchAndBoundStrategy(1000),
+			requirements:    &builder.InputSelectionRequirements{BaseTokens: 350},
+			expectedAmounts: []iotago.BaseToken{300, 50},
+		},
+		{
+			name:            "ok - mana requirement",
+			strategy:        builder.NewMinimizeDustStrategy(),
+			requirements:    &builder.InputSelectionRequirements{BaseTokens: 10, Mana: 5},
+			expectedAmounts: []iotago.BaseToken{50, 100, 250, 300},
+		},
+		{
+			name:     "ok - native tokens first",
+			strategy: builder.NewLargestFirstStrategy(),
+			requirements: &builder.InputSelectionRequirements{
+				BaseTokens:   100,
+				NativeTokens: iotago.NativeTokenSum{nativeTokenID: big.NewInt(30)},
+			},
+			expectedAmounts: []iotago.BaseToken{20, 300},
+		},
+		{
+			name:         "err - not enough base tokens",
+			strategy:     builder.NewLargestFirstStrategy(),
+			requirements: &builder.InputSelectionRequirements{BaseTokens: 1000},
+			expectedErr:  builder.ErrInputSelectionNotEnoughBaseTokens,
+		},
+		{
+			name:     "err - not enough native tokens",
+			strategy: builder.NewLargestFirstStrategy(),
+			requirements: &builder.InputSelectionRequirements{
+				NativeTokens: iotago.NativeTokenSum{nativeTokenID: big.NewInt(50)},
+			},
+			expectedErr: builder.ErrInputSelectionNotEnoughNativeTokens,
+		},
+		{
+			name:         "err - not enough mana",
+			strategy:     builder.NewLargestFirstStrategy(),
+			requirements: &builder.InputSelectionRequirements{Mana: 11},
+			expectedErr:  builder.ErrInputSelectionNotEnoughMana,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := builder.NewInputSelector(tpkg.ZeroCostTestAPI, inputSelectionTestSlot, test.strategy).Select(test.requirements, candidates)
+			if test.expectedErr != nil {
+				require.True(t, ierrors.Is(err, test.expectedErr), "wrong error : %s != %s", err, test.expectedErr)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedAmounts, selectedAmounts(result))
+
+			// the timelocked candidate and the candidate of the other address are never selected
+			require.GreaterOrEqual(t, len(result.Skipped), 2)
+
+			// the selection is deterministic
+			again, err := builder.NewInputSelector(tpkg.ZeroCostTestAPI, inputSelectionTestSlot, test.strategy).Select(test.requirements, candidates)
+			require.NoError(t, err)
+			require.Equal(t, result.TxInputs(), again.TxInputs())
+		})
+	}
+}
+
+func TestInputSelectorSkipsCandidatesCreatedAfterTargetSlot(t *testing.T) {
+	addr := tpkg.RandEd25519Address()
+
+	newerCandidate := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      iotago.OutputIDFromTransactionIDAndIndex(iotago.NewTransactionID(inputSelectionTestSlot+1, tpkg.RandIdentifier()), 0),
+		Input:        builder.NewBasicOutputBuilder(addr, 1000).MustBuild(),
+	}
+	candidates := []*builder.TxInput{
+		newerCandidate,
+		inputSelectionCandidate(addr, 0, builder.NewBasicOutputBuilder(addr, 100).MustBuild()),
+	}
+
+	result, err := builder.NewInputSelector(tpkg.ZeroCostTestAPI, inputSelectionTestSlot, builder.NewLargestFirstStrategy()).Select(&builder.InputSelectionRequirements{BaseTokens: 50}, candidates)
+	require.NoError(t, err)
+
+	require.Len(t, result.Selected, 1)
+	require.Equal(t, iotago.BaseToken(100), result.Selected[0].BaseTokens())
+
+	require.Len(t, result.Skipped, 1)
+	require.Equal(t, newerCandidate, result.Skipped[0].TxInput)
+	require.ErrorIs(t, result.Skipped[0].Reason, builder.ErrInputSelectionCandidateNotUnlockable)
+
+	// the newer candidate can't cover the requirements either
+	_, err = builder.NewInputSelector(tpkg.ZeroCostTestAPI, inputSelectionTestSlot, builder.NewLargestFirstStrategy()).Select(&builder.InputSelectionRequirements{BaseTokens: 500}, candidates)
+	require.ErrorIs(t, err, builder.ErrInputSelectionNotEnoughBaseTokens)
+}
+
+func TestInputSelectorRemainderDeposit(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	addr := tpkg.RandEd25519Address()
+
+	minDeposit, err := builder.RemainderDeposit(api, addr, nil)
+	require.NoError(t, err)
+
+	candidates := []*builder.TxInput{
+		inputSelectionCandidate(addr, 0, builder.NewBasicOutputBuilder(addr, 10*minDeposit).MustBuild()),
+		inputSelectionCandidate(addr, 1, builder.NewBasicOutputBuilder(addr, 10*minDeposit).MustBuild()),
+	}
+
+	// the remainder of the first candidate would be below the minimum deposit, so the second one is needed as well
+	result, err := builder.NewInputSelector(api, inputSelectionTestSlot, builder.NewLargestFirstStrategy()).Select(&builder.InputSelectionRequirements{BaseTokens: 10*minDeposit - 1}, candidates)
+	require.NoError(t, err)
+	require.Len(t, result.Selected, 2)
+	require.Equal(t, 10*minDeposit+1, result.RemainderBaseTokens)
+
+	// the remainder can't cover the minimum deposit with all candidates
+	_, err = builder.NewInputSelector(api, inputSelectionTestSlot, builder.NewLargestFirstStrategy()).Select(&builder.InputSelectionRequirements{BaseTokens: 20*minDeposit - 1}, candidates)
+	require.ErrorIs(t, err, builder.ErrInputSelectionRemainderDepositNotCovered)
+}
+
+func TestInputSelectionStrategyOverflow(t *testing.T) {
+	addr := tpkg.RandEd25519Address()
+
+	candidate := func(index uint16, amount iotago.BaseToken, mana iotago.Mana) *builder.InputCandidate {
+		return &builder.InputCandidate{
+			TxInput: inputSelectionCandidate(addr, index, builder.NewBasicOutputBuilder(addr, amount).MustBuild()),
+			Mana:    mana,
+		}
+	}
+
+	t.Run("fail - base tokens overflow", func(t *testing.T) {
+		target := &builder.InputSelectionTarget{RequiredBaseTokens: math.MaxUint64}
+		candidates := []*builder.InputCandidate{
+			candidate(0, math.MaxUint64/2+1, 0),
+			candidate(1, math.MaxUint64/2+1, 0),
+		}
+
+		for _, strategy := range []builder.InputSelectionStrategy{
+			builder.NewLargestFirstStrategy(),
+			builder.NewMinimizeDustStrategy(),
+			builder.NewBranchAndBoundStrategy(100),
+		} {
+			_, err := strategy.Select(target, candidates)
+			require.ErrorIs(t, err, safemath.ErrIntegerOverflow, strategy.Name())
+		}
+
+		_, err := target.CoveredBy(candidates)
+		require.ErrorIs(t, err, safemath.ErrIntegerOverflow)
+	})
+
+	t.Run("fail - mana overflow", func(t *testing.T) {
+		target := &builder.InputSelectionTarget{RequiredBaseTokens: 2, RequiredMana: math.MaxUint64}
+		candidates := []*builder.InputCandidate{
+			candidate(0, 1, math.MaxUint64/2+1),
+			candidate(1, 1, math.MaxUint64/2+1),
+		}
+
+		_, err := builder.NewLargestFirstStrategy().Select(target, candidates)
+		require.ErrorIs(t, err, safemath.ErrIntegerOverflow)
+	})
+}
+
+func TestTransactionBuilderSelectInputs(t *testing.T) {
+	prvKey := tpkg.RandEd25519PrivateKey()
+	addr := iotago.Ed25519AddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+	signer := iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)
+
+	candidates := []*builder.TxInput{
+		inputSelectionCandidate(addr, 0, builder.NewBasicOutputBuilder(addr, 100).MustBuild()),
+		inputSelectionCandidate(addr, 1, builder.NewBasicOutputBuilder(addr, 200).MustBuild()),
+	}
+
+	txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signer).
+		AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 200).MustBuild())
+
+	result, err := txBuilder.SelectInputs(inputSelectionTestSlot, builder.NewBranchAndBoundStrategy(100), 0, candidates)
+	require.NoError(t, err)
+	require.Len(t, result.Selected, 1)
+	require.Zero(t, result.RemainderBaseTokens)
+
+	tx, err := txBuilder.Build()
+	require.NoError(t, err)
+	require.Len(t, tx.Transaction.TransactionEssence.Inputs, 1)
+}
@@ -0,0 +1,345 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrNFTCollectionNoNFTs gets returned if a collection without NFTs is built.
+	ErrNFTCollectionNoNFTs = ierrors.New("collection contains no NFTs")
+	// ErrNFTCollectionIssuerMismatch gets returned if an NFT was not issued by the collection NFT.
+	ErrNFTCollectionIssuerMismatch = ierrors.New("NFT was not issued by the collection")
+)
+
+// NFTCollectionTransaction is a transaction built by a NFTCollectionBuilder.
+type NFTCollectionTransaction struct {
+	// The signed transaction.
+	SignedTransaction *iotago.SignedTransaction
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The indexes of the NFTs added to the builder that are minted by the transaction, in the order of NFTs.
+	NFTIndexes []int
+	// The NFTs that were minted or transferred by the transaction.
+	NFTs []*TxInput
+	// The next state of the collection NFT, nil if the collection NFT was not transitioned.
+	Collection *TxInput
+	// The result of the input selection of the transaction.
+	Selection *InputSelectionResult
+	// The remainder outputs of the transaction, which are available to the following transactions of the plan.
+	Remainders []*TxInput
+}
+
+// NFTCollectionPlan is the outcome of a NFTCollectionBuilder.
+// The transactions need to be issued in order, since every transaction consumes the collection NFT
+// and possibly the remainder outputs of the transaction before it.
+type NFTCollectionPlan struct {
+	Transactions []*NFTCollectionTransaction
+	// The final state of the collection NFT.
+	Collection *TxInput
+	// The candidates that were not consumed by the transactions, including the remainder outputs of the last transaction.
+	Unspent []*TxInput
+}
+
+// collectionNFT is an NFT that gets minted by a NFTCollectionBuilder.
+type collectionNFT struct {
+	recipient iotago.Address
+	metadata  *iotago.IRC27Metadata
+}
+
+// NewNFTCollectionBuilder creates a new NFTCollectionBuilder for the given collection NFT, which is the issuer
+// of all minted NFTs, and sends the remainders to the given address.
+// The inputs are selected with the LargestFirst strategy, unless another strategy is set.
+func NewNFTCollectionBuilder(api iotago.API, signer iotago.AddressSigner, collection *TxInput, remainderAddress iotago.Address) *NFTCollectionBuilder {
+	maxWorkScore, err := api.ProtocolParameters().WorkScoreParameters().MaxBlockWork()
+
+	return &NFTCollectionBuilder{
+		api:              api,
+		signer:           signer,
+		collection:       collection,
+		remainderAddress: remainderAddress,
+		strategy:         NewLargestFirstStrategy(),
+		maxWorkScore:     maxWorkScore,
+		occurredBuildErr: err,
+	}
+}
+
+// NFTCollectionBuilder mints the NFTs of a collection. Every minted NFT has the collection NFT as its immutable issuer
+// and holds its IRC27 metadata in its immutable MetadataFeature.
+//
+// The issuer of a new NFT needs to be unlocked, so every transaction transitions the collection NFT.
+// The NFTs are split into as few transactions as the protocol limits allow, which are limited by the maximum
+// amount of inputs and outputs, by the maximum payload size of a block and by the maximum work score.
+// The mana of the inputs is stored in the collection NFT.
+type NFTCollectionBuilder struct {
+	api              iotago.API
+	signer           iotago.AddressSigner
+	collection       *TxInput
+	remainderAddress iotago.Address
+	strategy         InputSelectionStrategy
+	maxWorkScore     iotago.WorkScore
+	nfts             []*collectionNFT
+	occurredBuildErr error
+}
+
+// AddNFT adds an NFT with the given metadata to the collection, which is sent to the given recipient.
+func (b *NFTCollectionBuilder) AddNFT(recipient iotago.Address, metadata *iotago.IRC27Metadata) *NFTCollectionBuilder {
+	b.nfts = append(b.nfts, &collectionNFT{recipient: recipient, metadata: metadata})
+
+	return b
+}
+
+// Strategy sets the strategy used to select the inputs of every transaction.
+func (b *NFTCollectionBuilder) Strategy(strategy InputSelectionStrategy) *NFTCollectionBuilder {
+	b.strategy = strategy
+
+	return b
+}
+
+// MaxWorkScore sets the maximum work score of a transaction, which defaults to the maximum work score of a block.
+// The work score of the transaction includes the offset for the block it is issued in, see iotago.Block.WorkScore.
+func (b *NFTCollectionBuilder) MaxWorkScore(maxWorkScore iotago.WorkScore) *NFTCollectionBuilder {
+	b.maxWorkScore = maxWorkScore
+
+	return b
+}
+
+// Collection returns the current state of the collection NFT.
+func (b *NFTCollectionBuilder) Collection() *TxInput {
+	return b.collection
+}
+
+// Build mints the NFTs of the collection in transactions which are funded by the given candidates and returns the plan.
+// The NFTs are kept in order, every transaction mints as many of the following NFTs as the limits allow.
+// The target slot is the slot of the commitment the transactions are going to reference and is used as their creation slot.
+func (b *NFTCollectionBuilder) Build(targetSlot iotago.SlotIndex, candidates []*TxInput) (*NFTCollectionPlan, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	if len(b.nfts) == 0 {
+		return nil, ErrNFTCollectionNoNFTs
+	}
+
+	collectionAddress, err := collectionAddressOf(b.collection)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*iotago.NFTOutput, len(b.nfts))
+	for i, nft := range b.nfts {
+		output, err := b.nftOutput(collectionAddress, nft)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to build the output of NFT %d", i)
+		}
+		outputs[i] = output
+	}
+
+	// the collection NFT of the builder is only advanced once the whole plan was built
+	plan := &NFTCollectionPlan{Unspent: candidates, Collection: b.collection}
+	for start := 0; start < len(outputs); {
+		// one output is reserved for the collection NFT and one for the remainder
+		maxCount := min(len(outputs)-start, iotago.MaxOutputsCount-2)
+
+		transaction, err := buildLargestFitting(maxCount, func(count int) (*NFTCollectionTransaction, error) {
+			return b.buildMintTransaction(targetSlot, plan.Collection, outputs, start, count, plan.Unspent)
+		})
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to build a transaction for NFT %d", start)
+		}
+
+		plan.Transactions = append(plan.Transactions, transaction)
+		plan.Unspent = remainingCandidates(plan.Unspent, transaction.Selection.Selected, transaction.Remainders)
+		plan.Collection = transaction.Collection
+		start += len(transaction.NFTIndexes)
+	}
+	b.collection = plan.Collection
+
+	return plan, nil
+}
+
+// Transfer sends the given NFTs of the collection to the recipient, keeping their features.
+// The transaction is funded by the given candidates, its remaining mana is stored in the first remainder output
+// or in the first NFT if there is no remainder.
+func (b *NFTCollectionBuilder) Transfer(targetSlot iotago.SlotIndex, nfts []*TxInput, recipient iotago.Address, candidates []*TxInput) (*NFTCollectionTransaction, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	if len(nfts) == 0 {
+		return nil, ErrNFTCollectionNoNFTs
+	}
+
+	collectionAddress, err := collectionAddressOf(b.collection)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+	for _, nft := range nfts {
+		nftOutput, isNFT := nft.Input.(*iotago.NFTOutput)
+		if !isNFT {
+			return nil, ierrors.WithMessagef(ErrTransactionBuilder, "output %s of type %s is not an NFT", nft.InputID.ToHex(), nft.Input.Type())
+		}
+
+		if issuer := nftOutput.ImmutableFeatureSet().Issuer(); issuer == nil || !issuer.Address.Equal(collectionAddress) {
+			return nil, ierrors.WithMessagef(ErrNFTCollectionIssuerMismatch, "NFT %s", nft.InputID.ToHex())
+		}
+
+		nftID := nftOutput.NFTID
+		if nftID.Empty() {
+			nftID = iotago.NFTIDFromOutputID(nft.InputID)
+		}
+
+		nextNFT, err := NewNFTOutputBuilderFromPrevious(nftOutput).NFTID(nftID).Address(recipient).Mana(0).Build()
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to build the next state of NFT %s", nftID.ToHex())
+		}
+
+		txBuilder.AddInput(nft)
+		txBuilder.AddOutput(nextNFT)
+	}
+
+	selection, err := txBuilder.SelectInputs(targetSlot, b.strategy, 0, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder.AddRemainderOutputs(b.remainderAddress)
+	storedManaOutputIndex := 0
+	if remainderOutputIndexes := txBuilder.RemainderOutputIndexes(); len(remainderOutputIndexes) > 0 {
+		storedManaOutputIndex = remainderOutputIndexes[0]
+	}
+	txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, storedManaOutputIndex)
+
+	return b.build(txBuilder, selection, len(nfts))
+}
+
+// collectionAddressOf returns the address of the given collection NFT, which is derived from its output ID if it was just created.
+func collectionAddressOf(collection *TxInput) (*iotago.NFTAddress, error) {
+	collectionOutput, isNFT := collection.Input.(*iotago.NFTOutput)
+	if !isNFT {
+		return nil, ierrors.WithMessagef(ErrTransactionBuilder, "collection output is of type %s instead of an NFT", collection.Input.Type())
+	}
+
+	nftID := collectionOutput.NFTID
+	if nftID.Empty() {
+		nftID = iotago.NFTIDFromOutputID(collection.InputID)
+	}
+
+	//nolint:forcetypeassert // the address of an NFTID is always an NFTAddress
+	return nftID.ToAddress().(*iotago.NFTAddress), nil
+}
+
+// nftOutput returns the NFTOutput of the given NFT, issued by the collection and holding the validated metadata.
+func (b *NFTCollectionBuilder) nftOutput(collectionAddress *iotago.NFTAddress, nft *collectionNFT) (*iotago.NFTOutput, error) {
+	metadataFeature, err := nft.metadata.MetadataFeature(b.api.ProtocolParameters().Bech32HRP())
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := NewNFTOutputBuilder(nft.recipient, 0).
+		ImmutableIssuer(collectionAddress).
+		ImmutableMetadata(metadataFeature.Entries).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Amount, err = b.api.StorageScoreStructure().MinDeposit(output); err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the minimum storage deposit")
+	}
+
+	return output, nil
+}
+
+// buildMintTransaction builds a transaction consuming the given state of the collection NFT and minting count NFTs
+// starting at the given index and checks the protocol limits.
+func (b *NFTCollectionBuilder) buildMintTransaction(targetSlot iotago.SlotIndex, collection *TxInput, outputs []*iotago.NFTOutput, start int, count int, candidates []*TxInput) (*NFTCollectionTransaction, error) {
+	collectionAddress, err := collectionAddressOf(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert // the type was checked by collectionAddressOf
+	nextCollection, err := NewNFTOutputBuilderFromPrevious(collection.Input.(*iotago.NFTOutput)).
+		NFTID(collectionAddress.NFTID()).
+		Mana(0).
+		Build()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to build the next state of the collection NFT")
+	}
+
+	txBuilder := NewTransactionBuilder(b.api, b.signer).SetCreationSlot(targetSlot)
+	txBuilder.AddInput(collection)
+	txBuilder.AddOutput(nextCollection)
+
+	// every build uses copies of the outputs, so a failed build can't modify the outputs of the following ones
+	nftIndexes := make([]int, 0, count)
+	for i := start; i < start+count; i++ {
+		txBuilder.AddOutput(outputs[i].Clone())
+		nftIndexes = append(nftIndexes, i)
+	}
+
+	selection, err := txBuilder.SelectInputs(targetSlot, b.strategy, 0, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder.AddRemainderOutputs(b.remainderAddress)
+	txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, 0)
+
+	transaction, err := b.build(txBuilder, selection, count+1)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.NFTIndexes = nftIndexes
+	transaction.Collection, transaction.NFTs = transaction.NFTs[0], transaction.NFTs[1:]
+
+	return transaction, nil
+}
+
+// build builds the transaction, checks the protocol limits and collects the first nftCount outputs,
+// which are the NFTs of the transaction, and the remainders.
+func (b *NFTCollectionBuilder) build(txBuilder *TransactionBuilder, selection *InputSelectionResult, nftCount int) (*NFTCollectionTransaction, error) {
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTransactionLimits(b.api, signedTx, b.maxWorkScore); err != nil {
+		return nil, err
+	}
+
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	transaction := &NFTCollectionTransaction{
+		SignedTransaction: signedTx,
+		TransactionID:     transactionID,
+		NFTs:              make([]*TxInput, 0, nftCount),
+		Selection:         selection,
+	}
+
+	for index, output := range signedTx.Transaction.Outputs[:nftCount] {
+		//nolint:forcetypeassert // the first outputs are always NFTs
+		transaction.NFTs = append(transaction.NFTs, &TxInput{
+			UnlockTarget: output.(*iotago.NFTOutput).Owner(),
+			InputID:      iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:        output,
+		})
+	}
+
+	for _, index := range txBuilder.RemainderOutputIndexes() {
+		transaction.Remainders = append(transaction.Remainders, &TxInput{
+			UnlockTarget: b.remainderAddress,
+			InputID:      iotago.OutputIDFromTransactionIDAndIndex(transactionID, uint16(index)),
+			Input:        signedTx.Transaction.Outputs[index],
+		})
+	}
+
+	return transaction, nil
+}
@@ -0,0 +1,173 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestNFTCollectionBuilder(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	_, recipient, recipientKeys := tpkg.RandEd25519Identity()
+	targetSlot := iotago.SlotIndex(100)
+
+	collection := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      tpkg.RandOutputIDWithCreationSlot(10, 0),
+		Input:        builder.NewNFTOutputBuilder(addr, 1_000_000).MustBuild(),
+	}
+	collectionAddress := iotago.NFTIDFromOutputID(collection.InputID).ToAddress()
+	funding := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      tpkg.RandOutputIDWithCreationSlot(10, 1),
+		Input:        builder.NewBasicOutputBuilder(addr, 1_000_000_000).Mana(1_000_000).MustBuild(),
+	}
+
+	ledger := vm.InputSet{collection.InputID: collection.Input, funding.InputID: funding.Input}
+	novaVM := nova.NewVirtualMachine()
+	execute := func(t *testing.T, transaction *builder.NFTCollectionTransaction) {
+		t.Helper()
+
+		inputSet := vm.InputSet{}
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			inputSet[input.OutputID()] = ledger[input.OutputID()]
+		}
+
+		resolvedInputs := vm.ResolvedInputs{InputSet: inputSet}
+		unlockedAddrs, err := novaVM.ValidateUnlocks(transaction.SignedTransaction, resolvedInputs)
+		require.NoError(t, err)
+		require.NoError(t, lo.Return2(novaVM.Execute(transaction.SignedTransaction.Transaction, resolvedInputs, unlockedAddrs)))
+
+		for index, output := range transaction.SignedTransaction.Transaction.Outputs {
+			ledger[iotago.OutputIDFromTransactionIDAndIndex(transaction.TransactionID, uint16(index))] = output
+		}
+	}
+
+	nftMetadata := func(i int) *iotago.IRC27Metadata {
+		metadata := iotago.NewIRC27Metadata("image/png", fmt.Sprintf("https://example.com/%d.png", i), fmt.Sprintf("NFT #%d", i))
+		metadata.CollectionName = "collection"
+
+		return metadata
+	}
+
+	t.Run("fail - invalid metadata", func(t *testing.T) {
+		_, err := builder.NewNFTCollectionBuilder(api, signer, collection, addr).
+			AddNFT(recipient, iotago.NewIRC27Metadata("image/png", "", "NFT")).
+			Build(targetSlot, []*builder.TxInput{funding})
+		require.ErrorIs(t, err, iotago.ErrIRC27MetadataInvalid)
+	})
+
+	t.Run("fail - no NFTs", func(t *testing.T) {
+		_, err := builder.NewNFTCollectionBuilder(api, signer, collection, addr).Build(targetSlot, []*builder.TxInput{funding})
+		require.ErrorIs(t, err, builder.ErrNFTCollectionNoNFTs)
+	})
+
+	t.Run("fail - work score limit too low", func(t *testing.T) {
+		_, err := builder.NewNFTCollectionBuilder(api, signer, collection, addr).
+			AddNFT(recipient, nftMetadata(0)).
+			MaxWorkScore(1).
+			Build(targetSlot, []*builder.TxInput{funding})
+		require.ErrorIs(t, err, builder.ErrTransactionLimitExceeded)
+	})
+
+	// limit the work score, so the NFTs need to be split into several transactions
+	single, err := builder.NewNFTCollectionBuilder(api, signer, collection, addr).AddNFT(recipient, nftMetadata(0)).Build(targetSlot, []*builder.TxInput{funding})
+	require.NoError(t, err)
+	singleWorkScore, err := single.Transactions[0].SignedTransaction.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+	require.NoError(t, err)
+	maxWorkScore := singleWorkScore * 3
+
+	collectionBuilder := builder.NewNFTCollectionBuilder(api, signer, collection, addr).MaxWorkScore(maxWorkScore)
+	const nftCount = 20
+	for i := range nftCount {
+		collectionBuilder.AddNFT(recipient, nftMetadata(i))
+	}
+
+	plan, err := collectionBuilder.Build(targetSlot, []*builder.TxInput{funding})
+	require.NoError(t, err)
+	require.Greater(t, len(plan.Transactions), 1)
+	require.Equal(t, plan.Collection, collectionBuilder.Collection())
+
+	var minted []*builder.TxInput
+	for _, transaction := range plan.Transactions {
+		execute(t, transaction)
+
+		workScore, err := transaction.SignedTransaction.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+		require.NoError(t, err)
+		require.LessOrEqual(t, workScore, maxWorkScore)
+
+		// the collection NFT is transitioned by every transaction to unlock the issuer of the minted NFTs
+		require.Equal(t, collectionAddress.(*iotago.NFTAddress).NFTID(), transaction.Collection.Input.(*iotago.NFTOutput).NFTID)
+		require.Len(t, transaction.NFTs, len(transaction.NFTIndexes))
+
+		for i, nft := range transaction.NFTs {
+			nftOutput := nft.Input.(*iotago.NFTOutput)
+			require.True(t, collectionAddress.Equal(nftOutput.ImmutableFeatureSet().Issuer().Address))
+			require.True(t, recipient.Equal(nftOutput.Owner()))
+
+			metadata, err := iotago.IRC27MetadataFromFeature(nftOutput.ImmutableFeatureSet().Metadata())
+			require.NoError(t, err)
+			require.Equal(t, nftMetadata(transaction.NFTIndexes[i]).URI, metadata.URI)
+		}
+		minted = append(minted, transaction.NFTs...)
+	}
+	require.Len(t, minted, nftCount)
+
+	t.Run("fail - later transaction exceeds the limits", func(t *testing.T) {
+		// the last NFT alone exceeds the work score limit, so only the transactions before it can be built
+		oversized := nftMetadata(nftCount)
+		oversized.Description = strings.Repeat("a", 4000)
+
+		failingBuilder := builder.NewNFTCollectionBuilder(api, signer, collection, addr).MaxWorkScore(maxWorkScore)
+		for i := range nftCount {
+			failingBuilder.AddNFT(recipient, nftMetadata(i))
+		}
+		failingBuilder.AddNFT(recipient, oversized)
+
+		_, err := failingBuilder.Build(targetSlot, []*builder.TxInput{funding})
+		require.ErrorIs(t, err, builder.ErrTransactionLimitExceeded)
+
+		// the collection NFT is not advanced to an output of the discarded plan, so a retry consumes the original one
+		require.Equal(t, collection, failingBuilder.Collection())
+	})
+
+	t.Run("ok - transfer", func(t *testing.T) {
+		nfts := minted[:2]
+		nextOwner := tpkg.RandEd25519Address()
+		transaction, err := builder.NewNFTCollectionBuilder(api, iotago.NewInMemoryAddressSigner(recipientKeys), plan.Collection, recipient).
+			Transfer(targetSlot, nfts, nextOwner, nil)
+		require.NoError(t, err)
+		execute(t, transaction)
+
+		require.Len(t, transaction.NFTs, 2)
+		for i, nft := range transaction.NFTs {
+			nftOutput := nft.Input.(*iotago.NFTOutput)
+			require.True(t, nextOwner.Equal(nftOutput.Owner()))
+			require.Equal(t, iotago.NFTIDFromOutputID(nfts[i].InputID), nftOutput.NFTID)
+			require.Equal(t, nfts[i].Input.(*iotago.NFTOutput).ImmutableFeatures, nftOutput.ImmutableFeatures)
+		}
+	})
+
+	t.Run("fail - transfer NFT of another issuer", func(t *testing.T) {
+		otherNFT := &builder.TxInput{
+			UnlockTarget: addr,
+			InputID:      tpkg.RandOutputIDWithCreationSlot(10, 2),
+			Input:        builder.NewNFTOutputBuilder(addr, 1_000_000).ImmutableIssuer(tpkg.RandNFTAddress()).MustBuild(),
+		}
+
+		_, err := builder.NewNFTCollectionBuilder(api, signer, plan.Collection, addr).Transfer(targetSlot, []*builder.TxInput{otherNFT}, recipient, plan.Unspent)
+		require.ErrorIs(t, err, builder.ErrNFTCollectionIssuerMismatch)
+	})
+}
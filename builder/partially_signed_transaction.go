@@ -0,0 +1,316 @@
+package builder
+
+import (
+	"context"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrPartiallySignedTransactionMismatch gets returned if partially signed transactions of different transactions are merged.
+	ErrPartiallySignedTransactionMismatch = ierrors.New("partially signed transactions do not belong to the same transaction")
+	// ErrPartiallySignedTransactionUnknownSigner gets returned if a signature is added for an address which has no signature slot.
+	ErrPartiallySignedTransactionUnknownSigner = ierrors.New("address has no signature slot in the partially signed transaction")
+	// ErrPartiallySignedTransactionChanged gets returned if finalizing a partially signed transaction would change the signed transaction.
+	ErrPartiallySignedTransactionChanged = ierrors.New("finalizing the partially signed transaction changed the transaction")
+)
+
+// PartiallySignedTransaction is a transaction together with its resolved inputs and the signatures collected so far.
+// It is used to collect the signatures of several parties independently, e.g. of air-gapped signers or the participants
+// of a MultiAddress, before it gets finalized into an iotago.SignedTransaction.
+// It can be encoded to binary and JSON with the serix API.
+type PartiallySignedTransaction struct {
+	API iotago.API
+	// The transaction that needs to be signed.
+	Transaction *iotago.Transaction `serix:""`
+	// The resolved inputs in the same order as the inputs of the transaction.
+	Inputs []*PartiallySignedInput `serix:",lenPrefix=uint16"`
+	// The slots for the signatures of all addresses that can take part in unlocking the inputs.
+	SignatureSlots []*SignatureSlot `serix:",lenPrefix=uint16"`
+}
+
+// PartiallySignedInput is a resolved input of a PartiallySignedTransaction.
+type PartiallySignedInput struct {
+	// The ID of the referenced input.
+	InputID iotago.OutputID `serix:""`
+	// The output which is used as an input.
+	Input iotago.TxEssenceOutput `serix:""`
+	// The address which needs to be unlocked to spend this input.
+	UnlockTarget iotago.Address `serix:""`
+}
+
+// SignatureSlot holds the signature of a directly unlockable address, which is nil if it was not signed yet.
+type SignatureSlot struct {
+	// The address that needs to sign the transaction.
+	Address iotago.Address `serix:""`
+	// The signature of the address.
+	Signature iotago.Signature `serix:",optional"`
+}
+
+// BuildPartiallySigned returns a PartiallySignedTransaction of the transaction without signing it.
+// It contains a signature slot for every directly unlockable address that owns an input
+// and for every directly unlockable address that is part of a MultiAddress that owns an input.
+// Inputs owned by a member of a MultiAddress that owns an input are moved in front of the other inputs, like in Build.
+func (b *TransactionBuilder) BuildPartiallySigned() (*PartiallySignedTransaction, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	// the inputs are ordered for the unlocks before the transaction is captured,
+	// because the signing message must not change after the parties signed it
+	transaction := orderInputsForUnlocks(b.transaction, b.inputOwner, signerUIDFromAddress).Clone()
+	transaction.Allotments.Sort()
+	transaction.TransactionEssence.ContextInputs.Sort()
+
+	partiallySignedTx := &PartiallySignedTransaction{
+		API:            b.api,
+		Transaction:    transaction,
+		Inputs:         make([]*PartiallySignedInput, 0, len(transaction.TransactionEssence.Inputs)),
+		SignatureSlots: make([]*SignatureSlot, 0),
+	}
+
+	seenAddresses := make(map[string]struct{})
+	addSignatureSlot := func(addr iotago.Address) {
+		if _, isDirectUnlockable := addr.(iotago.DirectUnlockableAddress); !isDirectUnlockable {
+			return
+		}
+
+		if _, seen := seenAddresses[addr.Key()]; seen {
+			return
+		}
+		seenAddresses[addr.Key()] = struct{}{}
+
+		partiallySignedTx.SignatureSlots = append(partiallySignedTx.SignatureSlots, &SignatureSlot{Address: addr.Clone()})
+	}
+
+	for _, inputRef := range transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		inputID := inputRef.(*iotago.UTXOInput).OutputID()
+
+		input, exists := b.inputs[inputID]
+		if !exists {
+			return nil, ierrors.Errorf("input %s is not known to the builder", inputID.ToHex())
+		}
+		owner := b.inputOwner[inputID]
+
+		partiallySignedTx.Inputs = append(partiallySignedTx.Inputs, &PartiallySignedInput{
+			InputID:      inputID,
+			Input:        input.Clone(),
+			UnlockTarget: owner.Clone(),
+		})
+
+		if restrictedAddr, isRestricted := owner.(*iotago.RestrictedAddress); isRestricted {
+			owner = restrictedAddr.Address
+		}
+
+		if multiAddr, isMultiAddress := owner.(*iotago.MultiAddress); isMultiAddress {
+			for _, addrWithWeight := range multiAddr.Addresses {
+				addSignatureSlot(addrWithWeight.Address)
+			}
+
+			continue
+		}
+
+		addSignatureSlot(owner)
+	}
+
+	return partiallySignedTx, nil
+}
+
+// SetDeserializationContext sets the API of the PartiallySignedTransaction.
+func (p *PartiallySignedTransaction) SetDeserializationContext(ctx context.Context) {
+	p.API = iotago.APIFromContext(ctx)
+}
+
+// MissingSignatures returns the addresses of the signature slots that were not signed yet.
+func (p *PartiallySignedTransaction) MissingSignatures() []iotago.Address {
+	missing := make([]iotago.Address, 0)
+	for _, slot := range p.SignatureSlots {
+		if slot.Signature == nil {
+			missing = append(missing, slot.Address)
+		}
+	}
+
+	return missing
+}
+
+// Sign adds the signatures of all addresses the given signer holds the keys for and returns the amount of added signatures.
+// Signature slots that were already signed are skipped.
+func (p *PartiallySignedTransaction) Sign(signer iotago.AddressSigner) (int, error) {
+	txEssenceData, err := p.Transaction.SigningMessage()
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate tx transaction for signing message")
+	}
+
+	var signed int
+	for _, slot := range p.SignatureSlots {
+		if slot.Signature != nil {
+			continue
+		}
+
+		if _, err := signer.SignerUIDForAddress(slot.Address); err != nil {
+			// the signer doesn't hold the keys for this address
+			continue
+		}
+
+		signature, err := signer.Sign(slot.Address, txEssenceData)
+		if err != nil {
+			return signed, ierrors.Wrapf(err, "failed to sign transaction for address %s", slot.Address.Bech32(p.API.ProtocolParameters().Bech32HRP()))
+		}
+		slot.Signature = signature
+		signed++
+	}
+
+	return signed, nil
+}
+
+// AddSignature adds the given signature of the given address after verifying it against the transaction.
+func (p *PartiallySignedTransaction) AddSignature(addr iotago.Address, signature iotago.Signature) error {
+	slot := p.signatureSlot(addr)
+	if slot == nil {
+		return ierrors.WithMessagef(ErrPartiallySignedTransactionUnknownSigner, "address %s", addr.Bech32(p.API.ProtocolParameters().Bech32HRP()))
+	}
+
+	txEssenceData, err := p.Transaction.SigningMessage()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate tx transaction for signing message")
+	}
+
+	//nolint:forcetypeassert // only directly unlockable addresses have signature slots
+	if err := slot.Address.(iotago.DirectUnlockableAddress).Unlock(txEssenceData, signature); err != nil {
+		return ierrors.Wrapf(err, "invalid signature for address %s", addr.Bech32(p.API.ProtocolParameters().Bech32HRP()))
+	}
+	slot.Signature = signature
+
+	return nil
+}
+
+// Merge adds the signatures of the given PartiallySignedTransactions of the same transaction.
+// The signatures are verified before they are added. Signature slots that were already signed are kept.
+func (p *PartiallySignedTransaction) Merge(others ...*PartiallySignedTransaction) error {
+	txID, err := p.Transaction.ID()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	for _, other := range others {
+		otherTxID, err := other.Transaction.ID()
+		if err != nil {
+			return ierrors.Wrap(err, "failed to calculate the transaction ID")
+		}
+
+		if txID != otherTxID {
+			return ierrors.WithMessagef(ErrPartiallySignedTransactionMismatch, "transaction %s != %s", txID.ToHex(), otherTxID.ToHex())
+		}
+
+		for _, otherSlot := range other.SignatureSlots {
+			if otherSlot.Signature == nil {
+				continue
+			}
+
+			if slot := p.signatureSlot(otherSlot.Address); slot != nil && slot.Signature != nil {
+				continue
+			}
+
+			if err := p.AddSignature(otherSlot.Address, otherSlot.Signature); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Finalize creates the unlocks out of the collected signatures and returns the iotago.SignedTransaction.
+// Inputs owned by a MultiAddress only need the signatures to reach the threshold of the MultiAddress.
+// The transaction is used as it is, an error is returned if finalizing it would change its ID.
+func (p *PartiallySignedTransaction) Finalize() (*iotago.SignedTransaction, error) {
+	txID, err := p.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	signer := &partialSignatureSigner{signatures: make(map[string]iotago.Signature, len(p.SignatureSlots))}
+	for _, slot := range p.SignatureSlots {
+		if slot.Signature != nil {
+			signer.signatures[slot.Address.Key()] = slot.Signature
+		}
+	}
+
+	txBuilder := NewTransactionBuilder(p.API, signer)
+	txBuilder.transaction = p.Transaction.Clone()
+	// the inputs were already ordered by BuildPartiallySigned
+	txBuilder.keepInputOrder = true
+	for _, input := range p.Inputs {
+		txBuilder.inputs[input.InputID] = input.Input
+		txBuilder.inputOwner[input.InputID] = input.UnlockTarget
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	signedTxID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	if txID != signedTxID {
+		return nil, ierrors.WithMessagef(ErrPartiallySignedTransactionChanged, "transaction %s != %s", txID.ToHex(), signedTxID.ToHex())
+	}
+
+	return signedTx, nil
+}
+
+// signatureSlot returns the signature slot of the given address, or nil if there is none.
+func (p *PartiallySignedTransaction) signatureSlot(addr iotago.Address) *SignatureSlot {
+	for _, slot := range p.SignatureSlots {
+		if slot.Address.Equal(addr) {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// signerUIDFromAddress returns the signer UID of a directly unlockable address without knowing its keys.
+// It is the blake2b-256 hash of the public key, which is what the address is derived from.
+func signerUIDFromAddress(addr iotago.Address) (iotago.Identifier, bool) {
+	switch addr := addr.(type) {
+	case *iotago.Ed25519Address:
+		return iotago.Identifier(*addr), true
+	case *iotago.ImplicitAccountCreationAddress:
+		return iotago.Identifier(*addr), true
+	default:
+		return iotago.EmptyIdentifier, false
+	}
+}
+
+// partialSignatureSigner implements iotago.AddressSigner by returning the signatures collected in a PartiallySignedTransaction.
+type partialSignatureSigner struct {
+	signatures map[string]iotago.Signature
+}
+
+func (s *partialSignatureSigner) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	signature, exists := s.signatures[addr.Key()]
+	if !exists {
+		return iotago.EmptyIdentifier, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "missing signature for address %s", addr)
+	}
+
+	return signature.SignerUID(), nil
+}
+
+func (s *partialSignatureSigner) Sign(addr iotago.Address, _ []byte) (iotago.Signature, error) {
+	signature, exists := s.signatures[addr.Key()]
+	if !exists {
+		return nil, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "missing signature for address %s", addr)
+	}
+
+	return signature, nil
+}
+
+func (s *partialSignatureSigner) EmptySignatureForAddress(_ iotago.Address) (iotago.Signature, error) {
+	return &iotago.Ed25519Signature{}, nil
+}
@@ -0,0 +1,186 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/tpkg/frameworks"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+func TestPartiallySignedTransaction(t *testing.T) {
+	prvKeys := []ed25519.PrivateKey{tpkg.RandEd25519PrivateKey(), tpkg.RandEd25519PrivateKey(), tpkg.RandEd25519PrivateKey()}
+	addrs := make([]*iotago.Ed25519Address, len(prvKeys))
+	signers := make([]iotago.AddressSigner, len(prvKeys))
+	for i, prvKey := range prvKeys {
+		addrs[i] = iotago.Ed25519AddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+		signers[i] = iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)
+	}
+
+	addressesWithWeight := iotago.AddressesWithWeight{
+		{Address: addrs[1], Weight: 1},
+		{Address: addrs[2], Weight: 1},
+	}
+	addressesWithWeight.Sort()
+	multiAddr := iotago.NewMultiAddress(addressesWithWeight, 2)
+
+	inputs := []*builder.TxInput{
+		{UnlockTarget: addrs[0], InputID: tpkg.RandOutputID(0), Input: builder.NewBasicOutputBuilder(addrs[0], 100).MustBuild()},
+		{UnlockTarget: multiAddr, InputID: tpkg.RandOutputID(1), Input: builder.NewBasicOutputBuilder(multiAddr, 100).MustBuild()},
+	}
+
+	newPartiallySignedTx := func(t *testing.T) *builder.PartiallySignedTransaction {
+		t.Helper()
+
+		txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, nil).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 200).MustBuild())
+		for _, input := range inputs {
+			txBuilder.AddInput(input)
+		}
+
+		partiallySignedTx, err := txBuilder.BuildPartiallySigned()
+		require.NoError(t, err)
+
+		return partiallySignedTx
+	}
+
+	// every party signs its own copy, which is transferred in its binary encoding
+	signCopy := func(t *testing.T, partiallySignedTx *builder.PartiallySignedTransaction, signer iotago.AddressSigner) *builder.PartiallySignedTransaction {
+		t.Helper()
+
+		data, err := tpkg.ZeroCostTestAPI.Encode(partiallySignedTx)
+		require.NoError(t, err)
+
+		partiallySignedTxCopy := &builder.PartiallySignedTransaction{}
+		_, err = tpkg.ZeroCostTestAPI.Decode(data, partiallySignedTxCopy)
+		require.NoError(t, err)
+
+		signed, err := partiallySignedTxCopy.Sign(signer)
+		require.NoError(t, err)
+		require.Equal(t, 1, signed)
+
+		return partiallySignedTxCopy
+	}
+
+	t.Run("ok - serialization", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTx(t)
+		_, err := partiallySignedTx.Sign(signers[0])
+		require.NoError(t, err)
+
+		(&frameworks.DeSerializeTest{
+			Name:   "ok - partially signed transaction",
+			Source: partiallySignedTx,
+			Target: &builder.PartiallySignedTransaction{},
+		}).Run(t)
+	})
+
+	t.Run("ok - sign, merge and finalize", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTx(t)
+		require.Len(t, partiallySignedTx.SignatureSlots, 3)
+		require.Len(t, partiallySignedTx.MissingSignatures(), 3)
+
+		_, err := partiallySignedTx.Finalize()
+		require.ErrorIs(t, err, iotago.ErrAddressKeysNotMapped)
+
+		require.NoError(t, partiallySignedTx.Merge(
+			signCopy(t, partiallySignedTx, signers[0]),
+			signCopy(t, partiallySignedTx, signers[1]),
+			signCopy(t, partiallySignedTx, signers[2]),
+		))
+		require.Empty(t, partiallySignedTx.MissingSignatures())
+
+		signedTx, err := partiallySignedTx.Finalize()
+		require.NoError(t, err)
+		require.IsType(t, &iotago.SignatureUnlock{}, signedTx.Unlocks[0])
+		require.IsType(t, &iotago.MultiUnlock{}, signedTx.Unlocks[1])
+
+		inputSet := vm.InputSet{}
+		for _, input := range inputs {
+			inputSet[input.InputID] = input.Input
+		}
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: inputSet})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok - input of a multi address member after the multi address input", func(t *testing.T) {
+		multiInput := &builder.TxInput{UnlockTarget: multiAddr, InputID: tpkg.RandOutputID(0), Input: builder.NewBasicOutputBuilder(multiAddr, 100).MustBuild()}
+		memberInput := &builder.TxInput{UnlockTarget: addrs[1], InputID: tpkg.RandOutputID(1), Input: builder.NewBasicOutputBuilder(addrs[1], 100).MustBuild()}
+
+		partiallySignedTx, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, nil).
+			AddInput(multiInput).
+			AddInput(memberInput).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 200).MustBuild()).
+			BuildPartiallySigned()
+		require.NoError(t, err)
+
+		// the input of the member is moved in front before anyone signs
+		require.Equal(t, iotago.TxEssenceInputs{memberInput.InputID.UTXOInput(), multiInput.InputID.UTXOInput()}, partiallySignedTx.Transaction.TransactionEssence.Inputs)
+		require.Equal(t, memberInput.InputID, partiallySignedTx.Inputs[0].InputID)
+		txID, err := partiallySignedTx.Transaction.ID()
+		require.NoError(t, err)
+
+		require.NoError(t, partiallySignedTx.Merge(
+			signCopy(t, partiallySignedTx, signers[1]),
+			signCopy(t, partiallySignedTx, signers[2]),
+		))
+
+		signedTx, err := partiallySignedTx.Finalize()
+		require.NoError(t, err)
+
+		signedTxID, err := signedTx.Transaction.ID()
+		require.NoError(t, err)
+		require.Equal(t, txID, signedTxID)
+
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: vm.InputSet{
+			multiInput.InputID:  multiInput.Input,
+			memberInput.InputID: memberInput.Input,
+		}})
+		require.NoError(t, err)
+	})
+
+	t.Run("err - finalizing changes the transaction", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTx(t)
+		require.NoError(t, partiallySignedTx.Merge(
+			signCopy(t, partiallySignedTx, signers[0]),
+			signCopy(t, partiallySignedTx, signers[1]),
+			signCopy(t, partiallySignedTx, signers[2]),
+		))
+
+		// the allotments are sorted when the transaction is finalized
+		allotments := iotago.Allotments{
+			{AccountID: tpkg.RandAccountID(), Mana: 1},
+			{AccountID: tpkg.RandAccountID(), Mana: 2},
+		}
+		allotments.Sort()
+		partiallySignedTx.Transaction.Allotments = iotago.Allotments{allotments[1], allotments[0]}
+
+		_, err := partiallySignedTx.Finalize()
+		require.ErrorIs(t, err, builder.ErrPartiallySignedTransactionChanged)
+	})
+
+	t.Run("err - threshold of multi address not reached", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTx(t)
+		require.NoError(t, partiallySignedTx.Merge(
+			signCopy(t, partiallySignedTx, signers[0]),
+			signCopy(t, partiallySignedTx, signers[1]),
+		))
+
+		_, err := partiallySignedTx.Finalize()
+		require.ErrorIs(t, err, iotago.ErrMultiAddressUnlockThresholdNotReached)
+	})
+
+	t.Run("err - invalid signature", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTx(t)
+		otherPartiallySignedTx := signCopy(t, newPartiallySignedTx(t), signers[0])
+
+		require.ErrorIs(t, partiallySignedTx.Merge(otherPartiallySignedTx), builder.ErrPartiallySignedTransactionMismatch)
+		require.Error(t, partiallySignedTx.AddSignature(addrs[0], otherPartiallySignedTx.SignatureSlots[0].Signature))
+		require.ErrorIs(t, partiallySignedTx.AddSignature(tpkg.RandEd25519Address(), otherPartiallySignedTx.SignatureSlots[0].Signature), builder.ErrPartiallySignedTransactionUnknownSigner)
+	})
+}
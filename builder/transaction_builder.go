@@ -1,6 +1,9 @@
 package builder
 
 import (
+	"math/big"
+	"slices"
+
 	"github.com/iotaledger/hive.go/core/safemath"
 	"github.com/iotaledger/hive.go/ierrors"
 	iotago "github.com/iotaledger/iota.go/v4"
@@ -40,6 +43,15 @@ type TransactionBuilder struct {
 	inputs           iotago.OutputSet
 	inputOwner       map[iotago.OutputID]iotago.Address
 	rewards          iotago.Mana
+	// additionalSigners holds the signers which are used in addition to the signer of the builder,
+	// e.g. to collect the signatures of the participants of a MultiAddress.
+	additionalSigners []iotago.AddressSigner
+	// remainderOutputIndexes holds the indexes of the outputs added by AddRemainderOutputs.
+	remainderOutputIndexes []int
+	// burnedNativeTokens holds the native tokens burned by the transaction.
+	burnedNativeTokens iotago.NativeTokenSum
+	// keepInputOrder is set if the inputs are already ordered for the unlocks and must not be moved by Build.
+	keepInputOrder bool
 }
 
 // TxInput defines an input with the address to unlock.
@@ -58,18 +70,41 @@ func (b *TransactionBuilder) Clone() *TransactionBuilder {
 		cpyInputOwner[outputID] = address.Clone()
 	}
 
+	var cpyBurnedNativeTokens iotago.NativeTokenSum
+	for nativeTokenID, amount := range b.burnedNativeTokens {
+		if cpyBurnedNativeTokens == nil {
+			cpyBurnedNativeTokens = make(iotago.NativeTokenSum, len(b.burnedNativeTokens))
+		}
+		cpyBurnedNativeTokens[nativeTokenID] = new(big.Int).Set(amount)
+	}
+
 	return &TransactionBuilder{
-		api:              b.api,
-		signer:           b.signer,
-		occurredBuildErr: b.occurredBuildErr,
-		transaction:      b.transaction.Clone(),
-		inputs:           b.inputs.Clone(),
-		inputOwner:       cpyInputOwner,
-		rewards:          b.rewards,
+		api:                    b.api,
+		signer:                 b.signer,
+		occurredBuildErr:       b.occurredBuildErr,
+		transaction:            b.transaction.Clone(),
+		inputs:                 b.inputs.Clone(),
+		inputOwner:             cpyInputOwner,
+		rewards:                b.rewards,
+		additionalSigners:      slices.Clone(b.additionalSigners),
+		remainderOutputIndexes: slices.Clone(b.remainderOutputIndexes),
+		burnedNativeTokens:     cpyBurnedNativeTokens,
+		keepInputOrder:         b.keepInputOrder,
 	}
 }
 
+// AddSigners adds further signers to the builder.
+// They are used for all addresses the signer of the builder has no keys for,
+// e.g. to collect the signatures of several participants to unlock inputs owned by a MultiAddress.
+func (b *TransactionBuilder) AddSigners(signers ...iotago.AddressSigner) *TransactionBuilder {
+	b.additionalSigners = append(b.additionalSigners, signers...)
+
+	return b
+}
+
 // AddInput adds the given input to the builder.
+// The inputs keep the order they were added in, except for inputs owned by a signer of a multi address,
+// which are moved in front of the other inputs of the payload returned by Build.
 func (b *TransactionBuilder) AddInput(input *TxInput) *TransactionBuilder {
 	b.inputOwner[input.InputID] = input.UnlockTarget
 	b.transaction.TransactionEssence.Inputs = append(b.transaction.TransactionEssence.Inputs, input.InputID.UTXOInput())
@@ -78,6 +113,56 @@ func (b *TransactionBuilder) AddInput(input *TxInput) *TransactionBuilder {
 	return b
 }
 
+// SelectInputs selects inputs out of the given candidates to cover the outputs and allotments that were
+// already added to the builder, as well as the given additional mana, and adds them to the builder.
+// The inputs that were already added to the builder are always part of the selection.
+// The target slot is the slot of the commitment the transaction is going to reference.
+func (b *TransactionBuilder) SelectInputs(targetSlot iotago.SlotIndex, strategy InputSelectionStrategy, additionalMana iotago.Mana, candidates []*TxInput) (*InputSelectionResult, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	requirements, err := NewInputSelectionRequirements(b.transaction.Outputs, b.transaction.Allotments)
+	if err != nil {
+		return nil, err
+	}
+
+	if requirements.Mana, err = safemath.SafeAdd(requirements.Mana, additionalMana); err != nil {
+		return nil, ierrors.Wrap(err, "failed to add additional mana to the requirements")
+	}
+	requirements.AdditionalMana = b.rewards
+
+	// minted tokens don't need to be covered by the inputs, while melted and burned tokens do
+	for nativeTokenID, delta := range b.foundryTokenDeltas() {
+		addNativeToken(requirements.NativeTokens, nativeTokenID, new(big.Int).Neg(delta))
+	}
+	for nativeTokenID, amount := range b.burnedNativeTokens {
+		addNativeToken(requirements.NativeTokens, nativeTokenID, amount)
+	}
+	requirements.NativeTokens = subNativeTokens(requirements.NativeTokens, iotago.NativeTokenSum{})
+
+	for _, input := range b.transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		inputID := input.(*iotago.UTXOInput).OutputID()
+		requirements.Preselected = append(requirements.Preselected, &TxInput{
+			UnlockTarget: b.inputOwner[inputID],
+			InputID:      inputID,
+			Input:        b.inputs[inputID],
+		})
+	}
+
+	result, err := NewInputSelector(b.api, targetSlot, strategy).Select(requirements, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, selected := range result.Selected[len(requirements.Preselected):] {
+		b.AddInput(selected.TxInput)
+	}
+
+	return result, nil
+}
+
 // TransactionBuilderInputFilter is a filter function which determines whether
 // an input should be used or not. (returning true = pass). The filter can also
 // be used to accumulate data over the set of inputs, i.e. the input sum etc.
@@ -90,6 +175,19 @@ func (b *TransactionBuilder) AddCommitmentInput(commitmentInput *iotago.Commitme
 	return b
 }
 
+// SetCommitmentInput replaces the commitment input of the builder with the given one, or adds it if there is none.
+func (b *TransactionBuilder) SetCommitmentInput(commitmentInput *iotago.CommitmentInput) *TransactionBuilder {
+	for index, contextInput := range b.transaction.TransactionEssence.ContextInputs {
+		if _, isCommitmentInput := contextInput.(*iotago.CommitmentInput); isCommitmentInput {
+			b.transaction.TransactionEssence.ContextInputs[index] = commitmentInput
+
+			return b
+		}
+	}
+
+	return b.AddCommitmentInput(commitmentInput)
+}
+
 // AddBlockIssuanceCreditInput adds the given block issuance credit input to the builder.
 func (b *TransactionBuilder) AddBlockIssuanceCreditInput(blockIssuanceCreditInput *iotago.BlockIssuanceCreditInput) *TransactionBuilder {
 	b.transaction.TransactionEssence.ContextInputs = append(b.transaction.TransactionEssence.ContextInputs, blockIssuanceCreditInput)
@@ -135,6 +233,17 @@ func (b *TransactionBuilder) AddOutput(output iotago.Output) *TransactionBuilder
 	return b
 }
 
+// BurnNativeToken burns the given amount of the native token, so that it is not added to the remainder outputs.
+// The capabilities of the transaction need to allow burning native tokens.
+func (b *TransactionBuilder) BurnNativeToken(nativeTokenID iotago.NativeTokenID, amount *big.Int) *TransactionBuilder {
+	if b.burnedNativeTokens == nil {
+		b.burnedNativeTokens = make(iotago.NativeTokenSum)
+	}
+	addNativeToken(b.burnedNativeTokens, nativeTokenID, amount)
+
+	return b
+}
+
 // WithTransactionCapabilities sets the capabilities of the transaction.
 func (b *TransactionBuilder) WithTransactionCapabilities(capabilities iotago.TransactionCapabilitiesBitMask) *TransactionBuilder {
 	b.transaction.Capabilities = capabilities
@@ -666,6 +775,8 @@ func (b *TransactionBuilder) MinRequiredAllottedMana(rmc iotago.Mana, blockIssue
 }
 
 // Build signs the transaction essence and returns the built payload.
+// Inputs owned by a signer of a multi address are moved in front of the other inputs of the built payload,
+// so their signatures can be referenced. The inputs and reward inputs of the builder itself are not reordered.
 func (b *TransactionBuilder) Build() (*iotago.SignedTransaction, error) {
 	return b.build(true)
 }
@@ -676,48 +787,41 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 	switch {
 	case b.occurredBuildErr != nil:
 		return nil, b.occurredBuildErr
-	case b.signer == nil:
+	case len(b.signers()) == 0:
 		return nil, ierrors.WithMessage(ErrTransactionBuilder, "must supply signer")
 	}
 
-	b.transaction.Allotments.Sort()
-	b.transaction.TransactionEssence.ContextInputs.Sort()
+	transaction := b.transaction
+	if !b.keepInputOrder {
+		transaction = orderInputsForUnlocks(b.transaction, b.inputOwner, b.signerUIDForAddress)
+	}
+	transaction.Allotments.Sort()
+	transaction.TransactionEssence.ContextInputs.Sort()
 
 	// prepare the inputs commitment in the same order as the inputs in the essence
 	var inputIDs iotago.OutputIDs
-	for _, input := range b.transaction.TransactionEssence.Inputs {
+	for _, input := range transaction.TransactionEssence.Inputs {
 		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
 		inputIDs = append(inputIDs, input.(*iotago.UTXOInput).OutputID())
 	}
 
 	inputs := inputIDs.OrderedSet(b.inputs)
 
-	txEssenceData, err := b.transaction.SigningMessage()
+	txEssenceData, err := transaction.SigningMessage()
 	if err != nil {
 		return nil, ierrors.Wrap(err, "failed to calculate tx transaction for signing message")
 	}
 
 	unlockedSet := &txBuilderUnlockedSet{
-		unlocks:        iotago.Unlocks{},
-		signerUIDs:     map[iotago.Identifier]int{},
-		unlockedChains: map[string]int{},
-	}
-
-	// resolveUnderlyingAddress returns the underlying address in case of a restricted address.
-	// this way we handle restricted addresses like normal addresses in the unlock logic.
-	resolveUnderlyingAddress := func(addr iotago.Address) iotago.Address {
-		switch addr := addr.(type) {
-		case *iotago.RestrictedAddress:
-			return addr.Address
-		default:
-			return addr
-		}
+		unlocks:                iotago.Unlocks{},
+		signerUIDs:             map[iotago.Identifier]int{},
+		unlockedChains:         map[string]int{},
+		unlockedMultiAddresses: map[string]int{},
 	}
 
-	for inputIndex, inputRef := range b.transaction.TransactionEssence.Inputs {
+	for inputIndex, inputRef := range transaction.TransactionEssence.Inputs {
 		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
-		owner := b.inputOwner[inputRef.(*iotago.UTXOInput).OutputID()]
-		owner = resolveUnderlyingAddress(owner)
+		owner := resolveUnderlyingAddress(b.inputOwner[inputRef.(*iotago.UTXOInput).OutputID()])
 
 		chainAddr, isChainAddress := owner.(iotago.ChainAddress)
 		if isChainAddress {
@@ -738,31 +842,42 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 			continue
 		}
 
+		if multiAddr, isMultiAddress := owner.(*iotago.MultiAddress); isMultiAddress {
+			if unlockedAtIndex, isUnlocked := unlockedSet.unlockedMultiAddresses[multiAddr.Key()]; isUnlocked {
+				// add a referential unlock to the former multi unlock position
+				unlockedSet.addReferentialUnlock(owner, unlockedAtIndex)
+			} else {
+				multiUnlock, err := b.multiUnlock(inputIndex, multiAddr, unlockedSet, txEssenceData, signEssence)
+				if err != nil {
+					return nil, err
+				}
+
+				unlockedSet.addUnlock(multiUnlock)
+				unlockedSet.unlockedMultiAddresses[multiAddr.Key()] = inputIndex
+			}
+
+			// always mark the chain as unlocked in case the output is a chain output
+			// e.g. "an account owned by a multi address".
+			unlockedSet.addChainAsUnlocked(inputs[inputIndex], inputIndex)
+
+			continue
+		}
+
 		if _, isDirectUnlockable := owner.(iotago.DirectUnlockableAddress); !isDirectUnlockable {
-			// we only support directly unlockable addresses in the transaction builder for now
-			return nil, ierrors.Errorf("input %d's owning address is not directly unlockable, address %s, type %s", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), owner.Type())
+			return nil, ierrors.Errorf("input %d's owning address can't be unlocked by the transaction builder, address %s, type %s", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), owner.Type())
 		}
 
 		// get the signer UID for the directly unlockable address
-		signerUID, err := b.signer.SignerUIDForAddress(owner)
+		signer, signerUID, err := b.signerForAddress(owner)
 		if err != nil {
 			return nil, ierrors.Wrapf(err, "failed to get signer UID for address %s", owner.Bech32(b.api.ProtocolParameters().Bech32HRP()))
 		}
 
 		unlockedAtIndex, alreadyUnlocked := unlockedSet.signerUIDs[signerUID]
 		if !alreadyUnlocked {
-			var err error
-			var signature iotago.Signature
-			if signEssence {
-				// sign the tx essence data
-				signature, err = b.signer.Sign(owner, txEssenceData)
-			} else {
-				// sign with empty signature.
-				// this is used for example to calculate the workscore of the transaction before the actual signing.
-				signature, err = b.signer.EmptySignatureForAddress(owner)
-			}
+			signature, err := signAddress(signer, owner, txEssenceData, signEssence)
 			if err != nil {
-				return nil, ierrors.Wrapf(err, "failed to sign transaction")
+				return nil, err
 			}
 
 			// add the new signature to the unlocks
@@ -782,13 +897,232 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 
 	sigTxPayload := &iotago.SignedTransaction{
 		API:         b.api,
-		Transaction: b.transaction,
+		Transaction: transaction,
 		Unlocks:     unlockedSet.unlocks,
 	}
 
 	return sigTxPayload, nil
 }
 
+// resolveUnderlyingAddress returns the underlying address in case of a restricted address.
+// this way we handle restricted addresses like normal addresses in the unlock logic.
+func resolveUnderlyingAddress(addr iotago.Address) iotago.Address {
+	switch addr := addr.(type) {
+	case *iotago.RestrictedAddress:
+		return addr.Address
+	default:
+		return addr
+	}
+}
+
+// orderInputsForUnlocks returns the transaction with the inputs owned by a signer of a multi address moved in front of the other inputs.
+// Signatures inside of a multi unlock can't be referenced and a signer must not sign twice,
+// so the signature needs to be part of a signature unlock which is referenced by the multi unlocks instead.
+// The order of the inputs is kept otherwise and the input indexes of the reward inputs are updated.
+// The given transaction is not modified, a copy is returned if the inputs need to be reordered.
+func orderInputsForUnlocks(transaction *iotago.Transaction, inputOwner map[iotago.OutputID]iotago.Address, signerUIDForAddress func(addr iotago.Address) (iotago.Identifier, bool)) *iotago.Transaction {
+	multiSignerUIDs := make(map[iotago.Identifier]struct{})
+	for _, inputRef := range transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		multiAddr, isMultiAddress := resolveUnderlyingAddress(inputOwner[inputRef.(*iotago.UTXOInput).OutputID()]).(*iotago.MultiAddress)
+		if !isMultiAddress {
+			continue
+		}
+
+		for _, addrWithWeight := range multiAddr.Addresses {
+			if signerUID, exists := signerUIDForAddress(addrWithWeight.Address); exists {
+				multiSignerUIDs[signerUID] = struct{}{}
+			}
+		}
+	}
+
+	if len(multiSignerUIDs) == 0 {
+		return transaction
+	}
+
+	signedByMultiSigner := func(inputRef iotago.Input) bool {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		owner := resolveUnderlyingAddress(inputOwner[inputRef.(*iotago.UTXOInput).OutputID()])
+		if _, isDirectUnlockable := owner.(iotago.DirectUnlockableAddress); !isDirectUnlockable {
+			return false
+		}
+
+		signerUID, exists := signerUIDForAddress(owner)
+		if !exists {
+			return false
+		}
+		_, isMultiSigner := multiSignerUIDs[signerUID]
+
+		return isMultiSigner
+	}
+
+	inputs := transaction.TransactionEssence.Inputs
+	ordered := make(iotago.TxEssenceInputs, 0, len(inputs))
+	newIndexes := make(map[uint16]uint16, len(inputs))
+	var reordered bool
+	for _, moveToFront := range []bool{true, false} {
+		for inputIndex, inputRef := range inputs {
+			if signedByMultiSigner(inputRef) == moveToFront {
+				newIndexes[uint16(inputIndex)] = uint16(len(ordered))
+				reordered = reordered || inputIndex != len(ordered)
+				ordered = append(ordered, inputRef)
+			}
+		}
+	}
+
+	if !reordered {
+		return transaction
+	}
+
+	orderedTransaction := transaction.Clone()
+	orderedTransaction.TransactionEssence.Inputs = ordered
+	for i, contextInput := range orderedTransaction.TransactionEssence.ContextInputs {
+		if rewardInput, isRewardInput := contextInput.(*iotago.RewardInput); isRewardInput {
+			orderedTransaction.TransactionEssence.ContextInputs[i] = &iotago.RewardInput{Index: newIndexes[rewardInput.Index]}
+		}
+	}
+
+	return orderedTransaction
+}
+
+// signerUIDForAddress returns the signer UID of the first signer that holds the keys for the given address.
+func (b *TransactionBuilder) signerUIDForAddress(addr iotago.Address) (iotago.Identifier, bool) {
+	_, signerUID, err := b.signerForAddress(addr)
+
+	return signerUID, err == nil
+}
+
+// signers returns all signers of the builder.
+func (b *TransactionBuilder) signers() []iotago.AddressSigner {
+	signers := make([]iotago.AddressSigner, 0, len(b.additionalSigners)+1)
+	if b.signer != nil {
+		signers = append(signers, b.signer)
+	}
+
+	for _, signer := range b.additionalSigners {
+		if signer != nil {
+			signers = append(signers, signer)
+		}
+	}
+
+	return signers
+}
+
+// signerForAddress returns the first signer that holds the keys for the given address and its signer UID.
+func (b *TransactionBuilder) signerForAddress(addr iotago.Address) (iotago.AddressSigner, iotago.Identifier, error) {
+	var lastErr error
+	for _, signer := range b.signers() {
+		signerUID, err := signer.SignerUIDForAddress(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		return signer, signerUID, nil
+	}
+
+	return nil, iotago.EmptyIdentifier, lastErr
+}
+
+// signAddress signs the tx essence data for the given address.
+// Depending on the value of "signEssence" it either signs the essence or returns an empty signature.
+func signAddress(signer iotago.AddressSigner, addr iotago.Address, txEssenceData []byte, signEssence bool) (iotago.Signature, error) {
+	var err error
+	var signature iotago.Signature
+	if signEssence {
+		// sign the tx essence data
+		signature, err = signer.Sign(addr, txEssenceData)
+	} else {
+		// sign with empty signature.
+		// this is used for example to calculate the workscore of the transaction before the actual signing.
+		signature, err = signer.EmptySignatureForAddress(addr)
+	}
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to sign transaction")
+	}
+
+	return signature, nil
+}
+
+// multiUnlock creates the MultiUnlock for the given MultiAddress.
+// Addresses which were already unlocked by former inputs are referenced, because they don't need an additional signature.
+// Afterwards the signatures of the signers of the builder are collected until the threshold of the MultiAddress is reached.
+// All remaining addresses get an EmptyUnlock.
+func (b *TransactionBuilder) multiUnlock(inputIndex int, owner *iotago.MultiAddress, unlockedSet *txBuilderUnlockedSet, txEssenceData []byte, signEssence bool) (*iotago.MultiUnlock, error) {
+	unlocks := make([]iotago.Unlock, len(owner.Addresses))
+	var cumulativeWeight uint16
+
+	// reuse the unlocks of former inputs first
+	for subIndex, addrWithWeight := range owner.Addresses {
+		if cumulativeWeight >= owner.Threshold {
+			break
+		}
+
+		var unlockedAtIndex int
+		var isUnlocked bool
+		switch addr := addrWithWeight.Address.(type) {
+		case iotago.ChainAddress:
+			unlockedAtIndex, isUnlocked = unlockedSet.isChainUnlocked(addr)
+			if isUnlocked {
+				// unlocks inside of multi unlocks are not allowed to reference other multi unlocks
+				_, isMultiUnlock := unlockedSet.unlocks[unlockedAtIndex].(*iotago.MultiUnlock)
+				isUnlocked = !isMultiUnlock
+			}
+
+		case iotago.DirectUnlockableAddress:
+			if _, signerUID, err := b.signerForAddress(addr); err == nil {
+				unlockedAtIndex, isUnlocked = unlockedSet.signerUIDs[signerUID]
+			}
+		}
+
+		if isUnlocked {
+			unlocks[subIndex] = referentialUnlock(addrWithWeight.Address, unlockedAtIndex)
+			cumulativeWeight += uint16(addrWithWeight.Weight)
+		}
+	}
+
+	// collect the missing signatures
+	for subIndex, addrWithWeight := range owner.Addresses {
+		if cumulativeWeight >= owner.Threshold {
+			break
+		}
+
+		if unlocks[subIndex] != nil {
+			continue
+		}
+
+		if _, isDirectUnlockable := addrWithWeight.Address.(iotago.DirectUnlockableAddress); !isDirectUnlockable {
+			continue
+		}
+
+		signer, _, err := b.signerForAddress(addrWithWeight.Address)
+		if err != nil {
+			// none of the signers holds the keys for this address
+			continue
+		}
+
+		signature, err := signAddress(signer, addrWithWeight.Address, txEssenceData, signEssence)
+		if err != nil {
+			return nil, err
+		}
+
+		unlocks[subIndex] = &iotago.SignatureUnlock{Signature: signature}
+		cumulativeWeight += uint16(addrWithWeight.Weight)
+	}
+
+	if cumulativeWeight < owner.Threshold {
+		return nil, ierrors.WithMessagef(iotago.ErrMultiAddressUnlockThresholdNotReached, "input %d's multi address %s can't be unlocked by the signers of the builder, weight %d < threshold %d", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), cumulativeWeight, owner.Threshold)
+	}
+
+	for subIndex, unlock := range unlocks {
+		if unlock == nil {
+			unlocks[subIndex] = &iotago.EmptyUnlock{}
+		}
+	}
+
+	return &iotago.MultiUnlock{Unlocks: unlocks}, nil
+}
+
 // txBuilderUnlockedSet is a helper struct to keep track of the unlocked inputs and their positions.
 type txBuilderUnlockedSet struct {
 	// unlocks holds the unlocks for the tx inputs.
@@ -797,6 +1131,8 @@ type txBuilderUnlockedSet struct {
 	signerUIDs map[iotago.Identifier]int
 	// unlockedChains maps the chain address key to the position of the unlock in the unlocks slice.
 	unlockedChains map[string]int
+	// unlockedMultiAddresses maps the multi address key to the position of the multi unlock in the unlocks slice.
+	unlockedMultiAddresses map[string]int
 }
 
 // addUnlock adds the given unlock to the set.
@@ -806,15 +1142,20 @@ func (u *txBuilderUnlockedSet) addUnlock(unlock iotago.Unlock) {
 
 // addReferentialUnlock adds a referential unlock to the set.
 func (u *txBuilderUnlockedSet) addReferentialUnlock(addr iotago.Address, referencedInputIndex int) {
+	u.addUnlock(referentialUnlock(addr, referencedInputIndex))
+}
+
+// referentialUnlock returns the referential unlock that matches the given address.
+func referentialUnlock(addr iotago.Address, referencedInputIndex int) iotago.Unlock {
 	switch addr.(type) {
 	case *iotago.AccountAddress:
-		u.addUnlock(&iotago.AccountUnlock{Reference: uint16(referencedInputIndex)})
+		return &iotago.AccountUnlock{Reference: uint16(referencedInputIndex)}
 	case *iotago.AnchorAddress:
-		u.addUnlock(&iotago.AnchorUnlock{Reference: uint16(referencedInputIndex)})
+		return &iotago.AnchorUnlock{Reference: uint16(referencedInputIndex)}
 	case *iotago.NFTAddress:
-		u.addUnlock(&iotago.NFTUnlock{Reference: uint16(referencedInputIndex)})
+		return &iotago.NFTUnlock{Reference: uint16(referencedInputIndex)}
 	default:
-		u.addUnlock(&iotago.ReferenceUnlock{Reference: uint16(referencedInputIndex)})
+		return &iotago.ReferenceUnlock{Reference: uint16(referencedInputIndex)}
 	}
 }
 
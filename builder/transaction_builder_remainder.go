@@ -0,0 +1,286 @@
+package builder
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// AddRemainderOutputs adds remainder BasicOutput(s) on the given address for the base tokens and native tokens
+// which are consumed by the inputs but not used by the outputs.
+// Every leftover native token is put into its own remainder output, since an output can only hold a single native token,
+// and an amount which exceeds the maximum amount of a single output is split across several outputs.
+// Base tokens below the minimum storage deposit of a remainder output are folded into the other remainder outputs
+// or into a copy of an existing output on the remainder address. If that is not possible, an error with the shortfall is set.
+// Native tokens minted or melted by foundry transitions of the transaction are taken into account.
+// The remaining mana is not touched, see AddRemainderOutputsAndStoreRemainingMana.
+func (b *TransactionBuilder) AddRemainderOutputs(remainderAddress iotago.Address) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	remainderBaseTokens, remainderNativeTokens, err := b.calculateRemainder()
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	storageScoreStructure := b.api.StorageScoreStructure()
+
+	// every native token needs its own output that covers its minimum storage deposit
+	nativeTokenOutputs := make([]*iotago.BasicOutput, 0, len(remainderNativeTokens))
+	var nativeTokenDeposits iotago.BaseToken
+	for _, nativeTokenID := range sortedNativeTokenIDs(remainderNativeTokens) {
+		for _, amount := range splitNativeTokenAmount(remainderNativeTokens[nativeTokenID]) {
+			output := NewBasicOutputBuilder(remainderAddress, 0).
+				NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: amount}).
+				MustBuild()
+
+			minDeposit, err := storageScoreStructure.MinDeposit(output)
+			if err != nil {
+				return b.setBuildError(ierrors.Wrap(err, "failed to calculate the minimum deposit of the remainder output"))
+			}
+			output.Amount = minDeposit
+
+			if nativeTokenDeposits, err = safemath.SafeAdd(nativeTokenDeposits, minDeposit); err != nil {
+				return b.setBuildError(ierrors.Wrap(err, "failed to add the minimum deposit of the remainder output"))
+			}
+			nativeTokenOutputs = append(nativeTokenOutputs, output)
+		}
+	}
+
+	if remainderBaseTokens < nativeTokenDeposits {
+		return b.setBuildError(ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered,
+			"remainder of %d base tokens can't cover the minimum deposit of %d for %d native token remainder outputs, shortfall %d",
+			remainderBaseTokens, nativeTokenDeposits, len(nativeTokenOutputs), nativeTokenDeposits-remainderBaseTokens))
+	}
+	remainderBaseTokens -= nativeTokenDeposits
+
+	if remainderBaseTokens > 0 {
+		baseTokenOutput := NewBasicOutputBuilder(remainderAddress, remainderBaseTokens).MustBuild()
+
+		minDeposit, err := storageScoreStructure.MinDeposit(baseTokenOutput)
+		if err != nil {
+			return b.setBuildError(ierrors.Wrap(err, "failed to calculate the minimum deposit of the remainder output"))
+		}
+
+		switch {
+		case remainderBaseTokens >= minDeposit:
+			b.addRemainderOutput(baseTokenOutput)
+
+		case len(nativeTokenOutputs) > 0:
+			// fold the dust into the first native token remainder output
+			nativeTokenOutputs[0].Amount += remainderBaseTokens
+
+		default:
+			// fold the dust into a copy of an existing output on the remainder address,
+			// so the output passed by the caller is not modified
+			outputIndex, isFound := b.dustReceivingOutputIndex(remainderAddress)
+			if !isFound {
+				return b.setBuildError(ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered,
+					"remainder of %d base tokens can't cover the minimum deposit of %d and there is no output to fold it into, shortfall %d",
+					remainderBaseTokens, minDeposit, minDeposit-remainderBaseTokens))
+			}
+
+			//nolint:forcetypeassert // dustReceivingOutputIndex only returns BasicOutputs
+			output := b.transaction.Outputs[outputIndex].Clone().(*iotago.BasicOutput)
+			output.Amount += remainderBaseTokens
+			b.transaction.Outputs[outputIndex] = output
+		}
+	}
+
+	for _, output := range nativeTokenOutputs {
+		b.addRemainderOutput(output)
+	}
+
+	if len(b.transaction.Outputs) > iotago.MaxOutputsCount {
+		return b.setBuildError(ierrors.WithMessagef(ErrTransactionLimitExceeded, "adding the remainder outputs exceeds the maximum amount of outputs (%d > %d)", len(b.transaction.Outputs), iotago.MaxOutputsCount))
+	}
+
+	return b
+}
+
+// AddRemainderOutputsAndStoreRemainingMana adds the remainder outputs like AddRemainderOutputs and moves the remaining mana
+// to stored mana on the first remainder output, see StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana.
+// If no remainder output is needed, the mana is stored on a copy of an existing output on the remainder address
+// or, if there is none, on a copy of the first output which can hold mana.
+func (b *TransactionBuilder) AddRemainderOutputsAndStoreRemainingMana(targetSlot iotago.SlotIndex, remainderAddress iotago.Address) *TransactionBuilder {
+	storedManaOutputIndex, err := b.addRemainderOutputsForMana(remainderAddress)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	return b.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, storedManaOutputIndex)
+}
+
+// AddRemainderOutputsAndAllotMinRequiredMana adds the remainder outputs like AddRemainderOutputs, allots the minimum required mana
+// needed to issue a block to the block issuer account and moves the remaining mana to stored mana on the first remainder output,
+// see AllotMinRequiredManaAndStoreRemainingManaInOutput.
+// If no remainder output is needed, the mana is stored on a copy of an existing output on the remainder address
+// or, if there is none, on a copy of the first output which can hold mana.
+func (b *TransactionBuilder) AddRemainderOutputsAndAllotMinRequiredMana(targetSlot iotago.SlotIndex, remainderAddress iotago.Address, rmc iotago.Mana, blockIssuerAccountID iotago.AccountID) *TransactionBuilder {
+	storedManaOutputIndex, err := b.addRemainderOutputsForMana(remainderAddress)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	return b.AllotMinRequiredManaAndStoreRemainingManaInOutput(targetSlot, rmc, blockIssuerAccountID, storedManaOutputIndex)
+}
+
+// addRemainderOutputsForMana adds the remainder outputs and returns the index of the output the remaining mana is stored on.
+func (b *TransactionBuilder) addRemainderOutputsForMana(remainderAddress iotago.Address) (int, error) {
+	if b.AddRemainderOutputs(remainderAddress); b.occurredBuildErr != nil {
+		return 0, b.occurredBuildErr
+	}
+
+	if len(b.remainderOutputIndexes) > 0 {
+		return b.remainderOutputIndexes[0], nil
+	}
+
+	outputIndex, isFound := b.dustReceivingOutputIndex(remainderAddress)
+	if !isFound {
+		if outputIndex, isFound = b.manaReceivingOutputIndex(); !isFound {
+			return 0, ierrors.WithMessage(ErrTransactionBuilder, "no remainder output is needed and there is no output to store the remaining mana")
+		}
+	}
+
+	// the mana is stored on a copy, so the output passed by the caller is not modified
+	b.transaction.Outputs[outputIndex] = b.transaction.Outputs[outputIndex].Clone()
+
+	return outputIndex, nil
+}
+
+// RemainderOutputIndexes returns the indexes of the outputs that were added by AddRemainderOutputs.
+func (b *TransactionBuilder) RemainderOutputIndexes() []int {
+	return b.remainderOutputIndexes
+}
+
+// addRemainderOutput adds the given remainder output and remembers its index.
+func (b *TransactionBuilder) addRemainderOutput(output *iotago.BasicOutput) {
+	b.remainderOutputIndexes = append(b.remainderOutputIndexes, len(b.transaction.Outputs))
+	b.AddOutput(output)
+}
+
+// dustReceivingOutputIndex returns the index of an existing BasicOutput on the given address without further unlock conditions,
+// which can receive additional base tokens.
+func (b *TransactionBuilder) dustReceivingOutputIndex(address iotago.Address) (int, bool) {
+	for outputIndex, output := range b.transaction.Outputs {
+		basicOutput, isBasicOutput := output.(*iotago.BasicOutput)
+		if !isBasicOutput || len(basicOutput.UnlockConditions) != 1 {
+			continue
+		}
+
+		if basicOutput.Owner().Equal(address) {
+			return outputIndex, true
+		}
+	}
+
+	return 0, false
+}
+
+// manaReceivingOutputIndex returns the index of the first output which can hold stored mana.
+func (b *TransactionBuilder) manaReceivingOutputIndex() (int, bool) {
+	for outputIndex, output := range b.transaction.Outputs {
+		switch output.(type) {
+		case *iotago.BasicOutput, *iotago.AccountOutput, *iotago.AnchorOutput, *iotago.NFTOutput:
+			return outputIndex, true
+		}
+	}
+
+	return 0, false
+}
+
+// splitNativeTokenAmount splits the given amount into parts that fit into the uint256 amount of a single NativeTokenFeature.
+func splitNativeTokenAmount(amount *big.Int) []*big.Int {
+	remaining := new(big.Int).Set(amount)
+
+	parts := make([]*big.Int, 0, 1)
+	for remaining.Cmp(abi.MaxUint256) > 0 {
+		parts = append(parts, new(big.Int).Set(abi.MaxUint256))
+		remaining.Sub(remaining, abi.MaxUint256)
+	}
+
+	return append(parts, remaining)
+}
+
+// calculateRemainder returns the base tokens and native tokens that are consumed by the inputs but not used by the outputs.
+func (b *TransactionBuilder) calculateRemainder() (iotago.BaseToken, iotago.NativeTokenSum, error) {
+	var inputBaseTokens, outputBaseTokens iotago.BaseToken
+	var err error
+
+	nativeTokens := make(iotago.NativeTokenSum)
+	for _, input := range b.inputs {
+		if inputBaseTokens, err = safemath.SafeAdd(inputBaseTokens, input.BaseTokenAmount()); err != nil {
+			return 0, nil, ierrors.Wrap(err, "failed to sum up the base tokens of the inputs")
+		}
+
+		if nativeToken := input.FeatureSet().NativeToken(); nativeToken != nil {
+			addNativeToken(nativeTokens, nativeToken.ID, nativeToken.Amount)
+		}
+	}
+
+	for _, output := range b.transaction.Outputs {
+		if outputBaseTokens, err = safemath.SafeAdd(outputBaseTokens, output.BaseTokenAmount()); err != nil {
+			return 0, nil, ierrors.Wrap(err, "failed to sum up the base tokens of the outputs")
+		}
+
+		if nativeToken := output.FeatureSet().NativeToken(); nativeToken != nil {
+			addNativeToken(nativeTokens, nativeToken.ID, new(big.Int).Neg(nativeToken.Amount))
+		}
+	}
+
+	if outputBaseTokens > inputBaseTokens {
+		return 0, nil, ierrors.WithMessagef(iotago.ErrInputOutputBaseTokenMismatch, "outputs require %d base tokens but inputs only provide %d, shortfall %d", outputBaseTokens, inputBaseTokens, outputBaseTokens-inputBaseTokens)
+	}
+
+	// minted tokens are added to and melted or burned tokens are removed from the remainder
+	for nativeTokenID, delta := range b.foundryTokenDeltas() {
+		addNativeToken(nativeTokens, nativeTokenID, delta)
+	}
+	for nativeTokenID, amount := range b.burnedNativeTokens {
+		addNativeToken(nativeTokens, nativeTokenID, new(big.Int).Neg(amount))
+	}
+
+	// only keep the native tokens with a positive remainder
+	return inputBaseTokens - outputBaseTokens, subNativeTokens(nativeTokens, iotago.NativeTokenSum{}), nil
+}
+
+// foundryTokenDeltas returns the difference between minted and melted tokens for every foundry transitioned by the transaction.
+func (b *TransactionBuilder) foundryTokenDeltas() iotago.NativeTokenSum {
+	inputFoundries := make(map[iotago.FoundryID]*iotago.SimpleTokenScheme)
+	for _, input := range b.inputs {
+		if foundryOutput, isFoundry := input.(*iotago.FoundryOutput); isFoundry {
+			if tokenScheme, isSimple := foundryOutput.TokenScheme.(*iotago.SimpleTokenScheme); isSimple {
+				inputFoundries[foundryOutput.MustFoundryID()] = tokenScheme
+			}
+		}
+	}
+
+	deltas := make(iotago.NativeTokenSum)
+	for _, output := range b.transaction.Outputs {
+		foundryOutput, isFoundry := output.(*iotago.FoundryOutput)
+		if !isFoundry {
+			continue
+		}
+
+		tokenScheme, isSimple := foundryOutput.TokenScheme.(*iotago.SimpleTokenScheme)
+		if !isSimple {
+			continue
+		}
+
+		foundryID := foundryOutput.MustFoundryID()
+		delta := new(big.Int).Sub(tokenScheme.MintedTokens, tokenScheme.MeltedTokens)
+		if inputTokenScheme, has := inputFoundries[foundryID]; has {
+			delta.Sub(delta, new(big.Int).Sub(inputTokenScheme.MintedTokens, inputTokenScheme.MeltedTokens))
+		}
+
+		if delta.Sign() != 0 {
+			deltas[foundryID] = delta
+		}
+	}
+
+	return deltas
+}
@@ -3,14 +3,19 @@ package builder_test
 
 import (
 	"crypto/ed25519"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/lo"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/builder"
 	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
 )
 
 func TestTransactionBuilder(t *testing.T) {
@@ -278,3 +283,277 @@ func TestTransactionBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionBuilderRemainder(t *testing.T) {
+	testAPI := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	prvKey := tpkg.RandEd25519PrivateKey()
+	inputAddr := iotago.Ed25519AddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+	remainderAddr := tpkg.RandEd25519Address()
+	signer := iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)
+
+	minDeposit := lo.PanicOnErr(testAPI.StorageScoreStructure().MinDeposit(builder.NewBasicOutputBuilder(remainderAddr, 0).MustBuild()))
+	nativeToken := tpkg.RandNativeTokenFeature()
+	minDepositNativeToken := lo.PanicOnErr(testAPI.StorageScoreStructure().MinDeposit(builder.NewBasicOutputBuilder(remainderAddr, 0).NativeToken(nativeToken).MustBuild()))
+
+	newBuilder := func(inputs ...iotago.Output) *builder.TransactionBuilder {
+		txBuilder := builder.NewTransactionBuilder(testAPI, signer)
+		for i, input := range inputs {
+			inputID := iotago.OutputIDFromTransactionIDAndIndex(tpkg.Rand36ByteArray(), uint16(i))
+			txBuilder.AddInput(&builder.TxInput{UnlockTarget: inputAddr, InputID: inputID, Input: input})
+		}
+
+		return txBuilder
+	}
+
+	remainderOutputs := func(t *testing.T, txBuilder *builder.TransactionBuilder) iotago.TxEssenceOutputs {
+		tx, err := txBuilder.Build()
+		require.NoError(t, err)
+
+		outputs := make(iotago.TxEssenceOutputs, 0)
+		for _, index := range txBuilder.RemainderOutputIndexes() {
+			outputs = append(outputs, tx.Transaction.Outputs[index])
+		}
+
+		return outputs
+	}
+
+	t.Run("ok - base token remainder", func(t *testing.T) {
+		txBuilder := newBuilder(builder.NewBasicOutputBuilder(inputAddr, 10*minDeposit).MustBuild()).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 5*minDeposit).MustBuild()).
+			AddRemainderOutputs(remainderAddr)
+
+		outputs := remainderOutputs(t, txBuilder)
+		require.Len(t, outputs, 1)
+		require.EqualValues(t, 5*minDeposit, outputs[0].BaseTokenAmount())
+		require.True(t, outputs[0].(*iotago.BasicOutput).Owner().Equal(remainderAddr))
+	})
+
+	t.Run("ok - native token remainder with folded dust", func(t *testing.T) {
+		txBuilder := newBuilder(
+			builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit).MustBuild(),
+			builder.NewBasicOutputBuilder(inputAddr, minDepositNativeToken+1).NativeToken(nativeToken).MustBuild(),
+		).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 5*minDeposit).MustBuild()).
+			AddRemainderOutputs(remainderAddr)
+
+		outputs := remainderOutputs(t, txBuilder)
+		require.Len(t, outputs, 1)
+		require.EqualValues(t, minDepositNativeToken+1, outputs[0].BaseTokenAmount())
+		require.True(t, outputs[0].FeatureSet().NativeToken().Equal(nativeToken))
+	})
+
+	t.Run("ok - dust folded into existing output", func(t *testing.T) {
+		txBuilder := newBuilder(builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit+1).MustBuild()).
+			AddOutput(builder.NewBasicOutputBuilder(remainderAddr, 5*minDeposit).MustBuild()).
+			AddRemainderOutputs(remainderAddr)
+
+		require.Empty(t, remainderOutputs(t, txBuilder))
+
+		tx, err := txBuilder.Build()
+		require.NoError(t, err)
+		require.EqualValues(t, 5*minDeposit+1, tx.Transaction.Outputs[0].BaseTokenAmount())
+	})
+
+	t.Run("ok - folding dust does not modify the given output", func(t *testing.T) {
+		output := builder.NewBasicOutputBuilder(remainderAddr, 5*minDeposit).MustBuild()
+
+		tx, err := newBuilder(builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit+1).MustBuild()).
+			AddOutput(output).
+			AddRemainderOutputs(remainderAddr).
+			Build()
+		require.NoError(t, err)
+		require.EqualValues(t, 5*minDeposit+1, tx.Transaction.Outputs[0].BaseTokenAmount())
+		require.EqualValues(t, 5*minDeposit, output.Amount)
+	})
+
+	t.Run("ok - native token remainder exceeding a single output is split", func(t *testing.T) {
+		maxAmountToken := &iotago.NativeTokenFeature{ID: nativeToken.ID, Amount: new(big.Int).Set(abi.MaxUint256)}
+
+		txBuilder := newBuilder(
+			builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit).NativeToken(maxAmountToken).MustBuild(),
+			builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit).NativeToken(maxAmountToken).MustBuild(),
+		).
+			AddRemainderOutputs(remainderAddr)
+
+		var nativeTokenOutputs int
+		for _, output := range remainderOutputs(t, txBuilder) {
+			if nativeTokenFeature := output.FeatureSet().NativeToken(); nativeTokenFeature != nil {
+				require.Zero(t, nativeTokenFeature.Amount.Cmp(abi.MaxUint256))
+				nativeTokenOutputs++
+			}
+		}
+		require.Equal(t, 2, nativeTokenOutputs)
+	})
+
+	t.Run("ok - remaining mana is stored on the remainder output", func(t *testing.T) {
+		inputID := tpkg.RandOutputIDWithCreationSlot(10, 0)
+		txBuilder := builder.NewTransactionBuilder(testAPI, signer).
+			SetCreationSlot(100).
+			AddInput(&builder.TxInput{UnlockTarget: inputAddr, InputID: inputID, Input: builder.NewBasicOutputBuilder(inputAddr, 10*minDeposit).Mana(1_000).MustBuild()}).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 5*minDeposit).MustBuild()).
+			AddRemainderOutputsAndStoreRemainingMana(100, remainderAddr)
+
+		outputs := remainderOutputs(t, txBuilder)
+		require.Len(t, outputs, 1)
+		require.Greater(t, outputs[0].StoredMana(), iotago.Mana(1_000))
+
+		remainingMana, err := txBuilder.CalculateAvailableManaRemaining(100)
+		require.NoError(t, err)
+		require.Zero(t, remainingMana.UnboundMana)
+	})
+
+	t.Run("ok - remaining mana is stored on a copy of an output if no remainder is needed", func(t *testing.T) {
+		output := builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 10*minDeposit).MustBuild()
+
+		inputID := tpkg.RandOutputIDWithCreationSlot(10, 0)
+		txBuilder := builder.NewTransactionBuilder(testAPI, signer).
+			SetCreationSlot(100).
+			AddInput(&builder.TxInput{UnlockTarget: inputAddr, InputID: inputID, Input: builder.NewBasicOutputBuilder(inputAddr, 10*minDeposit).Mana(1_000).MustBuild()}).
+			AddOutput(output).
+			AddRemainderOutputsAndStoreRemainingMana(100, remainderAddr)
+
+		require.Empty(t, remainderOutputs(t, txBuilder))
+
+		tx, err := txBuilder.Build()
+		require.NoError(t, err)
+		require.Greater(t, tx.Transaction.Outputs[0].StoredMana(), iotago.Mana(1_000))
+		require.Zero(t, output.Mana)
+	})
+
+	t.Run("err - remainder below minimum deposit", func(t *testing.T) {
+		_, err := newBuilder(builder.NewBasicOutputBuilder(inputAddr, 5*minDeposit+1).MustBuild()).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 5*minDeposit).MustBuild()).
+			AddRemainderOutputs(remainderAddr).
+			Build()
+		require.ErrorIs(t, err, iotago.ErrStorageDepositNotCovered)
+	})
+}
+
+func TestTransactionBuilderMultiAddress(t *testing.T) {
+	prvKeys := []ed25519.PrivateKey{tpkg.RandEd25519PrivateKey(), tpkg.RandEd25519PrivateKey(), tpkg.RandEd25519PrivateKey()}
+	addrs := make([]*iotago.Ed25519Address, len(prvKeys))
+	signers := make([]iotago.AddressSigner, len(prvKeys))
+	for i, prvKey := range prvKeys {
+		addrs[i] = iotago.Ed25519AddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+		signers[i] = iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)
+	}
+
+	addressesWithWeight := iotago.AddressesWithWeight{
+		{Address: addrs[0], Weight: 1},
+		{Address: addrs[1], Weight: 1},
+		{Address: addrs[2], Weight: 1},
+	}
+	addressesWithWeight.Sort()
+	multiAddr := iotago.NewMultiAddress(addressesWithWeight, 2)
+
+	output := builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 50).MustBuild()
+
+	txInput := func(addr iotago.Address) *builder.TxInput {
+		return &builder.TxInput{
+			UnlockTarget: addr,
+			InputID:      tpkg.RandOutputID(0),
+			Input:        builder.NewBasicOutputBuilder(addr, 100).MustBuild(),
+		}
+	}
+
+	// validates the unlocks syntactically and semantically
+	validateUnlocks := func(t *testing.T, txBuilder *builder.TransactionBuilder, inputs ...*builder.TxInput) *iotago.SignedTransaction {
+		t.Helper()
+
+		tx, err := txBuilder.Build()
+		require.NoError(t, err)
+		require.NoError(t, iotago.ValidateUnlocks(tx.Unlocks, iotago.SignaturesUniqueAndReferenceUnlocksValidator(tpkg.ZeroCostTestAPI)))
+
+		inputSet := vm.InputSet{}
+		for _, input := range inputs {
+			inputSet[input.InputID] = input.Input
+		}
+		_, err = vm.ValidateUnlocks(tx, vm.ResolvedInputs{InputSet: inputSet})
+		require.NoError(t, err)
+
+		return tx
+	}
+
+	t.Run("ok - threshold reached by additional signer and former signature", func(t *testing.T) {
+		inputs := []*builder.TxInput{txInput(addrs[0]), txInput(multiAddr), txInput(multiAddr)}
+
+		txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signers[0]).AddSigners(signers[2]).AddOutput(output)
+		for _, input := range inputs {
+			txBuilder.AddInput(input)
+		}
+
+		tx := validateUnlocks(t, txBuilder, inputs...)
+		require.IsType(t, &iotago.SignatureUnlock{}, tx.Unlocks[0])
+		require.Equal(t, &iotago.ReferenceUnlock{Reference: 1}, tx.Unlocks[2])
+
+		multiUnlock := tx.Unlocks[1].(*iotago.MultiUnlock)
+		require.Len(t, multiUnlock.Unlocks, 3)
+		for i, addrWithWeight := range multiAddr.Addresses {
+			switch {
+			case addrWithWeight.Address.Equal(addrs[0]):
+				// the signature of the first input is reused
+				require.Equal(t, &iotago.ReferenceUnlock{Reference: 0}, multiUnlock.Unlocks[i])
+			case addrWithWeight.Address.Equal(addrs[1]):
+				require.IsType(t, &iotago.EmptyUnlock{}, multiUnlock.Unlocks[i])
+			default:
+				require.IsType(t, &iotago.SignatureUnlock{}, multiUnlock.Unlocks[i])
+			}
+		}
+	})
+
+	t.Run("ok - multi address owns an account", func(t *testing.T) {
+		account := builder.NewAccountOutputBuilder(multiAddr, 100).AccountID(tpkg.RandAccountID()).MustBuild()
+		accountInput := &builder.TxInput{UnlockTarget: multiAddr, InputID: tpkg.RandOutputID(0), Input: account}
+		basicInput := txInput(account.AccountID.ToAddress())
+
+		txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signers[1]).AddSigners(signers[2]).
+			AddInput(accountInput).
+			AddInput(basicInput).
+			AddOutput(output)
+
+		tx := validateUnlocks(t, txBuilder, accountInput, basicInput)
+		require.Equal(t, &iotago.AccountUnlock{Reference: 0}, tx.Unlocks[1])
+	})
+
+	t.Run("err - threshold not reached", func(t *testing.T) {
+		_, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signers[0]).
+			AddInput(txInput(multiAddr)).
+			AddOutput(output).
+			Build()
+		require.ErrorIs(t, err, iotago.ErrMultiAddressUnlockThresholdNotReached)
+	})
+
+	t.Run("ok - input of a multi address signer added after the multi address input", func(t *testing.T) {
+		otherPrvKey := tpkg.RandEd25519PrivateKey()
+		otherInput := txInput(iotago.Ed25519AddressFromPubKey(otherPrvKey.Public().(ed25519.PublicKey)))
+		multiInput, signerInput := txInput(multiAddr), txInput(addrs[0])
+
+		txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signers[0]).
+			AddSigners(signers[1], iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(otherPrvKey)).
+			AddInput(otherInput).
+			AddInput(multiInput).
+			AddInput(signerInput).
+			AddRewardInput(&iotago.RewardInput{Index: 1}, 0).
+			AddOutput(output)
+
+		// the input of the signer is moved in front, so the multi unlock can reference its signature
+		tx := validateUnlocks(t, txBuilder, otherInput, multiInput, signerInput)
+		require.Equal(t, iotago.TxEssenceInputs{signerInput.InputID.UTXOInput(), otherInput.InputID.UTXOInput(), multiInput.InputID.UTXOInput()}, tx.Transaction.TransactionEssence.Inputs)
+		require.IsType(t, &iotago.SignatureUnlock{}, tx.Unlocks[0])
+		require.IsType(t, &iotago.SignatureUnlock{}, tx.Unlocks[1])
+
+		multiUnlock := tx.Unlocks[2].(*iotago.MultiUnlock)
+		for i, addrWithWeight := range multiAddr.Addresses {
+			if addrWithWeight.Address.Equal(addrs[0]) {
+				require.Equal(t, &iotago.ReferenceUnlock{Reference: 0}, multiUnlock.Unlocks[i])
+			}
+		}
+
+		// the reward input still references the multi address input
+		require.Equal(t, &iotago.RewardInput{Index: 2}, tx.Transaction.TransactionEssence.ContextInputs[0])
+
+		// the builder keeps the order of the caller, so indexes computed after Build still refer to the added inputs
+		tx = validateUnlocks(t, txBuilder.AddRewardInput(&iotago.RewardInput{Index: 2}, 0), otherInput, multiInput, signerInput)
+		require.Equal(t, iotago.TxEssenceContextInputs{&iotago.RewardInput{Index: 0}, &iotago.RewardInput{Index: 2}}, tx.Transaction.TransactionEssence.ContextInputs)
+	})
+}
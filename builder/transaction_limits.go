@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// ErrTransactionLimitExceeded gets returned if a transaction exceeds the protocol limits.
+var ErrTransactionLimitExceeded = ierrors.New("transaction exceeds the protocol limits")
+
+// checkTransactionLimits returns an error if the given transaction exceeds the maximum amount of inputs or outputs,
+// the maximum payload size of a block or the given maximum work score of the block that contains it.
+func checkTransactionLimits(api iotago.API, signedTx *iotago.SignedTransaction, maxWorkScore iotago.WorkScore) error {
+	if inputsCount := len(signedTx.Transaction.TransactionEssence.Inputs); inputsCount > iotago.MaxInputsCount {
+		return ierrors.WithMessagef(ErrTransactionLimitExceeded, "%d inputs exceed the maximum of %d", inputsCount, iotago.MaxInputsCount)
+	}
+
+	if outputsCount := len(signedTx.Transaction.Outputs); outputsCount > iotago.MaxOutputsCount {
+		return ierrors.WithMessagef(ErrTransactionLimitExceeded, "%d outputs exceed the maximum of %d", outputsCount, iotago.MaxOutputsCount)
+	}
+
+	if size := signedTx.Size(); size > iotago.MaxPayloadSize {
+		return ierrors.WithMessagef(ErrTransactionLimitExceeded, "size of %d bytes exceeds the maximum payload size of %d bytes", size, iotago.MaxPayloadSize)
+	}
+
+	// the work score of the payload already includes the offset for the block, it is the work score of the whole block
+	workScore, err := signedTx.WorkScore(api.ProtocolParameters().WorkScoreParameters())
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the work score")
+	}
+
+	if workScore > maxWorkScore {
+		return ierrors.WithMessagef(ErrTransactionLimitExceeded, "work score of %d exceeds the maximum of %d", workScore, maxWorkScore)
+	}
+
+	return nil
+}
+
+// buildLargestFitting builds a transaction for the largest count of items up to maxCount that fits into the protocol limits.
+// If maxCount items don't fit, the largest count is searched for, assuming that fewer items always fit better.
+// Only errors caused by the protocol limits lead to fewer items, every other error is returned immediately.
+// The error of a single item is returned if not even that fits.
+func buildLargestFitting[T any](maxCount int, build func(count int) (T, error)) (T, error) {
+	transaction, err := build(maxCount)
+	if err == nil || !ierrors.Is(err, ErrTransactionLimitExceeded) {
+		return transaction, err
+	}
+
+	lowerCount, upperCount := 0, maxCount
+	for lowerCount+1 < upperCount {
+		count := (lowerCount + upperCount) / 2
+
+		candidate, candidateErr := build(count)
+		if candidateErr != nil {
+			if !ierrors.Is(candidateErr, ErrTransactionLimitExceeded) {
+				return candidate, candidateErr
+			}
+			upperCount = count
+
+			continue
+		}
+
+		lowerCount, transaction = count, candidate
+	}
+
+	if lowerCount > 0 {
+		return transaction, nil
+	}
+
+	if maxCount > 1 {
+		// report the error of the single item
+		_, err = build(1)
+	}
+
+	return transaction, err
+}
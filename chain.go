@@ -1,5 +1,7 @@
 package iotago
 
+import "fmt"
+
 // ChainOutput is a type of Output which represents a chain of state transitions.
 type ChainOutput interface {
 	Output
@@ -26,5 +28,19 @@ const (
 	ChainTransitionTypeDestroy
 )
 
+func (transType ChainTransitionType) String() string {
+	if int(transType) >= len(chainTransitionTypeNames) {
+		return fmt.Sprintf("unknown chain transition type: %d", transType)
+	}
+
+	return chainTransitionTypeNames[transType]
+}
+
+var chainTransitionTypeNames = [ChainTransitionTypeDestroy + 1]string{
+	"Genesis",
+	"StateChange",
+	"Destroy",
+}
+
 // ChainOutputSet is a map of ChainID to ChainOutput.
 type ChainOutputSet map[ChainID]ChainOutput
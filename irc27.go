@@ -0,0 +1,148 @@
+package iotago
+
+import (
+	"encoding/json"
+	"mime"
+
+	"github.com/iotaledger/hive.go/ierrors"
+)
+
+const (
+	// IRC27MetadataFeatureKey is the key of the MetadataFeature entry that holds the IRC27 metadata of an NFT.
+	IRC27MetadataFeatureKey MetadataFeatureEntriesKey = "irc-27"
+	// IRC27Standard is the identifier of the IRC27 standard.
+	IRC27Standard = "IRC27"
+	// IRC27Version is the supported version of the IRC27 standard.
+	IRC27Version = "v1.0"
+)
+
+var (
+	// ErrIRC27MetadataInvalid gets returned if IRC27 metadata does not adhere to the standard.
+	ErrIRC27MetadataInvalid = ierrors.New("invalid IRC27 metadata")
+	// ErrIRC27MetadataMissing gets returned if a MetadataFeature holds no IRC27 metadata.
+	ErrIRC27MetadataMissing = ierrors.New("IRC27 metadata missing")
+)
+
+// IRC27Attribute is a trait of an NFT described by IRC27 metadata.
+type IRC27Attribute struct {
+	TraitType string `json:"trait_type"`
+	Value     any    `json:"value"`
+}
+
+// IRC27Metadata is the metadata of an NFT following the IRC27 standard.
+// It is stored as JSON in the immutable MetadataFeature of the NFT under the IRC27MetadataFeatureKey.
+type IRC27Metadata struct {
+	// The identifier of the standard, must be IRC27Standard.
+	Standard string `json:"standard"`
+	// The version of the standard, must be IRC27Version.
+	Version string `json:"version"`
+	// The MIME type of the asset the NFT represents.
+	Type string `json:"type"`
+	// The URI of the asset the NFT represents.
+	URI string `json:"uri"`
+	// The name of the NFT.
+	Name string `json:"name"`
+	// The optional name of the collection the NFT belongs to.
+	CollectionName string `json:"collectionName,omitempty"`
+	// The optional royalties, which map bech32 encoded addresses to their share of the sales, in the range (0, 1].
+	Royalties map[string]float64 `json:"royalties,omitempty"`
+	// The optional name of the issuer of the NFT.
+	IssuerName string `json:"issuerName,omitempty"`
+	// The optional description of the NFT.
+	Description string `json:"description,omitempty"`
+	// The optional traits of the NFT.
+	Attributes []*IRC27Attribute `json:"attributes,omitempty"`
+}
+
+// NewIRC27Metadata creates new IRC27Metadata for the asset of the given MIME type and URI.
+func NewIRC27Metadata(mimeType string, uri string, name string) *IRC27Metadata {
+	return &IRC27Metadata{
+		Standard: IRC27Standard,
+		Version:  IRC27Version,
+		Type:     mimeType,
+		URI:      uri,
+		Name:     name,
+	}
+}
+
+// IRC27MetadataFromFeature parses the IRC27 metadata held by the given MetadataFeature.
+func IRC27MetadataFromFeature(feature *MetadataFeature) (*IRC27Metadata, error) {
+	data, has := feature.Entries[IRC27MetadataFeatureKey]
+	if !has {
+		return nil, ErrIRC27MetadataMissing
+	}
+
+	metadata := new(IRC27Metadata)
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, ierrors.Join(ErrIRC27MetadataInvalid, err)
+	}
+
+	return metadata, nil
+}
+
+// SyntacticalValidation checks that the metadata adheres to the IRC27 standard.
+// The royalty addresses need to be encoded with the given network prefix.
+func (m *IRC27Metadata) SyntacticalValidation(bech32HRP NetworkPrefix) error {
+	switch {
+	case m.Standard != IRC27Standard:
+		return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "standard %q instead of %q", m.Standard, IRC27Standard)
+	case m.Version != IRC27Version:
+		return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "unsupported version %q", m.Version)
+	case m.URI == "":
+		return ierrors.WithMessage(ErrIRC27MetadataInvalid, "uri is empty")
+	case m.Name == "":
+		return ierrors.WithMessage(ErrIRC27MetadataInvalid, "name is empty")
+	}
+
+	if _, _, err := mime.ParseMediaType(m.Type); err != nil {
+		return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "invalid MIME type %q: %s", m.Type, err)
+	}
+
+	var royaltiesSum float64
+	for bech32Address, share := range m.Royalties {
+		hrp, _, err := ParseBech32(bech32Address)
+		if err != nil {
+			return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "invalid royalty address %q: %s", bech32Address, err)
+		}
+
+		if hrp != bech32HRP {
+			return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "royalty address %q is not encoded with the network prefix %q", bech32Address, bech32HRP)
+		}
+
+		if share <= 0 || share > 1 {
+			return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "royalty share %f of address %q is not in the range (0, 1]", share, bech32Address)
+		}
+		royaltiesSum += share
+	}
+
+	if royaltiesSum > 1 {
+		return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "royalty shares sum up to %f", royaltiesSum)
+	}
+
+	for index, attribute := range m.Attributes {
+		if attribute == nil || attribute.TraitType == "" {
+			return ierrors.WithMessagef(ErrIRC27MetadataInvalid, "attribute %d has no trait type", index)
+		}
+	}
+
+	return nil
+}
+
+// MetadataFeature validates the metadata and returns the MetadataFeature holding it.
+func (m *IRC27Metadata) MetadataFeature(bech32HRP NetworkPrefix) (*MetadataFeature, error) {
+	if err := m.SyntacticalValidation(bech32HRP); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, ierrors.Join(ErrIRC27MetadataInvalid, err)
+	}
+
+	feature := &MetadataFeature{Entries: MetadataFeatureEntries{IRC27MetadataFeatureKey: data}}
+	if mapSize := feature.mapSize(); mapSize > MaxMetadataMapSize {
+		return nil, ierrors.WithMessagef(ErrMetadataExceedsMaxSize, "IRC27 metadata has size %d; max allowed: %d", mapSize, MaxMetadataMapSize)
+	}
+
+	return feature, nil
+}
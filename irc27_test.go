@@ -0,0 +1,138 @@
+package iotago_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+func TestIRC27Metadata(t *testing.T) {
+	royaltyAddress := tpkg.RandEd25519Address()
+
+	validMetadata := func() *iotago.IRC27Metadata {
+		metadata := iotago.NewIRC27Metadata("image/png", "https://example.com/nft.png", "NFT #1")
+		metadata.CollectionName = "collection"
+		metadata.Royalties = map[string]float64{royaltyAddress.Bech32(iotago.PrefixMainnet): 0.05}
+		metadata.Attributes = []*iotago.IRC27Attribute{{TraitType: "color", Value: "red"}}
+
+		return metadata
+	}
+
+	tests := []struct {
+		name     string
+		metadata func() *iotago.IRC27Metadata
+		wantErr  error
+	}{
+		{
+			name:     "ok",
+			metadata: validMetadata,
+		},
+		{
+			name: "fail - wrong standard",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Standard = "IRC30"
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - unsupported version",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Version = "v2.0"
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - missing uri",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.URI = ""
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - invalid MIME type",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Type = "image/"
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - royalty address of another network",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Royalties = map[string]float64{royaltyAddress.Bech32(iotago.PrefixTestnet): 0.05}
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - royalty shares exceed one",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Royalties[tpkg.RandEd25519Address().Bech32(iotago.PrefixMainnet)] = 0.96
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - attribute without trait type",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Attributes = append(metadata.Attributes, &iotago.IRC27Attribute{Value: 1})
+
+				return metadata
+			},
+			wantErr: iotago.ErrIRC27MetadataInvalid,
+		},
+		{
+			name: "fail - exceeds max metadata size",
+			metadata: func() *iotago.IRC27Metadata {
+				metadata := validMetadata()
+				metadata.Description = strings.Repeat("a", iotago.MaxMetadataMapSize)
+
+				return metadata
+			},
+			wantErr: iotago.ErrMetadataExceedsMaxSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := tt.metadata()
+
+			feature, err := metadata.MetadataFeature(iotago.PrefixMainnet)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			parsed, err := iotago.IRC27MetadataFromFeature(feature)
+			require.NoError(t, err)
+			require.Equal(t, metadata.URI, parsed.URI)
+			require.Equal(t, metadata.Royalties, parsed.Royalties)
+			require.Equal(t, metadata.Attributes, parsed.Attributes)
+		})
+	}
+
+	_, err := iotago.IRC27MetadataFromFeature(&iotago.MetadataFeature{Entries: iotago.MetadataFeatureEntries{"data": []byte("{}")}})
+	require.ErrorIs(t, err, iotago.ErrIRC27MetadataMissing)
+}
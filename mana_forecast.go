@@ -0,0 +1,228 @@
+package iotago
+
+import (
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+)
+
+var (
+	// ErrManaNotAffordable gets returned if the required Mana is not available until the last slot of a forecast.
+	ErrManaNotAffordable = ierrors.New("required mana is not available in the forecast period")
+	// ErrManaSimulationNegativeBalance gets returned if more Mana is burned in a simulated epoch than is available.
+	ErrManaSimulationNegativeBalance = ierrors.New("mana balance becomes negative in the simulation")
+)
+
+// ManaAtSlot returns the Mana that is available at the target slot for the given stored Mana
+// and the given amount of base tokens that generate Mana, both created in the creation slot.
+// For an output, the generating amount is the part of its base tokens that exceeds the minimum storage deposit.
+func (p *ManaDecayProvider) ManaAtSlot(storedMana Mana, generatingAmount BaseToken, creationSlot SlotIndex, targetSlot SlotIndex) (Mana, error) {
+	decayedMana, err := p.DecayManaBySlots(storedMana, creationSlot, targetSlot)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to decay stored mana")
+	}
+
+	generatedMana, err := p.GenerateManaAndDecayBySlots(generatingAmount, creationSlot, targetSlot)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate generated mana")
+	}
+
+	result, err := safemath.SafeAdd(decayedMana, generatedMana)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate sum of stored and generated mana")
+	}
+
+	return result, nil
+}
+
+// EarliestSlotWithMana returns the first slot, not before the creation slot and not after the max slot,
+// at which ManaAtSlot returns at least the required Mana.
+//
+// Within an epoch the available Mana only grows, since the stored Mana is decayed at epoch boundaries only.
+// Therefore, the epochs are scanned in order and checked at their last slot, and the first slot with enough Mana
+// is searched by bisection within the first epoch that has enough Mana at its end.
+// The scan computes the decayed Mana once per epoch up to the max slot, so a distant max slot is costly if the Mana
+// is never reached.
+func (p *ManaDecayProvider) EarliestSlotWithMana(storedMana Mana, generatingAmount BaseToken, creationSlot SlotIndex, requiredMana Mana, maxSlot SlotIndex) (SlotIndex, error) {
+	if creationSlot > maxSlot {
+		return 0, ierrors.WithMessagef(ErrManaDecayCreationIndexExceedsTargetIndex, "the creation slot (%d) was greater than the max slot (%d)", creationSlot, maxSlot)
+	}
+
+	for epoch := p.timeProvider.EpochFromSlot(creationSlot); epoch <= p.timeProvider.EpochFromSlot(maxSlot); epoch++ {
+		startSlot := max(creationSlot, p.timeProvider.EpochStart(epoch))
+		endSlot := min(maxSlot, p.timeProvider.EpochEnd(epoch))
+
+		manaAtEnd, err := p.ManaAtSlot(storedMana, generatingAmount, creationSlot, endSlot)
+		if err != nil {
+			return 0, err
+		}
+
+		if manaAtEnd < requiredMana {
+			continue
+		}
+
+		// bisect for the first slot of the epoch with enough mana
+		for startSlot < endSlot {
+			middleSlot := startSlot + (endSlot-startSlot)/2
+
+			manaAtMiddle, err := p.ManaAtSlot(storedMana, generatingAmount, creationSlot, middleSlot)
+			if err != nil {
+				return 0, err
+			}
+
+			if manaAtMiddle >= requiredMana {
+				endSlot = middleSlot
+			} else {
+				startSlot = middleSlot + 1
+			}
+		}
+
+		return startSlot, nil
+	}
+
+	return 0, ierrors.WithMessagef(ErrManaNotAffordable, "required mana %d, max slot %d", requiredMana, maxSlot)
+}
+
+// EarliestSlotToAfford returns the first slot, not before the creation slot and not after the max slot,
+// at which the given stored Mana and generating amount are enough to pay for the given work score at the given RMC.
+func (p *ManaDecayProvider) EarliestSlotToAfford(storedMana Mana, generatingAmount BaseToken, creationSlot SlotIndex, rmc Mana, workScore WorkScore, maxSlot SlotIndex) (SlotIndex, error) {
+	manaCost, err := ManaCost(rmc, workScore)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate mana cost")
+	}
+
+	return p.EarliestSlotWithMana(storedMana, generatingAmount, creationSlot, manaCost, maxSlot)
+}
+
+// OutputGeneratingAmount returns the part of the base tokens of the given output that exceeds its minimum storage deposit,
+// which is the amount that generates Mana.
+func OutputGeneratingAmount(storageScoreStructure *StorageScoreStructure, output Output) (BaseToken, error) {
+	minDeposit, err := storageScoreStructure.MinDeposit(output)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate min deposit for the generating amount")
+	}
+
+	if output.BaseTokenAmount() < minDeposit {
+		return 0, nil
+	}
+
+	return output.BaseTokenAmount() - minDeposit, nil
+}
+
+// OutputManaAtSlot returns the Mana that is available at the target slot for the given output created in the creation slot,
+// which is its decayed stored Mana plus the Mana generated by its base tokens, see ManaAtSlot.
+func OutputManaAtSlot(manaDecayProvider *ManaDecayProvider, storageScoreStructure *StorageScoreStructure, output Output, creationSlot SlotIndex, targetSlot SlotIndex) (Mana, error) {
+	generatingAmount, err := OutputGeneratingAmount(storageScoreStructure, output)
+	if err != nil {
+		return 0, err
+	}
+
+	return manaDecayProvider.ManaAtSlot(output.StoredMana(), generatingAmount, creationSlot, targetSlot)
+}
+
+// EarliestSlotOutputCanAfford returns the first slot, not before the creation slot and not after the max slot,
+// at which the Mana of the given output created in the creation slot is enough to pay for the given work score at the given RMC.
+func EarliestSlotOutputCanAfford(manaDecayProvider *ManaDecayProvider, storageScoreStructure *StorageScoreStructure, output Output, creationSlot SlotIndex, rmc Mana, workScore WorkScore, maxSlot SlotIndex) (SlotIndex, error) {
+	generatingAmount, err := OutputGeneratingAmount(storageScoreStructure, output)
+	if err != nil {
+		return 0, err
+	}
+
+	return manaDecayProvider.EarliestSlotToAfford(output.StoredMana(), generatingAmount, creationSlot, rmc, workScore, maxSlot)
+}
+
+// ManaGeneratedPerEpoch returns the Mana the given amount of base tokens generates during a full epoch, without decay.
+func (p *ManaDecayProvider) ManaGeneratedPerEpoch(amount BaseToken) (Mana, error) {
+	return p.generateMana(amount, p.timeProvider.EpochDurationSlots())
+}
+
+// RequiredAmountForManaPerEpoch returns the smallest amount of base tokens that generates
+// at least the given Mana during a full epoch, see ManaGeneratedPerEpoch.
+func (p *ManaDecayProvider) RequiredAmountForManaPerEpoch(manaPerEpoch Mana) (BaseToken, error) {
+	if manaPerEpoch == 0 {
+		return 0, nil
+	}
+
+	if p.generationRate == 0 {
+		return 0, ierrors.New("mana can't be generated with a generation rate of zero")
+	}
+
+	// manaPerEpoch = amount * generationRate * 2^slotsPerEpochExponent / 2^generationRateExponent
+	amount, err := safemath.Safe64MulDiv(uint64(manaPerEpoch), 1<<p.generationRateExponent, p.generationRate<<p.slotsPerEpochExponent)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate required amount")
+	}
+
+	// the fixed point arithmetic of the generation rounds down, so the estimate might be slightly too low
+	for {
+		generatedMana, err := p.ManaGeneratedPerEpoch(BaseToken(amount))
+		if err != nil {
+			return 0, err
+		}
+
+		if generatedMana >= manaPerEpoch {
+			return BaseToken(amount), nil
+		}
+
+		if amount, err = safemath.SafeAdd(amount, 1); err != nil {
+			return 0, ierrors.Wrap(err, "failed to calculate required amount")
+		}
+	}
+}
+
+// ManaFlow is the Mana that is received and spent in an epoch of a Mana simulation.
+type ManaFlow struct {
+	Epoch EpochIndex
+	// Received is the Mana that is added in the epoch, e.g. by allotments or claimed rewards.
+	Received Mana
+	// Spent is the Mana that is removed in the epoch, e.g. by burning it for blocks or allotting it to other accounts.
+	Spent Mana
+}
+
+// SimulateMana simulates the Mana balance of the given stored Mana and generating amount from the start epoch
+// to the end epoch (both inclusive) and returns the balance at the end of each epoch.
+// The stored Mana is the balance at the start of the start epoch. In every epoch, the balance of the previous epoch
+// is decayed by one epoch, the Mana generated during the full epoch is added and the flows of the epoch are applied.
+func (p *ManaDecayProvider) SimulateMana(storedMana Mana, generatingAmount BaseToken, startEpoch EpochIndex, endEpoch EpochIndex, flows []*ManaFlow) ([]Mana, error) {
+	if startEpoch > endEpoch {
+		return nil, ierrors.WithMessagef(ErrManaDecayCreationIndexExceedsTargetIndex, "the start epoch (%d) was greater than the end epoch (%d)", startEpoch, endEpoch)
+	}
+
+	flowsByEpoch := make(map[EpochIndex][]*ManaFlow)
+	for _, flow := range flows {
+		flowsByEpoch[flow.Epoch] = append(flowsByEpoch[flow.Epoch], flow)
+	}
+
+	generatedManaPerEpoch, err := p.ManaGeneratedPerEpoch(generatingAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]Mana, 0, endEpoch-startEpoch+1)
+	balance := storedMana
+	for epoch := startEpoch; epoch <= endEpoch; epoch++ {
+		if epoch != startEpoch {
+			if balance, err = p.decay(balance, 1); err != nil {
+				return nil, ierrors.Wrapf(err, "failed to decay mana in epoch %d", epoch)
+			}
+		}
+
+		if balance, err = safemath.SafeAdd(balance, generatedManaPerEpoch); err != nil {
+			return nil, ierrors.Wrapf(err, "failed to add generated mana in epoch %d", epoch)
+		}
+
+		for _, flow := range flowsByEpoch[epoch] {
+			if balance, err = safemath.SafeAdd(balance, flow.Received); err != nil {
+				return nil, ierrors.Wrapf(err, "failed to add received mana in epoch %d", epoch)
+			}
+
+			if flow.Spent > balance {
+				return nil, ierrors.WithMessagef(ErrManaSimulationNegativeBalance, "epoch %d, balance %d, spent %d", epoch, balance, flow.Spent)
+			}
+			balance -= flow.Spent
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
@@ -0,0 +1,151 @@
+//nolint:golint,revive,stylecheck
+package iotago_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+func TestManaForecast_EarliestSlotWithMana(t *testing.T) {
+	creationSlot := testTimeProvider.EpochStart(10) + 100
+	maxSlot := testTimeProvider.EpochEnd(20)
+
+	type test struct {
+		name             string
+		storedMana       iotago.Mana
+		generatingAmount iotago.BaseToken
+		requiredMana     iotago.Mana
+	}
+
+	tests := []*test{
+		{name: "already affordable", storedMana: 1_000_000, generatingAmount: 0, requiredMana: 1000},
+		{name: "generated in the same epoch", storedMana: 0, generatingAmount: 1_000_000_000, requiredMana: 10_000},
+		{name: "generated over several epochs", storedMana: 100, generatingAmount: 1_000_000, requiredMana: 200_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot, err := testManaDecayProvider.EarliestSlotWithMana(tt.storedMana, tt.generatingAmount, creationSlot, tt.requiredMana, maxSlot)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, slot, creationSlot)
+
+			mana, err := testManaDecayProvider.ManaAtSlot(tt.storedMana, tt.generatingAmount, creationSlot, slot)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, mana, tt.requiredMana)
+
+			if slot > creationSlot {
+				manaBefore, err := testManaDecayProvider.ManaAtSlot(tt.storedMana, tt.generatingAmount, creationSlot, slot-1)
+				require.NoError(t, err)
+				require.Less(t, manaBefore, tt.requiredMana)
+			}
+		})
+	}
+
+	// stored mana only decays, so it never reaches the required mana
+	_, err := testManaDecayProvider.EarliestSlotWithMana(1000, 0, creationSlot, 1001, maxSlot)
+	require.ErrorIs(t, err, iotago.ErrManaNotAffordable)
+
+	_, err = testManaDecayProvider.EarliestSlotWithMana(1000, 0, maxSlot+1, 1000, maxSlot)
+	require.ErrorIs(t, err, iotago.ErrManaDecayCreationIndexExceedsTargetIndex)
+
+	// the mana cost of a work score at a given RMC
+	slot, err := testManaDecayProvider.EarliestSlotToAfford(0, 1_000_000_000, creationSlot, 500, 20, maxSlot)
+	require.NoError(t, err)
+	expectedSlot, err := testManaDecayProvider.EarliestSlotWithMana(0, 1_000_000_000, creationSlot, 10_000, maxSlot)
+	require.NoError(t, err)
+	require.Equal(t, expectedSlot, slot)
+}
+
+func TestManaForecast_RequiredAmountForManaPerEpoch(t *testing.T) {
+	for _, manaPerEpoch := range []iotago.Mana{0, 1, 1000, 123_456_789, 1 << 40} {
+		amount, err := testManaDecayProvider.RequiredAmountForManaPerEpoch(manaPerEpoch)
+		require.NoError(t, err)
+
+		generatedMana, err := testManaDecayProvider.ManaGeneratedPerEpoch(amount)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, generatedMana, manaPerEpoch)
+
+		if amount > 0 {
+			generatedMana, err = testManaDecayProvider.ManaGeneratedPerEpoch(amount - 1)
+			require.NoError(t, err)
+			require.Less(t, generatedMana, manaPerEpoch)
+		}
+	}
+
+	// the generation per epoch matches the generation from the first to the last slot of an epoch, which misses one slot
+	generatedMana, err := testManaDecayProvider.ManaGeneratedPerEpoch(1_000_000_000)
+	require.NoError(t, err)
+	expectedMana, err := testManaDecayProvider.GenerateManaAndDecayBySlots(1_000_000_000, testTimeProvider.EpochStart(1), testTimeProvider.EpochEnd(1))
+	require.NoError(t, err)
+	manaPerSlot := float64(generatedMana) / float64(testTimeProvider.EpochDurationSlots())
+	require.InDelta(t, float64(expectedMana)+manaPerSlot, float64(generatedMana), 1)
+}
+
+func TestManaForecast_SimulateMana(t *testing.T) {
+	generatedManaPerEpoch, err := testManaDecayProvider.ManaGeneratedPerEpoch(1_000_000_000)
+	require.NoError(t, err)
+
+	balances, err := testManaDecayProvider.SimulateMana(10_000_000, 1_000_000_000, 5, 8, []*iotago.ManaFlow{
+		{Epoch: 6, Received: 5000},
+		{Epoch: 7, Spent: 1_000_000},
+		{Epoch: 7, Received: 100},
+	})
+	require.NoError(t, err)
+	require.Len(t, balances, 4)
+
+	require.Equal(t, 10_000_000+generatedManaPerEpoch, balances[0])
+
+	expected := balances[0]
+	for i, flow := range []struct {
+		received iotago.Mana
+		spent    iotago.Mana
+	}{{5000, 0}, {100, 1_000_000}, {0, 0}} {
+		expected, err = testManaDecayProvider.DecayManaByEpochs(expected, 0, 1)
+		require.NoError(t, err)
+		expected = expected + generatedManaPerEpoch + flow.received - flow.spent
+		require.Equal(t, expected, balances[i+1])
+	}
+
+	_, err = testManaDecayProvider.SimulateMana(0, 0, 5, 8, []*iotago.ManaFlow{{Epoch: 6, Spent: 1}})
+	require.ErrorIs(t, err, iotago.ErrManaSimulationNegativeBalance)
+}
+
+func TestManaForecast_Output(t *testing.T) {
+	storageScoreStructure := testAPI.StorageScoreStructure()
+	creationSlot := testTimeProvider.EpochStart(10) + 100
+	targetSlot := testTimeProvider.EpochEnd(12)
+
+	output := &iotago.BasicOutput{
+		Amount:           1_000_000_000,
+		Mana:             5_000,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: &iotago.Ed25519Address{}}},
+	}
+
+	minDeposit, err := storageScoreStructure.MinDeposit(output)
+	require.NoError(t, err)
+
+	generatingAmount, err := iotago.OutputGeneratingAmount(storageScoreStructure, output)
+	require.NoError(t, err)
+	require.Equal(t, output.Amount-minDeposit, generatingAmount)
+
+	mana, err := iotago.OutputManaAtSlot(testManaDecayProvider, storageScoreStructure, output, creationSlot, targetSlot)
+	require.NoError(t, err)
+	expectedMana, err := testManaDecayProvider.ManaAtSlot(output.Mana, output.Amount-minDeposit, creationSlot, targetSlot)
+	require.NoError(t, err)
+	require.Equal(t, expectedMana, mana)
+
+	slot, err := iotago.EarliestSlotOutputCanAfford(testManaDecayProvider, storageScoreStructure, output, creationSlot, 500, 100, targetSlot)
+	require.NoError(t, err)
+	expectedSlot, err := testManaDecayProvider.EarliestSlotToAfford(output.Mana, output.Amount-minDeposit, creationSlot, 500, 100, targetSlot)
+	require.NoError(t, err)
+	require.Equal(t, expectedSlot, slot)
+
+	// an output holding only its minimum storage deposit doesn't generate mana
+	output.Amount = minDeposit
+	generatingAmount, err = iotago.OutputGeneratingAmount(storageScoreStructure, output)
+	require.NoError(t, err)
+	require.Zero(t, generatingAmount)
+}
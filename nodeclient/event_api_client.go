@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -69,12 +70,16 @@ type EventAPIClientSubscription struct {
 	mqttClient mqtt.Client
 	topic      string
 	error      error
+	// closed is closed by Close, which drops the messages that are still waiting to be delivered.
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 func newSubscription(client mqtt.Client, topic string) *EventAPIClientSubscription {
 	return &EventAPIClientSubscription{
 		mqttClient: client,
 		topic:      topic,
+		closed:     make(chan struct{}),
 	}
 }
 
@@ -90,10 +95,14 @@ func (s *EventAPIClientSubscription) Error() error {
 }
 
 // Close allows to close the subscription to cleanly unsubscribe from the node.
+// Messages which were received but not yet read from the channel of the subscription are dropped,
+// so they don't block the MQTT client once nobody reads the channel anymore.
 func (s *EventAPIClientSubscription) Close() error {
 	if s.error != nil {
 		return s.error
 	}
+	s.closeOnce.Do(func() { close(s.closed) })
+
 	if token := s.mqttClient.Unsubscribe(s.topic); token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
@@ -101,12 +110,21 @@ func (s *EventAPIClientSubscription) Close() error {
 	return nil
 }
 
-func panicIfEventAPIClientInactive(neac *EventAPIClient) {
-	if err := neac.ctx.Err(); err != nil {
-		panic(ierrors.WithMessage(ErrEventAPIClientInactive, "context is canceled/done"))
+// inactiveErr returns an ErrEventAPIClientInactive if registrations would panic.
+func (eac *EventAPIClient) inactiveErr() error {
+	if eac.ctx == nil || !eac.MQTTClient.IsConnected() {
+		return ierrors.WithMessage(ErrEventAPIClientInactive, "client is not connected")
 	}
-	if !neac.MQTTClient.IsConnected() {
-		panic(ierrors.WithMessage(ErrEventAPIClientInactive, "client is not connected"))
+	if err := eac.ctx.Err(); err != nil {
+		return ierrors.WithMessage(ErrEventAPIClientInactive, "context is canceled/done")
+	}
+
+	return nil
+}
+
+func panicIfEventAPIClientInactive(neac *EventAPIClient) {
+	if err := neac.inactiveErr(); err != nil {
+		panic(err)
 	}
 }
 
@@ -137,6 +155,7 @@ func (eac *EventAPIClient) Close() {
 func subscribeToTopic[T any](eac *EventAPIClient, topic string, deseriFunc func(payload []byte) (T, error)) (<-chan T, *EventAPIClientSubscription) {
 	panicIfEventAPIClientInactive(eac)
 	channel := make(chan T)
+	subscription := newSubscription(eac.MQTTClient, topic)
 	if token := eac.MQTTClient.Subscribe(topic, 2, func(_ mqtt.Client, mqttMsg mqtt.Message) {
 		obj, err := deseriFunc(mqttMsg.Payload())
 		if err != nil {
@@ -148,13 +167,15 @@ func subscribeToTopic[T any](eac *EventAPIClient, topic string, deseriFunc func(
 		select {
 		case <-eac.ctx.Done():
 			return
+		case <-subscription.closed:
+			return
 		case channel <- obj:
 		}
 	}); token.Wait() && token.Error() != nil {
 		return nil, newSubscriptionWithError(token.Error())
 	}
 
-	return channel, newSubscription(eac.MQTTClient, topic)
+	return channel, subscription
 }
 
 func (eac *EventAPIClient) subscribeToCommitmentsTopicRaw(topic string) (<-chan *iotago.Commitment, *EventAPIClientSubscription) {
@@ -0,0 +1,304 @@
+package nodeclient
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+// DefaultFeeEstimatorWindowSize is the default number of slots the FeeEstimator derives the RMC trend from.
+const DefaultFeeEstimatorWindowSize = 20
+
+// ErrFeeEstimatorNoData gets returned if the FeeEstimator didn't observe any reference mana cost yet.
+var ErrFeeEstimatorNoData = ierrors.New("fee estimator has no reference mana cost data")
+
+// FeeEstimatorOptions define options for the FeeEstimator.
+type FeeEstimatorOptions struct {
+	// The number of slots the trend is derived from.
+	windowSize int
+}
+
+// WithFeeEstimatorWindowSize sets the number of slots the RMC trend is derived from.
+func WithFeeEstimatorWindowSize(windowSize int) FeeEstimatorOption {
+	return func(opts *FeeEstimatorOptions) {
+		opts.windowSize = windowSize
+	}
+}
+
+// FeeEstimatorOption is a function setting a FeeEstimator option.
+type FeeEstimatorOption func(opts *FeeEstimatorOptions)
+
+// FeeRecommendation is a recommended reference mana cost and the resulting mana to burn for a work score.
+type FeeRecommendation struct {
+	// ReferenceManaCost is the RMC to pass to the builders, e.g. BasicBlockBuilder.CalculateAndSetMaxBurnedMana.
+	ReferenceManaCost iotago.Mana
+	// BurnedMana is the mana to burn for the work score of the estimate.
+	BurnedMana iotago.Mana
+}
+
+// FeeEstimate contains the fee recommendations for a work score.
+type FeeEstimate struct {
+	// Slot is the slot of the latest observed reference mana cost.
+	Slot iotago.SlotIndex
+	// ReferenceManaCost is the latest observed reference mana cost.
+	ReferenceManaCost iotago.Mana
+	// Ready is false if the node reported that the account is not ready to issue a block.
+	Ready bool
+	// Low burns the latest observed RMC and fails if the RMC increases before the block is issued.
+	Low *FeeRecommendation
+	// Normal covers the RMC increase expected from the trend of the observed window.
+	Normal *FeeRecommendation
+	// High covers the maximum possible RMC increase during the commitment age window.
+	High *FeeRecommendation
+}
+
+// FeeEstimator estimates the mana to burn for blocks from the reference mana cost (RMC)
+// of recent commitments, the congestion reported by the node and the congestion control parameters.
+//
+// A block that is built now might only be accepted with a commitment that is up to MaxCommittableAge slots newer,
+// and the RMC can change in every slot. Therefore, the recommendations include a safety margin
+// for the RMC change that is expected (Normal) or possible (High) within that window.
+type FeeEstimator struct {
+	client *Client
+	opts   *FeeEstimatorOptions
+
+	mutex sync.RWMutex
+	// referenceManaCosts holds the observed RMC per commitment slot.
+	referenceManaCosts map[iotago.SlotIndex]iotago.Mana
+	// congestion is the latest congestion response, its RMC is not part of the per-commitment window.
+	congestion *api.CongestionResponse
+}
+
+// NewFeeEstimator creates a new FeeEstimator which uses the given Client to query the node.
+func NewFeeEstimator(client *Client, opts ...FeeEstimatorOption) *FeeEstimator {
+	options := &FeeEstimatorOptions{
+		windowSize: DefaultFeeEstimatorWindowSize,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &FeeEstimator{
+		client:             client,
+		opts:               options,
+		referenceManaCosts: make(map[iotago.SlotIndex]iotago.Mana),
+	}
+}
+
+// AddCommitment adds the reference mana cost of the given commitment to the observations.
+func (f *FeeEstimator) AddCommitment(commitment *iotago.Commitment) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.addReferenceManaCost(commitment.Slot, commitment.ReferenceManaCost)
+}
+
+// AddCongestion remembers the given congestion response.
+// Its reference mana cost replaces the latest observed one if it is newer than the observed commitments,
+// but it is not used to derive the trend, as the slot of the response is not the slot of a commitment.
+func (f *FeeEstimator) AddCongestion(congestion *api.CongestionResponse) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.congestion == nil || congestion.Slot >= f.congestion.Slot {
+		f.congestion = congestion
+	}
+}
+
+// addReferenceManaCost adds an observation and drops the observations that fell out of the window.
+func (f *FeeEstimator) addReferenceManaCost(slot iotago.SlotIndex, referenceManaCost iotago.Mana) {
+	f.referenceManaCosts[slot] = referenceManaCost
+
+	latestSlot := f.latestSlot()
+	for observedSlot := range f.referenceManaCosts {
+		if latestSlot-observedSlot >= iotago.SlotIndex(f.opts.windowSize) {
+			delete(f.referenceManaCosts, observedSlot)
+		}
+	}
+}
+
+func (f *FeeEstimator) latestSlot() iotago.SlotIndex {
+	var latestSlot iotago.SlotIndex
+	for slot := range f.referenceManaCosts {
+		latestSlot = max(latestSlot, slot)
+	}
+
+	return latestSlot
+}
+
+// Sync fetches the commitments of the window up to the latest commitment of the node.
+// Commitments which were already observed are not fetched again.
+func (f *FeeEstimator) Sync(ctx context.Context) error {
+	info, err := f.client.Info(ctx)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to get the node info")
+	}
+
+	latestSlot := info.Status.LatestCommitmentID.Slot()
+	for i := 0; i < f.opts.windowSize && iotago.SlotIndex(i) <= latestSlot; i++ {
+		slot := latestSlot - iotago.SlotIndex(i)
+
+		f.mutex.RLock()
+		_, observed := f.referenceManaCosts[slot]
+		f.mutex.RUnlock()
+
+		if observed {
+			continue
+		}
+
+		commitment, err := f.client.CommitmentBySlot(ctx, slot)
+		if err != nil {
+			return ierrors.Wrapf(err, "failed to get the commitment of slot %d", slot)
+		}
+
+		f.AddCommitment(commitment)
+	}
+
+	return nil
+}
+
+// UpdateCongestion fetches the congestion of the given account for the given work score from the node.
+func (f *FeeEstimator) UpdateCongestion(ctx context.Context, accountAddress *iotago.AccountAddress, workScore iotago.WorkScore) error {
+	congestion, err := f.client.Congestion(ctx, accountAddress, workScore)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to get the congestion")
+	}
+
+	f.AddCongestion(congestion)
+
+	return nil
+}
+
+// Follow adds the latest commitments received from the event API to the observations until the context is done.
+// The EventAPIClient must already be connected.
+func (f *FeeEstimator) Follow(ctx context.Context, eventClient *EventAPIClient) error {
+	commitments, subscription := eventClient.CommitmentsLatest()
+	if err := subscription.Error(); err != nil {
+		return ierrors.Wrap(err, "failed to subscribe to the latest commitments")
+	}
+	defer func() { _ = subscription.Close() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case commitment, ok := <-commitments:
+			if !ok {
+				return nil
+			}
+			f.AddCommitment(commitment)
+		}
+	}
+}
+
+// Estimate returns the fee recommendations for a block with the given work score.
+func (f *FeeEstimator) Estimate(workScore iotago.WorkScore) (*FeeEstimate, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if len(f.referenceManaCosts) == 0 && f.congestion == nil {
+		return nil, ErrFeeEstimatorNoData
+	}
+
+	slots := make([]iotago.SlotIndex, 0, len(f.referenceManaCosts))
+	for slot := range f.referenceManaCosts {
+		slots = append(slots, slot)
+	}
+	slices.Sort(slots)
+
+	// the highest RMC of the window is a lower bound for the normal recommendation
+	var maxReferenceManaCost iotago.Mana
+	for _, slot := range slots {
+		maxReferenceManaCost = max(maxReferenceManaCost, f.referenceManaCosts[slot])
+	}
+
+	var latestSlot iotago.SlotIndex
+	var latestReferenceManaCost, trendIncrease iotago.Mana
+	var trendSlots iotago.SlotIndex
+	if len(slots) > 0 {
+		firstSlot := slots[0]
+		latestSlot = slots[len(slots)-1]
+		latestReferenceManaCost = f.referenceManaCosts[latestSlot]
+
+		if firstReferenceManaCost := f.referenceManaCosts[firstSlot]; latestReferenceManaCost > firstReferenceManaCost {
+			trendIncrease = latestReferenceManaCost - firstReferenceManaCost
+			trendSlots = latestSlot - firstSlot
+		}
+	}
+
+	ready := true
+	if f.congestion != nil {
+		ready = f.congestion.Ready
+
+		if len(slots) == 0 || f.congestion.Slot > latestSlot {
+			latestSlot = f.congestion.Slot
+			latestReferenceManaCost = f.congestion.ReferenceManaCost
+		}
+	}
+
+	protocolParameters := f.client.APIForSlot(latestSlot).ProtocolParameters()
+	congestionControlParameters := protocolParameters.CongestionControlParameters()
+	horizon := iotago.Mana(protocolParameters.MaxCommittableAge())
+
+	// the RMC can increase by at most the increase step per slot
+	maxIncrease, err := safemath.SafeMul(congestionControlParameters.Increase, horizon)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the maximum reference mana cost increase")
+	}
+
+	// extrapolate the average increase per slot of the window over the horizon
+	var expectedIncrease iotago.Mana
+	if trendSlots > 0 {
+		extrapolatedIncrease, err := safemath.SafeMul(trendIncrease, horizon)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to extrapolate the reference mana cost trend")
+		}
+		expectedIncrease = min(maxIncrease, extrapolatedIncrease/iotago.Mana(trendSlots))
+	}
+
+	lowReferenceManaCost := max(latestReferenceManaCost, congestionControlParameters.MinReferenceManaCost)
+
+	normalReferenceManaCost, err := safemath.SafeAdd(lowReferenceManaCost, expectedIncrease)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the normal reference mana cost")
+	}
+	normalReferenceManaCost = max(normalReferenceManaCost, maxReferenceManaCost)
+
+	highReferenceManaCost, err := safemath.SafeAdd(lowReferenceManaCost, maxIncrease)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the high reference mana cost")
+	}
+	highReferenceManaCost = max(highReferenceManaCost, normalReferenceManaCost)
+
+	estimate := &FeeEstimate{
+		Slot:              latestSlot,
+		ReferenceManaCost: latestReferenceManaCost,
+		Ready:             ready,
+	}
+
+	for _, recommendation := range []struct {
+		target            **FeeRecommendation
+		referenceManaCost iotago.Mana
+	}{
+		{&estimate.Low, lowReferenceManaCost},
+		{&estimate.Normal, normalReferenceManaCost},
+		{&estimate.High, highReferenceManaCost},
+	} {
+		burnedMana, err := iotago.ManaCost(recommendation.referenceManaCost, workScore)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to calculate the mana cost")
+		}
+
+		*recommendation.target = &FeeRecommendation{
+			ReferenceManaCost: recommendation.referenceManaCost,
+			BurnedMana:        burnedMana,
+		}
+	}
+
+	return estimate, nil
+}
@@ -0,0 +1,156 @@
+package nodeclient_test
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+func TestFeeEstimator(t *testing.T) {
+	defer gock.Off()
+
+	congestionControlParameters := mockAPI.ProtocolParameters().CongestionControlParameters()
+	maxCommittableAge := mockAPI.ProtocolParameters().MaxCommittableAge()
+	minReferenceManaCost := congestionControlParameters.MinReferenceManaCost
+
+	nodeAPI := nodeClient(t)
+
+	t.Run("fail - no data", func(t *testing.T) {
+		_, err := nodeclient.NewFeeEstimator(nodeAPI).Estimate(100)
+		require.ErrorIs(t, err, nodeclient.ErrFeeEstimatorNoData)
+	})
+
+	t.Run("ok - stable", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI)
+		for slot := iotago.SlotIndex(100); slot < 110; slot++ {
+			feeEstimator.AddCommitment(&iotago.Commitment{Slot: slot, ReferenceManaCost: minReferenceManaCost})
+		}
+
+		estimate, err := feeEstimator.Estimate(100)
+		require.NoError(t, err)
+		require.Equal(t, iotago.SlotIndex(109), estimate.Slot)
+		require.True(t, estimate.Ready)
+
+		require.Equal(t, minReferenceManaCost, estimate.Low.ReferenceManaCost)
+		require.Equal(t, minReferenceManaCost*100, estimate.Low.BurnedMana)
+		require.Equal(t, minReferenceManaCost, estimate.Normal.ReferenceManaCost)
+		require.Equal(t, minReferenceManaCost+congestionControlParameters.Increase*iotago.Mana(maxCommittableAge), estimate.High.ReferenceManaCost)
+		require.Equal(t, estimate.High.ReferenceManaCost*100, estimate.High.BurnedMana)
+	})
+
+	t.Run("ok - increasing", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI, nodeclient.WithFeeEstimatorWindowSize(5))
+
+		// the observations before the window are dropped
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 90, ReferenceManaCost: 1_000_000})
+		for slot := iotago.SlotIndex(100); slot < 110; slot++ {
+			feeEstimator.AddCommitment(&iotago.Commitment{Slot: slot, ReferenceManaCost: minReferenceManaCost + iotago.Mana(slot-100)})
+		}
+
+		estimate, err := feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.Equal(t, minReferenceManaCost+9, estimate.Low.ReferenceManaCost)
+		// the trend of one mana per slot is extrapolated over the commitment age window
+		require.Equal(t, min(minReferenceManaCost+9+iotago.Mana(maxCommittableAge), estimate.High.ReferenceManaCost), estimate.Normal.ReferenceManaCost)
+		require.GreaterOrEqual(t, estimate.High.ReferenceManaCost, estimate.Normal.ReferenceManaCost)
+	})
+
+	t.Run("ok - decreasing", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI)
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 100, ReferenceManaCost: minReferenceManaCost + 500})
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 101, ReferenceManaCost: minReferenceManaCost + 400})
+		feeEstimator.AddCongestion(&api.CongestionResponse{Slot: 102, ReferenceManaCost: minReferenceManaCost + 300, Ready: false})
+
+		estimate, err := feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.False(t, estimate.Ready)
+		require.Equal(t, minReferenceManaCost+300, estimate.Low.ReferenceManaCost)
+		// the highest RMC of the window is still recommended
+		require.Equal(t, minReferenceManaCost+500, estimate.Normal.ReferenceManaCost)
+	})
+
+	t.Run("ok - congestion is not part of the trend", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI)
+		for slot := iotago.SlotIndex(100); slot < 105; slot++ {
+			feeEstimator.AddCommitment(&iotago.Commitment{Slot: slot, ReferenceManaCost: minReferenceManaCost})
+		}
+		feeEstimator.AddCongestion(&api.CongestionResponse{Slot: 105, ReferenceManaCost: minReferenceManaCost + 50, Ready: true})
+
+		estimate, err := feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.Equal(t, iotago.SlotIndex(105), estimate.Slot)
+		require.Equal(t, minReferenceManaCost+50, estimate.Low.ReferenceManaCost)
+		require.Equal(t, minReferenceManaCost+50, estimate.Normal.ReferenceManaCost)
+
+		// a congestion response older than the latest commitment does not replace its RMC
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 110, ReferenceManaCost: minReferenceManaCost})
+		estimate, err = feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.Equal(t, iotago.SlotIndex(110), estimate.Slot)
+		require.Equal(t, minReferenceManaCost, estimate.Low.ReferenceManaCost)
+	})
+
+	t.Run("ok - only congestion", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI)
+		feeEstimator.AddCongestion(&api.CongestionResponse{Slot: 105, ReferenceManaCost: minReferenceManaCost + 50, Ready: true})
+
+		estimate, err := feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.Equal(t, minReferenceManaCost+50, estimate.Low.ReferenceManaCost)
+		require.Equal(t, minReferenceManaCost+50, estimate.Normal.ReferenceManaCost)
+	})
+
+	t.Run("fail - overflow", func(t *testing.T) {
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI)
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 100, ReferenceManaCost: minReferenceManaCost})
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: 101, ReferenceManaCost: math.MaxUint64 / 2})
+
+		_, err := feeEstimator.Estimate(1)
+		require.Error(t, err)
+	})
+
+	t.Run("ok - sync", func(t *testing.T) {
+		latestSlot := iotago.SlotIndex(1000)
+
+		mockGetJSON(api.CoreRouteInfo, 200, &api.InfoResponse{
+			Status: &api.InfoResNodeStatus{
+				LatestCommitmentID: iotago.NewCommitmentID(latestSlot, tpkg.Rand32ByteArray()),
+			},
+			ProtocolParameters: []*api.InfoResProtocolParameters{
+				{
+					StartEpoch: 0,
+					Parameters: tpkg.IOTAMainnetV3TestProtocolParameters,
+				},
+			},
+			BaseToken: &api.InfoResBaseToken{},
+		})
+
+		feeEstimator := nodeclient.NewFeeEstimator(nodeAPI, nodeclient.WithFeeEstimatorWindowSize(3))
+		// an already observed commitment is not fetched again
+		feeEstimator.AddCommitment(&iotago.Commitment{Slot: latestSlot - 2, ReferenceManaCost: minReferenceManaCost})
+
+		for slot := latestSlot - 1; slot <= latestSlot; slot++ {
+			mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteCommitmentBySlot, api.ParameterSlot, strconv.Itoa(int(slot))), 200, &iotago.Commitment{
+				Slot:              slot,
+				ReferenceManaCost: minReferenceManaCost + 10,
+			})
+		}
+
+		require.NoError(t, feeEstimator.Sync(context.Background()))
+		require.True(t, gock.IsDone())
+
+		estimate, err := feeEstimator.Estimate(1)
+		require.NoError(t, err)
+		require.Equal(t, latestSlot, estimate.Slot)
+		require.Equal(t, minReferenceManaCost+10, estimate.Low.ReferenceManaCost)
+	})
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/iotaledger/hive.go/ierrors"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
 )
 
+// maxConcurrentOutputFetches is the maximum number of outputs IndexerResultSet.TxInputs fetches concurrently.
+const maxConcurrentOutputFetches = 8
+
 var (
 	// ErrIndexerNotFound gets returned when the indexer doesn't find any result.
 	// Only applicable to single element queries.
@@ -94,6 +99,87 @@ func (resultSet *IndexerResultSet) Outputs(ctx context.Context) (iotago.Outputs[
 	return outputs, nil
 }
 
+// TxInputs collects/fetches the outputs result from the query as inputs for the builder.TransactionBuilder,
+// which can be used as candidates for the input selection.
+// Every output of the current page is fetched with its own request, at most 8 at a time.
+func (resultSet *IndexerResultSet) TxInputs(ctx context.Context, unlockTarget iotago.Address) ([]*builder.TxInput, error) {
+	outputIDs := resultSet.Response.Items.MustOutputIDs()
+	txInputs := make([]*builder.TxInput, len(outputIDs))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		indexes  = make(chan int, len(outputIDs))
+	)
+
+	for i := range outputIDs {
+		indexes <- i
+	}
+	close(indexes)
+
+	for range min(maxConcurrentOutputFetches, len(outputIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				output, err := resultSet.client.OutputByID(ctx, outputIDs[i])
+				if err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = ierrors.Wrapf(err, "unable to fetch output %s", outputIDs[i].ToHex())
+						// stop the remaining fetches
+						cancel()
+					}
+					mutex.Unlock()
+
+					return
+				}
+
+				txInputs[i] = &builder.TxInput{
+					UnlockTarget: unlockTarget,
+					InputID:      outputIDs[i],
+					Input:        output,
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return txInputs, nil
+}
+
+// SelectInputsFromIndexer queries the indexer for all outputs matching the query, uses them as candidates
+// unlocked by the given unlock target and selects the inputs needed to cover the outputs of the given TransactionBuilder.
+func SelectInputsFromIndexer(ctx context.Context, indexer IndexerClient, query IndexerQuery, unlockTarget iotago.Address, txBuilder *builder.TransactionBuilder, targetSlot iotago.SlotIndex, strategy builder.InputSelectionStrategy, additionalMana iotago.Mana) (*builder.InputSelectionResult, error) {
+	resultSet, err := indexer.Outputs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*builder.TxInput, 0)
+	for resultSet.Next() {
+		txInputs, err := resultSet.TxInputs(ctx, unlockTarget)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, txInputs...)
+	}
+	if resultSet.Error != nil {
+		return nil, ierrors.Wrap(resultSet.Error, "failed to query the indexer for input candidates")
+	}
+
+	return txBuilder.SelectInputs(targetSlot, strategy, additionalMana, candidates)
+}
+
 // Do executes a request against the endpoint.
 // This function is only meant to be used for special routes not covered through the standard API.
 func (client *indexerClient) Do(ctx context.Context, method string, route string, reqObj interface{}, resObj interface{}) (*http.Response, error) {
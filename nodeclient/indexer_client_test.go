@@ -9,6 +9,7 @@ import (
 
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
 	"github.com/iotaledger/iota.go/v4/nodeclient"
 	"github.com/iotaledger/iota.go/v4/tpkg"
 )
@@ -140,3 +141,163 @@ func TestIndexerClient_BasicOutputs(t *testing.T) {
 	require.NoError(t, resultSet.Error)
 	require.Equal(t, 2, runs)
 }
+
+// mockIndexerOutputs mocks the core API routes of the given outputs and returns their output IDs.
+func mockIndexerOutputs(t *testing.T, outputs ...iotago.TxEssenceOutput) iotago.OutputIDs {
+	t.Helper()
+
+	txCommitment := tpkg.Rand32ByteArray()
+	// the outputs are created before the target slot of the input selection
+	slot := iotago.SlotIndex(1000)
+
+	outputIDs := make(iotago.OutputIDs, len(outputs))
+	for i, output := range outputs {
+		outputIDProof, err := iotago.NewOutputIDProof(tpkg.ZeroCostTestAPI, txCommitment, slot, outputs, uint16(i))
+		require.NoError(t, err)
+
+		outputIDs[i], err = outputIDProof.OutputID(output)
+		require.NoError(t, err)
+
+		outputRoute := api.EndpointWithNamedParameterValue(api.CoreRouteOutput, api.ParameterOutputID, outputIDs[i].ToHex())
+		mockGetBinary(outputRoute, 200, &api.OutputResponse{
+			Output:        output,
+			OutputIDProof: outputIDProof,
+		})
+	}
+
+	return outputIDs
+}
+
+func TestIndexerClient_TxInputs(t *testing.T) {
+	defer gock.Off()
+
+	addr := tpkg.RandEd25519Address()
+
+	// more outputs than are fetched concurrently
+	outputs := make(iotago.TxEssenceOutputs, 20)
+	for i := range outputs {
+		outputs[i] = builder.NewBasicOutputBuilder(addr, iotago.BaseToken(1_000_000+i)).MustBuild()
+	}
+
+	mockGetJSON(api.RouteRoutes, 200, &api.RoutesResponse{
+		Routes: []iotago.PrefixedStringUint8{api.IndexerPluginName},
+	})
+
+	outputIDs := mockIndexerOutputs(t, outputs...)
+	mockGetJSONWithParams(api.IndexerRouteOutputsBasic, 200, &api.IndexerResponse{
+		CommittedSlot: 1337,
+		PageSize:      uint32(len(outputIDs)),
+		Items:         iotago.HexOutputIDsFromOutputIDs(outputIDs...),
+	}, map[string]string{
+		"address": addr.Bech32(iotago.PrefixTestnet),
+	})
+
+	client := nodeClient(t)
+
+	indexer, err := client.Indexer(context.TODO())
+	require.NoError(t, err)
+
+	resultSet, err := indexer.Outputs(context.TODO(), &api.BasicOutputsQuery{AddressBech32: addr.Bech32(iotago.PrefixTestnet)})
+	require.NoError(t, err)
+	require.True(t, resultSet.Next())
+
+	txInputs, err := resultSet.TxInputs(context.TODO(), addr)
+	require.NoError(t, err)
+	require.Len(t, txInputs, len(outputs))
+
+	// the inputs keep the order of the indexer response
+	for i, txInput := range txInputs {
+		require.Equal(t, outputIDs[i], txInput.InputID)
+		require.Equal(t, outputs[i], txInput.Input)
+		require.Equal(t, addr, txInput.UnlockTarget)
+	}
+
+	require.False(t, resultSet.Next())
+	require.NoError(t, resultSet.Error)
+}
+
+func TestIndexerClient_TxInputsOutputNotFound(t *testing.T) {
+	defer gock.Off()
+
+	addr := tpkg.RandEd25519Address()
+	output := builder.NewBasicOutputBuilder(addr, 1_000_000).MustBuild()
+
+	mockGetJSON(api.RouteRoutes, 200, &api.RoutesResponse{
+		Routes: []iotago.PrefixedStringUint8{api.IndexerPluginName},
+	})
+
+	outputIDs := mockIndexerOutputs(t, output)
+	missingOutputID := tpkg.RandOutputID(0)
+	gock.New(nodeAPIUrl).
+		Get(api.EndpointWithNamedParameterValue(api.CoreRouteOutput, api.ParameterOutputID, missingOutputID.ToHex())).
+		Reply(404)
+
+	mockGetJSONWithParams(api.IndexerRouteOutputsBasic, 200, &api.IndexerResponse{
+		CommittedSlot: 1337,
+		PageSize:      2,
+		Items:         iotago.HexOutputIDsFromOutputIDs(outputIDs[0], missingOutputID),
+	}, map[string]string{
+		"address": addr.Bech32(iotago.PrefixTestnet),
+	})
+
+	client := nodeClient(t)
+
+	indexer, err := client.Indexer(context.TODO())
+	require.NoError(t, err)
+
+	resultSet, err := indexer.Outputs(context.TODO(), &api.BasicOutputsQuery{AddressBech32: addr.Bech32(iotago.PrefixTestnet)})
+	require.NoError(t, err)
+	require.True(t, resultSet.Next())
+
+	_, err = resultSet.TxInputs(context.TODO(), addr)
+	require.ErrorIs(t, err, nodeclient.ErrHTTPNotFound)
+	require.ErrorContains(t, err, missingOutputID.ToHex())
+}
+
+func TestSelectInputsFromIndexer(t *testing.T) {
+	defer gock.Off()
+
+	addr := tpkg.RandEd25519Address()
+	outputs := make(iotago.TxEssenceOutputs, 3)
+	for i, amount := range []iotago.BaseToken{100, 200, 300} {
+		outputs[i] = builder.NewBasicOutputBuilder(addr, amount).MustBuild()
+	}
+
+	mockGetJSON(api.RouteRoutes, 200, &api.RoutesResponse{
+		Routes: []iotago.PrefixedStringUint8{api.IndexerPluginName},
+	})
+
+	outputIDs := mockIndexerOutputs(t, outputs...)
+
+	// the candidates are spread over two pages
+	mockGetJSONWithParams(api.IndexerRouteOutputsBasic, 200, &api.IndexerResponse{
+		CommittedSlot: 1337,
+		PageSize:      2,
+		Items:         iotago.HexOutputIDsFromOutputIDs(outputIDs[:2]...),
+		Cursor:        "some-offset-key",
+	}, map[string]string{
+		"address": addr.Bech32(iotago.PrefixTestnet),
+	})
+	mockGetJSONWithParams(api.IndexerRouteOutputsBasic, 200, &api.IndexerResponse{
+		CommittedSlot: 1337,
+		PageSize:      2,
+		Items:         iotago.HexOutputIDsFromOutputIDs(outputIDs[2:]...),
+	}, map[string]string{
+		"address": addr.Bech32(iotago.PrefixTestnet),
+		"cursor":  "some-offset-key",
+	})
+
+	client := nodeClient(t)
+
+	indexer, err := client.Indexer(context.TODO())
+	require.NoError(t, err)
+
+	txBuilder := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner()).
+		AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 300).MustBuild())
+
+	result, err := nodeclient.SelectInputsFromIndexer(context.TODO(), indexer, &api.BasicOutputsQuery{AddressBech32: addr.Bech32(iotago.PrefixTestnet)}, addr, txBuilder, 1337, builder.NewBranchAndBoundStrategy(100), 0)
+	require.NoError(t, err)
+	require.Len(t, result.Selected, 1)
+	require.Equal(t, outputIDs[2], result.Selected[0].InputID)
+	require.Zero(t, result.RemainderBaseTokens)
+}
@@ -0,0 +1,464 @@
+package nodeclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+const (
+	// DefaultTransactionIssuerPollInterval is the default interval in which the state of an issued transaction is polled.
+	DefaultTransactionIssuerPollInterval = time.Second
+	// DefaultTransactionIssuerMaxReissues is the default number of times a transaction is re-issued in a new block.
+	DefaultTransactionIssuerMaxReissues = 3
+)
+
+var (
+	// ErrTransactionFailed gets returned if the node reports that the transaction failed.
+	ErrTransactionFailed = ierrors.New("transaction failed")
+	// ErrTransactionReissuesExhausted gets returned if the transaction could not be included in a block
+	// after the maximum number of re-issues.
+	ErrTransactionReissuesExhausted = ierrors.New("transaction could not be included after the maximum number of re-issues")
+)
+
+// TransactionIssuerOptions define options for the TransactionIssuer.
+type TransactionIssuerOptions struct {
+	// The interval in which the state of an issued transaction is polled.
+	pollInterval time.Duration
+	// The number of times a transaction is re-issued in a new block.
+	maxReissues int
+	// The optional FeeEstimator used to allot mana for the block issuance.
+	feeEstimator *FeeEstimator
+}
+
+// WithTransactionIssuerPollInterval sets the interval in which the state of an issued transaction is polled.
+func WithTransactionIssuerPollInterval(pollInterval time.Duration) TransactionIssuerOption {
+	return func(opts *TransactionIssuerOptions) {
+		opts.pollInterval = pollInterval
+	}
+}
+
+// WithTransactionIssuerMaxReissues sets the number of times a transaction is re-issued in a new block
+// if its block was orphaned or dropped, its commitment became too old or the block could not be submitted.
+func WithTransactionIssuerMaxReissues(maxReissues int) TransactionIssuerOption {
+	return func(opts *TransactionIssuerOptions) {
+		opts.maxReissues = maxReissues
+	}
+}
+
+// WithTransactionIssuerFeeEstimator sets the FeeEstimator whose normal recommendation is used to allot the mana
+// for the block issuance and to calculate the max burned mana of the block, instead of the reference mana cost of the latest commitment.
+// The safety margin of the estimate allows to re-issue the transaction in later slots.
+func WithTransactionIssuerFeeEstimator(feeEstimator *FeeEstimator) TransactionIssuerOption {
+	return func(opts *TransactionIssuerOptions) {
+		opts.feeEstimator = feeEstimator
+	}
+}
+
+// TransactionIssuerOption is a function setting a TransactionIssuer option.
+type TransactionIssuerOption func(opts *TransactionIssuerOptions)
+
+// TransactionIssuer builds, allots, signs and issues transactions in blocks of a block issuer account
+// and follows them until they reach the requested state.
+type TransactionIssuer struct {
+	client *Client
+	opts   *TransactionIssuerOptions
+
+	// the account that issues the blocks.
+	blockIssuerAccountID iotago.AccountID
+	// the signer and the address of the block issuer key used to sign the blocks.
+	blockSigner        iotago.AddressSigner
+	blockSignerAddress iotago.Address
+}
+
+// NewTransactionIssuer creates a new TransactionIssuer which issues blocks with the given block issuer account.
+// The blocks are signed with the key of the given address held by the given signer.
+func NewTransactionIssuer(client *Client, blockIssuerAccountID iotago.AccountID, blockSigner iotago.AddressSigner, blockSignerAddress iotago.Address, opts ...TransactionIssuerOption) *TransactionIssuer {
+	options := &TransactionIssuerOptions{
+		pollInterval: DefaultTransactionIssuerPollInterval,
+		maxReissues:  DefaultTransactionIssuerMaxReissues,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &TransactionIssuer{
+		client:               client,
+		opts:                 options,
+		blockIssuerAccountID: blockIssuerAccountID,
+		blockSigner:          blockSigner,
+		blockSignerAddress:   blockSignerAddress,
+	}
+}
+
+// Issue allots the mana required to issue the transaction of the given builder to the block issuer account,
+// stores the remaining mana in the output with the given index, signs the transaction and issues it in a block.
+// The commitment input of the transaction is set to the latest commitment.
+// If no creation slot was set in the builder, the slot of the latest commitment is used.
+// The given builder is not modified.
+//
+// The returned handle resolves once the transaction reached the target state or failed.
+// If the block containing the transaction is orphaned or dropped, or its commitment became too old to be accepted,
+// the same signed transaction is re-issued in a new block with new parents and the latest commitment.
+// Only if the commitment input of the transaction became too old to be included in a new block,
+// the transaction is rebuilt with the latest commitment and re-signed, which changes its ID.
+// The handle stops following the transaction if the context is done.
+func (i *TransactionIssuer) Issue(ctx context.Context, txBuilder *builder.TransactionBuilder, storedManaOutputIndex int, targetState api.TransactionState) (*TransactionHandle, error) {
+	blockIssuance, err := i.client.BlockIssuance(ctx)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to get the block issuance infos")
+	}
+
+	handle := &TransactionHandle{
+		txBuilder:             txBuilder.Clone(),
+		storedManaOutputIndex: storedManaOutputIndex,
+		done:                  make(chan struct{}),
+	}
+
+	creationSlot := txBuilder.CreationSlot()
+	if creationSlot == 0 {
+		creationSlot = blockIssuance.LatestCommitment.Slot
+	}
+
+	signedTx, referenceManaCost, err := i.buildTransaction(handle, creationSlot, blockIssuance)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.issue(ctx, handle, signedTx, referenceManaCost, blockIssuance); err != nil {
+		return nil, err
+	}
+
+	go i.follow(ctx, handle, targetState)
+
+	return handle, nil
+}
+
+// buildTransaction builds and signs the transaction of the handle with the given creation slot
+// and the latest commitment of the given block issuance infos.
+// It returns the reference mana cost the allotment was calculated with.
+// The builder of the handle is cloned, so the transaction can be rebuilt for a later commitment.
+func (i *TransactionIssuer) buildTransaction(handle *TransactionHandle, creationSlot iotago.SlotIndex, blockIssuance *api.IssuanceBlockHeaderResponse) (*iotago.SignedTransaction, iotago.Mana, error) {
+	commitmentID, err := blockIssuance.LatestCommitment.ID()
+	if err != nil {
+		return nil, 0, ierrors.Wrap(err, "failed to calculate the commitment ID")
+	}
+
+	referenceManaCost := blockIssuance.LatestCommitment.ReferenceManaCost
+	if i.opts.feeEstimator != nil {
+		i.opts.feeEstimator.AddCommitment(blockIssuance.LatestCommitment)
+
+		estimate, err := i.opts.feeEstimator.Estimate(0)
+		if err != nil {
+			return nil, 0, ierrors.Wrap(err, "failed to estimate the fees")
+		}
+		referenceManaCost = estimate.Normal.ReferenceManaCost
+	}
+
+	signedTx, err := handle.txBuilder.Clone().
+		SetCreationSlot(creationSlot).
+		SetCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitmentID}).
+		AllotMinRequiredManaAndStoreRemainingManaInOutput(creationSlot, referenceManaCost, i.blockIssuerAccountID, handle.storedManaOutputIndex).
+		Build()
+	if err != nil {
+		return nil, 0, ierrors.Wrap(err, "failed to build the signed transaction")
+	}
+
+	return signedTx, referenceManaCost, nil
+}
+
+// issue issues the signed transaction in a new block using the given block issuance infos and lets the handle follow it.
+// The given reference mana cost is the one the allotment of the transaction was calculated with.
+func (i *TransactionIssuer) issue(ctx context.Context, handle *TransactionHandle, signedTx *iotago.SignedTransaction, referenceManaCost iotago.Mana, blockIssuance *api.IssuanceBlockHeaderResponse) error {
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	blockID, err := i.issueBlock(ctx, signedTx, referenceManaCost, blockIssuance)
+	if err != nil {
+		return err
+	}
+
+	handle.mutex.Lock()
+	defer handle.mutex.Unlock()
+
+	handle.signedTx = signedTx
+	handle.transactionID = transactionID
+	handle.referenceManaCost = referenceManaCost
+	handle.blockID = blockID
+	handle.commitmentSlot = blockIssuance.LatestCommitment.Slot
+
+	return nil
+}
+
+// issueBlock issues the signed transaction in a new block using the given block issuance infos.
+// The max burned mana of the block is calculated with the same reference mana cost as the allotment of the transaction,
+// so the allotment covers the block.
+func (i *TransactionIssuer) issueBlock(ctx context.Context, signedTx *iotago.SignedTransaction, referenceManaCost iotago.Mana, blockIssuance *api.IssuanceBlockHeaderResponse) (iotago.BlockID, error) {
+	commitmentID, err := blockIssuance.LatestCommitment.ID()
+	if err != nil {
+		return iotago.EmptyBlockID, ierrors.Wrap(err, "failed to calculate the commitment ID")
+	}
+
+	// the issuing time must be after the issuing time of the parents
+	issuingTime := time.Now()
+	if !issuingTime.After(blockIssuance.LatestParentBlockIssuingTime) {
+		issuingTime = blockIssuance.LatestParentBlockIssuingTime.Add(time.Nanosecond)
+	}
+
+	block, err := builder.NewBasicBlockBuilder(i.client.APIForSlot(blockIssuance.LatestCommitment.Slot)).
+		Payload(signedTx).
+		StrongParents(blockIssuance.StrongParents).
+		WeakParents(blockIssuance.WeakParents).
+		ShallowLikeParents(blockIssuance.ShallowLikeParents).
+		SlotCommitmentID(commitmentID).
+		LatestFinalizedSlot(blockIssuance.LatestFinalizedSlot).
+		IssuingTime(issuingTime).
+		CalculateAndSetMaxBurnedMana(referenceManaCost).
+		SignWithSigner(i.blockIssuerAccountID, i.blockSigner, i.blockSignerAddress).
+		Build()
+	if err != nil {
+		return iotago.EmptyBlockID, ierrors.Wrap(err, "failed to build the block")
+	}
+
+	blockID, err := i.client.SubmitBlock(ctx, block)
+	if err != nil {
+		return iotago.EmptyBlockID, ierrors.Wrap(err, "failed to submit the block")
+	}
+
+	return blockID, nil
+}
+
+// reissue issues the transaction of the handle in a new block with the latest commitment.
+// The same signed transaction is issued again, unless its commitment input is too old to be included in the new block.
+// Only then the transaction is rebuilt with the latest commitment and its creation slot is moved to the slot of the latest commitment.
+// The old transaction can't be included anymore in that case, so the handle only follows the new one.
+// The given block issuance infos are fetched from the node if they are nil.
+func (i *TransactionIssuer) reissue(ctx context.Context, handle *TransactionHandle, blockIssuance *api.IssuanceBlockHeaderResponse) error {
+	// count the attempt even if it fails, the block state is checked again in the next round
+	handle.mutex.Lock()
+	handle.reissues++
+	handle.mutex.Unlock()
+
+	if blockIssuance == nil {
+		var err error
+		if blockIssuance, err = i.client.BlockIssuance(ctx); err != nil {
+			return ierrors.Wrap(err, "failed to get the block issuance infos")
+		}
+	}
+
+	handle.mutex.RLock()
+	signedTx, referenceManaCost := handle.signedTx, handle.referenceManaCost
+	handle.mutex.RUnlock()
+
+	if i.commitmentInputStale(signedTx, blockIssuance.LatestCommitment.Slot) {
+		creationSlot := max(signedTx.Transaction.CreationSlot, blockIssuance.LatestCommitment.Slot)
+
+		var err error
+		if signedTx, referenceManaCost, err = i.buildTransaction(handle, creationSlot, blockIssuance); err != nil {
+			return err
+		}
+	}
+
+	return i.issue(ctx, handle, signedTx, referenceManaCost, blockIssuance)
+}
+
+// follow polls the state of the transaction and its block until the transaction reached the target state or failed.
+// Errors while polling are treated as temporary, since the node might not know the transaction yet.
+// A failed re-issue is retried in the next round, its error is part of the error the handle resolves with
+// once the re-issues are exhausted.
+func (i *TransactionIssuer) follow(ctx context.Context, handle *TransactionHandle, targetState api.TransactionState) {
+	ticker := time.NewTicker(i.opts.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			handle.resolve(nil, ctx.Err())
+
+			return
+		case <-ticker.C:
+		}
+
+		if metadata, err := i.client.TransactionMetadata(ctx, handle.TransactionID()); err == nil {
+			if metadata.TransactionState == api.TransactionStateFailed {
+				handle.resolve(metadata, ierrors.WithMessagef(ErrTransactionFailed, "transaction %s: %s", handle.TransactionID().ToHex(), api.TransactionFailureReasonError(metadata.TransactionFailureReason)))
+
+				return
+			}
+
+			if metadata.TransactionState >= targetState {
+				handle.resolve(metadata, nil)
+
+				return
+			}
+
+			// the transaction was included in a block, so there is no need to check the block anymore
+			if metadata.TransactionState >= api.TransactionStateAccepted {
+				continue
+			}
+		}
+
+		blockMetadata, err := i.client.BlockMetadataByBlockID(ctx, handle.BlockID())
+		if err != nil {
+			continue
+		}
+
+		var blockIssuance *api.IssuanceBlockHeaderResponse
+		reason := "is " + blockMetadata.BlockState.String()
+		switch blockMetadata.BlockState {
+		case api.BlockStateOrphaned, api.BlockStateDropped:
+		case api.BlockStatePending:
+			// a pending block can not be accepted anymore once its commitment is older than the commitment age window
+			if blockIssuance, err = i.client.BlockIssuance(ctx); err != nil || !i.commitmentStale(handle, blockIssuance.LatestCommitment.Slot) {
+				continue
+			}
+			reason = "has a stale commitment"
+		default:
+			continue
+		}
+
+		if handle.Reissues() >= i.opts.maxReissues {
+			handle.resolve(nil, ierrors.Join(
+				ierrors.WithMessagef(ErrTransactionReissuesExhausted, "transaction %s, last block %s %s", handle.TransactionID().ToHex(), handle.BlockID().ToHex(), reason),
+				handle.ReissueErr(),
+			))
+
+			return
+		}
+
+		err = i.reissue(ctx, handle, blockIssuance)
+
+		handle.mutex.Lock()
+		handle.reissueErr = err
+		handle.mutex.Unlock()
+	}
+}
+
+// commitmentStale returns whether the commitment of the latest block of the handle is older than
+// the commitment age window relative to the given latest commitment slot.
+func (i *TransactionIssuer) commitmentStale(handle *TransactionHandle, latestCommitmentSlot iotago.SlotIndex) bool {
+	commitmentSlot := handle.CommitmentSlot()
+	maxCommittableAge := i.client.APIForSlot(latestCommitmentSlot).ProtocolParameters().MaxCommittableAge()
+
+	return latestCommitmentSlot > commitmentSlot && latestCommitmentSlot-commitmentSlot > maxCommittableAge
+}
+
+// commitmentInputStale returns whether the commitment input of the given transaction is too old to be included in a block
+// that commits to the given latest commitment slot. Such a block is issued at least the min committable age after that slot,
+// and it can't contain a commitment input older than the max committable age.
+func (i *TransactionIssuer) commitmentInputStale(signedTx *iotago.SignedTransaction, latestCommitmentSlot iotago.SlotIndex) bool {
+	commitmentInput := signedTx.Transaction.CommitmentInput()
+	if commitmentInput == nil {
+		return false
+	}
+	protocolParameters := i.client.APIForSlot(latestCommitmentSlot).ProtocolParameters()
+
+	return latestCommitmentSlot+protocolParameters.MinCommittableAge() > commitmentInput.CommitmentID.Slot()+protocolParameters.MaxCommittableAge()
+}
+
+// TransactionHandle follows a transaction issued by a TransactionIssuer.
+type TransactionHandle struct {
+	// the builder of the transaction before the allotment, it is used to rebuild the transaction when it is re-issued.
+	txBuilder             *builder.TransactionBuilder
+	storedManaOutputIndex int
+
+	mutex         sync.RWMutex
+	signedTx      *iotago.SignedTransaction
+	transactionID iotago.TransactionID
+	// the reference mana cost the allotment of the transaction was calculated with.
+	referenceManaCost iotago.Mana
+	blockID           iotago.BlockID
+	commitmentSlot    iotago.SlotIndex
+	reissues          int
+	reissueErr        error
+	metadata          *api.TransactionMetadataResponse
+	err               error
+
+	done chan struct{}
+}
+
+// SignedTransaction returns the latest issued transaction.
+// The transaction is only rebuilt and signed again if it is re-issued after its commitment input became too old.
+func (h *TransactionHandle) SignedTransaction() *iotago.SignedTransaction {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.signedTx
+}
+
+// TransactionID returns the ID of the latest issued transaction.
+func (h *TransactionHandle) TransactionID() iotago.TransactionID {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.transactionID
+}
+
+// BlockID returns the ID of the latest block the transaction was issued in.
+func (h *TransactionHandle) BlockID() iotago.BlockID {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.blockID
+}
+
+// CommitmentSlot returns the slot of the commitment of the latest block the transaction was issued in.
+func (h *TransactionHandle) CommitmentSlot() iotago.SlotIndex {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.commitmentSlot
+}
+
+// Reissues returns the number of times the transaction was re-issued in a new block.
+func (h *TransactionHandle) Reissues() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.reissues
+}
+
+// ReissueErr returns the error of the last re-issue, nil if it succeeded or there was none.
+func (h *TransactionHandle) ReissueErr() error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.reissueErr
+}
+
+// Done returns a channel that is closed once the handle is resolved.
+func (h *TransactionHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait waits until the handle is resolved or the given context is done.
+// It returns the metadata of the transaction once it reached the target state,
+// or an error if the transaction failed or could not be followed anymore.
+func (h *TransactionHandle) Wait(ctx context.Context) (*api.TransactionMetadataResponse, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-h.done:
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.metadata, h.err
+}
+
+func (h *TransactionHandle) resolve(metadata *api.TransactionMetadataResponse, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.metadata = metadata
+	h.err = err
+	close(h.done)
+}
@@ -0,0 +1,273 @@
+package nodeclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/iotaledger/hive.go/serializer/v2/serix"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+func TestTransactionIssuer(t *testing.T) {
+	defer gock.Off()
+
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	blockIssuerAccountID := tpkg.RandAccountID()
+
+	mockBlockIssuance := func(slot iotago.SlotIndex) *iotago.Commitment {
+		commitment := &iotago.Commitment{
+			ProtocolVersion:      mockAPI.Version(),
+			Slot:                 slot,
+			PreviousCommitmentID: tpkg.Rand36ByteArray(),
+			RootsID:              tpkg.Rand32ByteArray(),
+			ReferenceManaCost:    500,
+		}
+
+		mockGetJSON(api.CoreRouteBlockIssuance, 200, &api.IssuanceBlockHeaderResponse{
+			StrongParents:                tpkg.SortedRandBlockIDs(2),
+			WeakParents:                  iotago.BlockIDs{},
+			ShallowLikeParents:           iotago.BlockIDs{},
+			LatestParentBlockIssuingTime: time.Now().Add(-time.Second).UTC(),
+			LatestFinalizedSlot:          slot - 5,
+			LatestCommitment:             commitment,
+		})
+
+		return commitment
+	}
+
+	mockSubmitBlock := func() iotago.BlockID {
+		blockID := tpkg.RandBlockID()
+		gock.New(nodeAPIUrl).
+			Post(api.CoreRouteBlocks).
+			MatchType(api.MIMEApplicationVendorIOTASerializerV2).
+			Reply(200).
+			AddHeader("Location", blockID.ToHex())
+
+		return blockID
+	}
+
+	mockTransactionState := func(transactionID iotago.TransactionID, state api.TransactionState, reason api.TransactionFailureReason) {
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteTransactionsMetadata, api.ParameterTransactionID, transactionID.ToHex()), 200, &api.TransactionMetadataResponse{
+			TransactionID:            transactionID,
+			TransactionState:         state,
+			TransactionFailureReason: reason,
+		})
+	}
+
+	mockBlockState := func(blockID iotago.BlockID, state api.BlockState) {
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteBlockMetadata, api.ParameterBlockID, blockID.ToHex()), 200, &api.BlockMetadataResponse{
+			BlockID:    blockID,
+			BlockState: state,
+		})
+	}
+
+	newTransactionBuilder := func() *builder.TransactionBuilder {
+		return builder.NewTransactionBuilder(mockAPI, signer).
+			AddInput(&builder.TxInput{
+				UnlockTarget: addr,
+				InputID:      tpkg.RandOutputIDWithCreationSlot(10, 0),
+				Input:        builder.NewBasicOutputBuilder(addr, 10_000_000).Mana(1_000_000_000).MustBuild(),
+			}).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 10_000_000).MustBuild())
+	}
+
+	// the ID of the transaction the issuer builds from the given builder for the given commitment
+	expectedTransactionID := func(t *testing.T, txBuilder *builder.TransactionBuilder, commitment *iotago.Commitment) iotago.TransactionID {
+		t.Helper()
+
+		commitmentID, err := commitment.ID()
+		require.NoError(t, err)
+
+		expectedTx, err := txBuilder.Clone().
+			SetCreationSlot(commitment.Slot).
+			SetCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitmentID}).
+			AllotMinRequiredManaAndStoreRemainingManaInOutput(commitment.Slot, 500, blockIssuerAccountID, 0).
+			Build()
+		require.NoError(t, err)
+		transactionID, err := expectedTx.Transaction.ID()
+		require.NoError(t, err)
+
+		return transactionID
+	}
+
+	// the mocks for the polling must be registered before the transaction is issued,
+	// so the transaction ID is derived from a clone of the builder with the same allotment
+	issue := func(t *testing.T, nodeAPI *nodeclient.Client, targetState api.TransactionState, mockFollow func(txBuilder *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID), opts ...nodeclient.TransactionIssuerOption) *nodeclient.TransactionHandle {
+		t.Helper()
+
+		txBuilder := newTransactionBuilder()
+		transactionID := expectedTransactionID(t, txBuilder, mockBlockIssuance(25))
+		blockID := mockSubmitBlock()
+		mockFollow(txBuilder, transactionID, blockID)
+
+		transactionIssuer := nodeclient.NewTransactionIssuer(nodeAPI, blockIssuerAccountID, signer, addr, append([]nodeclient.TransactionIssuerOption{nodeclient.WithTransactionIssuerPollInterval(10 * time.Millisecond)}, opts...)...)
+		handle, err := transactionIssuer.Issue(context.Background(), txBuilder, 0, targetState)
+		require.NoError(t, err)
+		require.Equal(t, transactionID, handle.TransactionID())
+		require.Equal(t, iotago.SlotIndex(25), handle.CommitmentSlot())
+
+		// the builder of the caller is not modified
+		require.Zero(t, txBuilder.CreationSlot())
+
+		signedTx := handle.SignedTransaction()
+		require.Equal(t, iotago.SlotIndex(25), signedTx.Transaction.CreationSlot)
+		require.Len(t, signedTx.Transaction.Allotments, 1)
+		require.Equal(t, blockIssuerAccountID, signedTx.Transaction.Allotments[0].AccountID)
+		require.NotNil(t, signedTx.Transaction.CommitmentInput())
+
+		_, err = mockAPI.Encode(signedTx, serix.WithValidation())
+		require.NoError(t, err)
+
+		return handle
+	}
+
+	t.Run("ok - confirmed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nodeAPI := nodeClient(t)
+		handle := issue(t, nodeAPI, api.TransactionStateCommitted, func(_ *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID) {
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStatePending)
+			// the commitment of the block is still recent enough
+			mockBlockIssuance(26)
+			mockTransactionState(transactionID, api.TransactionStateAccepted, api.TxFailureNone)
+			mockTransactionState(transactionID, api.TransactionStateFinalized, api.TxFailureNone)
+		})
+
+		metadata, err := handle.Wait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, api.TransactionStateFinalized, metadata.TransactionState)
+		require.Zero(t, handle.Reissues())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("ok - reissued after orphaned", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nodeAPI := nodeClient(t)
+		var issuedTransactionID iotago.TransactionID
+		var reissuedBlockID iotago.BlockID
+		handle := issue(t, nodeAPI, api.TransactionStateAccepted, func(_ *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID) {
+			issuedTransactionID = transactionID
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStateOrphaned)
+			mockBlockIssuance(30)
+			reissuedBlockID = mockSubmitBlock()
+			mockTransactionState(transactionID, api.TransactionStateAccepted, api.TxFailureNone)
+		})
+		issuedTx := handle.SignedTransaction()
+
+		metadata, err := handle.Wait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, api.TransactionStateAccepted, metadata.TransactionState)
+		require.Equal(t, 1, handle.Reissues())
+		require.Equal(t, reissuedBlockID, handle.BlockID())
+		require.Equal(t, iotago.SlotIndex(30), handle.CommitmentSlot())
+
+		// the commitment input is still recent enough, so the same transaction was issued in the new block
+		require.Equal(t, issuedTransactionID, handle.TransactionID())
+		require.Same(t, issuedTx, handle.SignedTransaction())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("ok - reissued after stale commitment", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		staleSlot := 25 + mockAPI.ProtocolParameters().MaxCommittableAge() + 1
+
+		nodeAPI := nodeClient(t)
+		var reissuedTransactionID iotago.TransactionID
+		handle := issue(t, nodeAPI, api.TransactionStateAccepted, func(txBuilder *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID) {
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStatePending)
+			reissuedTransactionID = expectedTransactionID(t, txBuilder, mockBlockIssuance(staleSlot))
+			mockSubmitBlock()
+			mockTransactionState(reissuedTransactionID, api.TransactionStateAccepted, api.TxFailureNone)
+		})
+
+		metadata, err := handle.Wait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, api.TransactionStateAccepted, metadata.TransactionState)
+		require.Equal(t, 1, handle.Reissues())
+		// the commitment input of the transaction was too old as well, so the handle follows the rebuilt transaction
+		require.Equal(t, reissuedTransactionID, handle.TransactionID())
+		require.Equal(t, staleSlot, handle.CommitmentSlot())
+		require.Equal(t, staleSlot, handle.SignedTransaction().Transaction.CreationSlot)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("fail - reissues exhausted", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nodeAPI := nodeClient(t)
+		handle := issue(t, nodeAPI, api.TransactionStateAccepted, func(_ *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID) {
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStateDropped)
+		}, nodeclient.WithTransactionIssuerMaxReissues(0))
+
+		_, err := handle.Wait(ctx)
+		require.ErrorIs(t, err, nodeclient.ErrTransactionReissuesExhausted)
+	})
+
+	t.Run("fail - reissue failed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nodeAPI := nodeClient(t)
+		handle := issue(t, nodeAPI, api.TransactionStateAccepted, func(_ *builder.TransactionBuilder, transactionID iotago.TransactionID, blockID iotago.BlockID) {
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStateDropped)
+			mockBlockIssuance(30)
+			gock.New(nodeAPIUrl).
+				Post(api.CoreRouteBlocks).
+				Reply(400)
+			mockTransactionState(transactionID, api.TransactionStatePending, api.TxFailureNone)
+			mockBlockState(blockID, api.BlockStateDropped)
+		}, nodeclient.WithTransactionIssuerMaxReissues(1))
+
+		_, err := handle.Wait(ctx)
+		require.ErrorIs(t, err, nodeclient.ErrTransactionReissuesExhausted)
+		require.ErrorContains(t, err, "failed to submit the block")
+		require.Error(t, handle.ReissueErr())
+		require.Equal(t, 1, handle.Reissues())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("fail - transaction failed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nodeAPI := nodeClient(t)
+		handle := issue(t, nodeAPI, api.TransactionStateFinalized, func(_ *builder.TransactionBuilder, transactionID iotago.TransactionID, _ iotago.BlockID) {
+			mockTransactionState(transactionID, api.TransactionStateFailed, api.TxFailureConflictRejected)
+		})
+
+		metadata, err := handle.Wait(ctx)
+		require.ErrorIs(t, err, nodeclient.ErrTransactionFailed)
+		require.Equal(t, api.TxFailureConflictRejected, metadata.TransactionFailureReason)
+	})
+
+	t.Run("fail - submit block", func(t *testing.T) {
+		nodeAPI := nodeClient(t)
+		mockBlockIssuance(25)
+		gock.New(nodeAPIUrl).
+			Post(api.CoreRouteBlocks).
+			Reply(400)
+
+		_, err := nodeclient.NewTransactionIssuer(nodeAPI, blockIssuerAccountID, signer, addr).Issue(context.Background(), newTransactionBuilder(), 0, api.TransactionStateAccepted)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,258 @@
+package nodeclient
+
+import (
+	"context"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+// SimulationOptions define options for a simulation of the TransactionSimulator.
+type SimulationOptions struct {
+	// The locally known inputs, which are not fetched from the node.
+	inputs vm.InputSet
+	// The locally known commitment, which is not fetched from the node.
+	commitment *iotago.Commitment
+	// The locally known block issuance credits, which are not fetched from the node.
+	blockIssuanceCredits vm.BlockIssuanceCreditInputSet
+	// The locally known rewards, which are not fetched from the node.
+	rewards vm.RewardsInputSet
+}
+
+// WithSimulationInputs sets locally known inputs of the transaction, e.g. outputs of transactions
+// that were not issued yet. Only the inputs that are not contained are fetched from the node.
+func WithSimulationInputs(inputs vm.InputSet) SimulationOption {
+	return func(opts *SimulationOptions) {
+		opts.inputs = inputs
+	}
+}
+
+// WithSimulationCommitment sets the commitment referenced by the commitment input of the transaction,
+// so it is not fetched from the node.
+func WithSimulationCommitment(commitment *iotago.Commitment) SimulationOption {
+	return func(opts *SimulationOptions) {
+		opts.commitment = commitment
+	}
+}
+
+// WithSimulationBlockIssuanceCredits sets locally known block issuance credits of the accounts
+// referenced by the block issuance credit inputs of the transaction.
+func WithSimulationBlockIssuanceCredits(blockIssuanceCredits vm.BlockIssuanceCreditInputSet) SimulationOption {
+	return func(opts *SimulationOptions) {
+		opts.blockIssuanceCredits = blockIssuanceCredits
+	}
+}
+
+// WithSimulationRewards sets locally known rewards of the accounts and delegations
+// referenced by the reward inputs of the transaction.
+func WithSimulationRewards(rewards vm.RewardsInputSet) SimulationOption {
+	return func(opts *SimulationOptions) {
+		opts.rewards = rewards
+	}
+}
+
+// SimulationOption is a function setting a simulation option.
+type SimulationOption func(opts *SimulationOptions)
+
+// SimulationResult is the outcome of a simulated transaction.
+type SimulationResult struct {
+	// The inputs the transaction was executed with.
+	ResolvedInputs vm.ResolvedInputs
+	// The reason why the transaction failed, TxFailureNone if it succeeded.
+	FailureReason api.TransactionFailureReason
+	// The error returned by the VM, nil if the transaction succeeded.
+	Error error
+	// The outputs created by the transaction, nil if it failed.
+	Outputs []iotago.Output
+	// The mana of the inputs, including the decayed stored and potential mana and the rewards.
+	TotalManaIn iotago.Mana
+	// The mana of the outputs and the allotments.
+	TotalManaOut iotago.Mana
+}
+
+// Succeeded returns whether the transaction would be accepted by the VM.
+func (r *SimulationResult) Succeeded() bool {
+	return r.FailureReason == api.TxFailureNone
+}
+
+// failed marks the result as failed because of the given error of the VM.
+func (r *SimulationResult) failed(err error) *SimulationResult {
+	r.Error = err
+	r.FailureReason = api.DetermineTransactionFailureReason(err)
+
+	return r
+}
+
+// TransactionSimulator executes transactions locally in the VM before they are issued,
+// so failing transactions are detected without burning the mana for the block issuance.
+//
+// The inputs, the commitment, the block issuance credits and the rewards required by the VM are fetched from the node,
+// unless they are passed as locally known data. The outcome equals the outcome on the node
+// as long as the ledger state of the node does not change until the transaction is issued.
+type TransactionSimulator struct {
+	client *Client
+	vm     vm.VirtualMachine
+}
+
+// NewTransactionSimulator creates a new TransactionSimulator which uses the given Client to query the node.
+func NewTransactionSimulator(client *Client) *TransactionSimulator {
+	return &TransactionSimulator{
+		client: client,
+		vm:     nova.NewVirtualMachine(),
+	}
+}
+
+// Simulate resolves the inputs of the given transaction and runs the unlock validation and the execution of the VM.
+// An error is only returned if the inputs could not be resolved, a failure of the transaction is part of the result.
+// The simulation fails with iotago.ErrInputAlreadySpent if the node reports an input as spent.
+func (s *TransactionSimulator) Simulate(ctx context.Context, signedTx *iotago.SignedTransaction, opts ...SimulationOption) (*SimulationResult, error) {
+	result := &SimulationResult{}
+
+	resolvedInputs, err := s.ResolveInputs(ctx, signedTx.Transaction, opts...)
+	if err != nil {
+		if ierrors.Is(err, iotago.ErrInputAlreadySpent) {
+			return result.failed(err), nil
+		}
+
+		return nil, err
+	}
+	result.ResolvedInputs = resolvedInputs
+
+	unlockedAddrs, err := s.vm.ValidateUnlocks(signedTx, resolvedInputs)
+	if err != nil {
+		return result.failed(err), nil
+	}
+
+	outputs, err := s.vm.Execute(signedTx.Transaction, resolvedInputs, unlockedAddrs)
+	if err != nil {
+		return result.failed(err), nil
+	}
+	result.Outputs = outputs
+
+	transactionAPI := signedTx.Transaction.API
+	if result.TotalManaIn, err = vm.TotalManaIn(transactionAPI.ManaDecayProvider(), transactionAPI.StorageScoreStructure(), signedTx.Transaction.CreationSlot, resolvedInputs.InputSet, resolvedInputs.RewardsInputSet); err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the mana of the inputs")
+	}
+
+	if result.TotalManaOut, err = vm.TotalManaOut(signedTx.Transaction.Outputs, signedTx.Transaction.Allotments); err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the mana of the outputs")
+	}
+
+	return result, nil
+}
+
+// ResolveInputs resolves the inputs and context inputs of the given transaction that are required by the VM.
+// The locally known data passed as options is used instead of querying the node.
+// It returns iotago.ErrInputAlreadySpent if the node reports an input that is fetched from it as spent.
+func (s *TransactionSimulator) ResolveInputs(ctx context.Context, transaction *iotago.Transaction, opts ...SimulationOption) (vm.ResolvedInputs, error) {
+	options := &SimulationOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	resolvedInputs := vm.ResolvedInputs{
+		InputSet:                    make(vm.InputSet, len(transaction.TransactionEssence.Inputs)),
+		BlockIssuanceCreditInputSet: make(vm.BlockIssuanceCreditInputSet),
+		RewardsInputSet:             make(vm.RewardsInputSet),
+	}
+
+	for _, input := range transaction.Inputs() {
+		outputID := input.OutputID()
+		if output, known := options.inputs[outputID]; known {
+			resolvedInputs.InputSet[outputID] = output
+
+			continue
+		}
+
+		output, metadata, err := s.client.OutputWithMetadataByID(ctx, outputID)
+		if err != nil {
+			return vm.ResolvedInputs{}, ierrors.Wrapf(err, "failed to fetch input %s", outputID.ToHex())
+		}
+
+		if metadata.Spent != nil {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrInputAlreadySpent, "input %s was spent by transaction %s", outputID.ToHex(), metadata.Spent.TransactionID.ToHex())
+		}
+		resolvedInputs.InputSet[outputID] = output
+	}
+
+	commitmentInput := transaction.CommitmentInput()
+	if commitmentInput != nil {
+		resolvedInputs.CommitmentInput = options.commitment
+		if options.commitment == nil {
+			commitment, err := s.client.CommitmentByID(ctx, commitmentInput.CommitmentID)
+			if err != nil {
+				return vm.ResolvedInputs{}, ierrors.Wrapf(err, "failed to fetch commitment %s", commitmentInput.CommitmentID.ToHex())
+			}
+			resolvedInputs.CommitmentInput = commitment
+		}
+	}
+
+	for _, bicInput := range transaction.BICInputs() {
+		if bic, known := options.blockIssuanceCredits[bicInput.AccountID]; known {
+			resolvedInputs.BlockIssuanceCreditInputSet[bicInput.AccountID] = bic
+
+			continue
+		}
+
+		// the block issuance credits are taken from the commitment referenced by the transaction
+		var optCommitmentID []iotago.CommitmentID
+		if commitmentInput != nil {
+			optCommitmentID = append(optCommitmentID, commitmentInput.CommitmentID)
+		}
+
+		//nolint:forcetypeassert // the address of an AccountID is always an AccountAddress
+		congestion, err := s.client.Congestion(ctx, bicInput.AccountID.ToAddress().(*iotago.AccountAddress), 0, optCommitmentID...)
+		if err != nil {
+			return vm.ResolvedInputs{}, ierrors.Wrapf(err, "failed to fetch the block issuance credits of account %s", bicInput.AccountID.ToHex())
+		}
+		resolvedInputs.BlockIssuanceCreditInputSet[bicInput.AccountID] = congestion.BlockIssuanceCredits
+	}
+
+	for _, rewardInput := range transaction.RewardInputs() {
+		if int(rewardInput.Index) >= len(transaction.TransactionEssence.Inputs) {
+			// the reward input is invalid, which is detected by the VM
+			continue
+		}
+
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		outputID := transaction.TransactionEssence.Inputs[rewardInput.Index].(*iotago.UTXOInput).OutputID()
+		chainID, isChain := rewardChainID(outputID, resolvedInputs.InputSet[outputID])
+		if !isChain {
+			// the rewards can only be claimed by accounts and delegations, which is checked by the VM
+			continue
+		}
+
+		if rewards, known := options.rewards[chainID]; known {
+			resolvedInputs.RewardsInputSet[chainID] = rewards
+
+			continue
+		}
+
+		rewards, err := s.client.Rewards(ctx, outputID)
+		if err != nil {
+			return vm.ResolvedInputs{}, ierrors.Wrapf(err, "failed to fetch the rewards of output %s", outputID.ToHex())
+		}
+		resolvedInputs.RewardsInputSet[chainID] = rewards.Rewards
+	}
+
+	return resolvedInputs, nil
+}
+
+// rewardChainID returns the chain ID the rewards of the given output are keyed by,
+// which is derived from the output ID for a delegation that was just created.
+func rewardChainID(outputID iotago.OutputID, output iotago.Output) (iotago.ChainID, bool) {
+	chainOutput, isChain := output.(iotago.ChainOutput)
+	if !isChain {
+		return nil, false
+	}
+
+	chainID := chainOutput.ChainID()
+	if utxoIDChainID, isUTXOIDChainID := chainID.(iotago.UTXOIDChainID); isUTXOIDChainID && chainID.Empty() {
+		chainID = utxoIDChainID.FromOutputID(outputID)
+	}
+
+	return chainID, true
+}
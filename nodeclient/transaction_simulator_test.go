@@ -0,0 +1,164 @@
+//nolint:forcetypeassert
+package nodeclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+func TestTransactionSimulator(t *testing.T) {
+	defer gock.Off()
+
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	signer := iotago.NewInMemoryAddressSigner(addrKeys)
+	recipient := tpkg.RandEd25519Address()
+	targetSlot := iotago.SlotIndex(20)
+
+	input := builder.NewBasicOutputBuilder(addr, 1_000_000).Mana(1_000).MustBuild()
+	inputProof, err := iotago.NewOutputIDProof(mockAPI, tpkg.Rand32ByteArray(), 10, iotago.TxEssenceOutputs{input}, 0)
+	require.NoError(t, err)
+	inputID, err := inputProof.OutputID(input)
+	require.NoError(t, err)
+
+	commitment := &iotago.Commitment{ProtocolVersion: mockAPI.Version(), Slot: targetSlot - 5}
+	commitmentID, err := commitment.ID()
+	require.NoError(t, err)
+
+	signedTransaction := func(t *testing.T, amount iotago.BaseToken) *iotago.SignedTransaction {
+		t.Helper()
+
+		signedTx, err := builder.NewTransactionBuilder(mockAPI, signer).
+			AddInput(&builder.TxInput{UnlockTarget: addr, InputID: inputID, Input: input}).
+			AddOutput(builder.NewBasicOutputBuilder(recipient, amount).MustBuild()).
+			AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitmentID}).
+			SetCreationSlot(targetSlot).
+			StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(targetSlot, 0).
+			Build()
+		require.NoError(t, err)
+
+		return signedTx
+	}
+
+	mockInput := func(spent *api.OutputConsumptionMetadata) {
+		mockGetBinary(api.EndpointWithNamedParameterValue(api.CoreRouteOutputWithMetadata, api.ParameterOutputID, inputID.ToHex()), 200, &api.OutputWithMetadataResponse{
+			Output:        input,
+			OutputIDProof: inputProof,
+			Metadata: &api.OutputMetadata{
+				OutputID: inputID,
+				BlockID:  tpkg.RandBlockID(),
+				Included: &api.OutputInclusionMetadata{
+					Slot:          inputID.CreationSlot(),
+					TransactionID: inputID.TransactionID(),
+				},
+				Spent:              spent,
+				LatestCommitmentID: commitmentID,
+			},
+		})
+	}
+
+	nodeAPI := nodeClient(t)
+	simulator := nodeclient.NewTransactionSimulator(nodeAPI)
+
+	t.Run("ok - resolved from the node", func(t *testing.T) {
+		mockInput(nil)
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteCommitmentByID, api.ParameterCommitmentID, commitmentID.ToHex()), 200, commitment)
+
+		result, err := simulator.Simulate(context.Background(), signedTransaction(t, 1_000_000))
+		require.NoError(t, err)
+		require.True(t, result.Succeeded(), result.Error)
+		require.Equal(t, api.TxFailureNone, result.FailureReason)
+		require.Len(t, result.Outputs, 1)
+		require.True(t, recipient.Equal(result.Outputs[0].UnlockConditionSet().Address().Address))
+		require.Equal(t, result.TotalManaIn, result.TotalManaOut)
+		require.Equal(t, commitment.Slot, result.ResolvedInputs.CommitmentInput.Slot)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("ok - resolved from the local cache", func(t *testing.T) {
+		result, err := simulator.Simulate(context.Background(), signedTransaction(t, 1_000_000),
+			nodeclient.WithSimulationInputs(vm.InputSet{inputID: input}),
+			nodeclient.WithSimulationCommitment(commitment),
+		)
+		require.NoError(t, err)
+		require.True(t, result.Succeeded(), result.Error)
+	})
+
+	t.Run("fail - base tokens not balanced", func(t *testing.T) {
+		result, err := simulator.Simulate(context.Background(), signedTransaction(t, 2_000_000),
+			nodeclient.WithSimulationInputs(vm.InputSet{inputID: input}),
+			nodeclient.WithSimulationCommitment(commitment),
+		)
+		require.NoError(t, err)
+		require.False(t, result.Succeeded())
+		require.Equal(t, api.TxFailureInputOutputBaseTokenMismatch, result.FailureReason)
+		require.ErrorIs(t, result.Error, iotago.ErrInputOutputBaseTokenMismatch)
+		require.Nil(t, result.Outputs)
+	})
+
+	t.Run("fail - input not found", func(t *testing.T) {
+		gock.New(nodeAPIUrl).
+			Get(api.EndpointWithNamedParameterValue(api.CoreRouteOutputWithMetadata, api.ParameterOutputID, inputID.ToHex())).
+			Reply(404)
+
+		_, err := simulator.Simulate(context.Background(), signedTransaction(t, 1_000_000))
+		require.ErrorIs(t, err, nodeclient.ErrHTTPNotFound)
+	})
+
+	t.Run("fail - input already spent", func(t *testing.T) {
+		mockInput(&api.OutputConsumptionMetadata{
+			Slot:          targetSlot - 1,
+			TransactionID: tpkg.RandTransactionID(),
+		})
+
+		result, err := simulator.Simulate(context.Background(), signedTransaction(t, 1_000_000))
+		require.NoError(t, err)
+		require.False(t, result.Succeeded())
+		require.Equal(t, api.TxFailureInputAlreadySpent, result.FailureReason)
+		require.ErrorIs(t, result.Error, iotago.ErrInputAlreadySpent)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("ok - resolve context inputs", func(t *testing.T) {
+		accountID := tpkg.RandAccountID()
+		delegationID := tpkg.RandOutputIDWithCreationSlot(10, 0)
+		delegation := builder.NewDelegationOutputBuilder(tpkg.RandAccountAddress(), addr, 1_000_000).MustBuild()
+
+		congestion := &api.CongestionResponse{Slot: commitment.Slot, BlockIssuanceCredits: 5_000}
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteCongestion, api.ParameterBech32Address, accountID.ToAddress().Bech32(mockAPI.ProtocolParameters().Bech32HRP())), 200, congestion)
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteRewards, api.ParameterOutputID, delegationID.ToHex()), 200, &api.ManaRewardsResponse{Rewards: 700})
+
+		transaction := &iotago.Transaction{
+			API: mockAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				CreationSlot: targetSlot,
+				ContextInputs: iotago.TxEssenceContextInputs{
+					&iotago.CommitmentInput{CommitmentID: commitmentID},
+					&iotago.BlockIssuanceCreditInput{AccountID: accountID},
+					&iotago.RewardInput{Index: 1},
+				},
+				Inputs: iotago.TxEssenceInputs{inputID.UTXOInput(), delegationID.UTXOInput()},
+			},
+		}
+
+		resolvedInputs, err := simulator.ResolveInputs(context.Background(), transaction,
+			nodeclient.WithSimulationInputs(vm.InputSet{inputID: input, delegationID: delegation}),
+			nodeclient.WithSimulationCommitment(commitment),
+		)
+		require.NoError(t, err)
+		require.Len(t, resolvedInputs.InputSet, 2)
+		require.Equal(t, vm.BlockIssuanceCreditInputSet{accountID: 5_000}, resolvedInputs.BlockIssuanceCreditInputSet)
+		// the delegation was just created, so its ID is derived from its output ID
+		require.Equal(t, vm.RewardsInputSet{iotago.DelegationIDFromOutputID(delegationID): 700}, resolvedInputs.RewardsInputSet)
+		require.True(t, gock.IsDone())
+	})
+}
@@ -0,0 +1,456 @@
+package nodeclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+const (
+	// DefaultTransactionTrackerPollInterval is the default interval in which the tracked transactions are polled.
+	DefaultTransactionTrackerPollInterval = 5 * time.Second
+	// DefaultTransactionTrackerEventBufferSize is the default size of the buffer of the events channel.
+	DefaultTransactionTrackerEventBufferSize = 100
+)
+
+// ErrTransactionTrackerRunning gets returned if the TransactionTracker is run more than once.
+var ErrTransactionTrackerRunning = ierrors.New("transaction tracker is already running")
+
+// TransactionTrackerOptions define options for the TransactionTracker.
+type TransactionTrackerOptions struct {
+	// The interval in which the tracked transactions are polled.
+	pollInterval time.Duration
+	// The size of the buffer of the events channel.
+	eventBufferSize int
+	// The optional EventAPIClient used to receive state changes without polling.
+	eventClient *EventAPIClient
+}
+
+// WithTransactionTrackerPollInterval sets the interval in which the tracked transactions are polled.
+func WithTransactionTrackerPollInterval(pollInterval time.Duration) TransactionTrackerOption {
+	return func(opts *TransactionTrackerOptions) {
+		opts.pollInterval = pollInterval
+	}
+}
+
+// WithTransactionTrackerEventBufferSize sets the size of the buffer of the events channel.
+func WithTransactionTrackerEventBufferSize(eventBufferSize int) TransactionTrackerOption {
+	return func(opts *TransactionTrackerOptions) {
+		opts.eventBufferSize = eventBufferSize
+	}
+}
+
+// WithTransactionTrackerEventAPIClient sets the EventAPIClient used to subscribe to the metadata
+// of the tracked transactions and their blocks. The EventAPIClient must already be connected.
+// Polling is still used as a fallback for missed events and failed subscriptions,
+// and for transactions that are tracked while the EventAPIClient is not connected.
+func WithTransactionTrackerEventAPIClient(eventClient *EventAPIClient) TransactionTrackerOption {
+	return func(opts *TransactionTrackerOptions) {
+		opts.eventClient = eventClient
+	}
+}
+
+// TransactionTrackerOption is a function setting a TransactionTracker option.
+type TransactionTrackerOption func(opts *TransactionTrackerOptions)
+
+// TransactionEvent is an event emitted by the TransactionTracker.
+// It is either a *TransactionStateChangedEvent or a *TransactionOrphanedEvent.
+type TransactionEvent interface {
+	transactionEvent()
+}
+
+// TransactionStateChangedEvent is emitted if the state of a tracked transaction changed.
+type TransactionStateChangedEvent struct {
+	TransactionID iotago.TransactionID
+	// PreviousState is the state before the change, TransactionStateUnknown for the first known state.
+	// A state lower than the previous state indicates a reorg.
+	PreviousState api.TransactionState
+	State         api.TransactionState
+	// Metadata is the metadata of the transaction reported by the node.
+	Metadata *api.TransactionMetadataResponse
+	// FailureReason is the decoded failure reason if the transaction failed, see api.TransactionFailureReasonError.
+	FailureReason error
+}
+
+func (e *TransactionStateChangedEvent) transactionEvent() {}
+
+// TransactionOrphanedEvent is emitted if the block a tracked transaction was issued in was orphaned or dropped
+// before the transaction was accepted. The transaction needs to be re-issued in a new block,
+// which can be tracked by calling TransactionTracker.Track with the new block ID.
+type TransactionOrphanedEvent struct {
+	TransactionID iotago.TransactionID
+	BlockID       iotago.BlockID
+	BlockState    api.BlockState
+}
+
+func (e *TransactionOrphanedEvent) transactionEvent() {}
+
+// trackedTransaction is the state of a transaction tracked by the TransactionTracker.
+type trackedTransaction struct {
+	transactionID iotago.TransactionID
+	blockID       iotago.BlockID
+	state         api.TransactionState
+	// orphanedBlockID is the block for which an orphaned event was already emitted.
+	orphanedBlockID iotago.BlockID
+
+	subscriptions []*EventAPIClientSubscription
+	// done is closed if the transaction is not tracked anymore, which stops the forwarding of subscribed events.
+	done    chan struct{}
+	stopped bool
+}
+
+// stop marks the transaction as not tracked anymore and returns its subscriptions, which need to be closed.
+// The subscriptions are closed by the caller without holding the lock of the TransactionTracker,
+// since unsubscribing waits for the node.
+func (t *trackedTransaction) stop() []*EventAPIClientSubscription {
+	if t.stopped {
+		return nil
+	}
+	t.stopped = true
+	close(t.done)
+
+	subscriptions := t.subscriptions
+	t.subscriptions = nil
+
+	return subscriptions
+}
+
+// closeSubscriptions closes the given subscriptions.
+func closeSubscriptions(subscriptions []*EventAPIClientSubscription) {
+	for _, subscription := range subscriptions {
+		_ = subscription.Close()
+	}
+}
+
+// TransactionTracker tracks the states of many transactions at once and emits an event for every state change.
+// It combines the subscriptions of the event API with polling the HTTP API as a fallback.
+// Transactions are tracked until they are finalized or failed, or until they are untracked.
+type TransactionTracker struct {
+	client *Client
+	opts   *TransactionTrackerOptions
+
+	mutex        sync.Mutex
+	transactions map[iotago.TransactionID]*trackedTransaction
+	running      bool
+
+	// updates receives the metadata from the event API subscriptions.
+	updates chan any
+	events  chan TransactionEvent
+}
+
+// NewTransactionTracker creates a new TransactionTracker which uses the given Client to poll the node.
+func NewTransactionTracker(client *Client, opts ...TransactionTrackerOption) *TransactionTracker {
+	options := &TransactionTrackerOptions{
+		pollInterval:    DefaultTransactionTrackerPollInterval,
+		eventBufferSize: DefaultTransactionTrackerEventBufferSize,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &TransactionTracker{
+		client:       client,
+		opts:         options,
+		transactions: make(map[iotago.TransactionID]*trackedTransaction),
+		updates:      make(chan any),
+		events:       make(chan TransactionEvent, options.eventBufferSize),
+	}
+}
+
+// Events returns the channel the events of the tracked transactions are emitted on.
+// The channel is closed once Run returns.
+func (t *TransactionTracker) Events() <-chan TransactionEvent {
+	return t.events
+}
+
+// Track starts tracking the given transaction, which was issued in the block with the given ID.
+// The block ID may be empty if the block is unknown, in which case orphaned blocks are not detected.
+// If the transaction is already tracked, the block ID is replaced, e.g. after the transaction was re-issued.
+func (t *TransactionTracker) Track(transactionID iotago.TransactionID, blockID iotago.BlockID) {
+	t.mutex.Lock()
+
+	tracked, exists := t.transactions[transactionID]
+	if exists && tracked.blockID == blockID {
+		t.mutex.Unlock()
+
+		return
+	}
+
+	newTracked := &trackedTransaction{
+		transactionID: transactionID,
+		blockID:       blockID,
+		done:          make(chan struct{}),
+	}
+
+	var staleSubscriptions []*EventAPIClientSubscription
+	if exists {
+		newTracked.state = tracked.state
+		newTracked.orphanedBlockID = tracked.orphanedBlockID
+
+		// resubscribe to the metadata of the new block
+		staleSubscriptions = tracked.stop()
+	}
+	t.transactions[transactionID] = newTracked
+
+	t.mutex.Unlock()
+
+	closeSubscriptions(staleSubscriptions)
+	t.subscribe(newTracked)
+}
+
+// Untrack stops tracking the given transaction.
+func (t *TransactionTracker) Untrack(transactionID iotago.TransactionID) {
+	t.mutex.Lock()
+
+	var staleSubscriptions []*EventAPIClientSubscription
+	if tracked, exists := t.transactions[transactionID]; exists {
+		staleSubscriptions = tracked.stop()
+		delete(t.transactions, transactionID)
+	}
+
+	t.mutex.Unlock()
+
+	closeSubscriptions(staleSubscriptions)
+}
+
+// Tracked returns the IDs of the tracked transactions.
+func (t *TransactionTracker) Tracked() iotago.TransactionIDs {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	transactionIDs := make(iotago.TransactionIDs, 0, len(t.transactions))
+	for transactionID := range t.transactions {
+		transactionIDs = append(transactionIDs, transactionID)
+	}
+
+	return transactionIDs
+}
+
+// subscribe subscribes to the metadata of the transaction and its block if an EventAPIClient is set.
+// Failed subscriptions are reported on the Errors channel of the EventAPIClient, the transaction is then only polled.
+// It must be called without holding the lock, since every subscription waits for the node.
+func (t *TransactionTracker) subscribe(tracked *trackedTransaction) {
+	eventClient := t.opts.eventClient
+	if eventClient == nil {
+		return
+	}
+
+	// subscribing to an inactive EventAPIClient panics
+	if err := eventClient.inactiveErr(); err != nil {
+		sendErrOrDrop(eventClient.Errors, ierrors.Wrapf(err, "failed to subscribe to the metadata of transaction %s", tracked.transactionID.ToHex()))
+
+		return
+	}
+
+	var subscriptions []*EventAPIClientSubscription
+
+	transactionMetadata, subscription := eventClient.TransactionMetadataByTransactionID(tracked.transactionID)
+	if err := subscription.Error(); err != nil {
+		sendErrOrDrop(eventClient.Errors, ierrors.Wrapf(err, "failed to subscribe to the metadata of transaction %s", tracked.transactionID.ToHex()))
+	} else {
+		subscriptions = append(subscriptions, subscription)
+		go forwardUpdates(transactionMetadata, t.updates, tracked.done)
+	}
+
+	if tracked.blockID != iotago.EmptyBlockID {
+		blockMetadata, subscription := eventClient.BlockMetadataByBlockID(tracked.blockID)
+		if err := subscription.Error(); err != nil {
+			sendErrOrDrop(eventClient.Errors, ierrors.Wrapf(err, "failed to subscribe to the metadata of block %s", tracked.blockID.ToHex()))
+		} else {
+			subscriptions = append(subscriptions, subscription)
+			go forwardUpdates(blockMetadata, t.updates, tracked.done)
+		}
+	}
+
+	t.mutex.Lock()
+	stopped := tracked.stopped
+	if !stopped {
+		tracked.subscriptions = subscriptions
+	}
+	t.mutex.Unlock()
+
+	// the transaction was untracked while subscribing
+	if stopped {
+		closeSubscriptions(subscriptions)
+	}
+}
+
+// forwardUpdates forwards the updates of a subscription until the transaction is not tracked anymore.
+// Updates that are still delivered afterwards are dropped once the subscription is closed.
+func forwardUpdates[T any](source <-chan T, target chan<- any, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case update := <-source:
+			select {
+			case <-done:
+				return
+			case target <- update:
+			}
+		}
+	}
+}
+
+// Run processes the events of the subscriptions and polls the tracked transactions until the context is done.
+// The events channel is closed and all transactions are untracked once Run returns.
+func (t *TransactionTracker) Run(ctx context.Context) error {
+	t.mutex.Lock()
+	if t.running {
+		t.mutex.Unlock()
+
+		return ErrTransactionTrackerRunning
+	}
+	t.running = true
+	t.mutex.Unlock()
+
+	defer func() {
+		t.mutex.Lock()
+
+		var staleSubscriptions []*EventAPIClientSubscription
+		for transactionID, tracked := range t.transactions {
+			staleSubscriptions = append(staleSubscriptions, tracked.stop()...)
+			delete(t.transactions, transactionID)
+		}
+		close(t.events)
+
+		t.mutex.Unlock()
+
+		closeSubscriptions(staleSubscriptions)
+	}()
+
+	ticker := time.NewTicker(t.opts.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case update := <-t.updates:
+			var events []TransactionEvent
+			switch update := update.(type) {
+			case *api.TransactionMetadataResponse:
+				events = t.applyTransactionMetadata(update)
+			case *api.BlockMetadataResponse:
+				events = t.applyBlockMetadata(update)
+			}
+
+			if !t.emit(ctx, events) {
+				return nil
+			}
+
+		case <-ticker.C:
+			if !t.emit(ctx, t.poll(ctx)) {
+				return nil
+			}
+		}
+	}
+}
+
+// poll fetches the metadata of all tracked transactions, and of the blocks of the transactions that are not accepted yet.
+// Errors are ignored, since the node might not know the transaction or block yet.
+func (t *TransactionTracker) poll(ctx context.Context) []TransactionEvent {
+	t.mutex.Lock()
+	transactions := make([]*trackedTransaction, 0, len(t.transactions))
+	for _, tracked := range t.transactions {
+		transactions = append(transactions, tracked)
+	}
+	t.mutex.Unlock()
+
+	var events []TransactionEvent
+	for _, tracked := range transactions {
+		if metadata, err := t.client.TransactionMetadata(ctx, tracked.transactionID); err == nil {
+			events = append(events, t.applyTransactionMetadata(metadata)...)
+		}
+
+		t.mutex.Lock()
+		blockID, state := tracked.blockID, tracked.state
+		t.mutex.Unlock()
+
+		if blockID == iotago.EmptyBlockID || state >= api.TransactionStateAccepted {
+			continue
+		}
+
+		if metadata, err := t.client.BlockMetadataByBlockID(ctx, blockID); err == nil {
+			events = append(events, t.applyBlockMetadata(metadata)...)
+		}
+	}
+
+	return events
+}
+
+// applyTransactionMetadata updates the state of the tracked transaction and returns the resulting events.
+func (t *TransactionTracker) applyTransactionMetadata(metadata *api.TransactionMetadataResponse) []TransactionEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tracked, exists := t.transactions[metadata.TransactionID]
+	if !exists || tracked.state == metadata.TransactionState {
+		return nil
+	}
+
+	event := &TransactionStateChangedEvent{
+		TransactionID: metadata.TransactionID,
+		PreviousState: tracked.state,
+		State:         metadata.TransactionState,
+		Metadata:      metadata,
+	}
+	tracked.state = metadata.TransactionState
+
+	switch metadata.TransactionState {
+	case api.TransactionStateFailed:
+		event.FailureReason = api.TransactionFailureReasonError(metadata.TransactionFailureReason)
+		fallthrough
+	case api.TransactionStateFinalized:
+		// unsubscribing waits for the node, so it doesn't block the processing of the other transactions
+		go closeSubscriptions(tracked.stop())
+		delete(t.transactions, metadata.TransactionID)
+	}
+
+	return []TransactionEvent{event}
+}
+
+// applyBlockMetadata checks whether the block of a tracked transaction was orphaned or dropped
+// and returns the resulting events.
+func (t *TransactionTracker) applyBlockMetadata(metadata *api.BlockMetadataResponse) []TransactionEvent {
+	if metadata.BlockState != api.BlockStateOrphaned && metadata.BlockState != api.BlockStateDropped {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var events []TransactionEvent
+	for _, tracked := range t.transactions {
+		if tracked.blockID != metadata.BlockID || tracked.orphanedBlockID == metadata.BlockID || tracked.state >= api.TransactionStateAccepted {
+			continue
+		}
+		tracked.orphanedBlockID = metadata.BlockID
+
+		events = append(events, &TransactionOrphanedEvent{
+			TransactionID: tracked.transactionID,
+			BlockID:       metadata.BlockID,
+			BlockState:    metadata.BlockState,
+		})
+	}
+
+	return events
+}
+
+// emit sends the events to the events channel and returns false if the context is done.
+func (t *TransactionTracker) emit(ctx context.Context, events []TransactionEvent) bool {
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return false
+		case t.events <- event:
+		}
+	}
+
+	return true
+}
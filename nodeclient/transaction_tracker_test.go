@@ -0,0 +1,339 @@
+package nodeclient_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+func TestTransactionTracker(t *testing.T) {
+	defer gock.Off()
+
+	nodeAPI := nodeClient(t)
+
+	mockTransactionState := func(transactionID iotago.TransactionID, state api.TransactionState, reason api.TransactionFailureReason) {
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteTransactionsMetadata, api.ParameterTransactionID, transactionID.ToHex()), 200, &api.TransactionMetadataResponse{
+			TransactionID:            transactionID,
+			TransactionState:         state,
+			TransactionFailureReason: reason,
+		})
+	}
+
+	mockBlockState := func(blockID iotago.BlockID, state api.BlockState) {
+		mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteBlockMetadata, api.ParameterBlockID, blockID.ToHex()), 200, &api.BlockMetadataResponse{
+			BlockID:    blockID,
+			BlockState: state,
+		})
+	}
+
+	confirmedTxID, confirmedBlockID := tpkg.RandTransactionID(), tpkg.RandBlockID()
+	orphanedTxID, orphanedBlockID, reissuedBlockID := tpkg.RandTransactionID(), tpkg.RandBlockID(), tpkg.RandBlockID()
+
+	// unmatched requests fail and are ignored by the tracker, so additional polls don't change the events
+	mockTransactionState(confirmedTxID, api.TransactionStatePending, api.TxFailureNone)
+	mockBlockState(confirmedBlockID, api.BlockStatePending)
+	mockTransactionState(confirmedTxID, api.TransactionStateAccepted, api.TxFailureNone)
+	mockTransactionState(confirmedTxID, api.TransactionStateFinalized, api.TxFailureNone)
+
+	mockTransactionState(orphanedTxID, api.TransactionStatePending, api.TxFailureNone)
+	mockBlockState(orphanedBlockID, api.BlockStateOrphaned)
+	mockBlockState(orphanedBlockID, api.BlockStateOrphaned)
+	mockBlockState(reissuedBlockID, api.BlockStatePending)
+	mockTransactionState(orphanedTxID, api.TransactionStatePending, api.TxFailureNone)
+	mockTransactionState(orphanedTxID, api.TransactionStatePending, api.TxFailureNone)
+	mockTransactionState(orphanedTxID, api.TransactionStateFailed, api.TxFailureInputAlreadySpent)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tracker := nodeclient.NewTransactionTracker(nodeAPI, nodeclient.WithTransactionTrackerPollInterval(10*time.Millisecond))
+	tracker.Track(confirmedTxID, confirmedBlockID)
+	tracker.Track(orphanedTxID, orphanedBlockID)
+	require.ElementsMatch(t, iotago.TransactionIDs{confirmedTxID, orphanedTxID}, tracker.Tracked())
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	events := make(map[iotago.TransactionID][]nodeclient.TransactionEvent)
+	for len(events[confirmedTxID]) < 3 || len(events[orphanedTxID]) < 3 {
+		select {
+		case <-ctx.Done():
+			require.FailNow(t, "timed out waiting for events", "received %v", events)
+		case event := <-tracker.Events():
+			switch event := event.(type) {
+			case *nodeclient.TransactionStateChangedEvent:
+				events[event.TransactionID] = append(events[event.TransactionID], event)
+			case *nodeclient.TransactionOrphanedEvent:
+				events[event.TransactionID] = append(events[event.TransactionID], event)
+
+				// the orphaned event is only emitted once per block
+				require.Equal(t, orphanedBlockID, event.BlockID)
+				tracker.Track(event.TransactionID, reissuedBlockID)
+			}
+		}
+	}
+
+	require.Equal(t, []nodeclient.TransactionEvent{
+		&nodeclient.TransactionStateChangedEvent{
+			TransactionID: confirmedTxID,
+			PreviousState: api.TransactionStateUnknown,
+			State:         api.TransactionStatePending,
+			Metadata:      &api.TransactionMetadataResponse{TransactionID: confirmedTxID, TransactionState: api.TransactionStatePending},
+		},
+		&nodeclient.TransactionStateChangedEvent{
+			TransactionID: confirmedTxID,
+			PreviousState: api.TransactionStatePending,
+			State:         api.TransactionStateAccepted,
+			Metadata:      &api.TransactionMetadataResponse{TransactionID: confirmedTxID, TransactionState: api.TransactionStateAccepted},
+		},
+		&nodeclient.TransactionStateChangedEvent{
+			TransactionID: confirmedTxID,
+			PreviousState: api.TransactionStateAccepted,
+			State:         api.TransactionStateFinalized,
+			Metadata:      &api.TransactionMetadataResponse{TransactionID: confirmedTxID, TransactionState: api.TransactionStateFinalized},
+		},
+	}, events[confirmedTxID])
+
+	orphanedEvents := events[orphanedTxID]
+	require.Equal(t, api.TransactionStatePending, orphanedEvents[0].(*nodeclient.TransactionStateChangedEvent).State)
+	require.Equal(t, &nodeclient.TransactionOrphanedEvent{
+		TransactionID: orphanedTxID,
+		BlockID:       orphanedBlockID,
+		BlockState:    api.BlockStateOrphaned,
+	}, orphanedEvents[1])
+
+	failedEvent := orphanedEvents[2].(*nodeclient.TransactionStateChangedEvent)
+	require.Equal(t, api.TransactionStatePending, failedEvent.PreviousState)
+	require.Equal(t, api.TransactionStateFailed, failedEvent.State)
+	require.ErrorIs(t, failedEvent.FailureReason, iotago.ErrInputAlreadySpent)
+
+	// finalized and failed transactions are not tracked anymore
+	require.Empty(t, tracker.Tracked())
+
+	cancel()
+	require.NoError(t, <-runErr)
+
+	_, open := <-tracker.Events()
+	require.False(t, open)
+	require.ErrorIs(t, tracker.Run(context.Background()), nodeclient.ErrTransactionTrackerRunning)
+}
+
+// mockTrackerMqttClient is an MQTT client which delivers the published messages synchronously to the subscribed handlers,
+// like the ordered delivery of the MQTT client does.
+type mockTrackerMqttClient struct {
+	mockMqttClient
+
+	mutex        sync.Mutex
+	connected    bool
+	handlers     map[string]mqtt.MessageHandler
+	unsubscribed []string
+}
+
+func newMockTrackerMqttClient(connected bool) *mockTrackerMqttClient {
+	return &mockTrackerMqttClient{
+		connected: connected,
+		handlers:  make(map[string]mqtt.MessageHandler),
+	}
+}
+
+func (m *mockTrackerMqttClient) IsConnected() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.connected
+}
+
+func (m *mockTrackerMqttClient) Subscribe(topic string, _ byte, callback mqtt.MessageHandler) mqtt.Token {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[topic] = callback
+
+	return &mockToken{}
+}
+
+func (m *mockTrackerMqttClient) Unsubscribe(topics ...string) mqtt.Token {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, topic := range topics {
+		delete(m.handlers, topic)
+		m.unsubscribed = append(m.unsubscribed, topic)
+	}
+
+	return &mockToken{}
+}
+
+func (m *mockTrackerMqttClient) handler(topic string) mqtt.MessageHandler {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.handlers[topic]
+}
+
+func (m *mockTrackerMqttClient) isUnsubscribed(topic string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return slices.Contains(m.unsubscribed, topic)
+}
+
+func transactionMetadataTopic(transactionID iotago.TransactionID) string {
+	return api.EndpointWithNamedParameterValue(api.EventAPITopicTransactionMetadata, api.ParameterTransactionID, transactionID.ToHex()) + api.EventAPITopicSuffixRaw
+}
+
+func blockMetadataTopic(blockID iotago.BlockID) string {
+	return api.EndpointWithNamedParameterValue(api.EventAPITopicBlockMetadata, api.ParameterBlockID, blockID.ToHex()) + api.EventAPITopicSuffixRaw
+}
+
+func TestTransactionTrackerEvents(t *testing.T) {
+	defer gock.Off()
+
+	nodeAPI := nodeClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mqttClient := newMockTrackerMqttClient(true)
+	eventClient := &nodeclient.EventAPIClient{
+		Client:     nodeAPI,
+		MQTTClient: mqttClient,
+		Errors:     make(chan error),
+	}
+	require.NoError(t, eventClient.Connect(ctx))
+
+	// the transactions are never polled
+	tracker := nodeclient.NewTransactionTracker(nodeAPI,
+		nodeclient.WithTransactionTrackerPollInterval(time.Hour),
+		nodeclient.WithTransactionTrackerEventAPIClient(eventClient),
+	)
+
+	transactionID, blockID := tpkg.RandTransactionID(), tpkg.RandBlockID()
+	tracker.Track(transactionID, blockID)
+
+	transactionHandler := mqttClient.handler(transactionMetadataTopic(transactionID))
+	require.NotNil(t, transactionHandler)
+	blockHandler := mqttClient.handler(blockMetadataTopic(blockID))
+	require.NotNil(t, blockHandler)
+
+	publish := func(handler mqtt.MessageHandler, response any) {
+		handler(mqttClient, &mockMsg{payload: lo.PanicOnErr(mockAPI.Encode(response))})
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	nextEvent := func() nodeclient.TransactionEvent {
+		select {
+		case <-ctx.Done():
+			require.FailNow(t, "timed out waiting for an event")
+
+			return nil
+		case event := <-tracker.Events():
+			return event
+		}
+	}
+
+	publish(blockHandler, &api.BlockMetadataResponse{BlockID: blockID, BlockState: api.BlockStateOrphaned})
+	require.Equal(t, &nodeclient.TransactionOrphanedEvent{
+		TransactionID: transactionID,
+		BlockID:       blockID,
+		BlockState:    api.BlockStateOrphaned,
+	}, nextEvent())
+
+	// the transaction was re-issued in a new block, the subscription of the old block is closed
+	reissuedBlockID := tpkg.RandBlockID()
+	tracker.Track(transactionID, reissuedBlockID)
+	require.True(t, mqttClient.isUnsubscribed(blockMetadataTopic(blockID)))
+	require.NotNil(t, mqttClient.handler(blockMetadataTopic(reissuedBlockID)))
+
+	transactionHandler = mqttClient.handler(transactionMetadataTopic(transactionID))
+	publish(transactionHandler, &api.TransactionMetadataResponse{TransactionID: transactionID, TransactionState: api.TransactionStateFinalized})
+
+	event, ok := nextEvent().(*nodeclient.TransactionStateChangedEvent)
+	require.True(t, ok)
+	require.Equal(t, api.TransactionStateFinalized, event.State)
+	require.Empty(t, tracker.Tracked())
+
+	require.Eventually(t, func() bool {
+		return mqttClient.isUnsubscribed(transactionMetadataTopic(transactionID)) && mqttClient.isUnsubscribed(blockMetadataTopic(reissuedBlockID))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a message that was in flight while unsubscribing doesn't block the MQTT client
+	delivered := make(chan struct{})
+	go func() {
+		publish(transactionHandler, &api.TransactionMetadataResponse{TransactionID: transactionID, TransactionState: api.TransactionStateFinalized})
+		close(delivered)
+	}()
+
+	select {
+	case <-ctx.Done():
+		require.FailNow(t, "in-flight message blocked the MQTT client")
+	case <-delivered:
+	}
+
+	cancel()
+	require.NoError(t, <-runErr)
+}
+
+func TestTransactionTrackerEventsNotConnected(t *testing.T) {
+	defer gock.Off()
+
+	nodeAPI := nodeClient(t)
+
+	transactionID := tpkg.RandTransactionID()
+	mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteTransactionsMetadata, api.ParameterTransactionID, transactionID.ToHex()), 200, &api.TransactionMetadataResponse{
+		TransactionID:    transactionID,
+		TransactionState: api.TransactionStateFinalized,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mqttClient := newMockTrackerMqttClient(true)
+	eventClient := &nodeclient.EventAPIClient{
+		Client:     nodeAPI,
+		MQTTClient: mqttClient,
+		Errors:     make(chan error, 1),
+	}
+	require.NoError(t, eventClient.Connect(ctx))
+
+	tracker := nodeclient.NewTransactionTracker(nodeAPI,
+		nodeclient.WithTransactionTrackerPollInterval(10*time.Millisecond),
+		nodeclient.WithTransactionTrackerEventAPIClient(eventClient),
+	)
+
+	// the connection was lost, the transaction is polled instead of panicking
+	mqttClient.mutex.Lock()
+	mqttClient.connected = false
+	mqttClient.mutex.Unlock()
+
+	tracker.Track(transactionID, iotago.EmptyBlockID)
+	require.ErrorIs(t, <-eventClient.Errors, nodeclient.ErrEventAPIClientInactive)
+	require.Nil(t, mqttClient.handler(transactionMetadataTopic(transactionID)))
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	select {
+	case <-ctx.Done():
+		require.FailNow(t, "timed out waiting for the polled state")
+	case event := <-tracker.Events():
+		require.Equal(t, api.TransactionStateFinalized, event.(*nodeclient.TransactionStateChangedEvent).State)
+	}
+
+	cancel()
+	require.NoError(t, <-runErr)
+}
@@ -0,0 +1,407 @@
+// Package rewards implements the calculation of the Mana rewards of validators and delegators,
+// so that the rewards reported by a node can be checked and future rewards can be projected offline.
+//
+// The calculation follows the formulas of the protocol:
+// every epoch, each pool of the committee receives a reward that depends on the share of the pool and the validator
+// in the total stake, the target reward of the epoch (which decays during the bootstrapping phase) and the performance
+// of the validator. The validator takes its fixed cost and a profit margin from the pool reward,
+// the rest is shared between the validator and the delegators proportionally to their stake.
+// Rewards are decayed from the epoch they were earned in until the epoch they are claimed in,
+// and are only retained for the rewards retention period.
+package rewards
+
+import (
+	"math/bits"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+var (
+	// ErrPoolNotInCommittee gets returned if a pool is not part of the committee.
+	ErrPoolNotInCommittee = ierrors.New("pool is not part of the committee")
+	// ErrInvalidEpochRange gets returned if the start epoch of a range is after its end epoch.
+	ErrInvalidEpochRange = ierrors.New("start epoch is after end epoch")
+)
+
+// SlotPerformance is the activity of a validator in a single slot.
+type SlotPerformance struct {
+	// SlotActivityVector has a bit set for each subslot in which the validator issued at least one validation block.
+	SlotActivityVector uint32
+	// BlocksIssuedCount is the number of validation blocks the validator issued in the slot.
+	BlocksIssuedCount uint8
+}
+
+// PoolStats are the statistics of the committee of an epoch.
+type PoolStats struct {
+	// TotalStake is the sum of the pool stakes of all committee members.
+	TotalStake iotago.BaseToken
+	// TotalValidatorStake is the sum of the validator stakes of all committee members.
+	TotalValidatorStake iotago.BaseToken
+	// ProfitMargin is the share of the pool rewards the validators take, scaled by 2^ProfitMarginExponent.
+	ProfitMargin uint64
+}
+
+// PoolRewards are the rewards of a single pool in an epoch.
+type PoolRewards struct {
+	// PoolStake is the sum of the validator stake and the delegated stake of the pool.
+	PoolStake iotago.BaseToken
+	// PoolRewards are the undecayed rewards of the whole pool.
+	PoolRewards iotago.Mana
+	// FixedCost is the fixed cost the validator takes from the pool rewards.
+	FixedCost iotago.Mana
+}
+
+// EpochPoolRewards are the pool statistics and the rewards of a single pool in an epoch.
+type EpochPoolRewards struct {
+	Epoch       iotago.EpochIndex
+	PoolStats   *PoolStats
+	PoolRewards *PoolRewards
+}
+
+// CommitteeMember is a pool that is part of the committee of an epoch.
+type CommitteeMember struct {
+	// PoolStake is the sum of the validator stake and the delegated stake of the pool.
+	PoolStake iotago.BaseToken
+	// ValidatorStake is the stake of the validator.
+	ValidatorStake iotago.BaseToken
+	// FixedCost is the fixed cost the validator takes from the pool rewards.
+	FixedCost iotago.Mana
+	// PerformanceFactor is the performance of the validator in the epoch, see Calculator.PerformanceFactor.
+	PerformanceFactor uint64
+}
+
+// Committee is the committee of an epoch.
+type Committee struct {
+	Epoch               iotago.EpochIndex
+	TotalStake          iotago.BaseToken
+	TotalValidatorStake iotago.BaseToken
+	Members             map[iotago.AccountID]*CommitteeMember
+}
+
+// Calculator calculates rewards with the protocol parameters of the epochs they are earned in.
+type Calculator struct {
+	apiProvider iotago.APIProvider
+}
+
+// NewCalculator creates a new Calculator that gets the protocol parameters from the given APIProvider.
+func NewCalculator(apiProvider iotago.APIProvider) *Calculator {
+	return &Calculator{
+		apiProvider: apiProvider,
+	}
+}
+
+// CommitteeFromResponse converts the committee returned by a node into a Committee.
+// Since the node doesn't report the performance of the validators,
+// all members are assumed to perform ideally, see Calculator.MaxPerformanceFactor.
+func (c *Calculator) CommitteeFromResponse(committeeResponse *api.CommitteeResponse) (*Committee, error) {
+	committee := &Committee{
+		Epoch:               committeeResponse.Epoch,
+		TotalStake:          committeeResponse.TotalStake,
+		TotalValidatorStake: committeeResponse.TotalValidatorStake,
+		Members:             make(map[iotago.AccountID]*CommitteeMember, len(committeeResponse.Committee)),
+	}
+
+	for _, member := range committeeResponse.Committee {
+		_, addr, err := iotago.ParseBech32(member.AddressBech32)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to parse the address of committee member %s", member.AddressBech32)
+		}
+
+		accountAddress, ok := addr.(*iotago.AccountAddress)
+		if !ok {
+			return nil, ierrors.Errorf("committee member %s is not an account address", member.AddressBech32)
+		}
+
+		committee.Members[accountAddress.AccountID()] = &CommitteeMember{
+			PoolStake:         member.PoolStake,
+			ValidatorStake:    member.ValidatorStake,
+			FixedCost:         member.FixedCost,
+			PerformanceFactor: c.MaxPerformanceFactor(committeeResponse.Epoch),
+		}
+	}
+
+	return committee, nil
+}
+
+// MaxPerformanceFactor returns the performance factor of a validator that issued
+// a validation block in every subslot of every slot of the epoch.
+func (c *Calculator) MaxPerformanceFactor(epoch iotago.EpochIndex) uint64 {
+	return uint64(c.apiProvider.APIForEpoch(epoch).ProtocolParameters().ValidationBlocksPerSlot())
+}
+
+// PerformanceFactor returns the performance factor of a validator in the given epoch.
+// Every subslot with at least one validation block counts as one. The result is the average over all slots of the epoch,
+// slots which are missing in the given performances count as slots without activity.
+// Like in the performance tracker of the node, a validator that issued more than ValidationBlocksPerSlot blocks
+// in any slot of the epoch gets a performance factor of 0 for the whole epoch.
+func (c *Calculator) PerformanceFactor(epoch iotago.EpochIndex, slotPerformances []*SlotPerformance) (uint64, error) {
+	protocolParameters := c.apiProvider.APIForEpoch(epoch).ProtocolParameters()
+
+	if len(slotPerformances) > 1<<protocolParameters.SlotsPerEpochExponent() {
+		return 0, ierrors.Errorf("got performances of %d slots, but an epoch only has %d slots", len(slotPerformances), 1<<protocolParameters.SlotsPerEpochExponent())
+	}
+
+	var performanceFactor uint64
+	for _, slotPerformance := range slotPerformances {
+		if slotPerformance == nil {
+			continue
+		}
+
+		if slotPerformance.BlocksIssuedCount > protocolParameters.ValidationBlocksPerSlot() {
+			// issuing more blocks than allowed is punished for the whole epoch
+			return 0, nil
+		}
+
+		performanceFactor += uint64(bits.OnesCount32(slotPerformance.SlotActivityVector))
+	}
+
+	return performanceFactor >> protocolParameters.SlotsPerEpochExponent(), nil
+}
+
+// ProfitMargin returns the profit margin of the validators in the given epoch, scaled by 2^ProfitMarginExponent.
+func (c *Calculator) ProfitMargin(epoch iotago.EpochIndex, totalValidatorStake iotago.BaseToken, totalStake iotago.BaseToken) (uint64, error) {
+	profitMarginExponent := c.apiProvider.APIForEpoch(epoch).ProtocolParameters().RewardsParameters().ProfitMarginExponent
+
+	scaledTotalValidatorStake, err := safemath.SafeLeftShift(uint64(totalValidatorStake), profitMarginExponent)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to scale the total validator stake")
+	}
+
+	stakeSum, err := safemath.SafeAdd(uint64(totalValidatorStake), uint64(totalStake))
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to add the total validator stake and the total stake")
+	}
+
+	if stakeSum == 0 {
+		return 0, nil
+	}
+
+	return scaledTotalValidatorStake / stakeSum, nil
+}
+
+// PoolCoefficient returns the share of the pool and its validator in the total stakes of the committee,
+// scaled by 2^PoolCoefficientExponent.
+func (c *Calculator) PoolCoefficient(epoch iotago.EpochIndex, poolStake iotago.BaseToken, totalStake iotago.BaseToken, validatorStake iotago.BaseToken, totalValidatorStake iotago.BaseToken) (uint64, error) {
+	if totalStake == 0 || totalValidatorStake == 0 {
+		return 0, ierrors.New("the total stakes of the committee must not be zero")
+	}
+
+	poolCoefficientExponent := c.apiProvider.APIForEpoch(epoch).ProtocolParameters().RewardsParameters().PoolCoefficientExponent
+
+	scaledPoolStake, err := safemath.SafeLeftShift(uint64(poolStake), poolCoefficientExponent)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to scale the pool stake")
+	}
+
+	scaledValidatorStake, err := safemath.SafeLeftShift(uint64(validatorStake), poolCoefficientExponent)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to scale the validator stake")
+	}
+
+	return safemath.SafeAdd(scaledPoolStake/uint64(totalStake), scaledValidatorStake/uint64(totalValidatorStake))
+}
+
+// PoolReward returns the undecayed reward of a pool in the given epoch.
+func (c *Calculator) PoolReward(epoch iotago.EpochIndex, totalValidatorStake iotago.BaseToken, totalStake iotago.BaseToken, poolStake iotago.BaseToken, validatorStake iotago.BaseToken, performanceFactor uint64) (iotago.Mana, error) {
+	apiForEpoch := c.apiProvider.APIForEpoch(epoch)
+	protocolParameters := apiForEpoch.ProtocolParameters()
+
+	targetReward, err := protocolParameters.RewardsParameters().TargetReward(epoch, apiForEpoch)
+	if err != nil {
+		return 0, ierrors.Wrapf(err, "failed to calculate the target reward of epoch %d", epoch)
+	}
+
+	poolCoefficient, err := c.PoolCoefficient(epoch, poolStake, totalStake, validatorStake, totalValidatorStake)
+	if err != nil {
+		return 0, ierrors.Wrapf(err, "failed to calculate the pool coefficient of epoch %d", epoch)
+	}
+
+	scaledPoolReward, err := safemath.SafeMul(poolCoefficient, uint64(targetReward))
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to scale the target reward by the pool coefficient")
+	}
+
+	poolReward, err := safemath.SafeMul(scaledPoolReward/uint64(protocolParameters.ValidationBlocksPerSlot()), performanceFactor)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to scale the pool reward by the performance factor")
+	}
+
+	// the pool coefficient is the sum of two shares, so it is scaled by 2^(PoolCoefficientExponent+1)
+	return iotago.Mana(poolReward >> (protocolParameters.RewardsParameters().PoolCoefficientExponent + 1)), nil
+}
+
+// EpochRewards returns the pool statistics of the committee and the rewards of all its pools.
+func (c *Calculator) EpochRewards(committee *Committee) (*PoolStats, map[iotago.AccountID]*PoolRewards, error) {
+	profitMargin, err := c.ProfitMargin(committee.Epoch, committee.TotalValidatorStake, committee.TotalStake)
+	if err != nil {
+		return nil, nil, ierrors.Wrapf(err, "failed to calculate the profit margin of epoch %d", committee.Epoch)
+	}
+
+	poolRewards := make(map[iotago.AccountID]*PoolRewards, len(committee.Members))
+	for accountID, member := range committee.Members {
+		poolReward, err := c.PoolReward(committee.Epoch, committee.TotalValidatorStake, committee.TotalStake, member.PoolStake, member.ValidatorStake, member.PerformanceFactor)
+		if err != nil {
+			return nil, nil, ierrors.Wrapf(err, "failed to calculate the pool reward of %s", accountID.ToHex())
+		}
+
+		poolRewards[accountID] = &PoolRewards{
+			PoolStake:   member.PoolStake,
+			PoolRewards: poolReward,
+			FixedCost:   member.FixedCost,
+		}
+	}
+
+	return &PoolStats{
+		TotalStake:          committee.TotalStake,
+		TotalValidatorStake: committee.TotalValidatorStake,
+		ProfitMargin:        profitMargin,
+	}, poolRewards, nil
+}
+
+// Project returns the rewards of the given pool for every epoch from startEpoch to endEpoch (both inclusive),
+// assuming that the committee stays the same in all these epochs.
+func (c *Calculator) Project(committee *Committee, validatorID iotago.AccountID, startEpoch iotago.EpochIndex, endEpoch iotago.EpochIndex) ([]*EpochPoolRewards, error) {
+	if startEpoch > endEpoch {
+		return nil, ierrors.WithMessagef(ErrInvalidEpochRange, "start epoch %d, end epoch %d", startEpoch, endEpoch)
+	}
+
+	if _, exists := committee.Members[validatorID]; !exists {
+		return nil, ierrors.WithMessagef(ErrPoolNotInCommittee, "pool %s", validatorID.ToHex())
+	}
+
+	epochRewards := make([]*EpochPoolRewards, 0, endEpoch-startEpoch+1)
+	for epoch := startEpoch; epoch <= endEpoch; epoch++ {
+		epochCommittee := *committee
+		epochCommittee.Epoch = epoch
+
+		poolStats, poolRewards, err := c.EpochRewards(&epochCommittee)
+		if err != nil {
+			return nil, err
+		}
+
+		epochRewards = append(epochRewards, &EpochPoolRewards{
+			Epoch:       epoch,
+			PoolStats:   poolStats,
+			PoolRewards: poolRewards[validatorID],
+		})
+	}
+
+	return epochRewards, nil
+}
+
+// ValidatorReward returns the decayed reward the validator with the given stake can claim in the claiming epoch
+// for the given epochs of its pool. Epochs which are not retained anymore in the claiming epoch are skipped.
+// If the fixed cost of the validator exceeds the pool rewards of an epoch, the validator gets no reward for that epoch.
+func (c *Calculator) ValidatorReward(validatorStake iotago.BaseToken, claimingEpoch iotago.EpochIndex, epochRewards []*EpochPoolRewards) (iotago.Mana, error) {
+	return c.decayedRewards(claimingEpoch, epochRewards, func(epochReward *EpochPoolRewards, profitMarginExponent uint8) (iotago.Mana, error) {
+		poolRewards := epochReward.PoolRewards
+		if poolRewards.PoolRewards < poolRewards.FixedCost {
+			return 0, nil
+		}
+		poolRewardsWithoutFixedCost := uint64(poolRewards.PoolRewards - poolRewards.FixedCost)
+
+		profitMarginReward, err := safemath.Safe64MulDiv(epochReward.PoolStats.ProfitMargin, poolRewardsWithoutFixedCost, 1<<profitMarginExponent)
+		if err != nil {
+			return 0, ierrors.Wrap(err, "failed to calculate the profit margin reward")
+		}
+
+		stakeReward, err := stakeShare(poolRewardsWithoutFixedCost, epochReward.PoolStats.ProfitMargin, profitMarginExponent, validatorStake, poolRewards.PoolStake)
+		if err != nil {
+			return 0, err
+		}
+
+		reward, err := safemath.SafeAdd(uint64(poolRewards.FixedCost), profitMarginReward)
+		if err != nil {
+			return 0, ierrors.Wrap(err, "failed to add the profit margin reward")
+		}
+
+		reward, err = safemath.SafeAdd(reward, stakeReward)
+		if err != nil {
+			return 0, ierrors.Wrap(err, "failed to add the stake reward")
+		}
+
+		return iotago.Mana(reward), nil
+	})
+}
+
+// DelegatorReward returns the decayed reward a delegator with the given delegated amount can claim in the claiming epoch
+// for the given epochs of the pool it delegated to. Epochs which are not retained anymore in the claiming epoch are skipped.
+// If the fixed cost of the validator exceeds the pool rewards of an epoch, the delegators get no reward for that epoch.
+func (c *Calculator) DelegatorReward(delegatedAmount iotago.BaseToken, claimingEpoch iotago.EpochIndex, epochRewards []*EpochPoolRewards) (iotago.Mana, error) {
+	return c.decayedRewards(claimingEpoch, epochRewards, func(epochReward *EpochPoolRewards, profitMarginExponent uint8) (iotago.Mana, error) {
+		poolRewards := epochReward.PoolRewards
+		if poolRewards.PoolRewards < poolRewards.FixedCost {
+			return 0, nil
+		}
+
+		reward, err := stakeShare(uint64(poolRewards.PoolRewards-poolRewards.FixedCost), epochReward.PoolStats.ProfitMargin, profitMarginExponent, delegatedAmount, poolRewards.PoolStake)
+		if err != nil {
+			return 0, err
+		}
+
+		return iotago.Mana(reward), nil
+	})
+}
+
+// decayedRewards sums up the rewards of all retained epochs, decayed to the claiming epoch.
+func (c *Calculator) decayedRewards(claimingEpoch iotago.EpochIndex, epochRewards []*EpochPoolRewards, epochReward func(epochReward *EpochPoolRewards, profitMarginExponent uint8) (iotago.Mana, error)) (iotago.Mana, error) {
+	retentionPeriod := iotago.EpochIndex(c.apiProvider.APIForEpoch(claimingEpoch).ProtocolParameters().RewardsParameters().RetentionPeriod)
+
+	var rewards iotago.Mana
+	for _, epochRewardsOfPool := range epochRewards {
+		epoch := epochRewardsOfPool.Epoch
+		if epoch >= claimingEpoch {
+			return 0, ierrors.Errorf("rewards of epoch %d can't be claimed before epoch %d", epoch, epoch+1)
+		}
+
+		// rewards of epochs older than the retention period were pruned
+		if claimingEpoch > retentionPeriod && epoch < claimingEpoch-retentionPeriod {
+			continue
+		}
+
+		if epochRewardsOfPool.PoolRewards == nil || epochRewardsOfPool.PoolRewards.PoolStake == 0 {
+			continue
+		}
+
+		apiForEpoch := c.apiProvider.APIForEpoch(epoch)
+
+		reward, err := epochReward(epochRewardsOfPool, apiForEpoch.ProtocolParameters().RewardsParameters().ProfitMarginExponent)
+		if err != nil {
+			return 0, ierrors.Wrapf(err, "failed to calculate the reward of epoch %d", epoch)
+		}
+
+		decayedReward, err := apiForEpoch.ManaDecayProvider().DecayManaByEpochs(reward, epoch, claimingEpoch)
+		if err != nil {
+			return 0, ierrors.Wrapf(err, "failed to decay the reward of epoch %d", epoch)
+		}
+
+		if rewards, err = safemath.SafeAdd(rewards, decayedReward); err != nil {
+			return 0, ierrors.Wrap(err, "failed to add the reward of the epoch")
+		}
+	}
+
+	return rewards, nil
+}
+
+// stakeShare returns the part of the pool rewards without the profit margin that belongs to the given stake.
+func stakeShare(poolRewardsWithoutFixedCost uint64, profitMargin uint64, profitMarginExponent uint8, stake iotago.BaseToken, poolStake iotago.BaseToken) (uint64, error) {
+	profitMarginComplement, err := safemath.SafeSub(uint64(1)<<profitMarginExponent, profitMargin)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "profit margin exceeds one")
+	}
+
+	rewardsWithoutProfitMargin, err := safemath.Safe64MulDiv(profitMarginComplement, poolRewardsWithoutFixedCost, uint64(1)<<profitMarginExponent)
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate the rewards without profit margin")
+	}
+
+	share, err := safemath.Safe64MulDiv(rewardsWithoutProfitMargin, uint64(stake), uint64(poolStake))
+	if err != nil {
+		return 0, ierrors.Wrap(err, "failed to calculate the share of the stake")
+	}
+
+	return share, nil
+}
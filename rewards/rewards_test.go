@@ -0,0 +1,212 @@
+package rewards_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/rewards"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+var (
+	testAPI        = iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	testCalculator = rewards.NewCalculator(iotago.SingleVersionProvider(testAPI))
+)
+
+func TestPerformanceFactor(t *testing.T) {
+	validationBlocksPerSlot := testAPI.ProtocolParameters().ValidationBlocksPerSlot()
+	slotsPerEpoch := 1 << testAPI.ProtocolParameters().SlotsPerEpochExponent()
+
+	ideal := make([]*rewards.SlotPerformance, slotsPerEpoch)
+	for i := range ideal {
+		ideal[i] = &rewards.SlotPerformance{
+			SlotActivityVector: 1<<validationBlocksPerSlot - 1,
+			BlocksIssuedCount:  validationBlocksPerSlot,
+		}
+	}
+
+	performanceFactor, err := testCalculator.PerformanceFactor(1, ideal)
+	require.NoError(t, err)
+	require.Equal(t, testCalculator.MaxPerformanceFactor(1), performanceFactor)
+
+	// only every second slot has activity, the others are missing
+	half := make([]*rewards.SlotPerformance, slotsPerEpoch)
+	for i := range half {
+		if i%2 == 0 {
+			half[i] = ideal[i]
+		}
+	}
+
+	performanceFactor, err = testCalculator.PerformanceFactor(1, half)
+	require.NoError(t, err)
+	require.Equal(t, uint64(validationBlocksPerSlot)/2, performanceFactor)
+
+	// a single slot with too many blocks punishes the whole epoch
+	half[1] = &rewards.SlotPerformance{
+		SlotActivityVector: 1<<validationBlocksPerSlot - 1,
+		BlocksIssuedCount:  validationBlocksPerSlot + 1,
+	}
+
+	performanceFactor, err = testCalculator.PerformanceFactor(1, half)
+	require.NoError(t, err)
+	require.Zero(t, performanceFactor)
+
+	_, err = testCalculator.PerformanceFactor(1, make([]*rewards.SlotPerformance, slotsPerEpoch+1))
+	require.Error(t, err)
+}
+
+func TestProfitMargin(t *testing.T) {
+	profitMarginExponent := testAPI.ProtocolParameters().RewardsParameters().ProfitMarginExponent
+
+	profitMargin, err := testCalculator.ProfitMargin(1, 1_000_000, 3_000_000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1)<<profitMarginExponent/4, profitMargin)
+
+	profitMargin, err = testCalculator.ProfitMargin(1, 0, 0)
+	require.NoError(t, err)
+	require.Zero(t, profitMargin)
+}
+
+func TestPoolReward(t *testing.T) {
+	epoch := iotago.EpochIndex(10)
+
+	targetReward, err := testAPI.ProtocolParameters().RewardsParameters().TargetReward(epoch, testAPI)
+	require.NoError(t, err)
+
+	// a single pool holding all stake with an ideal performance gets the whole target reward
+	poolReward, err := testCalculator.PoolReward(epoch, 1_000_000, 3_000_000, 3_000_000, 1_000_000, testCalculator.MaxPerformanceFactor(epoch))
+	require.NoError(t, err)
+	require.InDelta(t, float64(targetReward), float64(poolReward), float64(targetReward)/1000)
+
+	// without any activity there are no rewards
+	poolReward, err = testCalculator.PoolReward(epoch, 1_000_000, 3_000_000, 3_000_000, 1_000_000, 0)
+	require.NoError(t, err)
+	require.Zero(t, poolReward)
+
+	// the target reward decays during the bootstrapping phase
+	laterPoolReward, err := testCalculator.PoolReward(epoch+100, 1_000_000, 3_000_000, 3_000_000, 1_000_000, testCalculator.MaxPerformanceFactor(epoch))
+	require.NoError(t, err)
+	require.Less(t, laterPoolReward, targetReward)
+}
+
+func TestValidatorAndDelegatorReward(t *testing.T) {
+	validatorID := tpkg.RandAccountID()
+	otherValidatorID := tpkg.RandAccountID()
+
+	committee := &rewards.Committee{
+		Epoch:               1,
+		TotalStake:          10_000_000_000,
+		TotalValidatorStake: 2_000_000_000,
+		Members: map[iotago.AccountID]*rewards.CommitteeMember{
+			validatorID: {
+				PoolStake:         4_000_000_000,
+				ValidatorStake:    1_000_000_000,
+				FixedCost:         100,
+				PerformanceFactor: testCalculator.MaxPerformanceFactor(1),
+			},
+			otherValidatorID: {
+				PoolStake:         6_000_000_000,
+				ValidatorStake:    1_000_000_000,
+				FixedCost:         100,
+				PerformanceFactor: testCalculator.MaxPerformanceFactor(1),
+			},
+		},
+	}
+
+	epochRewards, err := testCalculator.Project(committee, validatorID, 10, 19)
+	require.NoError(t, err)
+	require.Len(t, epochRewards, 10)
+
+	claimingEpoch := iotago.EpochIndex(20)
+
+	validatorReward, err := testCalculator.ValidatorReward(1_000_000_000, claimingEpoch, epochRewards)
+	require.NoError(t, err)
+	require.NotZero(t, validatorReward)
+
+	// the delegators share the rest of the pool rewards proportionally to their stake
+	firstDelegatorReward, err := testCalculator.DelegatorReward(1_000_000_000, claimingEpoch, epochRewards)
+	require.NoError(t, err)
+	secondDelegatorReward, err := testCalculator.DelegatorReward(2_000_000_000, claimingEpoch, epochRewards)
+	require.NoError(t, err)
+	require.InDelta(t, float64(2*firstDelegatorReward), float64(secondDelegatorReward), 2*float64(len(epochRewards)))
+
+	// validator and delegators together get the whole decayed pool rewards
+	var decayedPoolRewards iotago.Mana
+	for _, epochReward := range epochRewards {
+		decayedPoolReward, err := testAPI.ManaDecayProvider().DecayManaByEpochs(epochReward.PoolRewards.PoolRewards, epochReward.Epoch, claimingEpoch)
+		require.NoError(t, err)
+		decayedPoolRewards += decayedPoolReward
+	}
+	require.InDelta(t, float64(decayedPoolRewards), float64(validatorReward+firstDelegatorReward+secondDelegatorReward), 10*float64(len(epochRewards)))
+
+	// rewards can only be claimed after the epoch ended
+	_, err = testCalculator.ValidatorReward(1_000_000_000, 19, epochRewards)
+	require.Error(t, err)
+
+	// rewards decay until they are claimed and are pruned after the retention period
+	retentionPeriod := iotago.EpochIndex(testAPI.ProtocolParameters().RewardsParameters().RetentionPeriod)
+
+	laterValidatorReward, err := testCalculator.ValidatorReward(1_000_000_000, claimingEpoch+10, epochRewards)
+	require.NoError(t, err)
+	require.Less(t, laterValidatorReward, validatorReward)
+
+	prunedValidatorReward, err := testCalculator.ValidatorReward(1_000_000_000, 20+retentionPeriod, epochRewards)
+	require.NoError(t, err)
+	require.Zero(t, prunedValidatorReward)
+
+	// a fixed cost higher than the pool rewards leaves nothing for anyone
+	committee.Members[validatorID].FixedCost = epochRewards[0].PoolRewards.PoolRewards + 1
+	epochRewards, err = testCalculator.Project(committee, validatorID, 10, 10)
+	require.NoError(t, err)
+
+	validatorReward, err = testCalculator.ValidatorReward(1_000_000_000, claimingEpoch, epochRewards)
+	require.NoError(t, err)
+	require.Zero(t, validatorReward)
+
+	delegatorReward, err := testCalculator.DelegatorReward(1_000_000_000, claimingEpoch, epochRewards)
+	require.NoError(t, err)
+	require.Zero(t, delegatorReward)
+
+	_, err = testCalculator.Project(committee, tpkg.RandAccountID(), 10, 19)
+	require.ErrorIs(t, err, rewards.ErrPoolNotInCommittee)
+
+	_, err = testCalculator.Project(committee, validatorID, 19, 10)
+	require.ErrorIs(t, err, rewards.ErrInvalidEpochRange)
+}
+
+func TestCommitteeFromResponse(t *testing.T) {
+	hrp := testAPI.ProtocolParameters().Bech32HRP()
+	accountAddress := tpkg.RandAccountAddress()
+
+	committee, err := testCalculator.CommitteeFromResponse(&api.CommitteeResponse{
+		Committee: []*api.CommitteeMemberResponse{
+			{
+				AddressBech32:  accountAddress.Bech32(hrp),
+				PoolStake:      2_000_000,
+				ValidatorStake: 1_000_000,
+				FixedCost:      10,
+			},
+		},
+		TotalStake:          2_000_000,
+		TotalValidatorStake: 1_000_000,
+		Epoch:               5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, iotago.EpochIndex(5), committee.Epoch)
+	require.Equal(t, &rewards.CommitteeMember{
+		PoolStake:         2_000_000,
+		ValidatorStake:    1_000_000,
+		FixedCost:         10,
+		PerformanceFactor: testCalculator.MaxPerformanceFactor(5),
+	}, committee.Members[accountAddress.AccountID()])
+
+	_, err = testCalculator.CommitteeFromResponse(&api.CommitteeResponse{
+		Committee: []*api.CommitteeMemberResponse{
+			{AddressBech32: tpkg.RandEd25519Address().Bech32(hrp)},
+		},
+	})
+	require.Error(t, err)
+}
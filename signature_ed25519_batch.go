@@ -0,0 +1,128 @@
+package iotago
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+
+	// the batch verification needs access to the low-level edwards25519 operations,
+	// which are not exposed by crypto/ed25519.
+	"filippo.io/edwards25519"
+
+	hiveEd25519 "github.com/iotaledger/hive.go/crypto/ed25519"
+)
+
+// Ed25519BatchVerifier verifies many Ed25519 signatures at once, which is considerably faster
+// than verifying them one by one.
+//
+// The batch applies the same validation criteria (ZIP-215) as the verification of a single Ed25519Signature,
+// so a batch is valid if and only if every signature in it is valid on its own.
+type Ed25519BatchVerifier struct {
+	entries []*ed25519BatchEntry
+}
+
+type ed25519BatchEntry struct {
+	signature *Ed25519Signature
+	message   []byte
+}
+
+// NewEd25519BatchVerifier creates a new empty Ed25519BatchVerifier.
+func NewEd25519BatchVerifier() *Ed25519BatchVerifier {
+	return &Ed25519BatchVerifier{}
+}
+
+// Add adds the given signature of the given message to the batch.
+func (v *Ed25519BatchVerifier) Add(signature *Ed25519Signature, message []byte) {
+	v.entries = append(v.entries, &ed25519BatchEntry{
+		signature: signature,
+		message:   message,
+	})
+}
+
+// Len returns the amount of signatures in the batch.
+func (v *Ed25519BatchVerifier) Len() int {
+	return len(v.entries)
+}
+
+// VerifyBatch verifies all signatures of the batch at once and returns whether all of them are valid.
+// Unlike Verify, it does not determine which signatures are invalid.
+func (v *Ed25519BatchVerifier) VerifyBatch() bool {
+	return verifyEd25519Batch(v.entries)
+}
+
+// Verify verifies all signatures of the batch and returns the indexes of the invalid ones, in the order they were added.
+// If the batch verification fails, the signatures are verified one by one to find the invalid ones.
+func (v *Ed25519BatchVerifier) Verify() (invalidIndexes []int) {
+	if len(v.entries) > 1 && v.VerifyBatch() {
+		return nil
+	}
+
+	for index, entry := range v.entries {
+		if !hiveEd25519.Verify(entry.signature.PublicKey[:], entry.message, entry.signature.Signature[:]) {
+			invalidIndexes = append(invalidIndexes, index)
+		}
+	}
+
+	return invalidIndexes
+}
+
+// verifyEd25519Batch checks the cofactored batch equation [8](sum(z_i*s_i)B - sum(z_i*R_i) - sum(z_i*h_i*A_i)) == 0
+// with random 128-bit coefficients z_i, which holds if and only if all signatures are valid (except with negligible probability).
+// Like hiveEd25519.Verify, it accepts non-canonical encodings of A and R as required by ZIP-215.
+func verifyEd25519Batch(entries []*ed25519BatchEntry) bool {
+	scalars := make([]*edwards25519.Scalar, 0, 2*len(entries)+1)
+	points := make([]*edwards25519.Point, 0, 2*len(entries)+1)
+
+	baseScalar := edwards25519.NewScalar()
+	scalars = append(scalars, baseScalar)
+	points = append(points, edwards25519.NewGeneratorPoint())
+
+	var digest [64]byte
+	var coefficientBytes [32]byte
+	for _, entry := range entries {
+		publicKey, sig := entry.signature.PublicKey[:], entry.signature.Signature[:]
+		if sig[63]&224 != 0 {
+			return false
+		}
+
+		// ZIP215: this works because SetBytes does not check that encodings are canonical
+		A, err := new(edwards25519.Point).SetBytes(publicKey)
+		if err != nil {
+			return false
+		}
+
+		R, err := new(edwards25519.Point).SetBytes(sig[:32])
+		if err != nil {
+			return false
+		}
+
+		// s must be in the range [0, order) to prevent signature malleability
+		s, err := new(edwards25519.Scalar).SetCanonicalBytes(sig[32:])
+		if err != nil {
+			return false
+		}
+
+		h := sha512.New()
+		h.Write(sig[:32])
+		h.Write(publicKey)
+		h.Write(entry.message)
+		h.Sum(digest[:0])
+
+		// the error can be ignored, SetUniformBytes only fails if the input is not 64 bytes long
+		hReduced, _ := new(edwards25519.Scalar).SetUniformBytes(digest[:])
+
+		if _, err := rand.Read(coefficientBytes[:16]); err != nil {
+			return false
+		}
+		// the error can be ignored, the upper half of the coefficient stays zero,
+		// so it is always a canonical encoding of a scalar smaller than the group order
+		z, _ := new(edwards25519.Scalar).SetCanonicalBytes(coefficientBytes[:])
+
+		baseScalar.MultiplyAdd(z, s, baseScalar)
+		scalars = append(scalars, new(edwards25519.Scalar).Negate(z), new(edwards25519.Scalar).Negate(new(edwards25519.Scalar).Multiply(z, hReduced)))
+		points = append(points, R, A)
+	}
+
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+
+	return check.MultByCofactor(check).Equal(edwards25519.NewIdentityPoint()) == 1
+}
@@ -1,6 +1,8 @@
+//nolint:forcetypeassert
 package iotago_test
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	hiveEd25519 "github.com/iotaledger/hive.go/crypto/ed25519"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/tpkg"
 	"github.com/iotaledger/iota.go/v4/tpkg/frameworks"
@@ -63,3 +66,107 @@ func TestEd25519Signature_Valid(t *testing.T) {
 		})
 	}
 }
+
+func TestEd25519BatchVerifier(t *testing.T) {
+	type test struct {
+		Message   tpkg.HexBytes `json:"message"`
+		PublicKey tpkg.HexBytes `json:"pubKey"`
+		Signature tpkg.HexBytes `json:"signature"`
+	}
+	var tests []test
+	// the edge cases of the verification of a single signature must be handled the same way in a batch
+	b, err := os.ReadFile(filepath.Join("testdata", "TestEd25519Signature_Valid.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &tests))
+
+	randSignature := func(message []byte) *iotago.Ed25519Signature {
+		prvKey := tpkg.RandEd25519PrivateKey()
+		sig := &iotago.Ed25519Signature{}
+		copy(sig.PublicKey[:], prvKey.Public().(ed25519.PublicKey))
+		copy(sig.Signature[:], ed25519.Sign(prvKey, message))
+
+		return sig
+	}
+
+	t.Run("ok - empty", func(t *testing.T) {
+		require.Empty(t, iotago.NewEd25519BatchVerifier().Verify())
+	})
+
+	t.Run("ok - valid signatures", func(t *testing.T) {
+		verifier := iotago.NewEd25519BatchVerifier()
+		for range 64 {
+			message := tpkg.RandBytes(32)
+			verifier.Add(randSignature(message), message)
+		}
+		require.Equal(t, 64, verifier.Len())
+		require.True(t, verifier.VerifyBatch())
+		require.Empty(t, verifier.Verify())
+	})
+
+	t.Run("fail - invalid signatures are found", func(t *testing.T) {
+		verifier := iotago.NewEd25519BatchVerifier()
+		for i := range 64 {
+			message := tpkg.RandBytes(32)
+			sig := randSignature(message)
+			if i == 7 || i == 42 {
+				// the signature is valid, but for another message
+				message = tpkg.RandBytes(32)
+			}
+			verifier.Add(sig, message)
+		}
+		require.False(t, verifier.VerifyBatch())
+		require.Equal(t, []int{7, 42}, verifier.Verify())
+	})
+
+	edgeCaseSignature := func(tt test) *iotago.Ed25519Signature {
+		sig := &iotago.Ed25519Signature{}
+		copy(sig.PublicKey[:], tt.PublicKey)
+		copy(sig.Signature[:], tt.Signature)
+
+		return sig
+	}
+
+	// the vectors also cover addresses not matching the public key, which is not checked by the verifier
+	var validEdgeCases, invalidEdgeCases []int
+	for i, tt := range tests {
+		sig := edgeCaseSignature(tt)
+		if hiveEd25519.Verify(sig.PublicKey[:], tt.Message, sig.Signature[:]) {
+			validEdgeCases = append(validEdgeCases, i)
+		} else {
+			invalidEdgeCases = append(invalidEdgeCases, i)
+		}
+	}
+	require.Greater(t, len(validEdgeCases), 1)
+	require.NotEmpty(t, invalidEdgeCases)
+
+	t.Run("ok - valid edge cases pass the batch verification", func(t *testing.T) {
+		// the small order and non-canonical encodings of A and R accepted by ZIP-215 must not trigger the fallback
+		verifier := iotago.NewEd25519BatchVerifier()
+		for _, i := range validEdgeCases {
+			verifier.Add(edgeCaseSignature(tests[i]), tests[i].Message)
+		}
+		require.True(t, verifier.VerifyBatch())
+		require.Empty(t, verifier.Verify())
+	})
+
+	t.Run("fail - invalid edge cases fail the batch verification", func(t *testing.T) {
+		for _, invalidIndex := range invalidEdgeCases {
+			verifier := iotago.NewEd25519BatchVerifier()
+			for _, i := range validEdgeCases {
+				verifier.Add(edgeCaseSignature(tests[i]), tests[i].Message)
+			}
+			verifier.Add(edgeCaseSignature(tests[invalidIndex]), tests[invalidIndex].Message)
+
+			require.False(t, verifier.VerifyBatch())
+			require.Equal(t, []int{len(validEdgeCases)}, verifier.Verify())
+		}
+	})
+
+	t.Run("edge cases", func(t *testing.T) {
+		verifier := iotago.NewEd25519BatchVerifier()
+		for _, tt := range tests {
+			verifier.Add(edgeCaseSignature(tt), tt.Message)
+		}
+		require.Equal(t, invalidEdgeCases, verifier.Verify())
+	})
+}
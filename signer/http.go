@@ -0,0 +1,345 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+const (
+	// RoutePublicKeys is the route to get the public keys of all keys held by the signer daemon.
+	// GET returns the public keys.
+	RoutePublicKeys = "/api/signer/v1/public-keys"
+	// RouteSign is the route to sign a message with a key held by the signer daemon.
+	// POST signs the message.
+	RouteSign = "/api/signer/v1/sign"
+
+	// maxRequestBodySize is the maximum size of a request body accepted by the Server.
+	maxRequestBodySize = 1 << 20
+)
+
+var (
+	// ErrRemoteSigner gets returned if the signer daemon responded with an error.
+	ErrRemoteSigner = ierrors.New("remote signer error")
+	// ErrNonLoopbackAddress gets returned if the Server should be bound to a non-loopback address.
+	ErrNonLoopbackAddress = ierrors.New("signer server must be bound to a loopback address")
+)
+
+// PublicKeysResponse defines the response of a GET public keys signer daemon call.
+type PublicKeysResponse struct {
+	// The hex encoded public keys of all keys held by the signer daemon.
+	PublicKeys []string `json:"publicKeys"`
+}
+
+// SignRequest defines the request of a POST sign signer daemon call.
+type SignRequest struct {
+	// The hex encoded public key of the key to sign with.
+	PublicKey string `json:"publicKey"`
+	// The hex encoded message to sign.
+	Message string `json:"message"`
+}
+
+// SignResponse defines the response of a POST sign signer daemon call.
+type SignResponse struct {
+	// The hex encoded signature.
+	Signature string `json:"signature"`
+}
+
+// ErrorResponse defines the response of a failed signer daemon call.
+type ErrorResponse struct {
+	// The error message.
+	Error string `json:"error"`
+}
+
+// the default options applied to the HTTPBackend.
+var defaultHTTPBackendOptions = []HTTPBackendOption{
+	WithHTTPClient(&http.Client{Timeout: 30 * time.Second}),
+	WithAuthToken(""),
+}
+
+// HTTPBackendOptions define options for the HTTPBackend.
+type HTTPBackendOptions struct {
+	// The HTTP client to use.
+	httpClient *http.Client
+	// The bearer token sent to the signer daemon.
+	authToken string
+}
+
+// applies the given HTTPBackendOption.
+func (o *HTTPBackendOptions) apply(opts ...HTTPBackendOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithHTTPClient sets the used HTTP Client.
+func WithHTTPClient(httpClient *http.Client) HTTPBackendOption {
+	return func(opts *HTTPBackendOptions) {
+		opts.httpClient = httpClient
+	}
+}
+
+// WithAuthToken sets the bearer token that is used to authenticate against the signer daemon.
+func WithAuthToken(authToken string) HTTPBackendOption {
+	return func(opts *HTTPBackendOptions) {
+		opts.authToken = authToken
+	}
+}
+
+// HTTPBackendOption is a function setting a HTTPBackend option.
+type HTTPBackendOption func(opts *HTTPBackendOptions)
+
+// HTTPBackend is a Backend that requests the signatures from a signer daemon over HTTP.
+type HTTPBackend struct {
+	// The base URL of the signer daemon.
+	BaseURL string
+
+	// holds the HTTPBackend options.
+	opts *HTTPBackendOptions
+}
+
+// NewHTTPBackend creates a new HTTPBackend for the signer daemon at the given base URL.
+func NewHTTPBackend(baseURL string, opts ...HTTPBackendOption) *HTTPBackend {
+	options := &HTTPBackendOptions{}
+	options.apply(defaultHTTPBackendOptions...)
+	options.apply(opts...)
+
+	return &HTTPBackend{
+		BaseURL: baseURL,
+		opts:    options,
+	}
+}
+
+// PublicKeys returns the public keys of all keys held by the signer daemon.
+func (b *HTTPBackend) PublicKeys(ctx context.Context) ([]ed25519.PublicKey, error) {
+	res := &PublicKeysResponse{}
+	if err := b.do(ctx, http.MethodGet, RoutePublicKeys, nil, res); err != nil {
+		return nil, err
+	}
+
+	publicKeys := make([]ed25519.PublicKey, 0, len(res.PublicKeys))
+	for _, hexPublicKey := range res.PublicKeys {
+		publicKey, err := hexutil.DecodeHex(hexPublicKey)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to decode public key")
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	return publicKeys, nil
+}
+
+// Sign requests the signature of the message for the given public key from the signer daemon.
+func (b *HTTPBackend) Sign(ctx context.Context, publicKey ed25519.PublicKey, msg []byte) ([]byte, error) {
+	req := &SignRequest{
+		PublicKey: hexutil.EncodeHex(publicKey),
+		Message:   hexutil.EncodeHex(msg),
+	}
+
+	res := &SignResponse{}
+	if err := b.do(ctx, http.MethodPost, RouteSign, req, res); err != nil {
+		return nil, err
+	}
+
+	signature, err := hexutil.DecodeHex(res.Signature)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to decode signature")
+	}
+
+	return signature, nil
+}
+
+func (b *HTTPBackend) do(ctx context.Context, method string, route string, reqObj interface{}, resObj interface{}) error {
+	var body io.Reader
+	if reqObj != nil {
+		data, err := json.Marshal(reqObj)
+		if err != nil {
+			return ierrors.Wrap(err, "unable to serialize request object to JSON")
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.BaseURL+route, body)
+	if err != nil {
+		return ierrors.Wrap(err, "unable to build http request")
+	}
+
+	if reqObj != nil {
+		req.Header.Set("Content-Type", api.MIMEApplicationJSON)
+	}
+
+	if b.opts.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.opts.authToken)
+	}
+
+	res, err := b.opts.httpClient.Do(req)
+	if err != nil {
+		return ierrors.Wrap(err, "request to signer daemon failed")
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return ierrors.Wrap(err, "unable to read response body")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		errRes := &ErrorResponse{}
+		_ = json.Unmarshal(resBody, errRes)
+
+		return ierrors.WithMessagef(ErrRemoteSigner, "status code %d, error message: %s", res.StatusCode, errRes.Error)
+	}
+
+	if err := json.Unmarshal(resBody, resObj); err != nil {
+		return ierrors.Wrap(err, "unable to deserialize response body")
+	}
+
+	return nil
+}
+
+// Server is the reference implementation of a local signer daemon that serves the keys of a Backend over HTTP.
+// It is meant to be bound to a loopback address only.
+type Server struct {
+	backend   Backend
+	authToken string
+	mux       *http.ServeMux
+}
+
+// NewServer creates a new Server for the given backend.
+// If an auth token is given, all requests need to provide it as bearer token.
+func NewServer(backend Backend, authToken string) *Server {
+	s := &Server{
+		backend:   backend,
+		authToken: authToken,
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc(RoutePublicKeys, s.handlePublicKeys)
+	s.mux.HandleFunc(RouteSign, s.handleSign)
+
+	return s
+}
+
+// Listen creates a listener on the given loopback address, e.g. "127.0.0.1:0".
+func Listen(bindAddress string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(bindAddress)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "invalid bind address %s", bindAddress)
+	}
+
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, ierrors.WithMessagef(ErrNonLoopbackAddress, "bind address %s", bindAddress)
+	}
+
+	return net.Listen("tcp", bindAddress)
+}
+
+// Serve serves the signer API on the given listener until the context is done.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = httpServer.Close()
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !ierrors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.authToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.authToken)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, &ErrorResponse{Error: "unauthorized"})
+
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handlePublicKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+
+		return
+	}
+
+	publicKeys, err := s.backend.PublicKeys(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, &ErrorResponse{Error: err.Error()})
+
+		return
+	}
+
+	res := &PublicKeysResponse{PublicKeys: make([]string, 0, len(publicKeys))}
+	for _, publicKey := range publicKeys {
+		res.PublicKeys = append(res.PublicKeys, hexutil.EncodeHex(publicKey))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+
+		return
+	}
+
+	req := &SignRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, &ErrorResponse{Error: "invalid request body"})
+
+		return
+	}
+
+	publicKey, err := hexutil.DecodeHex(req.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		writeJSON(w, http.StatusBadRequest, &ErrorResponse{Error: "invalid public key"})
+
+		return
+	}
+
+	msg, err := hexutil.DecodeHex(req.Message)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &ErrorResponse{Error: "invalid message"})
+
+		return
+	}
+
+	signature, err := s.backend.Sign(r.Context(), publicKey, msg)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if ierrors.Is(err, ErrUnknownPublicKey) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &SignResponse{Signature: hexutil.EncodeHex(signature)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", api.MIMEApplicationJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
@@ -0,0 +1,71 @@
+package signer
+
+import (
+	"context"
+	"crypto/ed25519"
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+)
+
+// LocalBackend is a Backend holding the keys in memory.
+// It is the reference backend for the signer Server.
+type LocalBackend struct {
+	mutex sync.RWMutex
+	// privateKeys maps the public key to its private key.
+	privateKeys map[string]ed25519.PrivateKey
+	// publicKeys holds the public keys in the order they were added.
+	publicKeys []ed25519.PublicKey
+}
+
+// NewLocalBackend creates a new LocalBackend holding the given private keys.
+func NewLocalBackend(privateKeys ...ed25519.PrivateKey) *LocalBackend {
+	b := &LocalBackend{
+		privateKeys: make(map[string]ed25519.PrivateKey, len(privateKeys)),
+		publicKeys:  make([]ed25519.PublicKey, 0, len(privateKeys)),
+	}
+	b.AddPrivateKeys(privateKeys...)
+
+	return b
+}
+
+// AddPrivateKeys adds the given private keys to the backend.
+func (b *LocalBackend) AddPrivateKeys(privateKeys ...ed25519.PrivateKey) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, privateKey := range privateKeys {
+		//nolint:forcetypeassert // we can safely assume that this is an ed25519.PublicKey
+		publicKey := privateKey.Public().(ed25519.PublicKey)
+		if _, exists := b.privateKeys[string(publicKey)]; exists {
+			continue
+		}
+
+		b.privateKeys[string(publicKey)] = privateKey
+		b.publicKeys = append(b.publicKeys, publicKey)
+	}
+}
+
+// PublicKeys returns the public keys of all keys held by the backend.
+func (b *LocalBackend) PublicKeys(_ context.Context) ([]ed25519.PublicKey, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	publicKeys := make([]ed25519.PublicKey, len(b.publicKeys))
+	copy(publicKeys, b.publicKeys)
+
+	return publicKeys, nil
+}
+
+// Sign signs the message with the private key of the given public key.
+func (b *LocalBackend) Sign(_ context.Context, publicKey ed25519.PublicKey, msg []byte) ([]byte, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	privateKey, exists := b.privateKeys[string(publicKey)]
+	if !exists {
+		return nil, ierrors.WithMessagef(ErrUnknownPublicKey, "public key %x", []byte(publicKey))
+	}
+
+	return ed25519.Sign(privateKey, msg), nil
+}
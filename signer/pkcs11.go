@@ -0,0 +1,115 @@
+package signer
+
+import (
+	"context"
+	"crypto/ed25519"
+	"slices"
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+)
+
+// PKCS11Module is the minimal set of operations a PKCS#11-style plugin, e.g. a wrapper around a hardware security module,
+// needs to provide to be used as a Backend. The private keys never leave the module.
+// The methods follow the PKCS#11 session model (C_OpenSession, C_Login, C_FindObjects, C_Sign with CKM_EDDSA, C_CloseSession).
+type PKCS11Module interface {
+	// OpenSession opens a session on the given slot of the token.
+	OpenSession(slot uint) (PKCS11Session, error)
+}
+
+// PKCS11Session is a session of a PKCS11Module.
+type PKCS11Session interface {
+	// Login authenticates the session with the user PIN.
+	Login(pin string) error
+	// FindEd25519Keys returns the handles and the public keys of all Ed25519 key pairs on the token.
+	FindEd25519Keys() (map[uint]ed25519.PublicKey, error)
+	// SignEdDSA signs the message with the private key of the given handle using the CKM_EDDSA mechanism.
+	SignEdDSA(keyHandle uint, msg []byte) ([]byte, error)
+	// Close logs out and closes the session.
+	Close() error
+}
+
+// PKCS11Backend is a Backend that signs with the keys of a PKCS11Module.
+type PKCS11Backend struct {
+	// PKCS#11 sessions must not be used concurrently.
+	mutex   sync.Mutex
+	session PKCS11Session
+	// keyHandles maps the public key to the handle of its key pair.
+	keyHandles map[string]uint
+	publicKeys []ed25519.PublicKey
+}
+
+// NewPKCS11Backend opens and authenticates a session on the given slot of the module and looks up its Ed25519 keys.
+// The session stays open until Close is called.
+func NewPKCS11Backend(module PKCS11Module, slot uint, pin string) (*PKCS11Backend, error) {
+	session, err := module.OpenSession(slot)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to open session on slot %d", slot)
+	}
+
+	if err := session.Login(pin); err != nil {
+		_ = session.Close()
+
+		return nil, ierrors.Wrap(err, "failed to login")
+	}
+
+	keys, err := session.FindEd25519Keys()
+	if err != nil {
+		_ = session.Close()
+
+		return nil, ierrors.Wrap(err, "failed to find the Ed25519 keys")
+	}
+
+	b := &PKCS11Backend{
+		session:    session,
+		keyHandles: make(map[string]uint, len(keys)),
+		publicKeys: make([]ed25519.PublicKey, 0, len(keys)),
+	}
+
+	keyHandles := make([]uint, 0, len(keys))
+	for keyHandle := range keys {
+		keyHandles = append(keyHandles, keyHandle)
+	}
+	slices.Sort(keyHandles)
+
+	for _, keyHandle := range keyHandles {
+		b.keyHandles[string(keys[keyHandle])] = keyHandle
+		b.publicKeys = append(b.publicKeys, keys[keyHandle])
+	}
+
+	return b, nil
+}
+
+// PublicKeys returns the public keys of all Ed25519 keys on the token.
+func (b *PKCS11Backend) PublicKeys(_ context.Context) ([]ed25519.PublicKey, error) {
+	publicKeys := make([]ed25519.PublicKey, len(b.publicKeys))
+	copy(publicKeys, b.publicKeys)
+
+	return publicKeys, nil
+}
+
+// Sign signs the message on the token with the private key of the given public key.
+func (b *PKCS11Backend) Sign(_ context.Context, publicKey ed25519.PublicKey, msg []byte) ([]byte, error) {
+	keyHandle, exists := b.keyHandles[string(publicKey)]
+	if !exists {
+		return nil, ierrors.WithMessagef(ErrUnknownPublicKey, "public key %x", []byte(publicKey))
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	signature, err := b.session.SignEdDSA(keyHandle, msg)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to sign with key handle %d", keyHandle)
+	}
+
+	return signature, nil
+}
+
+// Close closes the session of the module.
+func (b *PKCS11Backend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.session.Close()
+}
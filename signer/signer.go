@@ -0,0 +1,139 @@
+// Package signer provides iotago.AddressSigner implementations whose keys are held by a signer backend,
+// e.g. a remote signer daemon or a hardware security module, instead of being kept in memory.
+package signer
+
+import (
+	"context"
+	"crypto/ed25519"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrUnknownPublicKey gets returned if the backend doesn't hold the key for the requested public key.
+	ErrUnknownPublicKey = ierrors.New("unknown public key")
+	// ErrInvalidSignature gets returned if the backend returned an invalid signature.
+	ErrInvalidSignature = ierrors.New("backend returned an invalid signature")
+	// ErrUnsupportedAddress gets returned if an address is not backed by an Ed25519 key pair.
+	ErrUnsupportedAddress = ierrors.New("address type is not supported by the signer")
+)
+
+// Backend holds Ed25519 keys and signs messages without exposing the private keys.
+type Backend interface {
+	// PublicKeys returns the public keys of all keys held by the backend.
+	PublicKeys(ctx context.Context) ([]ed25519.PublicKey, error)
+	// Sign signs the message with the private key of the given public key.
+	Sign(ctx context.Context, publicKey ed25519.PublicKey, msg []byte) ([]byte, error)
+}
+
+// AddressSigner implements iotago.AddressSigner by delegating the signing to a Backend.
+// It can be used in the builder.TransactionBuilder and the builder.BasicBlockBuilder like any other iotago.AddressSigner.
+type AddressSigner struct {
+	ctx     context.Context
+	backend Backend
+	// addresses holds the Ed25519Addresses of all keys held by the backend.
+	addresses []*iotago.Ed25519Address
+	// publicKeys maps the key of the Ed25519Address and ImplicitAccountCreationAddress to the public key it was derived from.
+	publicKeys map[string]ed25519.PublicKey
+}
+
+// NewAddressSigner creates a new AddressSigner for all keys held by the given backend.
+// The given context is used for all requests to the backend.
+func NewAddressSigner(ctx context.Context, backend Backend) (*AddressSigner, error) {
+	publicKeys, err := backend.PublicKeys(ctx)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to get the public keys of the signer backend")
+	}
+
+	s := &AddressSigner{
+		ctx:        ctx,
+		backend:    backend,
+		publicKeys: make(map[string]ed25519.PublicKey, 2*len(publicKeys)),
+	}
+
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, ierrors.Errorf("signer backend returned a public key of invalid length %d", len(publicKey))
+		}
+
+		ed25519Address := iotago.Ed25519AddressFromPubKey(publicKey)
+		s.addresses = append(s.addresses, ed25519Address)
+
+		// add both address types for simplicity
+		s.publicKeys[ed25519Address.Key()] = publicKey
+		s.publicKeys[iotago.ImplicitAccountCreationAddressFromPubKey(publicKey).Key()] = publicKey
+	}
+
+	return s, nil
+}
+
+// Addresses returns the Ed25519Addresses of all keys held by the backend.
+func (s *AddressSigner) Addresses() []*iotago.Ed25519Address {
+	return s.addresses
+}
+
+// publicKeyForAddress returns the public key for the given address.
+func (s *AddressSigner) publicKeyForAddress(addr iotago.Address) (ed25519.PublicKey, error) {
+	if restrictedAddr, isRestricted := addr.(*iotago.RestrictedAddress); isRestricted {
+		addr = restrictedAddr.Address
+	}
+
+	switch addr.(type) {
+	case *iotago.Ed25519Address, *iotago.ImplicitAccountCreationAddress:
+		publicKey, exists := s.publicKeys[addr.Key()]
+		if !exists {
+			return nil, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "address %s", addr)
+		}
+
+		return publicKey, nil
+
+	default:
+		return nil, ierrors.WithMessagef(ErrUnsupportedAddress, "address type %T", addr)
+	}
+}
+
+// SignerUIDForAddress returns the signer unique identifier for a given address.
+func (s *AddressSigner) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	publicKey, err := s.publicKeyForAddress(addr)
+	if err != nil {
+		return iotago.EmptyIdentifier, err
+	}
+
+	// the UID is the blake2b 256 hash of the public key
+	return iotago.IdentifierFromData(publicKey), nil
+}
+
+// Sign produces the signature for the given message by requesting it from the backend.
+// The returned signature is verified before it is used.
+func (s *AddressSigner) Sign(addr iotago.Address, msg []byte) (iotago.Signature, error) {
+	publicKey, err := s.publicKeyForAddress(addr)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "can't sign message for address")
+	}
+
+	signature, err := s.backend.Sign(s.ctx, publicKey, msg)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "signer backend failed to sign message")
+	}
+
+	if len(signature) != ed25519.SignatureSize || !ed25519.Verify(publicKey, msg, signature) {
+		return nil, ierrors.WithMessagef(ErrInvalidSignature, "signature for public key %x", []byte(publicKey))
+	}
+
+	ed25519Sig := &iotago.Ed25519Signature{}
+	copy(ed25519Sig.Signature[:], signature)
+	copy(ed25519Sig.PublicKey[:], publicKey)
+
+	return ed25519Sig, nil
+}
+
+// EmptySignatureForAddress returns an empty signature for the given address.
+// This can be used to calculate the WorkScore of transactions without actually signing the transaction.
+func (s *AddressSigner) EmptySignatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	if _, err := s.publicKeyForAddress(addr); err != nil {
+		return nil, err
+	}
+
+	return &iotago.Ed25519Signature{}, nil
+}
@@ -0,0 +1,173 @@
+//nolint:forcetypeassert
+package signer_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/signer"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+// startLoopbackServer starts a signer server for the given keys on a loopback address and returns its base URL.
+func startLoopbackServer(t *testing.T, authToken string, privateKeys ...ed25519.PrivateKey) string {
+	t.Helper()
+
+	listener, err := signer.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- signer.NewServer(signer.NewLocalBackend(privateKeys...), authToken).Serve(ctx, listener)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-served)
+	})
+
+	return "http://" + listener.Addr().String()
+}
+
+func TestRemoteSigner(t *testing.T) {
+	prvKey := tpkg.RandEd25519PrivateKey()
+	addr := iotago.Ed25519AddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+
+	baseURL := startLoopbackServer(t, "secret", prvKey)
+
+	addressSigner, err := signer.NewAddressSigner(context.Background(), signer.NewHTTPBackend(baseURL, signer.WithAuthToken("secret")))
+	require.NoError(t, err)
+	require.Equal(t, []*iotago.Ed25519Address{addr}, addressSigner.Addresses())
+
+	t.Run("ok - transaction builder", func(t *testing.T) {
+		input := &builder.TxInput{
+			UnlockTarget: addr,
+			InputID:      tpkg.RandOutputID(0),
+			Input:        builder.NewBasicOutputBuilder(addr, 100).MustBuild(),
+		}
+
+		tx, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, addressSigner).
+			AddInput(input).
+			AddOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 100).MustBuild()).
+			Build()
+		require.NoError(t, err)
+
+		_, err = vm.ValidateUnlocks(tx, vm.ResolvedInputs{InputSet: vm.InputSet{input.InputID: input.Input}})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok - block builder", func(t *testing.T) {
+		block, err := builder.NewBasicBlockBuilder(tpkg.ZeroCostTestAPI).
+			StrongParents(tpkg.SortedRandBlockIDs(2)).
+			SignWithSigner(tpkg.RandAccountID(), addressSigner, addr).
+			Build()
+		require.NoError(t, err)
+
+		valid, err := block.VerifySignature()
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("err - unknown address", func(t *testing.T) {
+		_, err := addressSigner.Sign(tpkg.RandEd25519Address(), []byte("message"))
+		require.ErrorIs(t, err, iotago.ErrAddressKeysNotMapped)
+
+		_, err = addressSigner.SignerUIDForAddress(tpkg.RandAccountAddress())
+		require.ErrorIs(t, err, signer.ErrUnsupportedAddress)
+	})
+
+	t.Run("err - unauthorized", func(t *testing.T) {
+		_, err := signer.NewAddressSigner(context.Background(), signer.NewHTTPBackend(baseURL, signer.WithAuthToken("wrong")))
+		require.ErrorIs(t, err, signer.ErrRemoteSigner)
+	})
+
+	t.Run("err - unknown public key", func(t *testing.T) {
+		otherPrvKey := tpkg.RandEd25519PrivateKey()
+		_, err := signer.NewHTTPBackend(baseURL, signer.WithAuthToken("secret")).Sign(context.Background(), otherPrvKey.Public().(ed25519.PublicKey), []byte("message"))
+		require.ErrorIs(t, err, signer.ErrRemoteSigner)
+	})
+
+	t.Run("err - non loopback address", func(t *testing.T) {
+		_, err := signer.Listen("0.0.0.0:0")
+		require.ErrorIs(t, err, signer.ErrNonLoopbackAddress)
+	})
+}
+
+// mockPKCS11Module is a PKCS11Module holding the keys in memory.
+type mockPKCS11Module struct {
+	pin         string
+	privateKeys []ed25519.PrivateKey
+}
+
+func (m *mockPKCS11Module) OpenSession(_ uint) (signer.PKCS11Session, error) {
+	return &mockPKCS11Session{module: m}, nil
+}
+
+type mockPKCS11Session struct {
+	module   *mockPKCS11Module
+	loggedIn bool
+}
+
+func (s *mockPKCS11Session) Login(pin string) error {
+	if pin != s.module.pin {
+		return ierrors.New("CKR_PIN_INCORRECT")
+	}
+	s.loggedIn = true
+
+	return nil
+}
+
+func (s *mockPKCS11Session) FindEd25519Keys() (map[uint]ed25519.PublicKey, error) {
+	keys := make(map[uint]ed25519.PublicKey)
+	for i, privateKey := range s.module.privateKeys {
+		keys[uint(i)] = privateKey.Public().(ed25519.PublicKey)
+	}
+
+	return keys, nil
+}
+
+func (s *mockPKCS11Session) SignEdDSA(keyHandle uint, msg []byte) ([]byte, error) {
+	if !s.loggedIn {
+		return nil, ierrors.New("CKR_USER_NOT_LOGGED_IN")
+	}
+
+	return ed25519.Sign(s.module.privateKeys[keyHandle], msg), nil
+}
+
+func (s *mockPKCS11Session) Close() error {
+	s.loggedIn = false
+
+	return nil
+}
+
+func TestPKCS11Signer(t *testing.T) {
+	module := &mockPKCS11Module{pin: "1234", privateKeys: []ed25519.PrivateKey{tpkg.RandEd25519PrivateKey(), tpkg.RandEd25519PrivateKey()}}
+
+	_, err := signer.NewPKCS11Backend(module, 0, "wrong")
+	require.Error(t, err)
+
+	backend, err := signer.NewPKCS11Backend(module, 0, "1234")
+	require.NoError(t, err)
+	defer backend.Close()
+
+	addressSigner, err := signer.NewAddressSigner(context.Background(), backend)
+	require.NoError(t, err)
+	require.Len(t, addressSigner.Addresses(), 2)
+
+	msg := []byte("message")
+	for _, prvKey := range module.privateKeys {
+		addr := iotago.ImplicitAccountCreationAddressFromPubKey(prvKey.Public().(ed25519.PublicKey))
+
+		signature, err := addressSigner.Sign(addr, msg)
+		require.NoError(t, err)
+		require.NoError(t, addr.Unlock(msg, signature))
+	}
+}
@@ -0,0 +1,424 @@
+package vm
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+var (
+	// ErrBatchDependencyFailed gets returned for a transaction of a batch which consumes an output of another transaction of the batch that failed.
+	ErrBatchDependencyFailed = ierrors.New("transaction depends on a failed transaction of the batch")
+	// ErrBatchDependencyCycle gets returned for transactions of a batch which consume each other's outputs.
+	ErrBatchDependencyCycle = ierrors.New("transaction is part of a dependency cycle in the batch")
+)
+
+// BatchTransaction is a transaction to be validated by the BatchValidator.
+type BatchTransaction struct {
+	// The transaction to validate.
+	SignedTransaction *iotago.SignedTransaction
+	// The resolved inputs of the transaction.
+	// The inputs created by other transactions of the batch can be omitted, they are resolved from the outputs of these transactions.
+	ResolvedInputs ResolvedInputs
+}
+
+// BatchResult is the outcome of the validation of a BatchTransaction.
+type BatchResult struct {
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The reason why the transaction failed, TxFailureNone if it succeeded.
+	FailureReason api.TransactionFailureReason
+	// The error of the validation, nil if the transaction succeeded.
+	Error error
+	// The outputs created by the transaction, nil if it failed.
+	Outputs []iotago.Output
+}
+
+// Succeeded returns whether the transaction was accepted by the VM.
+func (r *BatchResult) Succeeded() bool {
+	return r.Error == nil
+}
+
+// failed marks the result as failed because of the given error.
+func (r *BatchResult) failed(err error) {
+	r.Error = err
+	r.FailureReason = api.DetermineTransactionFailureReason(err)
+}
+
+// BatchValidator validates many transactions concurrently on a pool of workers.
+//
+// The unlocks and the execution of independent transactions are validated in parallel,
+// while a transaction consuming outputs of other transactions of the batch is only validated
+// after these transactions succeeded.
+// Transactions of the batch which consume the same output are validated one after the other in the order of the batch,
+// unless one of them consumes an output of the other. Only the first of them which is valid succeeds,
+// the others fail with ErrTxConflictRejected.
+type BatchValidator struct {
+	vm         VirtualMachine
+	numWorkers int
+}
+
+// NewBatchValidator creates a new BatchValidator using the given VirtualMachine.
+// The optional numWorkers specifies how many go routines should be used, it defaults to the number of CPUs.
+func NewBatchValidator(vm VirtualMachine, numWorkers ...int) *BatchValidator {
+	v := &BatchValidator{
+		vm:         vm,
+		numWorkers: runtime.NumCPU(),
+	}
+	if len(numWorkers) > 0 && numWorkers[0] > 0 {
+		v.numWorkers = numWorkers[0]
+	}
+
+	return v
+}
+
+// batchTask is the state of a single transaction during the validation of a batch.
+type batchTask struct {
+	index int
+	// The indexes of the transactions of the batch which create inputs of this transaction.
+	dependencies []int
+	// The indexes of the transactions of the batch which are validated before this transaction and consume the same outputs.
+	conflicts []int
+	// The indexes of the transactions of the batch which consume outputs of this transaction or are validated
+	// after this transaction because they consume the same outputs.
+	dependents []int
+	// The number of dependencies and conflicts which are not validated yet.
+	pending int
+	// Whether the transaction can not be ordered, because it is part of or depends on a dependency cycle.
+	blocked bool
+}
+
+// Validate validates the given transactions and returns the results in the order of the transactions.
+// The transactions that were not validated before the context was canceled fail with the error of the context,
+// even if they depend on a transaction of the batch that failed.
+func (v *BatchValidator) Validate(ctx context.Context, transactions []*BatchTransaction) []*BatchResult {
+	results := make([]*BatchResult, len(transactions))
+	tasks := make([]*batchTask, len(transactions))
+	indexByTransactionID := make(map[iotago.TransactionID]int, len(transactions))
+
+	for index, transaction := range transactions {
+		results[index] = &BatchResult{}
+		tasks[index] = &batchTask{index: index}
+
+		transactionID, err := transaction.SignedTransaction.Transaction.ID()
+		if err != nil {
+			results[index].failed(ierrors.Wrap(err, "failed to compute the transaction ID"))
+
+			continue
+		}
+		results[index].TransactionID = transactionID
+
+		// in case a transaction is contained twice, its outputs are taken from the first one
+		if _, contained := indexByTransactionID[transactionID]; !contained {
+			indexByTransactionID[transactionID] = index
+		}
+	}
+
+	for index, transaction := range transactions {
+		if results[index].Error != nil {
+			continue
+		}
+
+		dependencies := make(map[int]struct{})
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			dependencyIndex, isBatchOutput := indexByTransactionID[input.OutputID().TransactionID()]
+			if !isBatchOutput || dependencyIndex == index {
+				continue
+			}
+
+			if _, added := dependencies[dependencyIndex]; added {
+				continue
+			}
+			dependencies[dependencyIndex] = struct{}{}
+
+			tasks[index].dependencies = append(tasks[index].dependencies, dependencyIndex)
+			tasks[dependencyIndex].dependents = append(tasks[dependencyIndex].dependents, index)
+		}
+		tasks[index].pending = len(tasks[index].dependencies)
+	}
+
+	markConflicts(transactions, tasks)
+	markCycles(tasks, results)
+	v.run(ctx, transactions, tasks, results)
+
+	return results
+}
+
+// markConflicts orders the transactions which consume the same output, so that a transaction is only validated
+// after the transactions it conflicts with. The transaction submitted earlier is validated first,
+// unless it consumes an output of the other transaction, which means that it is validated afterwards anyway.
+// A conflict is decided after the validation, so an invalid transaction submitted earlier doesn't reject a valid one.
+func markConflicts(transactions []*BatchTransaction, tasks []*batchTask) {
+	spendersByOutputID := make(map[iotago.OutputID][]int)
+	for index, transaction := range transactions {
+		conflicts := make(map[int]struct{})
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			outputID := input.OutputID()
+			for _, spenderIndex := range spendersByOutputID[outputID] {
+				if spenderIndex == index {
+					continue
+				}
+
+				if _, added := conflicts[spenderIndex]; added {
+					continue
+				}
+				conflicts[spenderIndex] = struct{}{}
+
+				if slices.Contains(tasks[spenderIndex].dependencies, index) {
+					tasks[spenderIndex].conflicts = append(tasks[spenderIndex].conflicts, index)
+
+					continue
+				}
+
+				tasks[index].conflicts = append(tasks[index].conflicts, spenderIndex)
+				tasks[index].pending++
+				tasks[spenderIndex].dependents = append(tasks[spenderIndex].dependents, index)
+			}
+			spendersByOutputID[outputID] = append(spendersByOutputID[outputID], index)
+		}
+	}
+}
+
+// markCycles fails the transactions which can not be ordered by their dependencies.
+// The transactions which are part of a dependency cycle fail with ErrBatchDependencyCycle,
+// the transactions which only depend on a cycle fail with ErrBatchDependencyFailed.
+func markCycles(tasks []*batchTask, results []*BatchResult) {
+	pending := make([]int, len(tasks))
+	var ordered []int
+	for index, task := range tasks {
+		pending[index] = task.pending
+		if task.pending == 0 {
+			ordered = append(ordered, index)
+		}
+	}
+
+	for i := 0; i < len(ordered); i++ {
+		for _, dependentIndex := range tasks[ordered[i]].dependents {
+			if pending[dependentIndex]--; pending[dependentIndex] == 0 {
+				ordered = append(ordered, dependentIndex)
+			}
+		}
+	}
+
+	for index, task := range tasks {
+		task.blocked = pending[index] > 0
+	}
+
+	inCycle := cycleMembers(tasks)
+	for index, task := range tasks {
+		if !task.blocked || results[index].Error != nil {
+			continue
+		}
+
+		if inCycle[index] {
+			results[index].failed(ierrors.Wrapf(ErrBatchDependencyCycle, "transaction %s", results[index].TransactionID.ToHex()))
+
+			continue
+		}
+		results[index].failed(ierrors.Wrapf(ErrBatchDependencyFailed, "transaction %s depends on a dependency cycle", results[index].TransactionID.ToHex()))
+	}
+}
+
+// cycleMembers returns which of the blocked tasks are part of a dependency cycle,
+// by searching the strongly connected components of the blocked tasks with Tarjan's algorithm.
+func cycleMembers(tasks []*batchTask) []bool {
+	var (
+		inCycle   = make([]bool, len(tasks))
+		order     = make([]int, len(tasks))
+		lowLink   = make([]int, len(tasks))
+		onStack   = make([]bool, len(tasks))
+		stack     []int
+		nextOrder = 1
+		visit     func(index int)
+	)
+
+	visit = func(index int) {
+		order[index], lowLink[index] = nextOrder, nextOrder
+		nextOrder++
+		stack = append(stack, index)
+		onStack[index] = true
+
+		for _, dependentIndex := range tasks[index].dependents {
+			if !tasks[dependentIndex].blocked {
+				continue
+			}
+
+			if order[dependentIndex] == 0 {
+				visit(dependentIndex)
+				lowLink[index] = min(lowLink[index], lowLink[dependentIndex])
+			} else if onStack[dependentIndex] {
+				lowLink[index] = min(lowLink[index], order[dependentIndex])
+			}
+		}
+
+		if lowLink[index] != order[index] {
+			return
+		}
+
+		// the task is the root of a strongly connected component, which is a cycle if it has more than one member
+		componentStart := len(stack) - 1
+		for stack[componentStart] != index {
+			componentStart--
+		}
+		for _, memberIndex := range stack[componentStart:] {
+			onStack[memberIndex] = false
+			inCycle[memberIndex] = len(stack)-componentStart > 1
+		}
+		stack = stack[:componentStart]
+	}
+
+	for index, task := range tasks {
+		if task.blocked && order[index] == 0 {
+			visit(index)
+		}
+	}
+
+	return inCycle
+}
+
+// run validates the tasks on the workers, scheduling every task as soon as all its dependencies are validated.
+func (v *BatchValidator) run(ctx context.Context, transactions []*BatchTransaction, tasks []*batchTask, results []*BatchResult) {
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		ready     = make(chan *batchTask, len(tasks))
+		remaining int
+	)
+
+	for _, task := range tasks {
+		if task.blocked {
+			continue
+		}
+		remaining++
+
+		if task.pending == 0 {
+			ready <- task
+		}
+	}
+
+	if remaining == 0 {
+		return
+	}
+
+	for range min(v.numWorkers, remaining) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for task := range ready {
+				v.validate(ctx, transactions, task, results)
+
+				mutex.Lock()
+				for _, dependentIndex := range task.dependents {
+					if tasks[dependentIndex].pending--; tasks[dependentIndex].pending == 0 {
+						ready <- tasks[dependentIndex]
+					}
+				}
+
+				if remaining--; remaining == 0 {
+					close(ready)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// validate validates a single transaction, whose dependencies were already validated.
+func (v *BatchValidator) validate(ctx context.Context, transactions []*BatchTransaction, task *batchTask, results []*BatchResult) {
+	result := results[task.index]
+	if result.Error != nil {
+		// the transaction ID could not be computed
+		return
+	}
+
+	// the cancellation takes precedence over the failed dependencies and conflicts
+	if err := ctx.Err(); err != nil {
+		result.failed(err)
+
+		return
+	}
+
+	transaction := transactions[task.index]
+	resolvedInputs := transaction.ResolvedInputs
+
+	if len(task.dependencies) > 0 {
+		// resolve the inputs created by the dependencies without modifying the input set of the caller
+		inputSet := make(InputSet, len(resolvedInputs.InputSet))
+		for outputID, output := range resolvedInputs.InputSet {
+			inputSet[outputID] = output
+		}
+
+		for _, dependencyIndex := range task.dependencies {
+			dependency := results[dependencyIndex]
+			if !dependency.Succeeded() {
+				result.failed(ierrors.Wrapf(ErrBatchDependencyFailed, "input created by transaction %s", dependency.TransactionID.ToHex()))
+
+				return
+			}
+		}
+
+		for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+			outputID := input.OutputID()
+			if _, resolved := inputSet[outputID]; resolved {
+				continue
+			}
+
+			dependencyIndex, isBatchOutput := transactionIndex(results, task.dependencies, outputID.TransactionID())
+			if !isBatchOutput || int(outputID.Index()) >= len(results[dependencyIndex].Outputs) {
+				continue
+			}
+			inputSet[outputID] = results[dependencyIndex].Outputs[outputID.Index()]
+		}
+		resolvedInputs.InputSet = inputSet
+	}
+
+	for _, conflictIndex := range task.conflicts {
+		if conflict := results[conflictIndex]; conflict.Succeeded() {
+			result.failed(ierrors.WithMessagef(iotago.ErrTxConflictRejected, "an input is already consumed by transaction %s of the batch", conflict.TransactionID.ToHex()))
+
+			return
+		}
+	}
+
+	// the VM expects all inputs to be resolved
+	for _, input := range transaction.SignedTransaction.Transaction.Inputs() {
+		if _, resolved := resolvedInputs.InputSet[input.OutputID()]; !resolved {
+			result.failed(ierrors.WithMessagef(iotago.ErrUTXOInputInvalid, "input %s is not resolved", input.OutputID().ToHex()))
+
+			return
+		}
+	}
+
+	unlockedAddrs, err := v.vm.ValidateUnlocks(transaction.SignedTransaction, resolvedInputs)
+	if err != nil {
+		result.failed(err)
+
+		return
+	}
+
+	outputs, err := v.vm.Execute(transaction.SignedTransaction.Transaction, resolvedInputs, unlockedAddrs)
+	if err != nil {
+		result.failed(err)
+
+		return
+	}
+	result.Outputs = outputs
+}
+
+// transactionIndex returns the index of the transaction with the given ID among the given indexes.
+func transactionIndex(results []*BatchResult, indexes []int, transactionID iotago.TransactionID) (int, bool) {
+	for _, index := range indexes {
+		if results[index].TransactionID == transactionID {
+			return index, true
+		}
+	}
+
+	return 0, false
+}
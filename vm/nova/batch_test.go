@@ -0,0 +1,216 @@
+package nova_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestBatchValidator(t *testing.T) {
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+	_, otherAddr, otherAddrKeys := tpkg.RandEd25519Identity()
+
+	newTransaction := func(inputID iotago.OutputID, signer iotago.AddressKeys, recipient iotago.Address) *iotago.SignedTransaction {
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       iotago.TxEssenceInputs{inputID.UTXOInput()},
+				CreationSlot: 100,
+				Capabilities: iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDoAnything()),
+			},
+			Outputs: iotago.TxEssenceOutputs{
+				&iotago.BasicOutput{
+					Amount:           OneIOTA,
+					UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: recipient}},
+				},
+			},
+		}
+		sigs, err := transaction.Sign(signer)
+		require.NoError(t, err)
+
+		return &iotago.SignedTransaction{
+			API:         testAPI,
+			Transaction: transaction,
+			Unlocks:     iotago.Unlocks{&iotago.SignatureUnlock{Signature: sigs[0]}},
+		}
+	}
+
+	outputOf := func(signedTx *iotago.SignedTransaction) iotago.OutputID {
+		return iotago.OutputIDFromTransactionIDAndIndex(lo.PanicOnErr(signedTx.Transaction.ID()), 0)
+	}
+
+	basicOutput := func(owner iotago.Address) iotago.Output {
+		return &iotago.BasicOutput{
+			Amount:           OneIOTA,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: owner}},
+		}
+	}
+
+	// a chain of three transactions: genesis -> first -> second
+	genesisInputID := tpkg.RandOutputIDWithCreationSlot(10, 0)
+	first := newTransaction(genesisInputID, addrKeys, addr)
+	second := newTransaction(outputOf(first), addrKeys, otherAddr)
+	third := newTransaction(outputOf(second), otherAddrKeys, addr)
+
+	// a transaction that is signed by the wrong key and its dependent
+	invalidInputID := tpkg.RandOutputIDWithCreationSlot(10, 1)
+	invalid := newTransaction(invalidInputID, otherAddrKeys, addr)
+	dependentOfInvalid := newTransaction(outputOf(invalid), addrKeys, addr)
+
+	// an independent transaction
+	independentInputID := tpkg.RandOutputIDWithCreationSlot(10, 2)
+	independent := newTransaction(independentInputID, addrKeys, otherAddr)
+
+	// the dependent transactions are submitted before the transactions they depend on
+	transactions := []*vm.BatchTransaction{
+		{SignedTransaction: third},
+		{SignedTransaction: dependentOfInvalid},
+		{SignedTransaction: second},
+		{SignedTransaction: independent, ResolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{independentInputID: basicOutput(addr)}}},
+		{SignedTransaction: invalid, ResolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{invalidInputID: basicOutput(addr)}}},
+		{SignedTransaction: first, ResolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{genesisInputID: basicOutput(addr)}}},
+	}
+
+	for _, numWorkers := range []int{1, 4} {
+		results := vm.NewBatchValidator(nova.NewVirtualMachine(), numWorkers).Validate(context.Background(), transactions)
+		require.Len(t, results, len(transactions))
+
+		for index, transaction := range transactions {
+			require.Equal(t, lo.PanicOnErr(transaction.SignedTransaction.Transaction.ID()), results[index].TransactionID)
+		}
+
+		for _, index := range []int{0, 2, 3, 5} {
+			require.True(t, results[index].Succeeded(), results[index].Error)
+			require.Equal(t, api.TxFailureNone, results[index].FailureReason)
+			require.Len(t, results[index].Outputs, 1)
+		}
+
+		require.ErrorIs(t, results[4].Error, iotago.ErrUnlockSignatureInvalid)
+		require.Equal(t, api.TxFailureUnlockSignatureInvalid, results[4].FailureReason)
+		require.Nil(t, results[4].Outputs)
+
+		require.ErrorIs(t, results[1].Error, vm.ErrBatchDependencyFailed)
+		require.Nil(t, results[1].Outputs)
+	}
+
+	t.Run("fail - double spend", func(t *testing.T) {
+		// both transactions spend the genesis output, the one submitted later is rejected together with its dependent
+		doubleSpend := newTransaction(genesisInputID, addrKeys, otherAddr)
+		dependentOfDoubleSpend := newTransaction(outputOf(doubleSpend), otherAddrKeys, addr)
+		genesisInput := vm.ResolvedInputs{InputSet: vm.InputSet{genesisInputID: basicOutput(addr)}}
+
+		results := vm.NewBatchValidator(nova.NewVirtualMachine()).Validate(context.Background(), []*vm.BatchTransaction{
+			{SignedTransaction: dependentOfDoubleSpend},
+			{SignedTransaction: first, ResolvedInputs: genesisInput},
+			{SignedTransaction: doubleSpend, ResolvedInputs: genesisInput},
+		})
+
+		require.True(t, results[1].Succeeded(), results[1].Error)
+
+		require.ErrorIs(t, results[2].Error, iotago.ErrTxConflictRejected)
+		require.Equal(t, api.TxFailureConflictRejected, results[2].FailureReason)
+		require.Nil(t, results[2].Outputs)
+
+		require.ErrorIs(t, results[0].Error, vm.ErrBatchDependencyFailed)
+		require.Nil(t, results[0].Outputs)
+	})
+
+	t.Run("ok - double spend after an invalid transaction", func(t *testing.T) {
+		// the transaction submitted first is invalid, so it doesn't reject the valid one submitted later
+		invalidSpend := newTransaction(genesisInputID, otherAddrKeys, otherAddr)
+		dependentOfFirst := newTransaction(outputOf(first), addrKeys, otherAddr)
+		genesisInput := vm.ResolvedInputs{InputSet: vm.InputSet{genesisInputID: basicOutput(addr)}}
+
+		for _, numWorkers := range []int{1, 4} {
+			results := vm.NewBatchValidator(nova.NewVirtualMachine(), numWorkers).Validate(context.Background(), []*vm.BatchTransaction{
+				{SignedTransaction: invalidSpend, ResolvedInputs: genesisInput},
+				{SignedTransaction: dependentOfFirst},
+				{SignedTransaction: first, ResolvedInputs: genesisInput},
+			})
+
+			require.ErrorIs(t, results[0].Error, iotago.ErrUnlockSignatureInvalid)
+			require.Nil(t, results[0].Outputs)
+
+			require.True(t, results[2].Succeeded(), results[2].Error)
+			require.True(t, results[1].Succeeded(), results[1].Error)
+		}
+	})
+
+	t.Run("fail - transaction contained twice", func(t *testing.T) {
+		genesisInput := vm.ResolvedInputs{InputSet: vm.InputSet{genesisInputID: basicOutput(addr)}}
+
+		results := vm.NewBatchValidator(nova.NewVirtualMachine()).Validate(context.Background(), []*vm.BatchTransaction{
+			{SignedTransaction: first, ResolvedInputs: genesisInput},
+			{SignedTransaction: first, ResolvedInputs: genesisInput},
+		})
+
+		require.True(t, results[0].Succeeded(), results[0].Error)
+		require.ErrorIs(t, results[1].Error, iotago.ErrTxConflictRejected)
+	})
+
+	t.Run("fail - input not resolved", func(t *testing.T) {
+		results := vm.NewBatchValidator(nova.NewVirtualMachine()).Validate(context.Background(), []*vm.BatchTransaction{{SignedTransaction: first}})
+		require.ErrorIs(t, results[0].Error, iotago.ErrUTXOInputInvalid)
+	})
+
+	t.Run("fail - canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results := vm.NewBatchValidator(nova.NewVirtualMachine()).Validate(ctx, transactions)
+		for _, result := range results {
+			require.False(t, result.Succeeded())
+			require.ErrorIs(t, result.Error, context.Canceled)
+		}
+	})
+
+	t.Run("fail - canceled during the validation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// the context is canceled while the first transaction of the chain is validated,
+		// which is after the independent and the invalid transaction on a single worker
+		cancelingVM := &cancelingVirtualMachine{
+			VirtualMachine: nova.NewVirtualMachine(),
+			transactionID:  lo.PanicOnErr(first.Transaction.ID()),
+			cancel:         cancel,
+		}
+
+		results := vm.NewBatchValidator(cancelingVM, 1).Validate(ctx, transactions)
+
+		for _, index := range []int{3, 5} {
+			require.True(t, results[index].Succeeded(), results[index].Error)
+		}
+		require.ErrorIs(t, results[4].Error, iotago.ErrUnlockSignatureInvalid)
+
+		// the dependents are not validated after the cancellation, even the one of the invalid transaction
+		for _, index := range []int{0, 1, 2} {
+			require.ErrorIs(t, results[index].Error, context.Canceled)
+			require.NotErrorIs(t, results[index].Error, vm.ErrBatchDependencyFailed)
+			require.Nil(t, results[index].Outputs)
+		}
+	})
+}
+
+// cancelingVirtualMachine cancels a context when it validates the unlocks of the given transaction.
+type cancelingVirtualMachine struct {
+	vm.VirtualMachine
+	transactionID iotago.TransactionID
+	cancel        context.CancelFunc
+}
+
+func (c *cancelingVirtualMachine) ValidateUnlocks(signedTransaction *iotago.SignedTransaction, inputs vm.ResolvedInputs) (vm.UnlockedAddresses, error) {
+	if lo.PanicOnErr(signedTransaction.Transaction.ID()) == c.transactionID {
+		c.cancel()
+	}
+
+	return c.VirtualMachine.ValidateUnlocks(signedTransaction, inputs)
+}
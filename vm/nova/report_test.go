@@ -0,0 +1,210 @@
+//nolint:forcetypeassert
+package nova_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestValidateAll(t *testing.T) {
+	_, addr1, addr1AddrKeys := tpkg.RandEd25519Identity()
+	addr2 := tpkg.RandEd25519Address()
+	sender := tpkg.RandEd25519Address()
+	issuer := tpkg.RandNFTAddress()
+	creationSlot := iotago.SlotIndex(100)
+
+	inputIDs := iotago.OutputIDs{
+		tpkg.RandOutputIDWithCreationSlot(10, 0),
+		tpkg.RandOutputIDWithCreationSlot(10, 1),
+	}
+	inputs := vm.InputSet{
+		inputIDs[0]: &iotago.BasicOutput{
+			Amount: OneIOTA,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: addr1},
+				&iotago.TimelockUnlockCondition{Slot: 500},
+			},
+		},
+		// owned by another address than the one that signed
+		inputIDs[1]: &iotago.BasicOutput{
+			Amount: OneIOTA,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: addr2},
+			},
+		},
+	}
+
+	newTransaction := func(outputs iotago.TxEssenceOutputs) *iotago.SignedTransaction {
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       inputIDs.UTXOInputs(),
+				CreationSlot: creationSlot,
+				Capabilities: iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDoAnything()),
+			},
+			Outputs: outputs,
+		}
+		sigs, err := transaction.Sign(addr1AddrKeys)
+		require.NoError(t, err)
+
+		return &iotago.SignedTransaction{
+			API:         testAPI,
+			Transaction: transaction,
+			Unlocks: iotago.Unlocks{
+				&iotago.SignatureUnlock{Signature: sigs[0]},
+				&iotago.SignatureUnlock{Signature: sigs[0]},
+			},
+		}
+	}
+	resolvedInputs := vm.ResolvedInputs{
+		InputSet:        inputs,
+		CommitmentInput: &iotago.Commitment{Slot: creationSlot},
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		validInputs := vm.InputSet{
+			inputIDs[0]: &iotago.BasicOutput{
+				Amount:           OneIOTA,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr1}},
+			},
+		}
+
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       inputIDs[:1].UTXOInputs(),
+				CreationSlot: creationSlot,
+				// the potential mana generated by the input is burned
+				Capabilities: iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDoAnything()),
+			},
+			Outputs: iotago.TxEssenceOutputs{
+				&iotago.BasicOutput{Amount: OneIOTA, UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr2}}},
+			},
+		}
+		sigs, err := transaction.Sign(addr1AddrKeys)
+		require.NoError(t, err)
+
+		report := nova.ValidateAll(&iotago.SignedTransaction{
+			API:         testAPI,
+			Transaction: transaction,
+			Unlocks:     iotago.Unlocks{&iotago.SignatureUnlock{Signature: sigs[0]}},
+		}, vm.ResolvedInputs{InputSet: validInputs})
+		require.True(t, report.Valid(), report.Err())
+		require.NoError(t, report.Err())
+	})
+
+	t.Run("fail - execution func without report", func(t *testing.T) {
+		signedTx := newTransaction(iotago.TxEssenceOutputs{
+			&iotago.BasicOutput{Amount: 2 * OneIOTA, UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr1}}},
+		})
+
+		report := &vm.ValidationReport{}
+		workingSet, err := nova.NewVMParamsWorkingSet(testAPI, signedTx.Transaction, resolvedInputs)
+		require.NoError(t, err)
+		vmParams := &vm.Params{API: testAPI, WorkingSet: workingSet}
+
+		vm.ReportExecution(novaVM, vmParams, report, vm.ExecFuncTimelocks(), func(_ vm.VirtualMachine, _ *vm.Params) error {
+			return iotago.ErrInputOutputManaMismatch
+		}, vm.ExecFuncSenderUnlocked())
+
+		require.Len(t, report.Violations, 2)
+		require.Equal(t, vm.CheckTimelocks, report.Violations[0].Check)
+		require.Equal(t, vm.CheckExecution, report.Violations[1].Check)
+		require.Equal(t, api.TxFailureInputOutputManaMismatch, report.Violations[1].FailureReason)
+		require.Nil(t, vmParams.Report)
+	})
+
+	t.Run("fail - all violations", func(t *testing.T) {
+		signedTx := newTransaction(iotago.TxEssenceOutputs{
+			&iotago.BasicOutput{
+				Amount:           OneIOTA,
+				Mana:             1_000_000,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr2}},
+				Features:         iotago.BasicOutputFeatures{&iotago.SenderFeature{Address: sender}},
+			},
+			&iotago.NFTOutput{
+				Amount:            3 * OneIOTA,
+				UnlockConditions:  iotago.NFTOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr1}},
+				ImmutableFeatures: iotago.NFTOutputImmFeatures{&iotago.IssuerFeature{Address: issuer}},
+			},
+		})
+
+		// the regular execution only reports the first violation
+		_, err := novaVM.ValidateUnlocks(signedTx, resolvedInputs)
+		require.ErrorIs(t, err, iotago.ErrUnlockSignatureInvalid)
+
+		report := nova.ValidateAll(signedTx, resolvedInputs)
+		require.False(t, report.Valid())
+
+		unlocks := report.Violated(vm.CheckUnlocks)
+		require.Len(t, unlocks, 1)
+		require.EqualValues(t, 1, *unlocks[0].InputIndex)
+		require.Equal(t, api.TxFailureUnlockSignatureInvalid, unlocks[0].FailureReason)
+
+		timelocks := report.Violated(vm.CheckTimelocks)
+		require.Len(t, timelocks, 1)
+		require.EqualValues(t, 0, *timelocks[0].InputIndex)
+		require.Equal(t, "500", timelocks[0].Expected)
+		require.Equal(t, "110", timelocks[0].Actual)
+		require.ErrorIs(t, timelocks[0].Error, iotago.ErrTimelockNotExpired)
+		require.Equal(t, api.TxFailureTimelockNotExpired, timelocks[0].FailureReason)
+
+		senders := report.Violated(vm.CheckSenders)
+		require.Len(t, senders, 1)
+		require.EqualValues(t, 0, *senders[0].OutputIndex)
+		require.Equal(t, sender.Bech32(testAPI.ProtocolParameters().Bech32HRP()), senders[0].Address)
+		require.Equal(t, api.TxFailureSenderFeatureNotUnlocked, senders[0].FailureReason)
+
+		baseTokens := report.Violated(vm.CheckBaseTokens)
+		require.Len(t, baseTokens, 1)
+		require.Equal(t, api.TxFailureInputOutputBaseTokenMismatch, baseTokens[0].FailureReason)
+		require.Equal(t, "2000000", baseTokens[0].Expected)
+		require.Equal(t, "4000000", baseTokens[0].Actual)
+
+		chainTransitions := report.Violated(vm.CheckChainTransitions)
+		require.Len(t, chainTransitions, 1)
+		require.EqualValues(t, 1, *chainTransitions[0].OutputIndex)
+		require.Nil(t, chainTransitions[0].InputIndex)
+		require.ErrorIs(t, chainTransitions[0].Error, iotago.ErrIssuerFeatureNotUnlocked)
+		require.Equal(t, iotago.NFTIDFromOutputID(iotago.OutputIDFromTransactionIDAndIndex(lo.PanicOnErr(signedTx.Transaction.ID()), 1)).ToHex(), chainTransitions[0].ChainID)
+
+		mana := report.Violated(vm.CheckMana)
+		require.Len(t, mana, 1)
+		require.ErrorIs(t, mana[0].Error, iotago.ErrInputOutputManaMismatch)
+		// the inputs only hold the potential mana generated since their creation
+		manaIn, err := vm.TotalManaIn(testAPI.ManaDecayProvider(), testAPI.StorageScoreStructure(), creationSlot, inputs, nil)
+		require.NoError(t, err)
+		require.Less(t, manaIn, iotago.Mana(1_000_000))
+		require.Equal(t, fmt.Sprintf("%d", manaIn), mana[0].Expected)
+		require.Equal(t, "1000000", mana[0].Actual)
+
+		require.Len(t, report.Violations, 6)
+		for _, wantErr := range []error{iotago.ErrUnlockSignatureInvalid, iotago.ErrTimelockNotExpired, iotago.ErrSenderFeatureNotUnlocked, iotago.ErrInputOutputBaseTokenMismatch, iotago.ErrIssuerFeatureNotUnlocked, iotago.ErrInputOutputManaMismatch} {
+			require.ErrorIs(t, report.Err(), wantErr)
+		}
+
+		// the execution finds the violations with the same checks, but stops at the first one
+		unlockedAddrs := vm.ReportUnlocks(signedTx, resolvedInputs, &vm.ValidationReport{})
+		_, err = novaVM.Execute(signedTx.Transaction, resolvedInputs, unlockedAddrs)
+		require.ErrorIs(t, err, iotago.ErrTimelockNotExpired)
+		require.Equal(t, api.TxFailureTimelockNotExpired, api.DetermineTransactionFailureReason(err))
+
+		// the report is machine-readable
+		var decoded map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(lo.PanicOnErr(json.Marshal(report)), &decoded))
+		require.Len(t, decoded["violations"], 6)
+		require.Equal(t, string(vm.CheckUnlocks), decoded["violations"][0]["check"])
+		require.EqualValues(t, 1, decoded["violations"][0]["inputIndex"])
+		require.NotContains(t, decoded["violations"][0], "outputIndex")
+	})
+}
@@ -0,0 +1,146 @@
+package nova_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestTracer(t *testing.T) {
+	_, addr1, addr1AddrKeys := tpkg.RandEd25519Identity()
+	addr2 := tpkg.RandEd25519Address()
+	nativeTokenID := tpkg.RandNativeTokenID()
+	bech32HRP := testAPI.ProtocolParameters().Bech32HRP()
+
+	inputID := tpkg.RandOutputIDWithCreationSlot(10, 0)
+	resolvedInputs := vm.ResolvedInputs{
+		InputSet: vm.InputSet{
+			inputID: &iotago.BasicOutput{
+				Amount:           2 * OneIOTA,
+				Mana:             1_000,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr1}},
+				Features:         iotago.BasicOutputFeatures{&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(50)}},
+			},
+		},
+	}
+
+	newTransaction := func(nftOutput *iotago.NFTOutput) *iotago.SignedTransaction {
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       iotago.TxEssenceInputs{inputID.UTXOInput()},
+				CreationSlot: 100,
+				Capabilities: iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDoAnything()),
+			},
+			Outputs: iotago.TxEssenceOutputs{
+				&iotago.BasicOutput{
+					Amount:           OneIOTA,
+					Mana:             500,
+					UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr2}},
+					Features:         iotago.BasicOutputFeatures{&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(50)}},
+				},
+				nftOutput,
+			},
+		}
+		sigs, err := transaction.Sign(addr1AddrKeys)
+		require.NoError(t, err)
+
+		return &iotago.SignedTransaction{
+			API:         testAPI,
+			Transaction: transaction,
+			Unlocks:     iotago.Unlocks{&iotago.SignatureUnlock{Signature: sigs[0]}},
+		}
+	}
+
+	trace := func(t *testing.T, signedTx *iotago.SignedTransaction) ([]map[string]any, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		tracer := vm.NewJSONLinesTracer(&buf, bech32HRP)
+		tracedVM := nova.NewVirtualMachine(nova.WithTracer(tracer))
+
+		unlockedAddrs, err := tracedVM.ValidateUnlocks(signedTx, resolvedInputs)
+		require.NoError(t, err)
+		_, execErr := tracedVM.Execute(signedTx.Transaction, resolvedInputs, unlockedAddrs)
+		require.NoError(t, tracer.Err())
+
+		var events []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			event := make(map[string]any)
+			require.NoError(t, json.Unmarshal(line, &event))
+			events = append(events, event)
+		}
+
+		return events, execErr
+	}
+
+	eventNames := func(events []map[string]any) []string {
+		names := make([]string, 0, len(events))
+		for _, event := range events {
+			//nolint:forcetypeassert // every event has a name
+			names = append(names, event["event"].(string))
+		}
+
+		return names
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		signedTx := newTransaction(&iotago.NFTOutput{
+			Amount:           OneIOTA,
+			UnlockConditions: iotago.NFTOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr2}},
+		})
+
+		events, err := trace(t, signedTx)
+		require.NoError(t, err)
+		require.Equal(t, []string{vm.TraceEventUnlock, vm.TraceEventNativeTokens, vm.TraceEventChainTransition, vm.TraceEventMana}, eventNames(events))
+
+		txID := lo.PanicOnErr(signedTx.Transaction.ID())
+		for _, event := range events {
+			require.Equal(t, txID.ToHex(), event["transactionId"])
+		}
+
+		require.EqualValues(t, 0, events[0]["inputIndex"])
+		require.Equal(t, addr1.Bech32(bech32HRP), events[0]["address"])
+		require.Equal(t, iotago.UnlockSignature.String(), events[0]["unlockType"])
+
+		amount := hexutil.EncodeUint256(big.NewInt(50))
+		require.Equal(t, map[string]any{nativeTokenID.ToHex(): amount}, events[1]["in"])
+		require.Equal(t, map[string]any{nativeTokenID.ToHex(): amount}, events[1]["out"])
+
+		nftID := iotago.NFTIDFromOutputID(iotago.OutputIDFromTransactionIDAndIndex(txID, 1))
+		require.Equal(t, nftID.ToHex(), events[2]["chainId"])
+		require.Equal(t, iotago.OutputNFT.String(), events[2]["outputType"])
+		require.Equal(t, iotago.ChainTransitionTypeGenesis.String(), events[2]["transitionType"])
+
+		totalManaIn, err := vm.TotalManaIn(testAPI.ManaDecayProvider(), testAPI.StorageScoreStructure(), signedTx.Transaction.CreationSlot, resolvedInputs.InputSet, nil)
+		require.NoError(t, err)
+		require.Equal(t, []any{"500", "0"}, events[3]["outputs"])
+		require.Equal(t, "500", events[3]["totalOut"])
+		require.Equal(t, strconv.FormatUint(uint64(totalManaIn), 10), events[3]["totalIn"])
+		//nolint:forcetypeassert // the inputs are a list of objects
+		input := events[3]["inputs"].([]any)[0].(map[string]any)
+		require.Equal(t, inputID.ToHex(), input["outputId"])
+		require.NotEqual(t, "0", input["potentialMana"])
+	})
+
+	t.Run("fail - the trace ends at the failing check", func(t *testing.T) {
+		events, err := trace(t, newTransaction(&iotago.NFTOutput{
+			Amount:            OneIOTA,
+			UnlockConditions:  iotago.NFTOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr2}},
+			ImmutableFeatures: iotago.NFTOutputImmFeatures{&iotago.IssuerFeature{Address: tpkg.RandNFTAddress()}},
+		}))
+		require.ErrorIs(t, err, iotago.ErrIssuerFeatureNotUnlocked)
+		require.Equal(t, []string{vm.TraceEventUnlock, vm.TraceEventNativeTokens, vm.TraceEventChainTransition}, eventNames(events))
+	})
+}
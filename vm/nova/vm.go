@@ -5,12 +5,17 @@ import (
 
 	"github.com/iotaledger/hive.go/core/safemath"
 	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/vm"
 )
 
 // NewVirtualMachine returns an VirtualMachine adhering to the Nova protocol.
-func NewVirtualMachine() vm.VirtualMachine {
+func NewVirtualMachine(opts ...options.Option[VirtualMachineOptions]) vm.VirtualMachine {
+	return newVirtualMachine(opts...)
+}
+
+func newVirtualMachine(opts ...options.Option[VirtualMachineOptions]) *virtualMachine {
 	return &virtualMachine{
 		execList: []vm.ExecFunc{
 			vm.ExecFuncTimelocks(),
@@ -21,11 +26,34 @@ func NewVirtualMachine() vm.VirtualMachine {
 			vm.ExecFuncBalancedMana(),
 			vm.ExecFuncAtMostOneImplicitAccountCreationAddress(),
 		},
+		opts: options.Apply(&VirtualMachineOptions{}, opts),
+	}
+}
+
+// VirtualMachineOptions define the options of the VirtualMachine created with NewVirtualMachine.
+type VirtualMachineOptions struct {
+	tracer         vm.Tracer
+	signatureBatch bool
+}
+
+// WithTracer sets the Tracer which receives the events of the unlock validation and the execution of transactions.
+func WithTracer(tracer vm.Tracer) options.Option[VirtualMachineOptions] {
+	return func(opts *VirtualMachineOptions) {
+		opts.tracer = tracer
+	}
+}
+
+// WithSignatureBatch lets the VirtualMachine verify the Ed25519 signatures of the unlocks of a transaction in a single batch.
+// See vm.ValidateUnlocksWithSignatureBatch.
+func WithSignatureBatch() options.Option[VirtualMachineOptions] {
+	return func(opts *VirtualMachineOptions) {
+		opts.signatureBatch = true
 	}
 }
 
 type virtualMachine struct {
 	execList []vm.ExecFunc
+	opts     *VirtualMachineOptions
 }
 
 func NewVMParamsWorkingSet(api iotago.API, t *iotago.Transaction, resolvedInputs vm.ResolvedInputs) (*vm.WorkingSet, error) {
@@ -100,12 +128,17 @@ func constructInputSet(inputSet vm.InputSet) vm.InputSet {
 }
 
 func (novaVM *virtualMachine) ValidateUnlocks(signedTransaction *iotago.SignedTransaction, inputs vm.ResolvedInputs) (unlockedAddrs vm.UnlockedAddresses, err error) {
-	return vm.ValidateUnlocks(signedTransaction, inputs)
+	if novaVM.opts.signatureBatch {
+		return vm.ValidateUnlocksWithSignatureBatch(signedTransaction, inputs, novaVM.opts.tracer)
+	}
+
+	return vm.ValidateUnlocksWithTracer(signedTransaction, inputs, novaVM.opts.tracer)
 }
 
 func (novaVM *virtualMachine) Execute(transaction *iotago.Transaction, resolvedInputs vm.ResolvedInputs, unlockedAddrs vm.UnlockedAddresses, execFunctions ...vm.ExecFunc) (outputs []iotago.Output, err error) {
 	vmParams := &vm.Params{
-		API: transaction.API,
+		API:    transaction.API,
+		Tracer: novaVM.opts.tracer,
 	}
 
 	if vmParams.WorkingSet, err = NewVMParamsWorkingSet(vmParams.API, transaction, resolvedInputs); err != nil {
@@ -130,6 +163,31 @@ func (novaVM *virtualMachine) Execute(transaction *iotago.Transaction, resolvedI
 	return outputs, nil
 }
 
+// ValidateAll runs every check of the VM on the given SignedTransaction and returns a report of all violations,
+// instead of stopping at the first error like ValidateUnlocks and Execute do.
+func ValidateAll(signedTransaction *iotago.SignedTransaction, resolvedInputs vm.ResolvedInputs) *vm.ValidationReport {
+	report := &vm.ValidationReport{}
+	unlockedAddrs := vm.ReportUnlocks(signedTransaction, resolvedInputs, report)
+
+	vmParams := &vm.Params{
+		API: signedTransaction.Transaction.API,
+	}
+
+	var err error
+	if vmParams.WorkingSet, err = NewVMParamsWorkingSet(vmParams.API, signedTransaction.Transaction, resolvedInputs); err != nil {
+		// the working set can only not be created if the mana of the inputs or outputs can not be calculated
+		report.Add(vm.CheckMana, ierrors.Wrap(err, "failed to create working set"))
+
+		return report
+	}
+	vmParams.WorkingSet.UnlockedAddrs = unlockedAddrs
+
+	novaVM := newVirtualMachine()
+	vm.ReportExecution(novaVM, vmParams, report, novaVM.execList...)
+
+	return report
+}
+
 func (novaVM *virtualMachine) ChainSTVF(vmParams *vm.Params, transType iotago.ChainTransitionType, input *vm.ChainOutputWithIDs, next iotago.ChainOutput) error {
 	transitionState := next
 	if transType != iotago.ChainTransitionTypeGenesis {
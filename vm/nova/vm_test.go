@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotaledger/hive.go/lo"
+	"github.com/iotaledger/hive.go/runtime/options"
 	"github.com/iotaledger/hive.go/serializer/v2/serix"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/builder"
@@ -5563,6 +5564,61 @@ func TestTxSemanticNativeTokens(t *testing.T) {
 				wantErr: iotago.ErrNativeTokenSumUnbalanced,
 			}
 		}(),
+
+		// fail - burning not allowed is reported before new output tokens
+		func() *test {
+			inputIDs := tpkg.RandOutputIDs(1)
+
+			// the token only residing on the output side has the lower ID
+			burnedNativeTokenFeature := tpkg.RandNativeTokenFeature()
+			burnedNativeTokenFeature.ID[0] = 0xff
+			newNativeTokenFeature := tpkg.RandNativeTokenFeature()
+			newNativeTokenFeature.ID[0] = 0x00
+
+			inputs := vm.InputSet{
+				inputIDs[0]: &iotago.BasicOutput{
+					Amount: 100,
+					UnlockConditions: iotago.BasicOutputUnlockConditions{
+						&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()},
+					},
+					Features: iotago.BasicOutputFeatures{
+						burnedNativeTokenFeature,
+					},
+				},
+			}
+
+			transaction := &iotago.Transaction{
+				API: testAPI,
+				TransactionEssence: &iotago.TransactionEssence{
+					Inputs: inputIDs.UTXOInputs(),
+				},
+				Outputs: iotago.TxEssenceOutputs{
+					&iotago.BasicOutput{
+						Amount: 100,
+						UnlockConditions: iotago.BasicOutputUnlockConditions{
+							&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()},
+						},
+						Features: iotago.BasicOutputFeatures{
+							newNativeTokenFeature,
+						},
+					},
+				},
+			}
+
+			return &test{
+				name: "fail - burning not allowed is reported before new output tokens",
+				vmParams: &vm.Params{
+					API: testAPI,
+				},
+				resolvedInputs: vm.ResolvedInputs{InputSet: inputs},
+				tx: &iotago.SignedTransaction{
+					API:         testAPI,
+					Transaction: transaction,
+					Unlocks:     iotago.Unlocks{},
+				},
+				wantErr: iotago.ErrTxCapabilitiesNativeTokenBurningNotAllowed,
+			}
+		}(),
 	}
 
 	for _, tt := range tests {
@@ -8217,6 +8273,65 @@ func TestTxSyntacticImplicitAccountMinDeposit(t *testing.T) {
 	require.NoError(t, depositValidationFunc(0, convertedAccount))
 }
 
+func TestValidateUnlocksSignatureBatch(t *testing.T) {
+	const inputCount = 8
+
+	inputIDs := tpkg.RandOutputIDs(inputCount)
+	inputs := vm.InputSet{}
+	var keys []iotago.AddressKeys
+	for i := range inputCount {
+		_, addr, addrKeys := tpkg.RandEd25519Identity()
+		keys = append(keys, addrKeys)
+		inputs[inputIDs[i]] = &iotago.BasicOutput{
+			Amount:           OneIOTA,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr}},
+		}
+	}
+
+	transaction := &iotago.Transaction{
+		API:                testAPI,
+		TransactionEssence: &iotago.TransactionEssence{Inputs: inputIDs.UTXOInputs()},
+		Outputs: iotago.TxEssenceOutputs{
+			&iotago.BasicOutput{
+				Amount:           inputCount * OneIOTA,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()}},
+			},
+		},
+	}
+	sigs, err := transaction.Sign(keys...)
+	require.NoError(t, err)
+
+	signedTransaction := func(sigs []iotago.Signature) *iotago.SignedTransaction {
+		unlocks := make(iotago.Unlocks, 0, len(sigs))
+		for _, sig := range sigs {
+			unlocks = append(unlocks, &iotago.SignatureUnlock{Signature: sig})
+		}
+
+		return &iotago.SignedTransaction{API: testAPI, Transaction: transaction, Unlocks: unlocks}
+	}
+	resolvedInputs := vm.ResolvedInputs{InputSet: inputs}
+	// the options can be collected before the VirtualMachine is created
+	batchOpts := []options.Option[nova.VirtualMachineOptions]{nova.WithSignatureBatch()}
+	batchVM := nova.NewVirtualMachine(batchOpts...)
+
+	unlockedAddrs, err := batchVM.ValidateUnlocks(signedTransaction(sigs), resolvedInputs)
+	require.NoError(t, err)
+	require.Len(t, unlockedAddrs, inputCount)
+
+	// a signature of the right key, but for another message, is only detected by the verification of the signature itself
+	invalidSigs := slices.Clone(sigs)
+	invalidSigs[5] = lo.PanicOnErr(iotago.NewInMemoryAddressSigner(keys[5]).Sign(keys[5].Address, []byte("another message")))
+
+	_, err = batchVM.ValidateUnlocks(signedTransaction(invalidSigs), resolvedInputs)
+	require.ErrorIs(t, err, iotago.ErrUnlockSignatureInvalid)
+	require.ErrorIs(t, err, iotago.ErrEd25519SignatureInvalid)
+	require.ErrorContains(t, err, "input 5's address is not unlocked")
+
+	// the error is the same as without the batch
+	_, unbatchedErr := novaVM.ValidateUnlocks(signedTransaction(invalidSigs), resolvedInputs)
+	require.EqualError(t, err, unbatchedErr.Error())
+}
+
 func validateAndExecuteSignedTransaction(tx *iotago.SignedTransaction, resolvedInputs vm.ResolvedInputs, execFunctions ...vm.ExecFunc) (err error) {
 	unlockedAddrs, err := novaVM.ValidateUnlocks(tx, resolvedInputs)
 	if err != nil {
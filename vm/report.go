@@ -0,0 +1,214 @@
+package vm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+// Check is the name of a check of the VM that found a Violation.
+type Check string
+
+const (
+	// CheckUnlocks checks that every input is unlocked.
+	CheckUnlocks Check = "unlocks"
+	// CheckTimelocks checks that the timelocks of the inputs are expired.
+	CheckTimelocks Check = "timelocks"
+	// CheckSenders checks that the senders of the outputs are unlocked.
+	CheckSenders Check = "senders"
+	// CheckBaseTokens checks that the base tokens are balanced and the storage deposit returns are fulfilled.
+	CheckBaseTokens Check = "baseTokens"
+	// CheckNativeTokens checks that the native tokens are balanced, unless their foundry is transitioned.
+	CheckNativeTokens Check = "nativeTokens"
+	// CheckChainTransitions checks the state transitions of the chain outputs.
+	CheckChainTransitions Check = "chainTransitions"
+	// CheckMana checks that the mana is balanced.
+	CheckMana Check = "mana"
+	// CheckImplicitAccounts checks that at most one input is owned by an implicit account creation address.
+	CheckImplicitAccounts Check = "implicitAccounts"
+	// CheckExecution is the check of the errors returned by ExecFuncs which don't report their violations.
+	CheckExecution Check = "execution"
+)
+
+// Violation is a single rule of the VM a transaction violates.
+// The optional fields point at the part of the transaction the violation was found in.
+type Violation struct {
+	// The check that found the violation.
+	Check Check `json:"check"`
+	// The failure reason a node reports for the violation.
+	FailureReason api.TransactionFailureReason `json:"failureReason"`
+	// The error describing the violation.
+	Error error `json:"-"`
+	// The message of the error.
+	Message string `json:"message"`
+	// The index of the input the violation was found in.
+	InputIndex *uint16 `json:"inputIndex,omitempty"`
+	// The index of the output the violation was found in.
+	OutputIndex *uint16 `json:"outputIndex,omitempty"`
+	// The hex encoded ID of the chain the violation was found in.
+	ChainID string `json:"chainId,omitempty"`
+	// The hex encoded ID of the native token the violation was found in.
+	NativeTokenID string `json:"nativeTokenId,omitempty"`
+	// The bech32 encoded address the violation was found for.
+	Address string `json:"address,omitempty"`
+	// The value that was required by the rule.
+	Expected string `json:"expected,omitempty"`
+	// The value that was found in the transaction.
+	Actual string `json:"actual,omitempty"`
+}
+
+// ValidationReport contains all violations of the rules of the VM by a transaction.
+//
+// In contrast to the execution of the VM, which stops at the first error, every independent check is run
+// and every input, output and chain is checked, even if an earlier one failed already.
+// The violations are found by the same ExecFuncs that execute the transaction, see Params.Report.
+// Violations may be followups of other violations, e.g. an input that can not be unlocked
+// doesn't unlock the sender of an output either.
+type ValidationReport struct {
+	// The violations in the order of the checks, the inputs and the outputs.
+	Violations []*Violation `json:"violations"`
+}
+
+// Valid returns whether the transaction doesn't violate any rule.
+func (r *ValidationReport) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+// Err returns the errors of all violations joined together, nil if the transaction doesn't violate any rule.
+func (r *ValidationReport) Err() error {
+	errs := make([]error, 0, len(r.Violations))
+	for _, violation := range r.Violations {
+		errs = append(errs, violation.Error)
+	}
+
+	return ierrors.Join(errs...)
+}
+
+// Violated returns the violations found by the given check.
+func (r *ValidationReport) Violated(check Check) []*Violation {
+	var violations []*Violation
+	for _, violation := range r.Violations {
+		if violation.Check == check {
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+// Add adds a violation of the given check caused by the given error.
+// The returned violation can be used to set the optional fields.
+func (r *ValidationReport) Add(check Check, err error) *Violation {
+	violation := &Violation{
+		Check:         check,
+		FailureReason: api.DetermineTransactionFailureReason(err),
+		Error:         err,
+		Message:       err.Error(),
+	}
+	r.Violations = append(r.Violations, violation)
+
+	return violation
+}
+
+// violationDetail sets an optional field of a Violation.
+type violationDetail func(violation *Violation)
+
+// atInput sets the input index of the violation.
+func atInput(inputIndex uint16) violationDetail {
+	return func(violation *Violation) {
+		violation.InputIndex = &inputIndex
+	}
+}
+
+// atOutput sets the output index of the violation.
+func atOutput(outputIndex uint16) violationDetail {
+	return func(violation *Violation) {
+		violation.OutputIndex = &outputIndex
+	}
+}
+
+// compared sets the expected and the actual amount or slot of the violation.
+func compared(expected any, actual any) violationDetail {
+	return func(violation *Violation) {
+		violation.Expected = fmt.Sprintf("%d", expected)
+		violation.Actual = fmt.Sprintf("%d", actual)
+	}
+}
+
+// forAddress sets the address of the violation.
+func forAddress(vmParams *Params, address iotago.Address) violationDetail {
+	return func(violation *Violation) {
+		violation.Address = address.Bech32(vmParams.API.ProtocolParameters().Bech32HRP())
+	}
+}
+
+// forChain sets the chain of the violation.
+func forChain(chainID iotago.ChainID) violationDetail {
+	return func(violation *Violation) {
+		violation.ChainID = chainID.ToHex()
+	}
+}
+
+// forNativeToken sets the native token of the violation.
+func forNativeToken(nativeTokenID iotago.NativeTokenID) violationDetail {
+	return func(violation *Violation) {
+		violation.NativeTokenID = nativeTokenID.ToHex()
+	}
+}
+
+// orZero returns the given amount, or zero if it is nil.
+func orZero(amount *big.Int) *big.Int {
+	if amount == nil {
+		return new(big.Int)
+	}
+
+	return amount
+}
+
+// violation handles a violation of the given check caused by the given error.
+// If the Params have a ValidationReport, the violation is added to it and nil is returned,
+// so the ExecFunc continues with the next rule. Otherwise the error is returned to stop the execution.
+func (params *Params) violation(check Check, err error, details ...violationDetail) error {
+	if params.Report == nil {
+		return err
+	}
+
+	violation := params.Report.Add(check, err)
+	for _, detail := range details {
+		detail(violation)
+	}
+
+	return nil
+}
+
+// ReportUnlocks unlocks every input of the given SignedTransaction and adds a violation for every input
+// that can not be unlocked. It returns the addresses unlocked by the other inputs.
+func ReportUnlocks(signedTransaction *iotago.SignedTransaction, resolvedInputs ResolvedInputs, report *ValidationReport) UnlockedAddresses {
+	return unlockInputs(signedTransaction, resolvedInputs, nil, nil, func(inputIndex uint16, err error) bool {
+		atInput(inputIndex)(report.Add(CheckUnlocks, err))
+
+		return true
+	})
+}
+
+// ReportExecution runs the given ExecFuncs and adds all their violations to the report,
+// instead of stopping at the first violation like RunVMFuncs does.
+// The working set of the given Params needs to contain the unlocked addresses.
+func ReportExecution(vm VirtualMachine, vmParams *Params, report *ValidationReport, execFuncs ...ExecFunc) {
+	vmParams.Report = report
+	defer func() {
+		vmParams.Report = nil
+	}()
+
+	for _, execFunc := range execFuncs {
+		if err := execFunc(vm, vmParams); err != nil {
+			// the ExecFunc doesn't report its violations, so it can not be continued
+			report.Add(CheckExecution, err)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// Tracer receives the events emitted by the VirtualMachine while it validates and executes a transaction,
+// which allows to follow how the VM reached its verdict.
+// The events are emitted before the corresponding checks are run, so a failing check is the last one traced.
+type Tracer interface {
+	// OnUnlock is called for every input which was successfully unlocked.
+	OnUnlock(event *UnlockEvent)
+	// OnChainTransition is called for every chain before its state transition is validated.
+	OnChainTransition(event *ChainTransitionEvent)
+	// OnMana is called with the Mana of the inputs and outputs before they are checked to be balanced.
+	OnMana(event *ManaEvent)
+	// OnNativeTokens is called with the sums of the NativeTokens before they are checked to be balanced.
+	OnNativeTokens(event *NativeTokensEvent)
+}
+
+// UnlockEvent describes the unlock of an input.
+type UnlockEvent struct {
+	// The ID of the transaction which unlocked the input.
+	TransactionID iotago.TransactionID
+	// The index of the unlocked input.
+	InputIndex uint16
+	// The address which unlocked the input, after resolving the expiration and restricted addresses.
+	Address iotago.Address
+	// The type of the unlock used for the input.
+	UnlockType iotago.UnlockType
+}
+
+// ChainTransitionEvent describes how a chain was classified.
+type ChainTransitionEvent struct {
+	// The ID of the transaction which transitions the chain.
+	TransactionID iotago.TransactionID
+	// The ID of the chain.
+	ChainID iotago.ChainID
+	// The type of the chain output.
+	OutputType iotago.OutputType
+	// Whether the chain is created, transitioned or destroyed.
+	TransitionType iotago.ChainTransitionType
+}
+
+// InputMana is the Mana of a single input.
+type InputMana struct {
+	// The index of the input.
+	InputIndex uint16
+	// The ID of the input.
+	OutputID iotago.OutputID
+	// The stored Mana, decayed to the creation slot of the transaction.
+	StoredMana iotago.Mana
+	// The potential Mana generated by the base tokens, decayed to the creation slot of the transaction.
+	PotentialMana iotago.Mana
+}
+
+// ManaEvent describes the Mana on the input and output side of a transaction.
+type ManaEvent struct {
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The Mana of the inputs, in the order of the inputs.
+	Inputs []*InputMana
+	// The Mana rewards claimed by the transaction.
+	Rewards RewardsInputSet
+	// The stored Mana of the outputs, in the order of the outputs.
+	Outputs []iotago.Mana
+	// The Mana allotted to accounts.
+	Allotments iotago.Allotments
+	// The total Mana on the input side.
+	TotalIn iotago.Mana
+	// The total Mana on the output side.
+	TotalOut iotago.Mana
+}
+
+// NativeTokensEvent describes the sums of the NativeTokens on the input and output side of a transaction.
+type NativeTokensEvent struct {
+	// The ID of the transaction.
+	TransactionID iotago.TransactionID
+	// The sum of NativeTokens at the input side.
+	In iotago.NativeTokenSum
+	// The sum of NativeTokens at the output side.
+	Out iotago.NativeTokenSum
+}
+
+// unlockEventRecorder is a Tracer which records the UnlockEvents to pass them to another Tracer later.
+type unlockEventRecorder struct {
+	events []*UnlockEvent
+}
+
+func (r *unlockEventRecorder) OnUnlock(event *UnlockEvent) {
+	r.events = append(r.events, event)
+}
+
+func (r *unlockEventRecorder) OnChainTransition(_ *ChainTransitionEvent) {}
+
+func (r *unlockEventRecorder) OnMana(_ *ManaEvent) {}
+
+func (r *unlockEventRecorder) OnNativeTokens(_ *NativeTokensEvent) {}
+
+// replay passes the recorded events to the given Tracer, it does nothing if the recorder is nil.
+func (r *unlockEventRecorder) replay(tracer Tracer) {
+	if r == nil {
+		return
+	}
+
+	for _, event := range r.events {
+		tracer.OnUnlock(event)
+	}
+}
+
+// manaEvent computes the Mana breakdown of the transaction of the given Params.
+func manaEvent(vmParams *Params) (*ManaEvent, error) {
+	manaDecayProvider := vmParams.API.ManaDecayProvider()
+	txCreationSlot := vmParams.WorkingSet.Tx.CreationSlot
+
+	event := &ManaEvent{
+		TransactionID: tracedTransactionID(vmParams),
+		Inputs:        make([]*InputMana, 0, len(vmParams.WorkingSet.UTXOInputs)),
+		Rewards:       vmParams.WorkingSet.Rewards,
+		Outputs:       make([]iotago.Mana, 0, len(vmParams.WorkingSet.Tx.Outputs)),
+		Allotments:    vmParams.WorkingSet.Tx.Allotments,
+		TotalIn:       vmParams.WorkingSet.TotalManaIn,
+		TotalOut:      vmParams.WorkingSet.TotalManaOut,
+	}
+
+	for inputIndex, input := range vmParams.WorkingSet.UTXOInputs {
+		outputID := vmParams.WorkingSet.UTXOInputAtIndex(uint16(inputIndex)).OutputID()
+
+		storedMana, err := manaDecayProvider.DecayManaBySlots(input.StoredMana(), outputID.CreationSlot(), txCreationSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "stored mana calculation failed for input %s", outputID)
+		}
+
+		potentialMana, err := iotago.PotentialMana(manaDecayProvider, vmParams.API.StorageScoreStructure(), input, outputID.CreationSlot(), txCreationSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "input %s potential mana calculation failed", outputID)
+		}
+
+		event.Inputs = append(event.Inputs, &InputMana{
+			InputIndex:    uint16(inputIndex),
+			OutputID:      outputID,
+			StoredMana:    storedMana,
+			PotentialMana: potentialMana,
+		})
+	}
+
+	for _, output := range vmParams.WorkingSet.Tx.Outputs {
+		event.Outputs = append(event.Outputs, output.StoredMana())
+	}
+
+	return event, nil
+}
+
+// tracedTransactionID returns the ID of the transaction of the given Params, which is part of every traced event.
+func tracedTransactionID(vmParams *Params) iotago.TransactionID {
+	txID, err := vmParams.WorkingSet.Tx.ID()
+	if err != nil {
+		panic(fmt.Sprintf("transaction ID computation should have succeeded: %s", err.Error()))
+	}
+
+	return txID
+}
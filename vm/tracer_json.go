@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+// The names of the traced events, as written by the JSONLinesTracer.
+const (
+	TraceEventUnlock          = "unlock"
+	TraceEventChainTransition = "chainTransition"
+	TraceEventMana            = "mana"
+	TraceEventNativeTokens    = "nativeTokens"
+)
+
+// JSONLinesTracer is a Tracer which writes every event as a JSON object on a separate line,
+// so the trace of a transaction can be stored and replayed by auditors.
+// Every event carries the ID of its transaction, so the traces of concurrently validated transactions can be told apart.
+// Amounts are encoded as decimal strings and native token amounts as hex, like in the node API.
+type JSONLinesTracer struct {
+	mutex     sync.Mutex
+	encoder   *json.Encoder
+	bech32HRP iotago.NetworkPrefix
+	err       error
+}
+
+// NewJSONLinesTracer creates a new JSONLinesTracer writing to the given writer.
+// Addresses are encoded in bech32 with the given human-readable part.
+func NewJSONLinesTracer(writer io.Writer, bech32HRP iotago.NetworkPrefix) *JSONLinesTracer {
+	return &JSONLinesTracer{
+		encoder:   json.NewEncoder(writer),
+		bech32HRP: bech32HRP,
+	}
+}
+
+// Err returns the first error that occurred while writing the events.
+// The events after a failed write are dropped.
+func (t *JSONLinesTracer) Err() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.err
+}
+
+type unlockEventJSON struct {
+	Event         string `json:"event"`
+	TransactionID string `json:"transactionId"`
+	InputIndex    uint16 `json:"inputIndex"`
+	Address       string `json:"address"`
+	UnlockType    string `json:"unlockType"`
+}
+
+// OnUnlock writes the given UnlockEvent.
+func (t *JSONLinesTracer) OnUnlock(event *UnlockEvent) {
+	t.write(&unlockEventJSON{
+		Event:         TraceEventUnlock,
+		TransactionID: event.TransactionID.ToHex(),
+		InputIndex:    event.InputIndex,
+		Address:       event.Address.Bech32(t.bech32HRP),
+		UnlockType:    event.UnlockType.String(),
+	})
+}
+
+type chainTransitionEventJSON struct {
+	Event          string `json:"event"`
+	TransactionID  string `json:"transactionId"`
+	ChainID        string `json:"chainId"`
+	OutputType     string `json:"outputType"`
+	TransitionType string `json:"transitionType"`
+}
+
+// OnChainTransition writes the given ChainTransitionEvent.
+func (t *JSONLinesTracer) OnChainTransition(event *ChainTransitionEvent) {
+	t.write(&chainTransitionEventJSON{
+		Event:          TraceEventChainTransition,
+		TransactionID:  event.TransactionID.ToHex(),
+		ChainID:        event.ChainID.ToHex(),
+		OutputType:     event.OutputType.String(),
+		TransitionType: event.TransitionType.String(),
+	})
+}
+
+type inputManaJSON struct {
+	InputIndex    uint16 `json:"inputIndex"`
+	OutputID      string `json:"outputId"`
+	StoredMana    string `json:"storedMana"`
+	PotentialMana string `json:"potentialMana"`
+}
+
+type allotmentJSON struct {
+	AccountID string `json:"accountId"`
+	Mana      string `json:"mana"`
+}
+
+type manaEventJSON struct {
+	Event         string            `json:"event"`
+	TransactionID string            `json:"transactionId"`
+	Inputs        []*inputManaJSON  `json:"inputs"`
+	Rewards       map[string]string `json:"rewards,omitempty"`
+	Outputs       []string          `json:"outputs"`
+	Allotments    []*allotmentJSON  `json:"allotments,omitempty"`
+	TotalIn       string            `json:"totalIn"`
+	TotalOut      string            `json:"totalOut"`
+}
+
+// OnMana writes the given ManaEvent.
+func (t *JSONLinesTracer) OnMana(event *ManaEvent) {
+	line := &manaEventJSON{
+		Event:         TraceEventMana,
+		TransactionID: event.TransactionID.ToHex(),
+		Inputs:        make([]*inputManaJSON, 0, len(event.Inputs)),
+		Outputs:       make([]string, 0, len(event.Outputs)),
+		TotalIn:       formatMana(event.TotalIn),
+		TotalOut:      formatMana(event.TotalOut),
+	}
+
+	for _, input := range event.Inputs {
+		line.Inputs = append(line.Inputs, &inputManaJSON{
+			InputIndex:    input.InputIndex,
+			OutputID:      input.OutputID.ToHex(),
+			StoredMana:    formatMana(input.StoredMana),
+			PotentialMana: formatMana(input.PotentialMana),
+		})
+	}
+
+	if len(event.Rewards) > 0 {
+		line.Rewards = make(map[string]string, len(event.Rewards))
+		for chainID, rewards := range event.Rewards {
+			line.Rewards[chainID.ToHex()] = formatMana(rewards)
+		}
+	}
+
+	for _, outputMana := range event.Outputs {
+		line.Outputs = append(line.Outputs, formatMana(outputMana))
+	}
+
+	for _, allotment := range event.Allotments {
+		line.Allotments = append(line.Allotments, &allotmentJSON{
+			AccountID: allotment.AccountID.ToHex(),
+			Mana:      formatMana(allotment.Mana),
+		})
+	}
+
+	t.write(line)
+}
+
+type nativeTokensEventJSON struct {
+	Event         string            `json:"event"`
+	TransactionID string            `json:"transactionId"`
+	In            map[string]string `json:"in"`
+	Out           map[string]string `json:"out"`
+}
+
+// OnNativeTokens writes the given NativeTokensEvent.
+func (t *JSONLinesTracer) OnNativeTokens(event *NativeTokensEvent) {
+	t.write(&nativeTokensEventJSON{
+		Event:         TraceEventNativeTokens,
+		TransactionID: event.TransactionID.ToHex(),
+		In:            formatNativeTokenSum(event.In),
+		Out:           formatNativeTokenSum(event.Out),
+	})
+}
+
+func (t *JSONLinesTracer) write(line any) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.err != nil {
+		return
+	}
+
+	if err := t.encoder.Encode(line); err != nil {
+		t.err = ierrors.Wrap(err, "failed to write trace event")
+	}
+}
+
+func formatMana(mana iotago.Mana) string {
+	return strconv.FormatUint(uint64(mana), 10)
+}
+
+func formatNativeTokenSum(sum iotago.NativeTokenSum) map[string]string {
+	formatted := make(map[string]string, len(sum))
+	for nativeTokenID, amount := range sum {
+		formatted[nativeTokenID.ToHex()] = hexutil.EncodeUint256(amount)
+	}
+
+	return formatted
+}
@@ -1,7 +1,9 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -27,6 +29,13 @@ type Params struct {
 
 	// The working set which is auto. populated during the semantic validation.
 	WorkingSet *WorkingSet
+
+	// The optional Tracer which receives the events of the execution, nil if the execution is not traced.
+	Tracer Tracer
+
+	// The optional ValidationReport which collects every violation found by the ExecFuncs,
+	// nil if the execution stops at the first violation.
+	Report *ValidationReport
 }
 
 // WorkingSet contains fields which get automatically populated
@@ -161,6 +170,8 @@ type unlockedAddressesSet struct {
 	SignatureUnlockedAddrsByIndex map[uint16]*unlockedAddressWithSignature
 	// UnlockedAddrsByAddrKey contains all unlocked addresses indexed by their address key.
 	UnlockedAddrsByAddrKey UnlockedAddresses
+	// SignatureBatch collects the Ed25519 signatures to verify them in a batch, nil if they are verified immediately.
+	SignatureBatch *iotago.Ed25519BatchVerifier
 }
 
 // UnlockedAddresses defines a set of addresses which are unlocked from the input side of a SignedTransaction.
@@ -170,7 +181,7 @@ type UnlockedAddresses map[string]*UnlockedAddress
 // SignatureUnlock performs a signature unlock check and adds the given address to the set of unlocked addresses if
 // the signature is valid, otherwise returns an error.
 func (s *unlockedAddressesSet) SignatureUnlock(addr iotago.DirectUnlockableAddress, essenceMsg []byte, sig iotago.Signature, inputIndex uint16, checkUnlockOnly bool) error {
-	if err := addr.Unlock(essenceMsg, sig); err != nil {
+	if err := s.verifySignature(addr, essenceMsg, sig); err != nil {
 		return ierrors.Wrapf(err, "input %d's address is not unlocked through its signature unlock", inputIndex)
 	}
 
@@ -194,6 +205,21 @@ func (s *unlockedAddressesSet) SignatureUnlock(addr iotago.DirectUnlockableAddre
 	return nil
 }
 
+// verifySignature verifies that the given signature unlocks the given address.
+// If the signatures are batched, only the type and the public key of an Ed25519 signature are checked,
+// the signature itself is verified later together with the rest of the batch.
+func (s *unlockedAddressesSet) verifySignature(addr iotago.DirectUnlockableAddress, essenceMsg []byte, sig iotago.Signature) error {
+	if s.SignatureBatch != nil {
+		if edSig, isEdSig := sig.(*iotago.Ed25519Signature); isEdSig && edSig.MatchesAddress(addr) {
+			s.SignatureBatch.Add(edSig, essenceMsg)
+
+			return nil
+		}
+	}
+
+	return addr.Unlock(essenceMsg, sig)
+}
+
 // ReferentialUnlockNonDirectlyUnlockable expects a non-directly unlockable address and performs a check whether the given address
 // is unlocked at referencedInputIndex and if so, it adds the input index to the set of unlocked inputs by this address.
 func (s *unlockedAddressesSet) ReferentialUnlockNonDirectlyUnlockable(owner iotago.Address, inputIndex uint16, referencedInputIndex uint16, checkUnlockOnly bool) error {
@@ -422,6 +448,57 @@ type ExecFunc func(vm VirtualMachine, svCtx *Params) error
 // ValidateUnlocks produces the UnlockedAddresses which will be set into the given Params and verifies that inputs are
 // correctly unlocked and that the inputs commitment matches.
 func ValidateUnlocks(signedTransaction *iotago.SignedTransaction, resolvedInputs ResolvedInputs) (unlockedAddrs UnlockedAddresses, err error) {
+	return ValidateUnlocksWithTracer(signedTransaction, resolvedInputs, nil)
+}
+
+// ValidateUnlocksWithTracer works like ValidateUnlocks, but additionally emits an UnlockEvent
+// for every unlocked input to the given Tracer.
+func ValidateUnlocksWithTracer(signedTransaction *iotago.SignedTransaction, resolvedInputs ResolvedInputs, tracer Tracer) (unlockedAddrs UnlockedAddresses, err error) {
+	unlockedAddrs = unlockInputs(signedTransaction, resolvedInputs, tracer, nil, func(_ uint16, unlockErr error) bool {
+		err = unlockErr
+
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unlockedAddrs, nil
+}
+
+// ValidateUnlocksWithSignatureBatch works like ValidateUnlocksWithTracer, but verifies the Ed25519 signatures
+// of the signature unlocks in a single batch, which is faster for transactions with many signature unlocks.
+// The given Tracer can be nil, the UnlockEvents are only emitted once the batch was verified.
+//
+// If an unlock is invalid, the unlocks are validated again one by one to return the same error as ValidateUnlocksWithTracer,
+// so an invalid transaction takes longer to validate than without the batch.
+func ValidateUnlocksWithSignatureBatch(signedTransaction *iotago.SignedTransaction, resolvedInputs ResolvedInputs, tracer Tracer) (unlockedAddrs UnlockedAddresses, err error) {
+	var recorder *unlockEventRecorder
+	var batchTracer Tracer
+	if tracer != nil {
+		recorder = &unlockEventRecorder{}
+		batchTracer = recorder
+	}
+
+	signatureBatch := iotago.NewEd25519BatchVerifier()
+	unlockedAddrs = unlockInputs(signedTransaction, resolvedInputs, batchTracer, signatureBatch, func(_ uint16, _ error) bool {
+		return false
+	})
+	if unlockedAddrs != nil && len(signatureBatch.Verify()) == 0 {
+		recorder.replay(tracer)
+
+		return unlockedAddrs, nil
+	}
+
+	return ValidateUnlocksWithTracer(signedTransaction, resolvedInputs, tracer)
+}
+
+// unlockInputs unlocks the inputs of the given SignedTransaction and returns the unlocked addresses.
+// If an input can not be unlocked, the given function is called with the error and decides whether to continue
+// with the next input, in which case the failed input doesn't unlock any address.
+// The successful unlocks are emitted to the given Tracer, if it is not nil.
+// If a signature batch is given, the Ed25519 signatures are not verified, but added to the batch instead.
+func unlockInputs(signedTransaction *iotago.SignedTransaction, resolvedInputs ResolvedInputs, tracer Tracer, signatureBatch *iotago.Ed25519BatchVerifier, onUnlockFailed func(inputIndex uint16, err error) bool) UnlockedAddresses {
 	utxoInputs := signedTransaction.Transaction.Inputs()
 
 	var inputs iotago.Outputs[iotago.Output]
@@ -442,12 +519,27 @@ func ValidateUnlocks(signedTransaction *iotago.SignedTransaction, resolvedInputs
 	unlockedAddrsSet := &unlockedAddressesSet{
 		SignatureUnlockedAddrsByIndex: make(map[uint16]*unlockedAddressWithSignature),
 		UnlockedAddrsByAddrKey:        make(UnlockedAddresses),
+		SignatureBatch:                signatureBatch,
 	}
 
 	outChains := signedTransaction.Transaction.Outputs.ChainOutputSet(txID)
 	for inputIndex, input := range inputs {
-		if err = unlockOutput(signedTransaction.Transaction, resolvedInputs.CommitmentInput, input, signedTransaction.Unlocks[inputIndex], uint16(inputIndex), unlockedAddrsSet, outChains, essenceMsgToSign); err != nil {
-			return nil, err
+		unlockedAddr, err := unlockOutput(signedTransaction.Transaction, resolvedInputs.CommitmentInput, input, signedTransaction.Unlocks[inputIndex], uint16(inputIndex), unlockedAddrsSet, outChains, essenceMsgToSign)
+		if err != nil {
+			if !onUnlockFailed(uint16(inputIndex), err) {
+				return nil
+			}
+
+			continue
+		}
+
+		if tracer != nil {
+			tracer.OnUnlock(&UnlockEvent{
+				TransactionID: txID,
+				InputIndex:    uint16(inputIndex),
+				Address:       unlockedAddr,
+				UnlockType:    signedTransaction.Unlocks[inputIndex].Type(),
+			})
 		}
 
 		// since this input is now unlocked, and it is a ChainOutput, the chain's address becomes automatically unlocked
@@ -477,7 +569,7 @@ func ValidateUnlocks(signedTransaction *iotago.SignedTransaction, resolvedInputs
 		}
 	}
 
-	return unlockedAddrsSet.UnlockedAddrsByAddrKey, err
+	return unlockedAddrsSet.UnlockedAddrsByAddrKey
 }
 
 func addressToUnlock(transaction *iotago.Transaction, input iotago.Output, inputIndex uint16, outChains iotago.ChainOutputSet) (iotago.Address, error) {
@@ -644,19 +736,25 @@ func resolveUnderlyingAddress(addr iotago.Address) iotago.Address {
 	}
 }
 
-func unlockOutput(transaction *iotago.Transaction, commitmentInput VMCommitmentInput, input iotago.Output, unlock iotago.Unlock, inputIndex uint16, unlockedAddrsSet *unlockedAddressesSet, outChains iotago.ChainOutputSet, essenceMsgToSign []byte) error {
+// unlockOutput unlocks the given input and returns the address which unlocked it.
+func unlockOutput(transaction *iotago.Transaction, commitmentInput VMCommitmentInput, input iotago.Output, unlock iotago.Unlock, inputIndex uint16, unlockedAddrsSet *unlockedAddressesSet, outChains iotago.ChainOutputSet, essenceMsgToSign []byte) (iotago.Address, error) {
 	ownerAddr, err := addressToUnlock(transaction, input, inputIndex, outChains)
 	if err != nil {
-		return ierrors.Wrapf(err, "unable to retrieve address to unlock of input %d", inputIndex)
+		return nil, ierrors.Wrapf(err, "unable to retrieve address to unlock of input %d", inputIndex)
 	}
 
 	if actualAddrToUnlock, err := checkExpiration(input, commitmentInput, transaction.API.ProtocolParameters()); err != nil {
-		return err
+		return nil, err
 	} else if actualAddrToUnlock != nil {
 		ownerAddr = actualAddrToUnlock
 	}
 
-	return unlockAddress(resolveUnderlyingAddress(ownerAddr), unlock, inputIndex, unlockedAddrsSet, essenceMsgToSign, false)
+	ownerAddr = resolveUnderlyingAddress(ownerAddr)
+	if err := unlockAddress(ownerAddr, unlock, inputIndex, unlockedAddrsSet, essenceMsgToSign, false); err != nil {
+		return nil, err
+	}
+
+	return ownerAddr, nil
 }
 
 // ExecFuncSenderUnlocked validates that for SenderFeature occurring on the output side,
@@ -672,7 +770,9 @@ func ExecFuncSenderUnlocked() ExecFunc {
 			// check unlocked
 			sender := resolveUnderlyingAddress(senderFeat.Address)
 			if _, isUnlocked := vmParams.WorkingSet.UnlockedAddrs[sender.Key()]; !isUnlocked {
-				return ierrors.Wrapf(iotago.ErrSenderFeatureNotUnlocked, "output %d", outputIndex)
+				if err := vmParams.violation(CheckSenders, ierrors.Wrapf(iotago.ErrSenderFeatureNotUnlocked, "output %d", outputIndex), atOutput(uint16(outputIndex)), forAddress(vmParams, senderFeat.Address)); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -683,10 +783,21 @@ func ExecFuncSenderUnlocked() ExecFunc {
 // ExecFuncBalancedMana validates that Mana is balanced from the input/output side.
 func ExecFuncBalancedMana() ExecFunc {
 	return func(_ VirtualMachine, vmParams *Params) error {
+		if vmParams.Tracer != nil {
+			event, err := manaEvent(vmParams)
+			if err != nil {
+				return vmParams.violation(CheckMana, err)
+			}
+			vmParams.Tracer.OnMana(event)
+		}
+
 		txCreationSlot := vmParams.WorkingSet.Tx.CreationSlot
-		for outputID := range vmParams.WorkingSet.UTXOInputsSet {
+		for inputIndex := range vmParams.WorkingSet.Tx.TransactionEssence.Inputs {
+			outputID := vmParams.WorkingSet.UTXOInputAtIndex(uint16(inputIndex)).OutputID()
 			if outputID.CreationSlot() > txCreationSlot {
-				return ierrors.WithMessagef(iotago.ErrInputCreationAfterTxCreation, "input %s has creation slot %d, tx creation slot %d", outputID, outputID.CreationSlot(), txCreationSlot)
+				if err := vmParams.violation(CheckMana, ierrors.WithMessagef(iotago.ErrInputCreationAfterTxCreation, "input %s has creation slot %d, tx creation slot %d", outputID, outputID.CreationSlot(), txCreationSlot), atInput(uint16(inputIndex)), compared(txCreationSlot, outputID.CreationSlot())); err != nil {
+					return err
+				}
 			}
 		}
 		manaIn := vmParams.WorkingSet.TotalManaIn
@@ -694,11 +805,11 @@ func ExecFuncBalancedMana() ExecFunc {
 
 		if manaIn < manaOut {
 			// less mana on input side than on output side => not allowed
-			return ierrors.WithMessagef(iotago.ErrInputOutputManaMismatch, "Mana in %d, Mana out %d", manaIn, manaOut)
+			return vmParams.violation(CheckMana, ierrors.WithMessagef(iotago.ErrInputOutputManaMismatch, "Mana in %d, Mana out %d", manaIn, manaOut), compared(manaIn, manaOut))
 		} else if manaIn > manaOut {
 			// less mana on output side than on input side => check if mana burning is allowed
 			if vmParams.WorkingSet.Tx.Capabilities.CannotBurnMana() {
-				return ierrors.Chain(iotago.ErrInputOutputManaMismatch, ierrors.WithMessagef(iotago.ErrTxCapabilitiesManaBurningNotAllowed, "Mana in %d, Mana out %d", manaIn, manaOut))
+				return vmParams.violation(CheckMana, ierrors.Chain(iotago.ErrInputOutputManaMismatch, ierrors.WithMessagef(iotago.ErrTxCapabilitiesManaBurningNotAllowed, "Mana in %d, Mana out %d", manaIn, manaOut)), compared(manaIn, manaOut))
 			}
 		}
 
@@ -714,8 +825,9 @@ func ExecFuncBalancedBaseTokens() ExecFunc {
 		// note that due to syntactic validation of outputs, input and output base token amount sums
 		// are always within bounds of the total token supply
 		var in, out iotago.BaseToken
+		returnAddresses := make(map[string]iotago.Address)
 		inputSumReturnAmountPerAddress := make(map[string]iotago.BaseToken)
-		for inputID, input := range vmParams.WorkingSet.UTXOInputsSet {
+		for inputIndex, input := range vmParams.WorkingSet.UTXOInputs {
 			in += input.BaseTokenAmount()
 
 			returnUnlockCond := input.UnlockConditionSet().StorageDepositReturn()
@@ -727,10 +839,11 @@ func ExecFuncBalancedBaseTokens() ExecFunc {
 
 			// if the return address unlocked this input, then the return amount does
 			// not have to be fulfilled (this can happen implicit through an expiration condition)
-			if vmParams.WorkingSet.UnlockedAddrs.UnlockedBy(vmParams.WorkingSet.InputIDToInputIndex[inputID], returnAddr) {
+			if vmParams.WorkingSet.UnlockedAddrs.UnlockedBy(uint16(inputIndex), returnAddr) {
 				continue
 			}
 
+			returnAddresses[returnAddr] = returnUnlockCond.ReturnAddress
 			inputSumReturnAmountPerAddress[returnAddr] += returnUnlockCond.Amount
 		}
 
@@ -746,16 +859,34 @@ func ExecFuncBalancedBaseTokens() ExecFunc {
 		}
 
 		if in != out {
-			return ierrors.WithMessagef(iotago.ErrInputOutputBaseTokenMismatch, "in %d, out %d", in, out)
+			if err := vmParams.violation(CheckBaseTokens, ierrors.WithMessagef(iotago.ErrInputOutputBaseTokenMismatch, "in %d, out %d", in, out), compared(in, out)); err != nil {
+				return err
+			}
 		}
 
-		for addr, returnSum := range inputSumReturnAmountPerAddress {
+		// the return addresses are checked in a deterministic order
+		returnAddrKeys := make([]string, 0, len(inputSumReturnAmountPerAddress))
+		for returnAddr := range inputSumReturnAmountPerAddress {
+			returnAddrKeys = append(returnAddrKeys, returnAddr)
+		}
+		slices.Sort(returnAddrKeys)
+
+		for _, addr := range returnAddrKeys {
+			returnSum := inputSumReturnAmountPerAddress[addr]
+
+			var err error
 			outSum, has := outputSimpleTransfersPerAddr[addr]
-			if !has {
-				return ierrors.WithMessagef(iotago.ErrReturnAmountNotFulFilled, "return amount of %d not fulfilled as there is no output for address (serialized) %s", returnSum, hexutil.EncodeHex([]byte(addr)))
+			switch {
+			case !has:
+				err = ierrors.WithMessagef(iotago.ErrReturnAmountNotFulFilled, "return amount of %d not fulfilled as there is no output for address (serialized) %s", returnSum, hexutil.EncodeHex([]byte(addr)))
+			case outSum < returnSum:
+				err = ierrors.WithMessagef(iotago.ErrReturnAmountNotFulFilled, "return amount of %d not fulfilled as output is only %d for address (serialized) %s", returnSum, outSum, hexutil.EncodeHex([]byte(addr)))
+			default:
+				continue
 			}
-			if outSum < returnSum {
-				return ierrors.WithMessagef(iotago.ErrReturnAmountNotFulFilled, "return amount of %d not fulfilled as output is only %d for address (serialized) %s", returnSum, outSum, hexutil.EncodeHex([]byte(addr)))
+
+			if err := vmParams.violation(CheckBaseTokens, err, compared(returnSum, outSum), forAddress(vmParams, returnAddresses[addr])); err != nil {
+				return err
 			}
 		}
 
@@ -766,16 +897,26 @@ func ExecFuncBalancedBaseTokens() ExecFunc {
 // ExecFuncTimelocks validates that the inputs' timelocks are expired.
 func ExecFuncTimelocks() ExecFunc {
 	return func(_ VirtualMachine, vmParams *Params) error {
-		for inputID, input := range vmParams.WorkingSet.UTXOInputsSet {
-			if input.UnlockConditionSet().HasTimelockCondition() {
-				commitment := vmParams.WorkingSet.Commitment
+		for inputIndex, input := range vmParams.WorkingSet.UTXOInputs {
+			timelock := input.UnlockConditionSet().Timelock()
+			if timelock == nil {
+				continue
+			}
 
-				if commitment == nil {
-					return iotago.ErrTimelockCommitmentInputMissing
+			commitment := vmParams.WorkingSet.Commitment
+			if commitment == nil {
+				if err := vmParams.violation(CheckTimelocks, iotago.ErrTimelockCommitmentInputMissing, atInput(uint16(inputIndex))); err != nil {
+					return err
 				}
-				futureBoundedIndex := vmParams.FutureBoundedSlotIndex(commitment.Slot)
-				if err := input.UnlockConditionSet().TimelocksExpired(futureBoundedIndex); err != nil {
-					return ierrors.Wrapf(err, "timelock of input %s is not expired", inputID.ToHex())
+
+				continue
+			}
+
+			futureBoundedIndex := vmParams.FutureBoundedSlotIndex(commitment.Slot)
+			if err := input.UnlockConditionSet().TimelocksExpired(futureBoundedIndex); err != nil {
+				inputID := vmParams.WorkingSet.UTXOInputAtIndex(uint16(inputIndex)).OutputID()
+				if err := vmParams.violation(CheckTimelocks, ierrors.Wrapf(err, "timelock of input %s is not expired", inputID.ToHex()), atInput(uint16(inputIndex)), compared(timelock.Slot, futureBoundedIndex)); err != nil {
+					return err
 				}
 			}
 		}
@@ -785,29 +926,73 @@ func ExecFuncTimelocks() ExecFunc {
 }
 
 // ExecFuncChainTransitions executes state transition validation functions on ChainOutput(s).
+// The chains of the inputs are validated in the order of the inputs, the new chains in the order of the outputs.
 func ExecFuncChainTransitions() ExecFunc {
 	return func(vm VirtualMachine, vmParams *Params) error {
-		for chainID, inputChain := range vmParams.WorkingSet.InChains {
+		txID, err := vmParams.WorkingSet.Tx.ID()
+		if err != nil {
+			panic(fmt.Sprintf("transaction ID computation should have succeeded: %s", err.Error()))
+		}
+
+		outputIndexes := make(map[iotago.ChainID]uint16, len(vmParams.WorkingSet.OutChains))
+		outputChainIDs := make([]iotago.ChainID, 0, len(vmParams.WorkingSet.OutChains))
+		for outputIndex, output := range vmParams.WorkingSet.Tx.Outputs {
+			chainOutput, isChain := output.(iotago.ChainOutput)
+			if !isChain {
+				continue
+			}
+
+			chainID := chainOutput.ChainID()
+			if utxoIDChainID, isUTXOIDChainID := chainID.(iotago.UTXOIDChainID); isUTXOIDChainID && chainID.Empty() {
+				chainID = utxoIDChainID.FromOutputID(iotago.OutputIDFromTransactionIDAndIndex(txID, uint16(outputIndex)))
+			}
+			outputIndexes[chainID] = uint16(outputIndex)
+			outputChainIDs = append(outputChainIDs, chainID)
+		}
+
+		inputChains := make([]*ChainOutputWithIDs, 0, len(vmParams.WorkingSet.InChains))
+		for _, inputChain := range vmParams.WorkingSet.InChains {
+			inputChains = append(inputChains, inputChain)
+		}
+		slices.SortFunc(inputChains, func(a *ChainOutputWithIDs, b *ChainOutputWithIDs) int {
+			return int(vmParams.WorkingSet.InputIDToInputIndex[a.OutputID]) - int(vmParams.WorkingSet.InputIDToInputIndex[b.OutputID])
+		})
+
+		for _, inputChain := range inputChains {
+			chainID := inputChain.ChainID
+			inputIndex := vmParams.WorkingSet.InputIDToInputIndex[inputChain.OutputID]
+
 			next := vmParams.WorkingSet.OutChains[chainID]
 			if next == nil {
+				traceChainTransition(vmParams, chainID, inputChain.Output, iotago.ChainTransitionTypeDestroy)
 				if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeDestroy, inputChain, nil); err != nil {
-					return ierrors.Wrapf(err, "invalid destruction for %s %s", inputChain.Output.Type(), chainID)
+					if err := vmParams.violation(CheckChainTransitions, ierrors.Wrapf(err, "invalid destruction for %s %s", inputChain.Output.Type(), chainID), atInput(inputIndex), forChain(chainID)); err != nil {
+						return err
+					}
 				}
 
 				continue
 			}
+
+			traceChainTransition(vmParams, chainID, inputChain.Output, iotago.ChainTransitionTypeStateChange)
 			if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeStateChange, inputChain, next); err != nil {
-				return ierrors.Wrapf(err, "invalid transition for %s %s", inputChain.Output.Type(), chainID)
+				if err := vmParams.violation(CheckChainTransitions, ierrors.Wrapf(err, "invalid transition for %s %s", inputChain.Output.Type(), chainID), atInput(inputIndex), atOutput(outputIndexes[chainID]), forChain(chainID)); err != nil {
+					return err
+				}
 			}
 		}
 
-		for chainID, outputChain := range vmParams.WorkingSet.OutChains {
+		for _, chainID := range outputChainIDs {
 			if _, chainPresentInInputs := vmParams.WorkingSet.InChains[chainID]; chainPresentInInputs {
 				continue
 			}
 
+			outputChain := vmParams.WorkingSet.OutChains[chainID]
+			traceChainTransition(vmParams, chainID, outputChain, iotago.ChainTransitionTypeGenesis)
 			if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeGenesis, nil, outputChain); err != nil {
-				return ierrors.Wrapf(err, "invalid creation of %s %s", outputChain.Type(), chainID)
+				if err := vmParams.violation(CheckChainTransitions, ierrors.Wrapf(err, "invalid creation of %s %s", outputChain.Type(), chainID), atOutput(outputIndexes[chainID]), forChain(chainID)); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -815,6 +1000,20 @@ func ExecFuncChainTransitions() ExecFunc {
 	}
 }
 
+// traceChainTransition emits a ChainTransitionEvent to the Tracer of the given Params, if it is set.
+func traceChainTransition(vmParams *Params, chainID iotago.ChainID, chainOutput iotago.ChainOutput, transType iotago.ChainTransitionType) {
+	if vmParams.Tracer == nil {
+		return
+	}
+
+	vmParams.Tracer.OnChainTransition(&ChainTransitionEvent{
+		TransactionID:  tracedTransactionID(vmParams),
+		ChainID:        chainID,
+		OutputType:     chainOutput.Type(),
+		TransitionType: transType,
+	})
+}
+
 // ExecFuncBalancedNativeTokens validates following rules regarding NativeTokens:
 //   - The NativeTokens between Inputs / Outputs must be balanced or have a deficit on the output side if there is no foundry state transition for a given NativeToken.
 //   - Max MaxNativeTokensCount native tokens within inputs + outputs
@@ -824,41 +1023,65 @@ func ExecFuncBalancedNativeTokens() ExecFunc {
 		var err error
 		vmParams.WorkingSet.InNativeTokens, err = vmParams.WorkingSet.UTXOInputs.NativeTokenSum()
 		if err != nil {
-			return ierrors.WithMessagef(iotago.ErrNativeTokenSetInvalid, "invalid input native token set: %w", err)
+			return vmParams.violation(CheckNativeTokens, ierrors.WithMessagef(iotago.ErrNativeTokenSetInvalid, "invalid input native token set: %w", err))
 		}
 
 		vmParams.WorkingSet.OutNativeTokens, err = vmParams.WorkingSet.Tx.Outputs.NativeTokenSum()
 		if err != nil {
-			return ierrors.Chain(iotago.ErrNativeTokenSetInvalid, err)
+			return vmParams.violation(CheckNativeTokens, ierrors.Chain(iotago.ErrNativeTokenSetInvalid, err))
+		}
+
+		if vmParams.Tracer != nil {
+			vmParams.Tracer.OnNativeTokens(&NativeTokensEvent{
+				TransactionID: tracedTransactionID(vmParams),
+				In:            vmParams.WorkingSet.InNativeTokens,
+				Out:           vmParams.WorkingSet.OutNativeTokens,
+			})
 		}
 
-		// check invariants for when token foundry is absent
+		// check invariants for when token foundry is absent, first for the native tokens on the input side
+		// and then for the ones only residing on the output side, each in a deterministic order
+		inNativeTokenIDs := make([]iotago.NativeTokenID, 0, len(vmParams.WorkingSet.InNativeTokens))
+		for nativeTokenID := range vmParams.WorkingSet.InNativeTokens {
+			inNativeTokenIDs = append(inNativeTokenIDs, nativeTokenID)
+		}
+		outOnlyNativeTokenIDs := make([]iotago.NativeTokenID, 0, len(vmParams.WorkingSet.OutNativeTokens))
+		for nativeTokenID := range vmParams.WorkingSet.OutNativeTokens {
+			if _, isInput := vmParams.WorkingSet.InNativeTokens[nativeTokenID]; !isInput {
+				outOnlyNativeTokenIDs = append(outOnlyNativeTokenIDs, nativeTokenID)
+			}
+		}
+		compareNativeTokenIDs := func(a iotago.NativeTokenID, b iotago.NativeTokenID) int {
+			return bytes.Compare(a[:], b[:])
+		}
+		slices.SortFunc(inNativeTokenIDs, compareNativeTokenIDs)
+		slices.SortFunc(outOnlyNativeTokenIDs, compareNativeTokenIDs)
 
-		for nativeTokenID, inSum := range vmParams.WorkingSet.InNativeTokens {
+		for _, nativeTokenID := range append(inNativeTokenIDs, outOnlyNativeTokenIDs...) {
 			if _, foundryIsTransitioning := vmParams.WorkingSet.OutChains[nativeTokenID]; foundryIsTransitioning {
 				continue
 			}
 
-			outSum := vmParams.WorkingSet.OutNativeTokens[nativeTokenID]
+			inSum, outSum := vmParams.WorkingSet.InNativeTokens[nativeTokenID], vmParams.WorkingSet.OutNativeTokens[nativeTokenID]
 
-			if vmParams.WorkingSet.Tx.Capabilities.CannotBurnNativeTokens() && (outSum == nil || inSum.Cmp(outSum) != 0) {
+			var err error
+			switch {
+			case inSum == nil:
+				// foundry must be present when native tokens only reside on the output side
+				// as they need to get minted by it within the tx
+				err = ierrors.WithMessagef(iotago.ErrNativeTokenSumUnbalanced, "native token %s is new on the output side but the foundry is not transitioning", nativeTokenID)
+			case vmParams.WorkingSet.Tx.Capabilities.CannotBurnNativeTokens() && (outSum == nil || inSum.Cmp(outSum) != 0):
 				// if burning is not allowed, the input sum must be equal to the output sum
-				return ierrors.WithMessagef(iotago.ErrTxCapabilitiesNativeTokenBurningNotAllowed, "%w: native token %s is less on output (%d) than input (%d) side but burning is not allowed in the transaction and the foundry is absent for melting", iotago.ErrNativeTokenSumUnbalanced, nativeTokenID, outSum, inSum)
-			} else if (outSum != nil) && (inSum.Cmp(outSum) == -1) {
+				err = ierrors.WithMessagef(iotago.ErrTxCapabilitiesNativeTokenBurningNotAllowed, "%w: native token %s is less on output (%d) than input (%d) side but burning is not allowed in the transaction and the foundry is absent for melting", iotago.ErrNativeTokenSumUnbalanced, nativeTokenID, outSum, inSum)
+			case (outSum != nil) && (inSum.Cmp(outSum) == -1):
 				// input sum must be greater equal the output sum (burning allows it to be greater)
-				return ierrors.WithMessagef(iotago.ErrNativeTokenSumUnbalanced, "native token %s is less on input (%d) than output (%d) side but the foundry is absent for minting", nativeTokenID, inSum, outSum)
-			}
-		}
-
-		for nativeTokenID := range vmParams.WorkingSet.OutNativeTokens {
-			if _, foundryIsTransitioning := vmParams.WorkingSet.OutChains[nativeTokenID]; foundryIsTransitioning {
+				err = ierrors.WithMessagef(iotago.ErrNativeTokenSumUnbalanced, "native token %s is less on input (%d) than output (%d) side but the foundry is absent for minting", nativeTokenID, inSum, outSum)
+			default:
 				continue
 			}
 
-			// foundry must be present when native tokens only reside on the output side
-			// as they need to get minted by it within the tx
-			if vmParams.WorkingSet.InNativeTokens[nativeTokenID] == nil {
-				return ierrors.WithMessagef(iotago.ErrNativeTokenSumUnbalanced, "native token %s is new on the output side but the foundry is not transitioning", nativeTokenID)
+			if err := vmParams.violation(CheckNativeTokens, err, compared(orZero(inSum), orZero(outSum)), forNativeToken(nativeTokenID)); err != nil {
+				return err
 			}
 		}
 
@@ -873,12 +1096,14 @@ func ExecFuncBalancedNativeTokens() ExecFunc {
 func ExecFuncAtMostOneImplicitAccountCreationAddress() ExecFunc {
 	return func(_ VirtualMachine, vmParams *Params) error {
 		transactionHasImplicitAccountCreationAddress := false
-		for _, input := range vmParams.WorkingSet.UTXOInputs {
+		for inputIndex, input := range vmParams.WorkingSet.UTXOInputs {
 			addressUnlockCondition := input.UnlockConditionSet().Address()
 			if input.Type() == iotago.OutputBasic && addressUnlockCondition != nil {
 				if addressUnlockCondition.Address.Type() == iotago.AddressImplicitAccountCreation {
 					if transactionHasImplicitAccountCreationAddress {
-						return iotago.ErrMultipleImplicitAccountCreationAddresses
+						if err := vmParams.violation(CheckImplicitAccounts, iotago.ErrMultipleImplicitAccountCreationAddresses, atInput(uint16(inputIndex))); err != nil {
+							return err
+						}
 					}
 					transactionHasImplicitAccountCreationAddress = true
 				}
@@ -0,0 +1,197 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+)
+
+const (
+	// DefaultDiscoveryGapLimit is the number of consecutive unused addresses after which the discovery stops.
+	DefaultDiscoveryGapLimit = 20
+
+	// ChangeExternal is the change index of the addresses that are handed out to receive funds.
+	ChangeExternal uint32 = 0
+	// ChangeInternal is the change index of the addresses that are used for remainders.
+	ChangeInternal uint32 = 1
+)
+
+// DerivedAddress is an address derived by the KeyManager.
+type DerivedAddress struct {
+	// The derivation indexes of the address.
+	Account      uint32
+	Change       uint32
+	AddressIndex uint32
+	// The derived address.
+	Address iotago.DirectUnlockableAddress
+}
+
+// DiscoveredAddress is an address derived by the KeyManager that owns outputs on the ledger.
+type DiscoveredAddress struct {
+	DerivedAddress
+	// Outputs are all outputs that can currently be unlocked by the address.
+	Outputs []*builder.TxInput
+}
+
+// AccountOutputs returns the account outputs that can be unlocked by the address.
+func (d *DiscoveredAddress) AccountOutputs() []*builder.TxInput {
+	return d.outputsOfType(iotago.OutputAccount)
+}
+
+// NFTOutputs returns the NFT outputs that can be unlocked by the address.
+func (d *DiscoveredAddress) NFTOutputs() []*builder.TxInput {
+	return d.outputsOfType(iotago.OutputNFT)
+}
+
+// BasicOutputs returns the basic outputs that can be unlocked by the address.
+func (d *DiscoveredAddress) BasicOutputs() []*builder.TxInput {
+	return d.outputsOfType(iotago.OutputBasic)
+}
+
+func (d *DiscoveredAddress) outputsOfType(outputType iotago.OutputType) []*builder.TxInput {
+	outputs := make([]*builder.TxInput, 0)
+	for _, output := range d.Outputs {
+		if output.Input.Type() == outputType {
+			outputs = append(outputs, output)
+		}
+	}
+
+	return outputs
+}
+
+// DiscoveryOptions define the options used to discover the addresses of a KeyManager.
+type DiscoveryOptions struct {
+	gapLimit      uint32
+	account       uint32
+	changes       []uint32
+	addressTypes  []iotago.AddressType
+	startingIndex uint32
+}
+
+// WithDiscoveryGapLimit sets the number of consecutive unused addresses after which the discovery stops.
+func WithDiscoveryGapLimit(gapLimit uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.gapLimit = gapLimit
+	}
+}
+
+// WithDiscoveryAccount sets the account index of the derivation path the addresses are discovered for.
+func WithDiscoveryAccount(account uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.account = account
+	}
+}
+
+// WithDiscoveryChanges sets the change indexes of the derivation path the addresses are discovered for.
+func WithDiscoveryChanges(changes ...uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.changes = changes
+	}
+}
+
+// WithDiscoveryAddressTypes sets the address types that are discovered.
+// Only iotago.AddressEd25519 and iotago.AddressImplicitAccountCreation are supported.
+func WithDiscoveryAddressTypes(addressTypes ...iotago.AddressType) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.addressTypes = addressTypes
+	}
+}
+
+// WithDiscoveryStartingIndex sets the address index the discovery starts at.
+func WithDiscoveryStartingIndex(startingIndex uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.startingIndex = startingIndex
+	}
+}
+
+// DiscoverAddresses walks the address indexes of the KeyManager and queries the indexer for the outputs
+// that can be unlocked by the derived addresses. The discovery of an address type and change index stops
+// after the gap limit of consecutive addresses without any outputs is reached.
+// By default, Ed25519 and implicit account creation addresses are discovered for the external and internal
+// addresses of account 0 with a gap limit of DefaultDiscoveryGapLimit.
+// Only the addresses that own outputs are returned.
+func DiscoverAddresses(ctx context.Context, indexer nodeclient.IndexerClient, keyManager *KeyManager, hrp iotago.NetworkPrefix, opts ...options.Option[DiscoveryOptions]) ([]*DiscoveredAddress, error) {
+	discoveryOptions := options.Apply(&DiscoveryOptions{
+		gapLimit:     DefaultDiscoveryGapLimit,
+		changes:      []uint32{ChangeExternal, ChangeInternal},
+		addressTypes: []iotago.AddressType{iotago.AddressEd25519, iotago.AddressImplicitAccountCreation},
+	}, opts)
+
+	if discoveryOptions.gapLimit == 0 {
+		return nil, ierrors.New("gap limit must be greater than zero")
+	}
+
+	discovered := make([]*DiscoveredAddress, 0)
+	for _, addressType := range discoveryOptions.addressTypes {
+		for _, change := range discoveryOptions.changes {
+			var unusedAddresses uint32
+			for addressIndex := discoveryOptions.startingIndex; unusedAddresses < discoveryOptions.gapLimit; addressIndex++ {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
+				addr, err := keyManager.AddressAt(addressType, discoveryOptions.account, change, addressIndex)
+				if err != nil {
+					return nil, err
+				}
+
+				outputs, _, err := queryUnlockableOutputs(ctx, indexer, addr, hrp)
+				if err != nil {
+					return nil, ierrors.Wrapf(err, "failed to query the outputs of address %s", addr.Bech32(hrp))
+				}
+
+				if len(outputs) == 0 {
+					unusedAddresses++
+
+					continue
+				}
+				unusedAddresses = 0
+
+				discovered = append(discovered, &DiscoveredAddress{
+					DerivedAddress: DerivedAddress{
+						Account:      discoveryOptions.account,
+						Change:       change,
+						AddressIndex: addressIndex,
+						Address:      addr,
+					},
+					Outputs: outputs,
+				})
+			}
+		}
+	}
+
+	return discovered, nil
+}
+
+// queryUnlockableOutputs queries the indexer for all outputs that can currently be unlocked by the given address.
+// It also returns the slot of the commitment the indexer answered the query with.
+func queryUnlockableOutputs(ctx context.Context, indexer nodeclient.IndexerClient, addr iotago.Address, hrp iotago.NetworkPrefix) ([]*builder.TxInput, iotago.SlotIndex, error) {
+	resultSet, err := indexer.Outputs(ctx, &api.OutputsQuery{
+		IndexerUnlockableByAddressParams: api.IndexerUnlockableByAddressParams{
+			UnlockableByAddressBech32: addr.Bech32(hrp),
+		},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	outputs := make([]*builder.TxInput, 0)
+	for resultSet.Next() {
+		txInputs, err := resultSet.TxInputs(ctx, addr)
+		if err != nil {
+			return nil, 0, err
+		}
+		outputs = append(outputs, txInputs...)
+	}
+	if resultSet.Error != nil {
+		return nil, 0, resultSet.Error
+	}
+
+	// the last response is kept, even if it contained no results
+	return outputs, resultSet.Response.CommittedSlot, nil
+}
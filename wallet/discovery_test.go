@@ -0,0 +1,193 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+const nodeAPIUrl = "http://127.0.0.1:14265"
+
+var mockAPI = iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+
+func mockGetJSON(route string, body interface{}, params map[string]string, persist ...bool) {
+	m := gock.New(nodeAPIUrl).
+		Get(route).
+		MatchParams(params)
+
+	if len(persist) > 0 && persist[0] {
+		m.Persist()
+	}
+
+	m.Reply(200).
+		SetHeader("Content-Type", api.MIMEApplicationJSON).
+		BodyString(string(lo.PanicOnErr(mockAPI.JSONEncode(body))))
+}
+
+func mockGetBinary(route string, body interface{}) {
+	gock.New(nodeAPIUrl).
+		Get(route).
+		MatchHeader("Accept", api.MIMEApplicationVendorIOTASerializerV2).
+		Reply(200).
+		SetHeader("Content-Type", api.MIMEApplicationVendorIOTASerializerV2).
+		BodyString(string(lo.PanicOnErr(mockAPI.Encode(body))))
+}
+
+// mockNodeClient returns a client for a mocked node which supports the indexer plugin.
+//
+//nolint:thelper
+func mockNodeClient(t *testing.T) *nodeclient.Client {
+	ts := time.Now()
+	mockGetJSON(api.CoreRouteInfo, &api.InfoResponse{
+		Name:    "iota-core",
+		Version: "1.0.0",
+		Status: &api.InfoResNodeStatus{
+			IsHealthy:                   true,
+			IsNetworkHealthy:            true,
+			AcceptedTangleTime:          ts,
+			RelativeAcceptedTangleTime:  ts,
+			ConfirmedTangleTime:         ts,
+			RelativeConfirmedTangleTime: ts,
+			LatestCommitmentID:          tpkg.Rand36ByteArray(),
+		},
+		ProtocolParameters: []*api.InfoResProtocolParameters{
+			{
+				StartEpoch: 0,
+				Parameters: tpkg.IOTAMainnetV3TestProtocolParameters,
+			},
+		},
+		BaseToken: &api.InfoResBaseToken{
+			Name:         "TestCoin",
+			TickerSymbol: "TEST",
+			Unit:         "TEST",
+			Subunit:      "testies",
+			Decimals:     6,
+		},
+	}, nil)
+	mockGetJSON(api.RouteRoutes, &api.RoutesResponse{
+		Routes: []iotago.PrefixedStringUint8{api.IndexerPluginName},
+	}, nil, true)
+
+	client, err := nodeclient.New(nodeAPIUrl)
+	require.NoError(t, err)
+
+	return client
+}
+
+// mockCommittedSlot is the slot of the commitment the mocked indexer answers with.
+const mockCommittedSlot iotago.SlotIndex = 1_000
+
+// mockUnlockableOutputs mocks the indexer to return the given outputs for the address and returns their IDs.
+//
+//nolint:thelper
+func mockUnlockableOutputs(t *testing.T, addr iotago.Address, outputs ...iotago.Output) iotago.OutputIDs {
+	txOutputs := make(iotago.TxEssenceOutputs, len(outputs))
+	for i, output := range outputs {
+		txOutputs[i] = output.(iotago.TxEssenceOutput) //nolint:forcetypeassert
+	}
+
+	txCommitment := tpkg.Rand32ByteArray()
+	txCreationSlot := tpkg.RandSlot()
+
+	outputIDs := make(iotago.OutputIDs, len(outputs))
+	for i, output := range outputs {
+		outputIDProof, err := iotago.NewOutputIDProof(mockAPI, txCommitment, txCreationSlot, txOutputs, uint16(i))
+		require.NoError(t, err)
+
+		outputIDs[i], err = outputIDProof.OutputID(output)
+		require.NoError(t, err)
+
+		mockGetBinary(api.EndpointWithNamedParameterValue(api.CoreRouteOutput, api.ParameterOutputID, outputIDs[i].ToHex()), &api.OutputResponse{
+			Output:        output,
+			OutputIDProof: outputIDProof,
+		})
+	}
+
+	mockGetJSON(api.IndexerRouteOutputs, &api.IndexerResponse{
+		CommittedSlot: mockCommittedSlot,
+		PageSize:      uint32(len(outputs)),
+		Items:         iotago.HexOutputIDsFromOutputIDs(outputIDs...),
+	}, map[string]string{
+		"unlockableByAddress": addr.Bech32(mockAPI.ProtocolParameters().Bech32HRP()),
+	})
+
+	return outputIDs
+}
+
+// mockNoUnlockableOutputs mocks the indexer to return no outputs for all addresses that were not mocked before.
+func mockNoUnlockableOutputs() {
+	mockGetJSON(api.IndexerRouteOutputs, &api.IndexerResponse{
+		CommittedSlot: mockCommittedSlot,
+		Items:         iotago.HexOutputIDs{},
+	}, nil, true)
+}
+
+func TestDiscoverAddresses(t *testing.T) {
+	defer gock.Off()
+
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	client := mockNodeClient(t)
+	indexer, err := client.Indexer(context.Background())
+	require.NoError(t, err)
+
+	external0 := lo.PanicOnErr(keyManager.AddressAt(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0))
+	external3 := lo.PanicOnErr(keyManager.AddressAt(iotago.AddressEd25519, 0, wallet.ChangeExternal, 3))
+	internal1 := lo.PanicOnErr(keyManager.AddressAt(iotago.AddressEd25519, 0, wallet.ChangeInternal, 1))
+	implicit0 := lo.PanicOnErr(keyManager.AddressAt(iotago.AddressImplicitAccountCreation, 0, wallet.ChangeExternal, 0))
+	// beyond the gap limit
+	external9 := lo.PanicOnErr(keyManager.AddressAt(iotago.AddressEd25519, 0, wallet.ChangeExternal, 9))
+
+	basicOutputIDs := mockUnlockableOutputs(t, external0,
+		builder.NewBasicOutputBuilder(external0, 1_000_000).MustBuild(),
+		builder.NewBasicOutputBuilder(external0, 2_000_000).MustBuild(),
+	)
+	accountOutputIDs := mockUnlockableOutputs(t, external3,
+		builder.NewAccountOutputBuilder(external3, 1_000_000).AccountID(tpkg.RandAccountID()).MustBuild(),
+		builder.NewBasicOutputBuilder(external3, 1_000_000).MustBuild(),
+	)
+	nftOutputIDs := mockUnlockableOutputs(t, internal1,
+		builder.NewNFTOutputBuilder(internal1, 1_000_000).NFTID(tpkg.RandNFTAddress().NFTID()).MustBuild(),
+	)
+	mockUnlockableOutputs(t, implicit0, builder.NewBasicOutputBuilder(implicit0, 1_000_000).MustBuild())
+	mockUnlockableOutputs(t, external9, builder.NewBasicOutputBuilder(external9, 1_000_000).MustBuild())
+	mockNoUnlockableOutputs()
+
+	discovered, err := wallet.DiscoverAddresses(context.Background(), indexer, keyManager, mockAPI.ProtocolParameters().Bech32HRP(), wallet.WithDiscoveryGapLimit(5))
+	require.NoError(t, err)
+	require.Len(t, discovered, 4)
+
+	require.Equal(t, external0, discovered[0].Address)
+	require.Equal(t, uint32(0), discovered[0].AddressIndex)
+	require.Len(t, discovered[0].BasicOutputs(), 2)
+	require.Equal(t, basicOutputIDs[0], discovered[0].Outputs[0].InputID)
+	require.Equal(t, external0, discovered[0].Outputs[0].UnlockTarget)
+
+	require.Equal(t, external3, discovered[1].Address)
+	require.Equal(t, uint32(3), discovered[1].AddressIndex)
+	require.Len(t, discovered[1].AccountOutputs(), 1)
+	require.Equal(t, accountOutputIDs[0], discovered[1].AccountOutputs()[0].InputID)
+
+	require.Equal(t, internal1, discovered[2].Address)
+	require.Equal(t, wallet.ChangeInternal, discovered[2].Change)
+	require.Len(t, discovered[2].NFTOutputs(), 1)
+	require.Equal(t, nftOutputIDs[0], discovered[2].NFTOutputs()[0].InputID)
+
+	require.Equal(t, implicit0, discovered[3].Address)
+	require.Len(t, discovered[3].Outputs, 1)
+
+	_, err = wallet.DiscoverAddresses(context.Background(), indexer, keyManager, mockAPI.ProtocolParameters().Bech32HRP(), wallet.WithDiscoveryGapLimit(0))
+	require.Error(t, err)
+}
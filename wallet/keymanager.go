@@ -2,7 +2,6 @@ package wallet
 
 import (
 	"crypto/ed25519"
-	"fmt"
 
 	"github.com/iotaledger/iota-crypto-demo/pkg/bip32path"
 	"github.com/iotaledger/iota-crypto-demo/pkg/bip39"
@@ -17,13 +16,32 @@ import (
 const (
 	DefaultIOTAPath    = "m/44'/4218'/0'/0'/0'"
 	DefaultShimmerPath = "m/44'/4219'/0'/0'/0'"
+
+	// the indexes of the BIP44 path segments.
+	pathSegmentCoinType     = 1
+	pathSegmentAccount      = 2
+	pathSegmentChange       = 3
+	pathSegmentAddressIndex = 4
+
+	// hardened is the bit that marks a hardened BIP32 path segment.
+	hardened = uint32(1 << 31)
+)
+
+var (
+	// ErrInvalidMnemonic gets returned if the mnemonic is not a valid BIP39 mnemonic.
+	ErrInvalidMnemonic = ierrors.New("invalid mnemonic")
+	// ErrInvalidDerivationPath gets returned if a key can't be derived with the given path or indexes.
+	ErrInvalidDerivationPath = ierrors.New("invalid derivation path")
 )
 
 // KeyManager is a hierarchical deterministic key manager.
+// The keys are derived with SLIP10 along a BIP44 path "m/44'/coin_type'/account'/change'/address_index'".
 // NOTE: The seed is stored in memory and is not protected against memory dumps.
 type KeyManager struct {
 	seed []byte
 	path bip32path.Path
+	// mnemonic is only set if the key manager was created from a mnemonic.
+	mnemonic bip39.Mnemonic
 }
 
 // NewKeyManagerFromRandom creates a new key manager from random entropy.
@@ -37,19 +55,33 @@ func NewKeyManagerFromRandom(path string) (*KeyManager, error) {
 	return NewKeyManager(random.Seed(), path)
 }
 
-// NewKeyManagerFromMnemonic creates a new key manager from a mnemonic.
+// NewKeyManagerFromMnemonic creates a new key manager from a mnemonic without a BIP39 passphrase.
 func NewKeyManagerFromMnemonic(mnemonic string, path string) (*KeyManager, error) {
+	return NewKeyManagerFromMnemonicWithPassphrase(mnemonic, "", path)
+}
+
+// NewKeyManagerFromMnemonicWithPassphrase creates a new key manager from a mnemonic
+// with 12, 15, 18, 21 or 24 words and the given BIP39 passphrase (the "25th word").
+func NewKeyManagerFromMnemonicWithPassphrase(mnemonic string, passphrase string, path string) (*KeyManager, error) {
 	mnemonicSentence := bip39.ParseMnemonic(mnemonic)
-	if len(mnemonicSentence) != 24 {
-		return nil, ierrors.New("mnemomic sentence is not 24 words long")
+	switch len(mnemonicSentence) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, ierrors.WithMessagef(ErrInvalidMnemonic, "mnemonic sentence has %d words, but must have 12, 15, 18, 21 or 24 words", len(mnemonicSentence))
+	}
+
+	seed, err := bip39.MnemonicToSeed(mnemonicSentence, passphrase)
+	if err != nil {
+		return nil, ierrors.Join(ErrInvalidMnemonic, ierrors.Wrap(err, "failed to convert mnemonic to seed"))
 	}
 
-	seed, err := bip39.MnemonicToSeed(mnemonicSentence, "")
+	keyManager, err := NewKeyManager(seed, path)
 	if err != nil {
-		return nil, ierrors.Wrap(err, "failed to convert mnemonic to seed")
+		return nil, err
 	}
+	keyManager.mnemonic = mnemonicSentence
 
-	return NewKeyManager(seed, path)
+	return keyManager, nil
 }
 
 // NewKeyManager creates a new key manager.
@@ -66,76 +98,165 @@ func NewKeyManager(seed []byte, path string) (*KeyManager, error) {
 }
 
 // KeyPair calculates an ed25519 key pair by using slip10.
-func (k *KeyManager) KeyPair(index ...uint32) (ed25519.PrivateKey, ed25519.PublicKey) {
-	curve := eddsa.Ed25519()
-	key, err := slip10.DeriveKeyFromPath(k.seed, curve, k.Path(index...))
+// If an index is given, it replaces the address index of the path of the key manager.
+func (k *KeyManager) KeyPair(index ...uint32) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	path, err := k.Path(index...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return k.KeyPairForPath(path)
+}
+
+// KeyPairAt calculates the ed25519 key pair for the given account, change and address index.
+func (k *KeyManager) KeyPairAt(account uint32, change uint32, addressIndex uint32) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	path, err := k.DerivationPath(account, change, addressIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return k.KeyPairForPath(path)
+}
+
+// KeyPairForPath calculates the ed25519 key pair for the given path by using slip10.
+func (k *KeyManager) KeyPairForPath(path bip32path.Path) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	key, err := slip10.DeriveKeyFromPath(k.seed, eddsa.Ed25519(), path)
 	if err != nil {
-		panic(err)
+		return nil, nil, ierrors.Join(ErrInvalidDerivationPath, ierrors.Wrapf(err, "failed to derive key for path %s", path))
 	}
 
 	pubKey, privKey := key.Key.(eddsa.Seed).Ed25519Key()
 
-	return ed25519.PrivateKey(privKey), ed25519.PublicKey(pubKey)
+	return ed25519.PrivateKey(privKey), ed25519.PublicKey(pubKey), nil
 }
 
-func (k *KeyManager) Path(index ...uint32) bip32path.Path {
+// Path returns the path of the key manager.
+// If an index is given, it replaces the address index of the path.
+func (k *KeyManager) Path(index ...uint32) (bip32path.Path, error) {
 	if len(index) == 0 {
 		// no additional index given, use the internal path
-		return k.path
+		return k.path, nil
 	}
 
 	// new index given, check if the internal path contains the index part
-	if len(k.path) < 5 {
-		panic("invalid path length")
+	if len(k.path) <= pathSegmentAddressIndex {
+		return nil, ierrors.WithMessagef(ErrInvalidDerivationPath, "path %s has no address index", k.path)
+	}
+
+	addressIndex, err := hardenedIndex(index[0])
+	if err != nil {
+		return nil, err
 	}
 
 	// copy the former path
 	newPath := lo.CopySlice(k.path)
 
 	// set the new index
-	newPath[4] = index[0] | (1 << 31)
+	newPath[pathSegmentAddressIndex] = addressIndex
 
-	return newPath
+	return newPath, nil
+}
+
+// DerivationPath returns the path for the given account, change and address index,
+// using the purpose and coin type of the path of the key manager.
+func (k *KeyManager) DerivationPath(account uint32, change uint32, addressIndex uint32) (bip32path.Path, error) {
+	if len(k.path) <= pathSegmentCoinType {
+		return nil, ierrors.WithMessagef(ErrInvalidDerivationPath, "path %s has no coin type", k.path)
+	}
+
+	newPath := make(bip32path.Path, pathSegmentAddressIndex+1)
+	copy(newPath, k.path[:pathSegmentCoinType+1])
+
+	for i, index := range []uint32{account, change, addressIndex} {
+		segment, err := hardenedIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		newPath[pathSegmentAccount+i] = segment
+	}
+
+	return newPath, nil
+}
+
+// hardenedIndex returns the hardened path segment of the given index.
+// Only hardened segments are supported for ed25519 keys by slip10.
+func hardenedIndex(index uint32) (uint32, error) {
+	if index >= hardened {
+		return 0, ierrors.WithMessagef(ErrInvalidDerivationPath, "index %d is out of range", index)
+	}
+
+	return index | hardened, nil
 }
 
 // Mnemonic returns the mnemonic of the key manager.
-func (k *KeyManager) Mnemonic() bip39.Mnemonic {
+// If the key manager was not created from a mnemonic, the seed is converted to a mnemonic,
+// which fails if the seed has no valid BIP39 entropy length.
+func (k *KeyManager) Mnemonic() (bip39.Mnemonic, error) {
+	if k.mnemonic != nil {
+		return k.mnemonic, nil
+	}
+
 	mnemonic, err := bip39.EntropyToMnemonic(k.seed)
 	if err != nil {
-		panic(ierrors.Wrap(err, "failed to convert seed to mnemonic"))
+		return nil, ierrors.Wrap(err, "failed to convert seed to mnemonic")
 	}
 
-	return mnemonic
+	return mnemonic, nil
 }
 
 // AddressSigner returns an address signer.
-func (k *KeyManager) AddressSigner(indexes ...uint32) iotago.AddressSigner {
+func (k *KeyManager) AddressSigner(indexes ...uint32) (iotago.AddressSigner, error) {
 	privKeys := make([]ed25519.PrivateKey, 0)
 
 	if len(indexes) == 0 {
-		privKey, _ := k.KeyPair()
+		privKey, _, err := k.KeyPair()
+		if err != nil {
+			return nil, err
+		}
 		privKeys = append(privKeys, privKey)
 	} else {
 		for _, index := range indexes {
-			privKey, _ := k.KeyPair(index)
+			privKey, _, err := k.KeyPair(index)
+			if err != nil {
+				return nil, err
+			}
 			privKeys = append(privKeys, privKey)
 		}
 	}
 
-	return iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(privKeys...)
+	return iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(privKeys...), nil
 }
 
 // Address calculates an address of the specified type.
-func (k *KeyManager) Address(addressType iotago.AddressType, index ...uint32) iotago.DirectUnlockableAddress {
-	_, pubKey := k.KeyPair(index...)
+// If an index is given, it replaces the address index of the path of the key manager.
+func (k *KeyManager) Address(addressType iotago.AddressType, index ...uint32) (iotago.DirectUnlockableAddress, error) {
+	_, pubKey, err := k.KeyPair(index...)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressFromPublicKey(addressType, pubKey)
+}
+
+// AddressAt calculates an address of the specified type for the given account, change and address index.
+func (k *KeyManager) AddressAt(addressType iotago.AddressType, account uint32, change uint32, addressIndex uint32) (iotago.DirectUnlockableAddress, error) {
+	_, pubKey, err := k.KeyPairAt(account, change, addressIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressFromPublicKey(addressType, pubKey)
+}
 
+// addressFromPublicKey returns the address of the specified type for the given public key.
+func addressFromPublicKey(addressType iotago.AddressType, pubKey ed25519.PublicKey) (iotago.DirectUnlockableAddress, error) {
 	//nolint:exhaustive // we only support two address types
 	switch addressType {
 	case iotago.AddressEd25519:
-		return iotago.Ed25519AddressFromPubKey(pubKey)
+		return iotago.Ed25519AddressFromPubKey(pubKey), nil
 	case iotago.AddressImplicitAccountCreation:
-		return iotago.ImplicitAccountCreationAddressFromPubKey(pubKey)
+		return iotago.ImplicitAccountCreationAddressFromPubKey(pubKey), nil
 	default:
-		panic(fmt.Sprintf("address type %s is not supported", addressType))
+		return nil, ierrors.Errorf("address type %s is not supported", addressType)
 	}
 }
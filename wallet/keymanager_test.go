@@ -0,0 +1,109 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestKeyManagerMnemonicLengths(t *testing.T) {
+	for _, mnemonic := range []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon address",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon admit",
+		keystoreTestMnemonic,
+	} {
+		keyManager, err := wallet.NewKeyManagerFromMnemonic(mnemonic, wallet.DefaultIOTAPath)
+		require.NoError(t, err)
+
+		keyManagerMnemonic, err := keyManager.Mnemonic()
+		require.NoError(t, err)
+		require.Equal(t, mnemonic, keyManagerMnemonic.String())
+	}
+
+	// a seed without a valid BIP39 entropy length can't be converted to a mnemonic
+	keyManager, err := wallet.NewKeyManager(make([]byte, 17), wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+	_, err = keyManager.Mnemonic()
+	require.Error(t, err)
+
+	_, err = wallet.NewKeyManagerFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", wallet.DefaultIOTAPath)
+	require.ErrorIs(t, err, wallet.ErrInvalidMnemonic)
+
+	// invalid checksum
+	_, err = wallet.NewKeyManagerFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", wallet.DefaultIOTAPath)
+	require.ErrorIs(t, err, wallet.ErrInvalidMnemonic)
+}
+
+func TestKeyManagerPassphrase(t *testing.T) {
+	withoutPassphrase, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	emptyPassphrase, err := wallet.NewKeyManagerFromMnemonicWithPassphrase(keystoreTestMnemonic, "", wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	withPassphrase, err := wallet.NewKeyManagerFromMnemonicWithPassphrase(keystoreTestMnemonic, "TREZOR", wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	addr, err := withoutPassphrase.Address(iotago.AddressEd25519)
+	require.NoError(t, err)
+
+	emptyPassphraseAddr, err := emptyPassphrase.Address(iotago.AddressEd25519)
+	require.NoError(t, err)
+	require.Equal(t, addr, emptyPassphraseAddr)
+
+	passphraseAddr, err := withPassphrase.Address(iotago.AddressEd25519)
+	require.NoError(t, err)
+	require.NotEqual(t, addr, passphraseAddr)
+}
+
+func TestKeyManagerDerivationPath(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultShimmerPath)
+	require.NoError(t, err)
+
+	path, err := keyManager.DerivationPath(1, 1, 7)
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/4219'/1'/1'/7'", path.String())
+
+	// the address index of the path of the key manager gets replaced
+	path, err = keyManager.Path(7)
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/4219'/0'/0'/7'", path.String())
+
+	addr, err := keyManager.AddressAt(iotago.AddressEd25519, 0, 0, 7)
+	require.NoError(t, err)
+
+	indexAddr, err := keyManager.Address(iotago.AddressEd25519, 7)
+	require.NoError(t, err)
+	require.Equal(t, addr, indexAddr)
+
+	otherAccountAddr, err := keyManager.AddressAt(iotago.AddressEd25519, 1, 0, 7)
+	require.NoError(t, err)
+	require.NotEqual(t, addr, otherAccountAddr)
+
+	changeAddr, err := keyManager.AddressAt(iotago.AddressEd25519, 0, 1, 7)
+	require.NoError(t, err)
+	require.NotEqual(t, addr, changeAddr)
+
+	_, err = keyManager.DerivationPath(1<<31, 0, 0)
+	require.ErrorIs(t, err, wallet.ErrInvalidDerivationPath)
+
+	_, _, err = keyManager.KeyPair(1 << 31)
+	require.ErrorIs(t, err, wallet.ErrInvalidDerivationPath)
+
+	_, err = keyManager.Address(iotago.AddressAccount)
+	require.Error(t, err)
+
+	shortPathKeyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, "m/44'/4218'")
+	require.NoError(t, err)
+
+	_, err = shortPathKeyManager.Address(iotago.AddressEd25519, 1)
+	require.ErrorIs(t, err, wallet.ErrInvalidDerivationPath)
+
+	_, err = shortPathKeyManager.AddressAt(iotago.AddressEd25519, 0, 0, 1)
+	require.NoError(t, err)
+}
@@ -0,0 +1,480 @@
+package wallet
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip39"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+const (
+	// KeystoreVersion1 is the initial keystore version.
+	// The secrets are encrypted with XChaCha20-Poly1305, the metadata and KDF parameters are authenticated.
+	KeystoreVersion1 uint8 = 1
+	// KeystoreVersionLatest is the version new keystores are created with.
+	KeystoreVersionLatest = KeystoreVersion1
+
+	// KeystoreKDFScrypt derives the encryption key with scrypt.
+	KeystoreKDFScrypt = "scrypt"
+	// KeystoreKDFArgon2id derives the encryption key with Argon2id.
+	KeystoreKDFArgon2id = "argon2id"
+
+	// KeystoreCipherXChaCha20Poly1305 is the cipher used by KeystoreVersion1.
+	KeystoreCipherXChaCha20Poly1305 = "xchacha20-poly1305"
+
+	keystoreKeyLength  = 32
+	keystoreSaltLength = 32
+
+	// the upper bounds of the KDF parameters, which prevent a crafted keystore from demanding excessive resources.
+	keystoreMaxKDFMemory        = 1 << 30
+	keystoreMaxArgon2idPasses   = 16
+	keystoreMaxScryptParallel   = 16
+	keystoreMaxArgon2idThreads  = 64
+	keystoreScryptBytesPerBlock = 128
+)
+
+var (
+	// ErrKeystoreDecryptionFailed gets returned if the keystore can't be decrypted, e.g. because of a wrong password.
+	ErrKeystoreDecryptionFailed = ierrors.New("keystore decryption failed")
+	// ErrKeystoreUnsupportedVersion gets returned if the keystore version is unknown.
+	ErrKeystoreUnsupportedVersion = ierrors.New("unsupported keystore version")
+	// ErrKeystoreInvalid gets returned if the keystore is malformed.
+	ErrKeystoreInvalid = ierrors.New("invalid keystore")
+)
+
+// Keystore is the encrypted on-disk representation of a KeyManager.
+// The mnemonic, the seed and the BIP32 path are encrypted with a key derived from a password,
+// the metadata is stored in plain text, but is authenticated.
+type Keystore struct {
+	// The version of the keystore format.
+	Version uint8 `json:"version"`
+	// The encrypted secrets.
+	Crypto *KeystoreCrypto `json:"crypto"`
+	// The plain text metadata.
+	Metadata *KeystoreMetadata `json:"metadata"`
+}
+
+// KeystoreCrypto holds the encrypted secrets and the parameters needed to decrypt them.
+type KeystoreCrypto struct {
+	// The key derivation function.
+	KDF string `json:"kdf"`
+	// The parameters of the key derivation function.
+	KDFParams *KeystoreKDFParams `json:"kdfParams"`
+	// The authenticated encryption cipher.
+	Cipher string `json:"cipher"`
+	// The hex encoded nonce.
+	Nonce string `json:"nonce"`
+	// The hex encoded encrypted secrets.
+	Ciphertext string `json:"ciphertext"`
+}
+
+// KeystoreKDFParams are the parameters of the key derivation function.
+type KeystoreKDFParams struct {
+	// The hex encoded salt.
+	Salt string `json:"salt"`
+	// The scrypt CPU/memory cost parameter.
+	N int `json:"n,omitempty"`
+	// The scrypt block size parameter.
+	R int `json:"r,omitempty"`
+	// The scrypt parallelization parameter.
+	P int `json:"p,omitempty"`
+	// The Argon2id number of passes over the memory.
+	Time uint32 `json:"time,omitempty"`
+	// The Argon2id memory size in KiB.
+	Memory uint32 `json:"memory,omitempty"`
+	// The Argon2id number of threads.
+	Threads uint8 `json:"threads,omitempty"`
+}
+
+// validate checks that the parameters are usable with the given KDF and don't exceed the resource limits.
+func (p *KeystoreKDFParams) validate(kdf string) error {
+	switch kdf {
+	case KeystoreKDFScrypt:
+		if p.N <= 1 || p.N&(p.N-1) != 0 || p.R <= 0 || p.P <= 0 {
+			return ierrors.WithMessage(ErrKeystoreInvalid, "invalid scrypt parameters")
+		}
+		if p.P > keystoreMaxScryptParallel || p.N > keystoreMaxKDFMemory/keystoreScryptBytesPerBlock/p.R {
+			return ierrors.WithMessagef(ErrKeystoreInvalid, "scrypt parameters n=%d, r=%d, p=%d exceed the limits", p.N, p.R, p.P)
+		}
+	case KeystoreKDFArgon2id:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return ierrors.WithMessage(ErrKeystoreInvalid, "invalid argon2id parameters")
+		}
+		if p.Time > keystoreMaxArgon2idPasses || p.Memory > keystoreMaxKDFMemory/1024 || p.Threads > keystoreMaxArgon2idThreads {
+			return ierrors.WithMessagef(ErrKeystoreInvalid, "argon2id parameters time=%d, memory=%d, threads=%d exceed the limits", p.Time, p.Memory, p.Threads)
+		}
+	default:
+		return ierrors.WithMessagef(ErrKeystoreInvalid, "unsupported KDF %s", kdf)
+	}
+
+	return nil
+}
+
+// KeystoreMetadata is the plain text metadata of a Keystore.
+type KeystoreMetadata struct {
+	// The name of the keystore.
+	Name string `json:"name,omitempty"`
+	// The ID of the account the keys belong to, used to load the keystore into an Account.
+	AccountID iotago.AccountID `json:"accountId"`
+	// The time the keystore was created.
+	CreatedAt time.Time `json:"createdAt"`
+	// Additional application specific metadata.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// keystoreSecrets are the secrets encrypted in a Keystore.
+type keystoreSecrets struct {
+	Mnemonic string `json:"mnemonic,omitempty"`
+	Seed     string `json:"seed"`
+	Path     string `json:"path"`
+}
+
+// KeystoreOptions define the options used to encrypt a Keystore.
+type KeystoreOptions struct {
+	kdf       string
+	kdfParams *KeystoreKDFParams
+}
+
+// WithKeystoreScrypt derives the encryption key with scrypt and the given parameters.
+func WithKeystoreScrypt(n int, r int, p int) options.Option[KeystoreOptions] {
+	return func(opts *KeystoreOptions) {
+		opts.kdf = KeystoreKDFScrypt
+		opts.kdfParams = &KeystoreKDFParams{N: n, R: r, P: p}
+	}
+}
+
+// WithKeystoreArgon2id derives the encryption key with Argon2id and the given parameters.
+func WithKeystoreArgon2id(passes uint32, memory uint32, threads uint8) options.Option[KeystoreOptions] {
+	return func(opts *KeystoreOptions) {
+		opts.kdf = KeystoreKDFArgon2id
+		opts.kdfParams = &KeystoreKDFParams{Time: passes, Memory: memory, Threads: threads}
+	}
+}
+
+// NewKeystore encrypts the secrets of the given KeyManager with the given password.
+// By default, the encryption key is derived with Argon2id (3 passes, 64 MiB, 4 threads).
+func NewKeystore(keyManager *KeyManager, password []byte, metadata *KeystoreMetadata, opts ...options.Option[KeystoreOptions]) (*Keystore, error) {
+	// the metadata is copied, so the one of the caller is not modified
+	keystoreMetadata := &KeystoreMetadata{}
+	if metadata != nil {
+		*keystoreMetadata = *metadata
+		keystoreMetadata.Extra = maps.Clone(metadata.Extra)
+	}
+	if keystoreMetadata.CreatedAt.IsZero() {
+		keystoreMetadata.CreatedAt = time.Now().UTC()
+	}
+
+	keystore := &Keystore{
+		Version:  KeystoreVersionLatest,
+		Metadata: keystoreMetadata,
+	}
+
+	if err := keystore.encrypt(keyManager, password, opts...); err != nil {
+		return nil, err
+	}
+
+	return keystore, nil
+}
+
+// LoadKeystore reads the Keystore from the given file.
+func LoadKeystore(filePath string) (*Keystore, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to read keystore file %s", filePath)
+	}
+
+	keystore := &Keystore{}
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalid, err)
+	}
+
+	if keystore.Crypto == nil || keystore.Crypto.KDFParams == nil {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalid, "missing crypto parameters")
+	}
+
+	if keystore.Metadata == nil {
+		keystore.Metadata = &KeystoreMetadata{}
+	}
+
+	return keystore, nil
+}
+
+// Save writes the Keystore to the given file, which is only readable by the owner.
+// The file is replaced atomically, so an existing keystore is never left in a corrupted state.
+func (k *Keystore) Save(filePath string) error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return ierrors.Wrap(err, "failed to marshal keystore")
+	}
+
+	return writeFileAtomically(filePath, data)
+}
+
+// writeFileAtomically writes the data to a temporary file with permissions 0600 and renames it to the given path,
+// so that the file at the given path is either the old or the new version, even if the process crashes.
+func writeFileAtomically(filePath string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return ierrors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := tmpFile.Chmod(0o600); err != nil {
+		_ = tmpFile.Close()
+
+		return ierrors.Wrap(err, "failed to set file permissions")
+	}
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+
+		return ierrors.Wrap(err, "failed to write file")
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+
+		return ierrors.Wrap(err, "failed to sync file")
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return ierrors.Wrap(err, "failed to close file")
+	}
+
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+// KeyManager decrypts the Keystore and returns the KeyManager.
+func (k *Keystore) KeyManager(password []byte) (*KeyManager, error) {
+	secrets, err := k.decrypt(password)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hexutil.DecodeHex(secrets.Seed)
+	if err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalid, err)
+	}
+
+	keyManager, err := NewKeyManager(seed, secrets.Path)
+	if err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalid, err)
+	}
+
+	if secrets.Mnemonic != "" {
+		keyManager.mnemonic = bip39.ParseMnemonic(secrets.Mnemonic)
+	}
+
+	return keyManager, nil
+}
+
+// Account decrypts the Keystore and returns the Account of the account ID stored in the metadata,
+// which is controlled by the key pair at the given index.
+func (k *Keystore) Account(password []byte, index ...uint32) (Account, error) {
+	if k.Metadata.AccountID.Empty() {
+		return nil, ierrors.New("keystore metadata contains no account ID")
+	}
+
+	keyManager, err := k.KeyManager(password)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, _, err := keyManager.KeyPair(index...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEd25519Account(k.Metadata.AccountID, privateKey), nil
+}
+
+// ChangePassword re-encrypts the Keystore with the new password.
+func (k *Keystore) ChangePassword(oldPassword []byte, newPassword []byte, opts ...options.Option[KeystoreOptions]) error {
+	keyManager, err := k.KeyManager(oldPassword)
+	if err != nil {
+		return err
+	}
+
+	changed := &Keystore{
+		Version:  KeystoreVersionLatest,
+		Metadata: k.Metadata,
+	}
+	if err := changed.encrypt(keyManager, newPassword, opts...); err != nil {
+		return err
+	}
+	*k = *changed
+
+	return nil
+}
+
+// encrypt encrypts the secrets of the given KeyManager in the latest keystore version.
+func (k *Keystore) encrypt(keyManager *KeyManager, password []byte, opts ...options.Option[KeystoreOptions]) error {
+	keystoreOptions := options.Apply(&KeystoreOptions{}, opts, func(o *KeystoreOptions) {
+		if o.kdfParams == nil {
+			o.kdf = KeystoreKDFArgon2id
+			o.kdfParams = &KeystoreKDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+		}
+	})
+
+	salt := make([]byte, keystoreSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return ierrors.Wrap(err, "failed to generate salt")
+	}
+
+	kdfParams := *keystoreOptions.kdfParams
+	kdfParams.Salt = hexutil.EncodeHex(salt)
+	if err := kdfParams.validate(keystoreOptions.kdf); err != nil {
+		return err
+	}
+
+	k.Crypto = &KeystoreCrypto{
+		KDF:       keystoreOptions.kdf,
+		KDFParams: &kdfParams,
+		Cipher:    KeystoreCipherXChaCha20Poly1305,
+	}
+
+	secrets := &keystoreSecrets{
+		Seed: hexutil.EncodeHex(keyManager.seed),
+		Path: keyManager.path.String(),
+	}
+	if keyManager.mnemonic != nil {
+		secrets.Mnemonic = keyManager.mnemonic.String()
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to marshal keystore secrets")
+	}
+	defer clear(plaintext)
+
+	aead, err := k.aead(password)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return ierrors.Wrap(err, "failed to generate nonce")
+	}
+
+	associatedData, err := k.associatedData()
+	if err != nil {
+		return err
+	}
+
+	k.Crypto.Nonce = hexutil.EncodeHex(nonce)
+	k.Crypto.Ciphertext = hexutil.EncodeHex(aead.Seal(nil, nonce, plaintext, associatedData))
+
+	return nil
+}
+
+// decrypt decrypts the secrets of the Keystore.
+// There is only a single version of the format so far, so every other version is rejected.
+func (k *Keystore) decrypt(password []byte) (*keystoreSecrets, error) {
+	if k.Version != KeystoreVersionLatest {
+		return nil, ierrors.WithMessagef(ErrKeystoreUnsupportedVersion, "version %d, expected version %d", k.Version, KeystoreVersionLatest)
+	}
+
+	if k.Crypto.Cipher != KeystoreCipherXChaCha20Poly1305 {
+		return nil, ierrors.WithMessagef(ErrKeystoreInvalid, "cipher %s is not supported in keystore version %d", k.Crypto.Cipher, k.Version)
+	}
+
+	aead, err := k.aead(password)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hexutil.DecodeHex(k.Crypto.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalid, "invalid nonce")
+	}
+
+	ciphertext, err := hexutil.DecodeHex(k.Crypto.Ciphertext)
+	if err != nil {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalid, "invalid ciphertext")
+	}
+
+	associatedData, err := k.associatedData()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, ierrors.WithMessage(ErrKeystoreDecryptionFailed, "wrong password or keystore was modified")
+	}
+	defer clear(plaintext)
+
+	secrets := &keystoreSecrets{}
+	if err := json.Unmarshal(plaintext, secrets); err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalid, err)
+	}
+
+	return secrets, nil
+}
+
+// aead derives the encryption key from the password and returns the cipher of the Keystore.
+func (k *Keystore) aead(password []byte) (cipher.AEAD, error) {
+	params := k.Crypto.KDFParams
+
+	salt, err := hexutil.DecodeHex(params.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalid, "invalid salt")
+	}
+
+	// the parameters are read from the file, so they are bounded before the key is derived
+	if err := params.validate(k.Crypto.KDF); err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	switch k.Crypto.KDF {
+	case KeystoreKDFScrypt:
+		if key, err = scrypt.Key(password, salt, params.N, params.R, params.P, keystoreKeyLength); err != nil {
+			return nil, ierrors.Join(ErrKeystoreInvalid, err)
+		}
+	case KeystoreKDFArgon2id:
+		key = argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, keystoreKeyLength)
+	}
+	defer clear(key)
+
+	if k.Crypto.Cipher != KeystoreCipherXChaCha20Poly1305 {
+		return nil, ierrors.WithMessagef(ErrKeystoreInvalid, "unsupported cipher %s", k.Crypto.Cipher)
+	}
+
+	return chacha20poly1305.NewX(key)
+}
+
+// associatedData returns the data that is authenticated together with the secrets,
+// which is the whole keystore except the nonce and the ciphertext.
+func (k *Keystore) associatedData() ([]byte, error) {
+	header := &Keystore{
+		Version: k.Version,
+		Crypto: &KeystoreCrypto{
+			KDF:       k.Crypto.KDF,
+			KDFParams: k.Crypto.KDFParams,
+			Cipher:    k.Crypto.Cipher,
+		},
+		Metadata: k.Metadata,
+	}
+
+	associatedData, err := json.Marshal(header)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to marshal keystore header")
+	}
+
+	return associatedData, nil
+}
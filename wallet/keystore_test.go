@@ -0,0 +1,145 @@
+package wallet_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+const keystoreTestMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+
+func TestKeystore(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	accountID := tpkg.RandAccountID()
+	filePath := filepath.Join(t.TempDir(), "keystore.json")
+
+	// keep the KDF cheap for the tests
+	fastKDF := wallet.WithKeystoreArgon2id(1, 1024, 1)
+
+	metadata := &wallet.KeystoreMetadata{
+		Name:      "treasury",
+		AccountID: accountID,
+		Extra:     map[string]string{"network": "testnet"},
+	}
+	keystore, err := wallet.NewKeystore(keyManager, []byte("password"), metadata, fastKDF)
+	require.NoError(t, err)
+	require.False(t, keystore.Metadata.CreatedAt.IsZero())
+	// the metadata of the caller is not modified
+	require.True(t, metadata.CreatedAt.IsZero())
+	require.NoError(t, keystore.Save(filePath))
+
+	fileInfo, err := os.Stat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fileInfo.Mode().Perm())
+
+	loaded, err := wallet.LoadKeystore(filePath)
+	require.NoError(t, err)
+	require.Equal(t, wallet.KeystoreVersionLatest, loaded.Version)
+	require.Equal(t, "treasury", loaded.Metadata.Name)
+
+	t.Run("ok - load key manager and account", func(t *testing.T) {
+		loadedKeyManager, err := loaded.KeyManager([]byte("password"))
+		require.NoError(t, err)
+		require.Equal(t, lo.PanicOnErr(keyManager.Mnemonic()), lo.PanicOnErr(loadedKeyManager.Mnemonic()))
+		require.Equal(t, lo.PanicOnErr(keyManager.Path()), lo.PanicOnErr(loadedKeyManager.Path()))
+		require.Equal(t, lo.PanicOnErr(keyManager.Address(iotago.AddressEd25519, 3)), lo.PanicOnErr(loadedKeyManager.Address(iotago.AddressEd25519, 3)))
+
+		account, err := loaded.Account([]byte("password"), 1)
+		require.NoError(t, err)
+		require.Equal(t, accountID, account.ID())
+		require.Equal(t, lo.PanicOnErr(keyManager.Address(iotago.AddressEd25519, 1)), account.OwnerAddress())
+	})
+
+	t.Run("err - wrong password", func(t *testing.T) {
+		_, err := loaded.KeyManager([]byte("wrong"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreDecryptionFailed)
+	})
+
+	t.Run("err - modified metadata", func(t *testing.T) {
+		modified, err := wallet.LoadKeystore(filePath)
+		require.NoError(t, err)
+		modified.Metadata.AccountID = tpkg.RandAccountID()
+
+		_, err = modified.KeyManager([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreDecryptionFailed)
+	})
+
+	t.Run("ok - change password", func(t *testing.T) {
+		changed, err := wallet.LoadKeystore(filePath)
+		require.NoError(t, err)
+		require.NoError(t, changed.ChangePassword([]byte("password"), []byte("new password"), wallet.WithKeystoreScrypt(1024, 8, 1)))
+		require.Equal(t, wallet.KeystoreKDFScrypt, changed.Crypto.KDF)
+
+		_, err = changed.KeyManager([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreDecryptionFailed)
+
+		changedKeyManager, err := changed.KeyManager([]byte("new password"))
+		require.NoError(t, err)
+		require.Equal(t, lo.PanicOnErr(keyManager.Mnemonic()), lo.PanicOnErr(changedKeyManager.Mnemonic()))
+	})
+}
+
+func TestKeystoreUnsupportedVersion(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	keystore, err := wallet.NewKeystore(keyManager, []byte("password"), nil, wallet.WithKeystoreArgon2id(1, 1024, 1))
+	require.NoError(t, err)
+
+	// there is no migration, so older versions are rejected as well
+	for _, version := range []uint8{0, wallet.KeystoreVersionLatest + 1} {
+		keystore.Version = version
+		_, err = keystore.KeyManager([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreUnsupportedVersion)
+
+		require.ErrorIs(t, keystore.ChangePassword([]byte("password"), []byte("new password")), wallet.ErrKeystoreUnsupportedVersion)
+	}
+}
+
+func TestKeystoreKDFLimits(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	keystore, err := wallet.NewKeystore(keyManager, []byte("password"), nil, wallet.WithKeystoreArgon2id(1, 1024, 1))
+	require.NoError(t, err)
+
+	t.Run("err - argon2id memory of a crafted keystore", func(t *testing.T) {
+		kdfParams := *keystore.Crypto.KDFParams
+		kdfParams.Memory = 4 * 1024 * 1024
+		crypto := *keystore.Crypto
+		crypto.KDFParams = &kdfParams
+		crafted := *keystore
+		crafted.Crypto = &crypto
+
+		_, err := crafted.KeyManager([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalid)
+	})
+
+	t.Run("err - scrypt memory of a crafted keystore", func(t *testing.T) {
+		crafted := *keystore
+		crafted.Crypto = &wallet.KeystoreCrypto{
+			KDF:        wallet.KeystoreKDFScrypt,
+			KDFParams:  &wallet.KeystoreKDFParams{Salt: keystore.Crypto.KDFParams.Salt, N: 1 << 24, R: 8, P: 1},
+			Cipher:     keystore.Crypto.Cipher,
+			Nonce:      keystore.Crypto.Nonce,
+			Ciphertext: keystore.Crypto.Ciphertext,
+		}
+
+		_, err := crafted.KeyManager([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalid)
+	})
+
+	t.Run("err - create with parameters exceeding the limits", func(t *testing.T) {
+		_, err := wallet.NewKeystore(keyManager, []byte("password"), nil, wallet.WithKeystoreArgon2id(100, 1024, 1))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalid)
+	})
+}
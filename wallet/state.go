@@ -0,0 +1,189 @@
+package wallet
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+// StateChangeType defines the type of a StateChange.
+type StateChangeType byte
+
+const (
+	// StateChangeTrackAddress adds a derived address to the tracked addresses.
+	StateChangeTrackAddress StateChangeType = iota + 1
+	// StateChangePutOutput adds an output.
+	StateChangePutOutput
+	// StateChangeDeleteOutput removes an output.
+	StateChangeDeleteOutput
+	// StateChangePutPendingSpend marks an output as spent by a pending transaction.
+	StateChangePutPendingSpend
+	// StateChangeDeletePendingSpend removes the spent marker of an output.
+	StateChangeDeletePendingSpend
+	// StateChangePutPendingTransaction adds a pending transaction.
+	StateChangePutPendingTransaction
+	// StateChangeDeletePendingTransaction removes a pending transaction.
+	StateChangeDeletePendingTransaction
+	// StateChangeSyncedSlot sets the last synced commitment slot.
+	StateChangeSyncedSlot
+)
+
+// State is the state of a Wallet that is persisted by a StateStore.
+type State struct {
+	// The tracked addresses in the order they were added.
+	Addresses []*DerivedAddress
+	// The known outputs which can be unlocked by the tracked addresses.
+	Outputs map[iotago.OutputID]*builder.TxInput
+	// The outputs consumed by pending transactions mapped to the ID of the transaction.
+	PendingSpends map[iotago.OutputID]iotago.TransactionID
+	// The transactions which were created but not confirmed yet.
+	PendingTransactions map[iotago.TransactionID]*iotago.SignedTransaction
+	// The slot of the commitment the state was last synced with.
+	SyncedSlot iotago.SlotIndex
+}
+
+// NewState creates a new empty State.
+func NewState() *State {
+	return &State{
+		Addresses:           make([]*DerivedAddress, 0),
+		Outputs:             make(map[iotago.OutputID]*builder.TxInput),
+		PendingSpends:       make(map[iotago.OutputID]iotago.TransactionID),
+		PendingTransactions: make(map[iotago.TransactionID]*iotago.SignedTransaction),
+	}
+}
+
+// Clone returns a copy of the State. The addresses, outputs and transactions are shared with the copy.
+func (s *State) Clone() *State {
+	clone := NewState()
+	clone.Addresses = append(clone.Addresses, s.Addresses...)
+	for outputID, output := range s.Outputs {
+		clone.Outputs[outputID] = output
+	}
+	for outputID, transactionID := range s.PendingSpends {
+		clone.PendingSpends[outputID] = transactionID
+	}
+	for transactionID, signedTx := range s.PendingTransactions {
+		clone.PendingTransactions[transactionID] = signedTx
+	}
+	clone.SyncedSlot = s.SyncedSlot
+
+	return clone
+}
+
+// Address returns the tracked address with the same key as the given address.
+func (s *State) Address(addr iotago.Address) (*DerivedAddress, bool) {
+	for _, derivedAddress := range s.Addresses {
+		if derivedAddress.Address.Equal(addr) {
+			return derivedAddress, true
+		}
+	}
+
+	return nil, false
+}
+
+// Changes returns the changes that recreate the State on an empty State.
+func (s *State) Changes() []*StateChange {
+	changes := make([]*StateChange, 0, len(s.Addresses)+len(s.Outputs)+len(s.PendingSpends)+len(s.PendingTransactions)+1)
+	for _, derivedAddress := range s.Addresses {
+		changes = append(changes, TrackAddressChange(derivedAddress))
+	}
+	for _, output := range s.Outputs {
+		changes = append(changes, PutOutputChange(output))
+	}
+	for transactionID, signedTx := range s.PendingTransactions {
+		changes = append(changes, &StateChange{Type: StateChangePutPendingTransaction, TransactionID: transactionID, Transaction: signedTx})
+	}
+	for outputID, transactionID := range s.PendingSpends {
+		changes = append(changes, PutPendingSpendChange(outputID, transactionID))
+	}
+
+	return append(changes, SyncedSlotChange(s.SyncedSlot))
+}
+
+// Apply applies the given changes to the State.
+// All changes are idempotent, applying a change twice results in the same State.
+func (s *State) Apply(changes ...*StateChange) error {
+	for _, change := range changes {
+		switch change.Type {
+		case StateChangeTrackAddress:
+			if _, isTracked := s.Address(change.Address.Address); !isTracked {
+				s.Addresses = append(s.Addresses, change.Address)
+			}
+		case StateChangePutOutput:
+			s.Outputs[change.Output.InputID] = change.Output
+		case StateChangeDeleteOutput:
+			delete(s.Outputs, change.OutputID)
+		case StateChangePutPendingSpend:
+			s.PendingSpends[change.OutputID] = change.TransactionID
+		case StateChangeDeletePendingSpend:
+			delete(s.PendingSpends, change.OutputID)
+		case StateChangePutPendingTransaction:
+			s.PendingTransactions[change.TransactionID] = change.Transaction
+		case StateChangeDeletePendingTransaction:
+			delete(s.PendingTransactions, change.TransactionID)
+		case StateChangeSyncedSlot:
+			s.SyncedSlot = change.Slot
+		default:
+			return ierrors.Errorf("unknown state change type %d", change.Type)
+		}
+	}
+
+	return nil
+}
+
+// StateChange is a single modification of a State.
+// Only the fields needed by the type of the change are set.
+type StateChange struct {
+	Type          StateChangeType
+	Address       *DerivedAddress
+	Output        *builder.TxInput
+	OutputID      iotago.OutputID
+	TransactionID iotago.TransactionID
+	Transaction   *iotago.SignedTransaction
+	Slot          iotago.SlotIndex
+}
+
+// TrackAddressChange returns a StateChange that adds the given address to the tracked addresses.
+func TrackAddressChange(derivedAddress *DerivedAddress) *StateChange {
+	return &StateChange{Type: StateChangeTrackAddress, Address: derivedAddress}
+}
+
+// PutOutputChange returns a StateChange that adds the given output.
+func PutOutputChange(output *builder.TxInput) *StateChange {
+	return &StateChange{Type: StateChangePutOutput, Output: output}
+}
+
+// DeleteOutputChange returns a StateChange that removes the given output.
+func DeleteOutputChange(outputID iotago.OutputID) *StateChange {
+	return &StateChange{Type: StateChangeDeleteOutput, OutputID: outputID}
+}
+
+// PutPendingSpendChange returns a StateChange that marks the given output as spent by the given transaction.
+func PutPendingSpendChange(outputID iotago.OutputID, transactionID iotago.TransactionID) *StateChange {
+	return &StateChange{Type: StateChangePutPendingSpend, OutputID: outputID, TransactionID: transactionID}
+}
+
+// DeletePendingSpendChange returns a StateChange that removes the spent marker of the given output.
+func DeletePendingSpendChange(outputID iotago.OutputID) *StateChange {
+	return &StateChange{Type: StateChangeDeletePendingSpend, OutputID: outputID}
+}
+
+// PutPendingTransactionChange returns a StateChange that adds the given pending transaction.
+func PutPendingTransactionChange(signedTx *iotago.SignedTransaction) (*StateChange, error) {
+	transactionID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the transaction ID")
+	}
+
+	return &StateChange{Type: StateChangePutPendingTransaction, TransactionID: transactionID, Transaction: signedTx}, nil
+}
+
+// DeletePendingTransactionChange returns a StateChange that removes the given pending transaction.
+func DeletePendingTransactionChange(transactionID iotago.TransactionID) *StateChange {
+	return &StateChange{Type: StateChangeDeletePendingTransaction, TransactionID: transactionID}
+}
+
+// SyncedSlotChange returns a StateChange that sets the last synced commitment slot.
+func SyncedSlotChange(slot iotago.SlotIndex) *StateChange {
+	return &StateChange{Type: StateChangeSyncedSlot, Slot: slot}
+}
@@ -0,0 +1,687 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+const (
+	// StateStoreVersion is the version of the file format written by the FileStateStore.
+	StateStoreVersion byte = 1
+
+	// DefaultStateStoreCompactionThreshold is the number of log records after which the FileStateStore writes a new snapshot.
+	DefaultStateStoreCompactionThreshold = 1000
+
+	stateSnapshotFileName = "state.snapshot"
+	stateLogFileName      = "state.log"
+)
+
+var (
+	stateSnapshotMagic = []byte("IWSS")
+	stateLogMagic      = []byte("IWSL")
+)
+
+var (
+	// errRecordTorn gets returned if a record ends before its announced length, e.g. because of a crash while writing it.
+	errRecordTorn = ierrors.New("record is incomplete")
+	// errRecordChecksumMismatch gets returned if the checksum of a record doesn't match its data.
+	errRecordChecksumMismatch = ierrors.New("record checksum mismatch")
+)
+
+var (
+	// ErrStateStoreUnsupportedVersion gets returned if the files of a FileStateStore were written in an unsupported version.
+	ErrStateStoreUnsupportedVersion = ierrors.New("unsupported state store version")
+	// ErrStateStoreCorrupted gets returned if the files of a FileStateStore can't be read.
+	ErrStateStoreCorrupted = ierrors.New("state store is corrupted")
+	// ErrStateStoreClosed gets returned if a closed StateStore is used.
+	ErrStateStoreClosed = ierrors.New("state store is closed")
+)
+
+// StateStore persists the State of a Wallet.
+type StateStore interface {
+	// Load returns the persisted State.
+	Load() (*State, error)
+	// Apply persists the given changes as one batch.
+	Apply(changes ...*StateChange) error
+	// Close closes the StateStore.
+	Close() error
+}
+
+var _ StateStore = &MemoryStateStore{}
+
+// MemoryStateStore is a StateStore that keeps the State in memory.
+type MemoryStateStore struct {
+	mutex  sync.RWMutex
+	state  *State
+	closed bool
+}
+
+// NewMemoryStateStore creates a new empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		state: NewState(),
+	}
+}
+
+// Load returns a copy of the State.
+func (m *MemoryStateStore) Load() (*State, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.closed {
+		return nil, ErrStateStoreClosed
+	}
+
+	return m.state.Clone(), nil
+}
+
+// Apply applies the changes to the State.
+func (m *MemoryStateStore) Apply(changes ...*StateChange) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return ErrStateStoreClosed
+	}
+
+	return m.state.Apply(changes...)
+}
+
+// Close closes the MemoryStateStore.
+func (m *MemoryStateStore) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.closed = true
+
+	return nil
+}
+
+var _ StateStore = &FileStateStore{}
+
+// FileStateStoreOptions define the options of a FileStateStore.
+type FileStateStoreOptions struct {
+	compactionThreshold int
+}
+
+// WithStateStoreCompactionThreshold sets the number of log records after which a new snapshot is written.
+func WithStateStoreCompactionThreshold(compactionThreshold int) options.Option[FileStateStoreOptions] {
+	return func(opts *FileStateStoreOptions) {
+		opts.compactionThreshold = compactionThreshold
+	}
+}
+
+// FileStateStore is a StateStore that persists the State in a directory.
+// Every batch of changes is appended to a log file, which is compacted into a snapshot file
+// once it contains more records than the compaction threshold.
+// Every log record is checksummed. A torn or corrupted batch at the very end of the log, e.g. caused by a crash
+// while writing, is discarded on opening. A corrupted batch followed by further batches is reported as ErrStateStoreCorrupted,
+// also if its corrupted length spans the further batches.
+// Since all changes are idempotent, replaying a log that was already compacted into the snapshot is safe.
+// Files written in any other version than StateStoreVersion are rejected with ErrStateStoreUnsupportedVersion.
+type FileStateStore struct {
+	api       iotago.API
+	directory string
+	opts      *FileStateStoreOptions
+
+	mutex         sync.Mutex
+	state         *State
+	logFile       *os.File
+	logRecords    int
+	compactionErr error
+}
+
+// NewFileStateStore opens the FileStateStore in the given directory, which is created if it doesn't exist.
+func NewFileStateStore(api iotago.API, directory string, opts ...options.Option[FileStateStoreOptions]) (*FileStateStore, error) {
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		return nil, ierrors.Wrap(err, "failed to create state store directory")
+	}
+
+	f := &FileStateStore{
+		api:       api,
+		directory: directory,
+		opts: options.Apply(&FileStateStoreOptions{
+			compactionThreshold: DefaultStateStoreCompactionThreshold,
+		}, opts),
+		state: NewState(),
+	}
+
+	if err := f.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	if err := f.openLog(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Load returns a copy of the State.
+func (f *FileStateStore) Load() (*State, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.logFile == nil {
+		return nil, ErrStateStoreClosed
+	}
+
+	return f.state.Clone(), nil
+}
+
+// Apply appends the changes to the log and compacts the log if needed.
+// The changes are applied once they are persisted in the log, so a failed compaction doesn't fail Apply.
+// If the batch can't be persisted, the log is truncated to its former length and the State is left unchanged.
+// It is retried with every following Apply and the error of the last attempt is returned by CompactionErr.
+func (f *FileStateStore) Apply(changes ...*StateChange) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.logFile == nil {
+		return ErrStateStoreClosed
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// the changes are applied to a copy first, so the log never contains changes the state rejected
+	state := f.state.Clone()
+	if err := state.Apply(changes...); err != nil {
+		return err
+	}
+
+	batch, err := f.encodeRecords(changes, true)
+	if err != nil {
+		return err
+	}
+
+	if err := f.appendBatch(batch); err != nil {
+		return err
+	}
+	f.state = state
+
+	f.logRecords += len(changes)
+	if f.logRecords >= f.opts.compactionThreshold {
+		f.compactionErr = f.compact()
+	}
+
+	return nil
+}
+
+// CompactionErr returns the error of the last automatic compaction, nil if it succeeded.
+func (f *FileStateStore) CompactionErr() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.compactionErr
+}
+
+// Compact writes the State to a new snapshot and truncates the log.
+func (f *FileStateStore) Compact() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.logFile == nil {
+		return ErrStateStoreClosed
+	}
+
+	f.compactionErr = f.compact()
+
+	return f.compactionErr
+}
+
+// Close closes the log of the FileStateStore.
+func (f *FileStateStore) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.logFile == nil {
+		return nil
+	}
+
+	err := f.logFile.Close()
+	f.logFile = nil
+
+	return err
+}
+
+// appendBatch appends the batch to the log and syncs it.
+// A failed append is rolled back, so the following batches are not appended behind a torn one.
+// If the rollback fails too, the log is closed, since the torn batch is only dropped when the log is opened again.
+func (f *FileStateStore) appendBatch(batch []byte) error {
+	offset, err := f.logFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to get the offset of the state log")
+	}
+
+	if _, err = f.logFile.Write(batch); err != nil {
+		err = ierrors.Wrap(err, "failed to append to the state log")
+	} else if err = f.logFile.Sync(); err != nil {
+		err = ierrors.Wrap(err, "failed to sync the state log")
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if rollbackErr := truncateLog(f.logFile, offset); rollbackErr != nil {
+		_ = f.logFile.Close()
+		f.logFile = nil
+
+		return ierrors.Join(err, rollbackErr, ErrStateStoreClosed)
+	}
+
+	return err
+}
+
+// truncateLog truncates the log to the given length and moves the offset to its end.
+func truncateLog(logFile *os.File, length int64) error {
+	if err := logFile.Truncate(length); err != nil {
+		return ierrors.Wrap(err, "failed to truncate the state log")
+	}
+
+	if _, err := logFile.Seek(length, io.SeekStart); err != nil {
+		return ierrors.Wrap(err, "failed to seek to the end of the state log")
+	}
+
+	return nil
+}
+
+func (f *FileStateStore) compact() error {
+	records, err := f.encodeRecords(f.state.Changes(), false)
+	if err != nil {
+		return err
+	}
+
+	snapshot := bytes.NewBuffer(nil)
+	snapshot.Write(stateSnapshotMagic)
+	snapshot.WriteByte(StateStoreVersion)
+	writeRecord(snapshot, records)
+
+	if err := writeFileAtomically(filepath.Join(f.directory, stateSnapshotFileName), snapshot.Bytes()); err != nil {
+		return ierrors.Wrap(err, "failed to write the state snapshot")
+	}
+
+	// a crash before the log is reset only leads to replaying changes which are already part of the snapshot
+	if err := resetLog(f.logFile); err != nil {
+		return err
+	}
+	f.logRecords = 0
+
+	return nil
+}
+
+// resetLog empties the log and writes the header of the latest version.
+func resetLog(logFile *os.File) error {
+	if err := truncateLog(logFile, 0); err != nil {
+		return err
+	}
+
+	if _, err := logFile.Write(append(append([]byte{}, stateLogMagic...), StateStoreVersion)); err != nil {
+		return ierrors.Wrap(err, "failed to write the state log header")
+	}
+
+	return nil
+}
+
+// loadSnapshot reads the snapshot file if it exists.
+func (f *FileStateStore) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(f.directory, stateSnapshotFileName))
+	if err != nil {
+		if ierrors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return ierrors.Wrap(err, "failed to read the state snapshot")
+	}
+
+	reader := bytes.NewReader(data)
+	if err := readHeader(reader, stateSnapshotMagic); err != nil {
+		return ierrors.Wrap(err, "failed to read the state snapshot")
+	}
+
+	snapshot, err := readRecord(reader, int64(reader.Len()))
+	if err != nil {
+		return ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to read the state snapshot"))
+	}
+
+	records, err := readRecords(snapshot)
+	if err != nil {
+		return ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to read the state snapshot records"))
+	}
+
+	changes, err := f.decodeRecords(records)
+	if err != nil {
+		return ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to decode the state snapshot"))
+	}
+
+	return f.state.Apply(changes...)
+}
+
+// openLog replays the log file on top of the snapshot and opens it for appending.
+// An incomplete batch at the end of the log is removed.
+func (f *FileStateStore) openLog() error {
+	logFile, err := os.OpenFile(filepath.Join(f.directory, stateLogFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to open the state log")
+	}
+
+	fileInfo, err := logFile.Stat()
+	if err != nil {
+		_ = logFile.Close()
+
+		return ierrors.Wrap(err, "failed to stat the state log")
+	}
+
+	validLength := int64(len(stateLogMagic) + 1)
+	if fileInfo.Size() == 0 {
+		header := append(append([]byte{}, stateLogMagic...), StateStoreVersion)
+		if _, err := logFile.Write(header); err != nil {
+			_ = logFile.Close()
+
+			return ierrors.Wrap(err, "failed to write the state log header")
+		}
+	} else {
+		if validLength, err = f.replayLog(logFile, fileInfo.Size()); err != nil {
+			_ = logFile.Close()
+
+			return err
+		}
+	}
+
+	// drop the torn tail of the log
+	if err := truncateLog(logFile, validLength); err != nil {
+		_ = logFile.Close()
+
+		return err
+	}
+
+	f.logFile = logFile
+
+	return nil
+}
+
+// replayLog applies all complete batches of the log and returns the length of its valid part.
+// Only a torn or corrupted batch at the very end of the log, which is not followed by any complete batch,
+// is treated as the result of a crash while appending.
+func (f *FileStateStore) replayLog(logFile *os.File, size int64) (int64, error) {
+	reader := bufio.NewReader(logFile)
+	if err := readHeader(reader, stateLogMagic); err != nil {
+		return 0, ierrors.Wrap(err, "failed to read the state log")
+	}
+
+	validLength := int64(len(stateLogMagic) + 1)
+	for {
+		batch, err := readRecord(reader, size-validLength)
+		if err != nil {
+			if ierrors.Is(err, io.EOF) {
+				return validLength, nil
+			}
+
+			_, peekErr := reader.Peek(1)
+			if ierrors.Is(err, errRecordTorn) || (ierrors.Is(err, errRecordChecksumMismatch) && ierrors.Is(peekErr, io.EOF)) {
+				// the last batch was not completely written before a crash, unless a corrupted length
+				// made the batch swallow further batches, which were acknowledged to the caller
+				followed, tailErr := followedByBatch(logFile, validLength+recordHeaderLength, size)
+				if tailErr != nil {
+					return 0, ierrors.Wrap(tailErr, "failed to read the end of the state log")
+				}
+
+				if !followed {
+					return validLength, nil
+				}
+			}
+
+			return 0, ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrapf(err, "failed to read the state log batch at offset %d", validLength))
+		}
+
+		records, err := readRecords(batch)
+		if err != nil {
+			return 0, ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to read a state log batch"))
+		}
+
+		changes, err := f.decodeRecords(records)
+		if err != nil {
+			return 0, ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to decode a state log batch"))
+		}
+
+		if err := f.state.Apply(changes...); err != nil {
+			return 0, err
+		}
+
+		validLength += int64(recordHeaderLength + len(batch))
+		f.logRecords += len(changes)
+	}
+}
+
+// followedByBatch returns whether the log contains a complete batch between the given offset and its end.
+// A torn batch only contains a prefix of a single batch, so a complete batch after its header means that
+// the length of the batch is corrupted.
+func followedByBatch(logFile *os.File, offset int64, size int64) (bool, error) {
+	if offset >= size {
+		return false, nil
+	}
+
+	tail := make([]byte, size-offset)
+	if _, err := logFile.ReadAt(tail, offset); err != nil {
+		return false, err
+	}
+
+	for start := range tail {
+		batch, err := readRecord(bytes.NewReader(tail[start:]), int64(len(tail)-start))
+		if err != nil {
+			continue
+		}
+
+		if records, err := readRecords(batch); err == nil && len(records) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// stateChangeRecord is the serialized form of a StateChange.
+type stateChangeRecord struct {
+	Type          StateChangeType           `serix:""`
+	Account       uint32                    `serix:""`
+	Change        uint32                    `serix:""`
+	AddressIndex  uint32                    `serix:""`
+	Address       iotago.Address            `serix:",optional"`
+	OutputID      iotago.OutputID           `serix:""`
+	Output        iotago.TxEssenceOutput    `serix:",optional"`
+	TransactionID iotago.TransactionID      `serix:""`
+	Transaction   *iotago.SignedTransaction `serix:",optional"`
+	Slot          iotago.SlotIndex          `serix:""`
+}
+
+// encodeRecords serializes the changes into checksummed records.
+// If asBatch is true, the records are wrapped into a single checksummed batch record.
+func (f *FileStateStore) encodeRecords(changes []*StateChange, asBatch bool) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	for _, change := range changes {
+		record := &stateChangeRecord{
+			Type:          change.Type,
+			OutputID:      change.OutputID,
+			TransactionID: change.TransactionID,
+			Transaction:   change.Transaction,
+			Slot:          change.Slot,
+		}
+
+		if change.Address != nil {
+			record.Account = change.Address.Account
+			record.Change = change.Address.Change
+			record.AddressIndex = change.Address.AddressIndex
+			record.Address = change.Address.Address
+		}
+
+		if change.Output != nil {
+			output, ok := change.Output.Input.(iotago.TxEssenceOutput)
+			if !ok {
+				return nil, ierrors.Errorf("output %s has unsupported type %T", change.Output.InputID.ToHex(), change.Output.Input)
+			}
+			record.Address = change.Output.UnlockTarget
+			record.OutputID = change.Output.InputID
+			record.Output = output
+		}
+
+		data, err := f.api.Encode(record)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to encode state change of type %d", change.Type)
+		}
+		writeRecord(buffer, data)
+	}
+
+	if !asBatch {
+		return buffer.Bytes(), nil
+	}
+
+	batch := bytes.NewBuffer(nil)
+	writeRecord(batch, buffer.Bytes())
+
+	return batch.Bytes(), nil
+}
+
+// decodeRecords deserializes the given records into changes.
+func (f *FileStateStore) decodeRecords(records [][]byte) ([]*StateChange, error) {
+	changes := make([]*StateChange, 0, len(records))
+	for _, data := range records {
+		record := new(stateChangeRecord)
+		if _, err := f.api.Decode(data, record); err != nil {
+			return nil, err
+		}
+
+		change := &StateChange{
+			Type:          record.Type,
+			OutputID:      record.OutputID,
+			TransactionID: record.TransactionID,
+			Transaction:   record.Transaction,
+			Slot:          record.Slot,
+		}
+
+		switch record.Type {
+		case StateChangeTrackAddress:
+			addr, ok := record.Address.(iotago.DirectUnlockableAddress)
+			if !ok {
+				return nil, ierrors.Errorf("tracked address has unsupported type %T", record.Address)
+			}
+			change.Address = &DerivedAddress{
+				Account:      record.Account,
+				Change:       record.Change,
+				AddressIndex: record.AddressIndex,
+				Address:      addr,
+			}
+		case StateChangePutOutput:
+			if record.Output == nil || record.Address == nil {
+				return nil, ierrors.New("output record without output or unlock target")
+			}
+			change.Output = &builder.TxInput{
+				UnlockTarget: record.Address,
+				InputID:      record.OutputID,
+				Input:        record.Output,
+			}
+		case StateChangePutPendingTransaction:
+			if record.Transaction == nil {
+				return nil, ierrors.New("pending transaction record without transaction")
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// recordHeaderLength is the length of the length prefix and the checksum of a record.
+const recordHeaderLength = 8
+
+// writeRecord writes the data prefixed with its length and CRC32 checksum.
+func writeRecord(buffer *bytes.Buffer, data []byte) {
+	var header [recordHeaderLength]byte
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(data))
+	buffer.Write(header[:])
+	buffer.Write(data)
+}
+
+// readRecord reads a record written by writeRecord and verifies its checksum.
+// The remaining length of the input bounds the length of the record, so a corrupted header can't cause a huge allocation.
+// It returns io.EOF if the input ends before the record and errRecordTorn if it ends within the record.
+func readRecord(reader io.Reader, remainingLength int64) ([]byte, error) {
+	var header [recordHeaderLength]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if ierrors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errRecordTorn
+		}
+
+		return nil, err
+	}
+
+	length := int64(binary.LittleEndian.Uint32(header[:4]))
+	if length > remainingLength-recordHeaderLength {
+		return nil, ierrors.WithMessagef(errRecordTorn, "record length %d exceeds the remaining %d bytes", length, remainingLength-recordHeaderLength)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if ierrors.Is(err, io.EOF) || ierrors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errRecordTorn
+		}
+
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errRecordChecksumMismatch
+	}
+
+	return data, nil
+}
+
+// readRecords reads all records of the given data.
+func readRecords(data []byte) ([][]byte, error) {
+	reader := bytes.NewReader(data)
+
+	records := make([][]byte, 0)
+	for reader.Len() > 0 {
+		record, err := readRecord(reader, int64(reader.Len()))
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// readHeader reads and checks the magic and the version of a state store file.
+// There is only a single version of the format so far, so every other version is rejected.
+func readHeader(reader io.Reader, magic []byte) error {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return ierrors.Join(ErrStateStoreCorrupted, ierrors.Wrap(err, "failed to read the header"))
+	}
+
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return ierrors.WithMessagef(ErrStateStoreCorrupted, "invalid magic %q", header[:len(magic)])
+	}
+
+	if version := header[len(magic)]; version != StateStoreVersion {
+		return ierrors.WithMessagef(ErrStateStoreUnsupportedVersion, "version %d, expected version %d", version, StateStoreVersion)
+	}
+
+	return nil
+}
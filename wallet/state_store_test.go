@@ -0,0 +1,415 @@
+package wallet_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+// randStateChanges returns changes that track an address, add two outputs and spend one of them in a pending transaction.
+func randStateChanges(t *testing.T) []*wallet.StateChange {
+	t.Helper()
+
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	addr, err := keyManager.AddressAt(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+
+	spentOutput := &builder.TxInput{
+		UnlockTarget: addr,
+		InputID:      tpkg.RandOutputID(0),
+		Input:        builder.NewBasicOutputBuilder(addr, 1_000_000).MustBuild(),
+	}
+
+	signedTx := tpkg.RandSignedTransaction(mockAPI)
+	pendingTransactionChange, err := wallet.PutPendingTransactionChange(signedTx)
+	require.NoError(t, err)
+
+	return []*wallet.StateChange{
+		wallet.TrackAddressChange(&wallet.DerivedAddress{Address: addr}),
+		wallet.PutOutputChange(spentOutput),
+		wallet.PutOutputChange(&builder.TxInput{
+			UnlockTarget: addr,
+			InputID:      tpkg.RandOutputID(1),
+			Input:        builder.NewBasicOutputBuilder(addr, 2_000_000).MustBuild(),
+		}),
+		pendingTransactionChange,
+		wallet.PutPendingSpendChange(spentOutput.InputID, pendingTransactionChange.TransactionID),
+		wallet.SyncedSlotChange(42),
+	}
+}
+
+func requireStateEqual(t *testing.T, expected *wallet.State, actual *wallet.State) {
+	t.Helper()
+
+	require.Len(t, actual.Addresses, len(expected.Addresses))
+	for i := range expected.Addresses {
+		require.Equal(t, expected.Addresses[i].AddressIndex, actual.Addresses[i].AddressIndex)
+		require.True(t, expected.Addresses[i].Address.Equal(actual.Addresses[i].Address))
+	}
+
+	require.Len(t, actual.Outputs, len(expected.Outputs))
+	for outputID, output := range expected.Outputs {
+		require.Contains(t, actual.Outputs, outputID)
+		require.True(t, output.Input.Equal(actual.Outputs[outputID].Input))
+		require.True(t, output.UnlockTarget.Equal(actual.Outputs[outputID].UnlockTarget))
+	}
+
+	require.Equal(t, expected.PendingSpends, actual.PendingSpends)
+
+	require.Len(t, actual.PendingTransactions, len(expected.PendingTransactions))
+	for transactionID := range expected.PendingTransactions {
+		require.Contains(t, actual.PendingTransactions, transactionID)
+		actualTransactionID, err := actual.PendingTransactions[transactionID].Transaction.ID()
+		require.NoError(t, err)
+		require.Equal(t, transactionID, actualTransactionID)
+	}
+
+	require.Equal(t, expected.SyncedSlot, actual.SyncedSlot)
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	changes := randStateChanges(t)
+
+	expected := wallet.NewState()
+	require.NoError(t, expected.Apply(changes...))
+
+	store := wallet.NewMemoryStateStore()
+	require.NoError(t, store.Apply(changes...))
+
+	state, err := store.Load()
+	require.NoError(t, err)
+	requireStateEqual(t, expected, state)
+
+	// the loaded state is a copy
+	require.NoError(t, state.Apply(wallet.SyncedSlotChange(100)))
+	state, err = store.Load()
+	require.NoError(t, err)
+	require.Equal(t, iotago.SlotIndex(42), state.SyncedSlot)
+
+	require.NoError(t, store.Close())
+	require.ErrorIs(t, store.Apply(wallet.SyncedSlotChange(100)), wallet.ErrStateStoreClosed)
+}
+
+func TestFileStateStore(t *testing.T) {
+	changes := randStateChanges(t)
+
+	expected := wallet.NewState()
+	require.NoError(t, expected.Apply(changes...))
+
+	t.Run("ok - reopen", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		// every change in its own batch
+		for _, change := range changes {
+			require.NoError(t, store.Apply(change))
+		}
+		require.NoError(t, store.Close())
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		defer store.Close()
+
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+	})
+
+	t.Run("ok - torn tail", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+		require.NoError(t, store.Close())
+
+		logPath := filepath.Join(directory, "state.log")
+		validLog, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+
+		// simulate a crash while appending the next batch
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(wallet.DeleteOutputChange(tpkg.RandOutputID(0)), wallet.SyncedSlotChange(100)))
+		require.NoError(t, store.Close())
+
+		log, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(logPath, log[:len(log)-3], 0o600))
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+
+		// the torn batch was removed and new batches are appended to the valid part of the log
+		require.NoError(t, store.Apply(wallet.SyncedSlotChange(200)))
+		require.NoError(t, store.Close())
+
+		log, err = os.ReadFile(logPath)
+		require.NoError(t, err)
+		require.Equal(t, validLog, log[:len(validLog)])
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		defer store.Close()
+
+		state, err = store.Load()
+		require.NoError(t, err)
+		require.Equal(t, iotago.SlotIndex(200), state.SyncedSlot)
+	})
+
+	t.Run("ok - compaction", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory, wallet.WithStateStoreCompactionThreshold(len(changes)))
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+		require.NoError(t, store.Close())
+
+		require.FileExists(t, filepath.Join(directory, "state.snapshot"))
+		logInfo, err := os.Stat(filepath.Join(directory, "state.log"))
+		require.NoError(t, err)
+		require.EqualValues(t, 5, logInfo.Size())
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		defer store.Close()
+
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+	})
+
+	t.Run("fail - unsupported version", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+		require.NoError(t, store.Compact())
+		require.NoError(t, store.Close())
+
+		snapshotPath := filepath.Join(directory, "state.snapshot")
+		snapshot, err := os.ReadFile(snapshotPath)
+		require.NoError(t, err)
+		snapshot[4] = wallet.StateStoreVersion + 1
+		require.NoError(t, os.WriteFile(snapshotPath, snapshot, 0o600))
+
+		_, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.ErrorIs(t, err, wallet.ErrStateStoreUnsupportedVersion)
+
+		// older versions are rejected as well, since there is no migration
+		snapshot[4] = wallet.StateStoreVersion - 1
+		require.NoError(t, os.WriteFile(snapshotPath, snapshot, 0o600))
+
+		_, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.ErrorIs(t, err, wallet.ErrStateStoreUnsupportedVersion)
+
+		snapshot[4] = wallet.StateStoreVersion
+		require.NoError(t, os.WriteFile(snapshotPath, snapshot, 0o600))
+
+		logPath := filepath.Join(directory, "state.log")
+		log, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		log[4] = wallet.StateStoreVersion + 1
+		require.NoError(t, os.WriteFile(logPath, log, 0o600))
+
+		_, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.ErrorIs(t, err, wallet.ErrStateStoreUnsupportedVersion)
+
+		// the rejected files are left untouched
+		rejectedLog, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		require.Equal(t, log, rejectedLog)
+	})
+
+	t.Run("ok - huge length of the last batch", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+		require.NoError(t, store.Close())
+
+		logPath := filepath.Join(directory, "state.log")
+		validLog, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+
+		// a torn batch whose length field announces 4 GiB
+		require.NoError(t, os.WriteFile(logPath, append(validLog, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 2, 3), 0o600))
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		defer store.Close()
+
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+	})
+
+	t.Run("fail - corrupted batch followed by further batches", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+		require.NoError(t, store.Apply(wallet.SyncedSlotChange(100)))
+		require.NoError(t, store.Close())
+
+		logPath := filepath.Join(directory, "state.log")
+		log, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+
+		// flip a bit in the data of the first batch, the acknowledged second batch must not be dropped silently
+		log[5+8+10] ^= 0x01
+		require.NoError(t, os.WriteFile(logPath, log, 0o600))
+
+		_, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.ErrorIs(t, err, wallet.ErrStateStoreCorrupted)
+	})
+
+	t.Run("fail - corrupted length of a batch followed by further batches", func(t *testing.T) {
+		for _, corruptedLength := range []func(log []byte) uint32{
+			// the length exceeds the log
+			func([]byte) uint32 { return 0xffffffff },
+			// the length runs exactly to the end of the log
+			func(log []byte) uint32 { return uint32(len(log) - 5 - 8) },
+		} {
+			directory := t.TempDir()
+
+			store, err := wallet.NewFileStateStore(mockAPI, directory)
+			require.NoError(t, err)
+			require.NoError(t, store.Apply(changes...))
+			require.NoError(t, store.Apply(wallet.SyncedSlotChange(100)))
+			require.NoError(t, store.Close())
+
+			logPath := filepath.Join(directory, "state.log")
+			log, err := os.ReadFile(logPath)
+			require.NoError(t, err)
+
+			// corrupt the length field of the first batch, the acknowledged second batch must not be dropped silently
+			binary.LittleEndian.PutUint32(log[5:], corruptedLength(log))
+			require.NoError(t, os.WriteFile(logPath, log, 0o600))
+
+			_, err = wallet.NewFileStateStore(mockAPI, directory)
+			require.ErrorIs(t, err, wallet.ErrStateStoreCorrupted)
+		}
+	})
+
+	t.Run("fail - rejected changes are not logged", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		require.NoError(t, store.Apply(changes...))
+
+		logPath := filepath.Join(directory, "state.log")
+		validLog, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+
+		require.Error(t, store.Apply(wallet.SyncedSlotChange(100), &wallet.StateChange{Type: 255}))
+
+		log, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		require.Equal(t, validLog, log)
+
+		// neither the in-memory state nor the log contain the valid change of the rejected batch
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+
+		require.NoError(t, store.Apply(wallet.SyncedSlotChange(200)))
+		require.NoError(t, store.Close())
+
+		store, err = wallet.NewFileStateStore(mockAPI, directory)
+		require.NoError(t, err)
+		defer store.Close()
+
+		state, err = store.Load()
+		require.NoError(t, err)
+		require.Equal(t, iotago.SlotIndex(200), state.SyncedSlot)
+	})
+
+	t.Run("ok - failed compaction does not fail apply", func(t *testing.T) {
+		directory := t.TempDir()
+
+		store, err := wallet.NewFileStateStore(mockAPI, directory, wallet.WithStateStoreCompactionThreshold(1))
+		require.NoError(t, err)
+		defer store.Close()
+
+		// the snapshot can't replace a non-empty directory
+		snapshotPath := filepath.Join(directory, "state.snapshot")
+		require.NoError(t, os.MkdirAll(filepath.Join(snapshotPath, "blocker"), 0o700))
+
+		require.NoError(t, store.Apply(changes...))
+		require.Error(t, store.CompactionErr())
+
+		state, err := store.Load()
+		require.NoError(t, err)
+		requireStateEqual(t, expected, state)
+
+		// the compaction is retried with the next batch
+		require.NoError(t, os.RemoveAll(snapshotPath))
+		require.NoError(t, store.Apply(wallet.SyncedSlotChange(42)))
+		require.NoError(t, store.CompactionErr())
+	})
+}
+
+func TestLoadWallet(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	directory := t.TempDir()
+
+	store, err := wallet.NewFileStateStore(mockAPI, directory)
+	require.NoError(t, err)
+
+	w, err := wallet.LoadWallet(mockAPI, keyManager, store)
+	require.NoError(t, err)
+
+	addr, err := w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+
+	creationSlot := iotago.SlotIndex(100)
+	require.NoError(t, w.AddOutput(addr, tpkg.RandOutputIDWithCreationSlot(creationSlot, 0), builder.NewBasicOutputBuilder(addr, 10_000_000).MustBuild()))
+	require.NoError(t, w.AddOutput(addr, tpkg.RandOutputIDWithCreationSlot(creationSlot, 1), builder.NewBasicOutputBuilder(addr, 5_000_000).MustBuild()))
+
+	signedTx, err := w.Send(creationSlot+10, []iotago.Output{builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 8_000_000).MustBuild()})
+	require.NoError(t, err)
+	transactionID, err := signedTx.Transaction.ID()
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = wallet.NewFileStateStore(mockAPI, directory)
+	require.NoError(t, err)
+	defer store.Close()
+
+	restored, err := wallet.LoadWallet(mockAPI, keyManager, store)
+	require.NoError(t, err)
+
+	require.Len(t, restored.Addresses(), 1)
+	require.True(t, addr.Equal(restored.Addresses()[0].Address))
+	require.Len(t, restored.Outputs(), 2)
+	require.Equal(t, w.PendingSpends(), restored.PendingSpends())
+	require.Contains(t, restored.PendingTransactions(), transactionID)
+
+	balance, err := restored.Balance(creationSlot + 10)
+	require.NoError(t, err)
+	expectedBalance, err := w.Balance(creationSlot + 10)
+	require.NoError(t, err)
+	require.Equal(t, expectedBalance.BaseTokens, balance.BaseTokens)
+}
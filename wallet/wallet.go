@@ -0,0 +1,723 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"maps"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+)
+
+var (
+	// ErrAddressNotTracked gets returned if an address is not tracked by the Wallet.
+	ErrAddressNotTracked = ierrors.New("address is not tracked by the wallet")
+	// ErrNoAddressTracked gets returned if the Wallet needs an address but doesn't track any.
+	ErrNoAddressTracked = ierrors.New("wallet doesn't track any address")
+)
+
+// Wallet owns a KeyManager and keeps a local set of the outputs that can be unlocked by the tracked addresses.
+// The set is kept up to date by following the event API of a node and by periodic resyncs with the indexer.
+// Outputs consumed by transactions created with Send are marked as pending spends
+// until the node reports them as spent. All changes of the State are persisted by the StateStore of the Wallet.
+type Wallet struct {
+	api        iotago.API
+	keyManager *KeyManager
+	store      StateStore
+
+	mutex sync.RWMutex
+	state *State
+}
+
+// NewWallet creates a new Wallet for the given KeyManager, which doesn't track any address yet.
+// The State of the Wallet is only kept in memory.
+func NewWallet(api iotago.API, keyManager *KeyManager) *Wallet {
+	return &Wallet{
+		api:        api,
+		keyManager: keyManager,
+		store:      NewMemoryStateStore(),
+		state:      NewState(),
+	}
+}
+
+// LoadWallet creates a Wallet for the given KeyManager with the State persisted in the given StateStore.
+func LoadWallet(api iotago.API, keyManager *KeyManager, store StateStore) (*Wallet, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to load the wallet state")
+	}
+
+	return &Wallet{
+		api:        api,
+		keyManager: keyManager,
+		store:      store,
+		state:      state,
+	}, nil
+}
+
+// KeyManager returns the KeyManager of the Wallet.
+func (w *Wallet) KeyManager() *KeyManager {
+	return w.keyManager
+}
+
+// apply persists the changes and applies them to the State.
+func (w *Wallet) apply(changes ...*StateChange) error {
+	if err := w.store.Apply(changes...); err != nil {
+		return ierrors.Wrap(err, "failed to persist the wallet state")
+	}
+
+	return w.state.Apply(changes...)
+}
+
+// TrackAddress derives the address of the given type and indexes and adds it to the tracked addresses.
+func (w *Wallet) TrackAddress(addressType iotago.AddressType, account uint32, change uint32, addressIndex uint32) (iotago.DirectUnlockableAddress, error) {
+	addr, err := w.keyManager.AddressAt(addressType, account, change, addressIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, isTracked := w.state.Address(addr); isTracked {
+		return addr, nil
+	}
+
+	if err := w.apply(TrackAddressChange(&DerivedAddress{
+		Account:      account,
+		Change:       change,
+		AddressIndex: addressIndex,
+		Address:      addr,
+	})); err != nil {
+		return nil, err
+	}
+
+	return addr, nil
+}
+
+// Addresses returns the tracked addresses.
+func (w *Wallet) Addresses() []*DerivedAddress {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return slices.Clone(w.state.Addresses)
+}
+
+// SyncedSlot returns the slot of the commitment the Wallet was last synced with.
+func (w *Wallet) SyncedSlot() iotago.SlotIndex {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.state.SyncedSlot
+}
+
+// Discover runs the address discovery for the KeyManager of the Wallet
+// and tracks the discovered addresses together with their outputs.
+func (w *Wallet) Discover(ctx context.Context, indexer nodeclient.IndexerClient, opts ...options.Option[DiscoveryOptions]) error {
+	discovered, err := DiscoverAddresses(ctx, indexer, w.keyManager, w.api.ProtocolParameters().Bech32HRP(), opts...)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	changes := make([]*StateChange, 0)
+	for _, discoveredAddress := range discovered {
+		derivedAddress := discoveredAddress.DerivedAddress
+		changes = append(changes, TrackAddressChange(&derivedAddress))
+
+		for _, output := range discoveredAddress.Outputs {
+			changes = append(changes, PutOutputChange(output))
+		}
+	}
+
+	return w.apply(changes...)
+}
+
+// AddOutput adds an output that can be unlocked by the given tracked address to the Wallet.
+func (w *Wallet) AddOutput(unlockTarget iotago.Address, outputID iotago.OutputID, output iotago.Output) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	derivedAddress, isTracked := w.state.Address(unlockTarget)
+	if !isTracked {
+		return ierrors.WithMessagef(ErrAddressNotTracked, "address %s", unlockTarget.Bech32(w.api.ProtocolParameters().Bech32HRP()))
+	}
+
+	return w.apply(PutOutputChange(&builder.TxInput{
+		UnlockTarget: derivedAddress.Address,
+		InputID:      outputID,
+		Input:        output,
+	}))
+}
+
+// RemoveOutput removes a spent output from the Wallet.
+func (w *Wallet) RemoveOutput(outputID iotago.OutputID) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.apply(w.deleteOutputsChanges(outputID)...)
+}
+
+// deleteOutputsChanges returns the changes that remove the given outputs and their pending spends.
+// Pending transactions are removed once none of their inputs is pending anymore.
+func (w *Wallet) deleteOutputsChanges(outputIDs ...iotago.OutputID) []*StateChange {
+	changes := make([]*StateChange, 0, len(outputIDs))
+	deleted := make(map[iotago.OutputID]struct{}, len(outputIDs))
+	affectedTransactionIDs := make(map[iotago.TransactionID]struct{})
+
+	for _, outputID := range outputIDs {
+		changes = append(changes, DeleteOutputChange(outputID))
+		deleted[outputID] = struct{}{}
+
+		if transactionID, isPending := w.state.PendingSpends[outputID]; isPending {
+			changes = append(changes, DeletePendingSpendChange(outputID))
+			affectedTransactionIDs[transactionID] = struct{}{}
+		}
+	}
+
+	for outputID, transactionID := range w.state.PendingSpends {
+		if _, isDeleted := deleted[outputID]; !isDeleted {
+			// the transaction still has pending inputs
+			delete(affectedTransactionIDs, transactionID)
+		}
+	}
+
+	for transactionID := range affectedTransactionIDs {
+		changes = append(changes, DeletePendingTransactionChange(transactionID))
+	}
+
+	return changes
+}
+
+// Outputs returns all outputs of the Wallet sorted by their ID, including the ones that are pending to be spent.
+func (w *Wallet) Outputs() []*builder.TxInput {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.filteredOutputs(func(_ *builder.TxInput) bool {
+		return true
+	})
+}
+
+// UnspentOutputs returns the outputs of the Wallet sorted by their ID, which are not pending to be spent.
+func (w *Wallet) UnspentOutputs() []*builder.TxInput {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.unspentOutputs()
+}
+
+func (w *Wallet) unspentOutputs() []*builder.TxInput {
+	return w.filteredOutputs(func(output *builder.TxInput) bool {
+		_, isPending := w.state.PendingSpends[output.InputID]
+
+		return !isPending
+	})
+}
+
+func (w *Wallet) filteredOutputs(filter func(output *builder.TxInput) bool) []*builder.TxInput {
+	outputs := make([]*builder.TxInput, 0, len(w.state.Outputs))
+	for _, output := range w.state.Outputs {
+		if filter(output) {
+			outputs = append(outputs, output)
+		}
+	}
+
+	slices.SortFunc(outputs, func(a *builder.TxInput, b *builder.TxInput) int {
+		return bytes.Compare(a.InputID[:], b.InputID[:])
+	})
+
+	return outputs
+}
+
+// PendingSpends returns the outputs which are consumed by transactions that were not confirmed yet,
+// mapped to the ID of the consuming transaction.
+func (w *Wallet) PendingSpends() map[iotago.OutputID]iotago.TransactionID {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return maps.Clone(w.state.PendingSpends)
+}
+
+// PendingTransactions returns the transactions created by Send which were not confirmed yet.
+func (w *Wallet) PendingTransactions() map[iotago.TransactionID]*iotago.SignedTransaction {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return maps.Clone(w.state.PendingTransactions)
+}
+
+// ReleasePendingSpends makes the outputs consumed by the given transaction available again,
+// e.g. because the transaction could not be issued or was rejected.
+func (w *Wallet) ReleasePendingSpends(transactionID iotago.TransactionID) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	changes := make([]*StateChange, 0)
+	for outputID, pendingTransactionID := range w.state.PendingSpends {
+		if pendingTransactionID == transactionID {
+			changes = append(changes, DeletePendingSpendChange(outputID))
+		}
+	}
+
+	return w.apply(append(changes, DeletePendingTransactionChange(transactionID))...)
+}
+
+// Balance is the balance of the outputs of a Wallet.
+type Balance struct {
+	// The base tokens held by the outputs.
+	BaseTokens iotago.BaseToken
+	// The native tokens held by the outputs.
+	NativeTokens iotago.NativeTokenSum
+	// The stored mana of the outputs, decayed to the target slot.
+	StoredMana iotago.Mana
+	// The mana generated by the base tokens of the outputs until the target slot.
+	PotentialMana iotago.Mana
+}
+
+// Balance returns the balance of the outputs of the Wallet at the given slot.
+// Outputs which are pending to be spent are not part of the balance.
+func (w *Wallet) Balance(targetSlot iotago.SlotIndex) (*Balance, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	balance := &Balance{
+		NativeTokens: make(iotago.NativeTokenSum),
+	}
+
+	for _, output := range w.unspentOutputs() {
+		var err error
+		if balance.BaseTokens, err = safemath.SafeAdd(balance.BaseTokens, output.Input.BaseTokenAmount()); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the base tokens of the output")
+		}
+
+		if nativeToken := output.Input.FeatureSet().NativeToken(); nativeToken != nil {
+			amount := balance.NativeTokens.ValueOrBigInt0(nativeToken.ID)
+			balance.NativeTokens[nativeToken.ID] = new(big.Int).Add(amount, nativeToken.Amount)
+		}
+
+		// the mana of outputs created after the target slot is not decayed yet
+		creationSlot := output.InputID.CreationSlot()
+		manaSlot := max(targetSlot, creationSlot)
+
+		storedMana, err := w.api.ManaDecayProvider().DecayManaBySlots(output.Input.StoredMana(), creationSlot, manaSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to calculate the stored mana of output %s", output.InputID.ToHex())
+		}
+		if balance.StoredMana, err = safemath.SafeAdd(balance.StoredMana, storedMana); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the stored mana of the output")
+		}
+
+		potentialMana, err := iotago.PotentialMana(w.api.ManaDecayProvider(), w.api.StorageScoreStructure(), output.Input, creationSlot, manaSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to calculate the potential mana of output %s", output.InputID.ToHex())
+		}
+		if balance.PotentialMana, err = safemath.SafeAdd(balance.PotentialMana, potentialMana); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the potential mana of the output")
+		}
+	}
+
+	return balance, nil
+}
+
+// Sync adds the outputs the indexer reports as unlockable by the tracked addresses to the Wallet
+// and remembers the slot of the oldest commitment the indexer answered with as synced slot.
+// Outputs which are not reported anymore are removed together with their pending spends, unless they were created
+// after the synced slot, e.g. because they were added by Follow, since the indexer can't know them yet.
+// Without any tracked address, there is nothing to sync and the synced slot is kept.
+func (w *Wallet) Sync(ctx context.Context, indexer nodeclient.IndexerClient) error {
+	addresses := w.Addresses()
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	hrp := w.api.ProtocolParameters().Bech32HRP()
+
+	outputs := make(map[iotago.OutputID]*builder.TxInput)
+	var syncedSlot iotago.SlotIndex
+	for i, derivedAddress := range addresses {
+		addressOutputs, committedSlot, err := queryUnlockableOutputs(ctx, indexer, derivedAddress.Address, hrp)
+		if err != nil {
+			return ierrors.Wrapf(err, "failed to query the outputs of address %s", derivedAddress.Address.Bech32(hrp))
+		}
+
+		if i == 0 || committedSlot < syncedSlot {
+			syncedSlot = committedSlot
+		}
+
+		for _, output := range addressOutputs {
+			if _, exists := outputs[output.InputID]; !exists {
+				outputs[output.InputID] = output
+			}
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	deletedOutputIDs := make([]iotago.OutputID, 0)
+	for outputID := range w.state.Outputs {
+		if _, exists := outputs[outputID]; !exists && outputID.CreationSlot() <= syncedSlot {
+			deletedOutputIDs = append(deletedOutputIDs, outputID)
+		}
+	}
+
+	changes := w.deleteOutputsChanges(deletedOutputIDs...)
+	for outputID, output := range outputs {
+		if _, exists := w.state.Outputs[outputID]; !exists {
+			changes = append(changes, PutOutputChange(output))
+		}
+	}
+
+	return w.apply(append(changes, SyncedSlotChange(syncedSlot))...)
+}
+
+// Follow keeps the outputs of the Wallet up to date until the context is done.
+// It syncs the Wallet with the indexer, subscribes to the outputs of the tracked addresses via the event API
+// and resyncs with the indexer in the given interval. The EventAPIClient must already be connected.
+// Addresses tracked after Follow was called are only picked up by the resyncs.
+// Follow returns as soon as a change can't be persisted by the StateStore.
+func (w *Wallet) Follow(ctx context.Context, eventClient *nodeclient.EventAPIClient, indexer nodeclient.IndexerClient, resyncInterval time.Duration) error {
+	if err := w.Sync(ctx, indexer); err != nil {
+		return err
+	}
+
+	// the first error of the subscriptions is reported, the subscriptions stop once Follow returns
+	applyErrors := make(chan error, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	subscriptions := make([]*nodeclient.EventAPIClientSubscription, 0)
+	defer func() {
+		for _, subscription := range subscriptions {
+			_ = subscription.Close()
+		}
+	}()
+
+	for _, derivedAddress := range w.Addresses() {
+		outputs, subscription := eventClient.OutputsWithMetadataByUnlockConditionAndAddress(api.EventAPIUnlockConditionAddress, derivedAddress.Address)
+		if err := subscription.Error(); err != nil {
+			return ierrors.Wrapf(err, "failed to subscribe to the outputs of address %s", derivedAddress.Address.Bech32(w.api.ProtocolParameters().Bech32HRP()))
+		}
+		subscriptions = append(subscriptions, subscription)
+
+		go func(unlockTarget iotago.Address) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case output, ok := <-outputs:
+					if !ok {
+						return
+					}
+					if err := w.applyOutputWithMetadata(unlockTarget, output); err != nil {
+						select {
+						case applyErrors <- err:
+						default:
+						}
+
+						return
+					}
+				}
+			}
+		}(derivedAddress.Address)
+	}
+
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-applyErrors:
+			return err
+		case <-ticker.C:
+			if err := w.Sync(ctx, indexer); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// applyOutputWithMetadata adds or removes the output reported by the event API.
+func (w *Wallet) applyOutputWithMetadata(unlockTarget iotago.Address, output *api.OutputWithMetadataResponse) error {
+	if output.Metadata.Spent != nil {
+		if err := w.RemoveOutput(output.Metadata.OutputID); err != nil {
+			return ierrors.Wrapf(err, "failed to remove spent output %s", output.Metadata.OutputID.ToHex())
+		}
+
+		return nil
+	}
+
+	// the address is tracked, since we only subscribe to tracked addresses
+	if err := w.AddOutput(unlockTarget, output.Metadata.OutputID, output.Output); err != nil {
+		return ierrors.Wrapf(err, "failed to add output %s", output.Metadata.OutputID.ToHex())
+	}
+
+	return nil
+}
+
+// SendOptions define the options used to create a transaction with Send.
+type SendOptions struct {
+	strategy         builder.InputSelectionStrategy
+	remainderAddress iotago.Address
+	taggedData       *iotago.TaggedData
+	commitmentID     iotago.CommitmentID
+}
+
+// WithSendStrategy sets the strategy used to select the inputs.
+func WithSendStrategy(strategy builder.InputSelectionStrategy) options.Option[SendOptions] {
+	return func(opts *SendOptions) {
+		opts.strategy = strategy
+	}
+}
+
+// WithSendRemainderAddress sets the address the remainder is sent to.
+func WithSendRemainderAddress(remainderAddress iotago.Address) options.Option[SendOptions] {
+	return func(opts *SendOptions) {
+		opts.remainderAddress = remainderAddress
+	}
+}
+
+// WithSendTaggedData adds the given tagged data payload to the transaction.
+func WithSendTaggedData(taggedData *iotago.TaggedData) options.Option[SendOptions] {
+	return func(opts *SendOptions) {
+		opts.taggedData = taggedData
+	}
+}
+
+// WithSendCommitmentID sets the commitment the transaction references in its commitment input,
+// which is required to spend outputs with a timelock or an expiration unlock condition.
+func WithSendCommitmentID(commitmentID iotago.CommitmentID) options.Option[SendOptions] {
+	return func(opts *SendOptions) {
+		opts.commitmentID = commitmentID
+	}
+}
+
+// Send creates a signed transaction that funds the given outputs with the unspent basic outputs of the Wallet.
+// The remainder is sent to the first tracked internal Ed25519 address or, if there is none, to the first tracked
+// Ed25519 address, and the remaining mana is stored in the remainder. If the inputs match the outputs exactly,
+// the remaining mana is stored in one of the given outputs instead. The target slot is the slot of the
+// commitment the transaction is going to reference and is used as creation slot of the transaction.
+// Outputs with a timelock or an expiration unlock condition are only spent if a commitment is set with WithSendCommitmentID.
+// The transaction is stored as pending transaction and the consumed outputs are marked as its pending spends.
+// The transaction still needs to be issued in a block.
+func (w *Wallet) Send(targetSlot iotago.SlotIndex, outputs []iotago.Output, opts ...options.Option[SendOptions]) (*iotago.SignedTransaction, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	sendOptions := options.Apply(&SendOptions{
+		strategy: builder.NewLargestFirstStrategy(),
+	}, opts)
+
+	if sendOptions.remainderAddress == nil {
+		remainderAddress, err := w.remainderAddress()
+		if err != nil {
+			return nil, err
+		}
+		sendOptions.remainderAddress = remainderAddress
+	}
+
+	addressSigner, err := w.addressSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder := builder.NewTransactionBuilder(w.api, addressSigner).SetCreationSlot(targetSlot)
+	for _, output := range outputs {
+		txBuilder.AddOutput(output)
+	}
+	if sendOptions.taggedData != nil {
+		txBuilder.AddTaggedDataPayload(sendOptions.taggedData)
+	}
+
+	candidates := w.unspentOutputs()
+	if sendOptions.commitmentID != iotago.EmptyCommitmentID {
+		txBuilder.SetCommitmentInput(&iotago.CommitmentInput{CommitmentID: sendOptions.commitmentID})
+	} else {
+		// the unlock conditions can't be checked by the VM without a commitment input
+		candidates = slices.DeleteFunc(candidates, func(candidate *builder.TxInput) bool {
+			unlockConditions := candidate.Input.UnlockConditionSet()
+
+			return unlockConditions.HasTimelockCondition() || unlockConditions.HasExpirationCondition()
+		})
+	}
+
+	selection, err := txBuilder.SelectInputs(targetSlot, sendOptions.strategy, 0, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder.AddRemainderOutputsAndStoreRemainingMana(targetSlot, sendOptions.remainderAddress)
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	pendingTransactionChange, err := PutPendingTransactionChange(signedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []*StateChange{pendingTransactionChange}
+	for _, selected := range selection.Selected {
+		changes = append(changes, PutPendingSpendChange(selected.InputID, pendingTransactionChange.TransactionID))
+	}
+
+	if err := w.apply(changes...); err != nil {
+		return nil, err
+	}
+
+	return signedTx, nil
+}
+
+// ConsolidateOptions define the options used to consolidate the outputs of the Wallet with Consolidate.
+type ConsolidateOptions struct {
+	targetAddress       iotago.Address
+	includeNativeTokens bool
+	maxInputs           int
+	commitmentID        iotago.CommitmentID
+}
+
+// WithConsolidateTargetAddress sets the address the outputs are consolidated on.
+func WithConsolidateTargetAddress(targetAddress iotago.Address) options.Option[ConsolidateOptions] {
+	return func(opts *ConsolidateOptions) {
+		opts.targetAddress = targetAddress
+	}
+}
+
+// WithConsolidateNativeTokens sets whether outputs holding native tokens are consolidated.
+func WithConsolidateNativeTokens(includeNativeTokens bool) options.Option[ConsolidateOptions] {
+	return func(opts *ConsolidateOptions) {
+		opts.includeNativeTokens = includeNativeTokens
+	}
+}
+
+// WithConsolidateMaxInputs sets the maximum amount of inputs of a consolidation transaction.
+func WithConsolidateMaxInputs(maxInputs int) options.Option[ConsolidateOptions] {
+	return func(opts *ConsolidateOptions) {
+		opts.maxInputs = maxInputs
+	}
+}
+
+// WithConsolidateCommitmentID sets the commitment the transactions reference in their commitment input,
+// which is required to consolidate outputs with a timelock or an expiration unlock condition.
+func WithConsolidateCommitmentID(commitmentID iotago.CommitmentID) options.Option[ConsolidateOptions] {
+	return func(opts *ConsolidateOptions) {
+		opts.commitmentID = commitmentID
+	}
+}
+
+// Consolidate creates the signed transactions that merge the unspent outputs of all tracked addresses
+// into the fewest possible outputs, see builder.ConsolidationBuilder. The outputs are consolidated on the same address
+// as the remainder of Send, unless another target address is set. The target slot is the slot of the commitment
+// the transactions are going to reference and is used as their creation slot.
+// The transactions are stored as pending transactions and the consumed outputs are marked as their pending spends.
+// The transactions still need to be issued in blocks in the order of the plan.
+func (w *Wallet) Consolidate(targetSlot iotago.SlotIndex, opts ...options.Option[ConsolidateOptions]) (*builder.ConsolidationPlan, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	consolidateOptions := options.Apply(&ConsolidateOptions{
+		maxInputs: iotago.MaxInputsCount,
+	}, opts)
+
+	if consolidateOptions.targetAddress == nil {
+		targetAddress, err := w.remainderAddress()
+		if err != nil {
+			return nil, err
+		}
+		consolidateOptions.targetAddress = targetAddress
+	}
+
+	addressSigner, err := w.addressSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	consolidationBuilder := builder.NewConsolidationBuilder(w.api, addressSigner, consolidateOptions.targetAddress).
+		IncludeNativeTokens(consolidateOptions.includeNativeTokens).
+		MaxInputs(consolidateOptions.maxInputs)
+	if consolidateOptions.commitmentID != iotago.EmptyCommitmentID {
+		consolidationBuilder.CommitmentInput(&iotago.CommitmentInput{CommitmentID: consolidateOptions.commitmentID})
+	}
+
+	plan, err := consolidationBuilder.Build(targetSlot, w.unspentOutputs())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*StateChange, 0)
+	for _, transaction := range plan.Transactions {
+		pendingTransactionChange, err := PutPendingTransactionChange(transaction.SignedTransaction)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, pendingTransactionChange)
+
+		// the consolidated outputs of the previous transaction are not known to the wallet yet
+		for _, input := range transaction.Inputs {
+			if _, isTracked := w.state.Outputs[input.InputID]; isTracked {
+				changes = append(changes, PutPendingSpendChange(input.InputID, pendingTransactionChange.TransactionID))
+			}
+		}
+	}
+
+	if err := w.apply(changes...); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// remainderAddress returns the first tracked internal Ed25519 address or, if there is none, the first tracked Ed25519 address.
+func (w *Wallet) remainderAddress() (iotago.Address, error) {
+	var remainderAddress iotago.Address
+	for _, derivedAddress := range w.state.Addresses {
+		if derivedAddress.Address.Type() != iotago.AddressEd25519 {
+			continue
+		}
+
+		if derivedAddress.Change == ChangeInternal {
+			return derivedAddress.Address, nil
+		}
+
+		if remainderAddress == nil {
+			remainderAddress = derivedAddress.Address
+		}
+	}
+
+	if remainderAddress == nil {
+		return nil, ierrors.WithMessage(ErrNoAddressTracked, "no Ed25519 address to send the remainder to")
+	}
+
+	return remainderAddress, nil
+}
+
+// addressSigner returns an iotago.AddressSigner holding the keys of all tracked addresses.
+func (w *Wallet) addressSigner() (iotago.AddressSigner, error) {
+	privateKeys := make([]ed25519.PrivateKey, 0, len(w.state.Addresses))
+	for _, derivedAddress := range w.state.Addresses {
+		privateKey, _, err := w.keyManager.KeyPairAt(derivedAddress.Account, derivedAddress.Change, derivedAddress.AddressIndex)
+		if err != nil {
+			return nil, err
+		}
+		privateKeys = append(privateKeys, privateKey)
+	}
+
+	return iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(privateKeys...), nil
+}
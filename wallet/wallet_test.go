@@ -0,0 +1,259 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestWallet(t *testing.T) {
+	defer gock.Off()
+
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(mockAPI, keyManager)
+
+	external, err := w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+	internal, err := w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeInternal, 0)
+	require.NoError(t, err)
+
+	// tracking an address twice is a no-op
+	_, err = w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+	require.Len(t, w.Addresses(), 2)
+
+	creationSlot := iotago.SlotIndex(100)
+	nativeToken := tpkg.RandNativeTokenFeature()
+
+	require.NoError(t, w.AddOutput(external, tpkg.RandOutputIDWithCreationSlot(creationSlot, 0), builder.NewBasicOutputBuilder(external, 10_000_000).Mana(1000).MustBuild()))
+	require.NoError(t, w.AddOutput(external, tpkg.RandOutputIDWithCreationSlot(creationSlot, 1), builder.NewBasicOutputBuilder(external, 5_000_000).NativeToken(nativeToken).MustBuild()))
+	require.NoError(t, w.AddOutput(internal, tpkg.RandOutputIDWithCreationSlot(creationSlot, 2), builder.NewBasicOutputBuilder(internal, 2_000_000).MustBuild()))
+
+	err = w.AddOutput(tpkg.RandEd25519Address(), tpkg.RandOutputID(0), builder.NewBasicOutputBuilder(external, 1_000_000).MustBuild())
+	require.ErrorIs(t, err, wallet.ErrAddressNotTracked)
+
+	t.Run("ok - balance", func(t *testing.T) {
+		balance, err := w.Balance(creationSlot)
+		require.NoError(t, err)
+		require.Equal(t, iotago.BaseToken(17_000_000), balance.BaseTokens)
+		require.Equal(t, nativeToken.Amount, balance.NativeTokens[nativeToken.ID])
+		require.Equal(t, iotago.Mana(1000), balance.StoredMana)
+		require.Zero(t, balance.PotentialMana)
+
+		laterBalance, err := w.Balance(creationSlot + 1000)
+		require.NoError(t, err)
+		require.Equal(t, balance.BaseTokens, laterBalance.BaseTokens)
+		require.LessOrEqual(t, laterBalance.StoredMana, balance.StoredMana)
+		require.Greater(t, laterBalance.PotentialMana, balance.PotentialMana)
+	})
+
+	t.Run("ok - send", func(t *testing.T) {
+		recipient := tpkg.RandEd25519Address()
+		targetSlot := creationSlot + 10
+
+		inputSet := vm.InputSet{}
+		for _, output := range w.Outputs() {
+			inputSet[output.InputID] = output.Input
+		}
+
+		signedTx, err := w.Send(targetSlot, []iotago.Output{builder.NewBasicOutputBuilder(recipient, 11_000_000).MustBuild()})
+		require.NoError(t, err)
+		require.Equal(t, targetSlot, signedTx.Transaction.CreationSlot)
+
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: inputSet})
+		require.NoError(t, err)
+
+		transactionID, err := signedTx.Transaction.ID()
+		require.NoError(t, err)
+
+		pendingSpends := w.PendingSpends()
+		require.Len(t, pendingSpends, len(signedTx.Transaction.Inputs()))
+		for _, pendingTransactionID := range pendingSpends {
+			require.Equal(t, transactionID, pendingTransactionID)
+		}
+
+		// the remainder is sent to the internal address
+		var outputsSum iotago.BaseToken
+		for _, output := range signedTx.Transaction.Outputs {
+			outputsSum += output.BaseTokenAmount()
+			if output.(*iotago.BasicOutput).Owner().Equal(recipient) { //nolint:forcetypeassert
+				continue
+			}
+			require.True(t, output.(*iotago.BasicOutput).Owner().Equal(internal)) //nolint:forcetypeassert
+		}
+
+		var inputsSum iotago.BaseToken
+		for _, input := range signedTx.Transaction.Inputs() {
+			inputsSum += inputSet[input.OutputID()].BaseTokenAmount()
+		}
+		require.Equal(t, inputsSum, outputsSum)
+
+		// pending spends are not part of the balance and can't be spent again
+		balance, err := w.Balance(targetSlot)
+		require.NoError(t, err)
+		require.Equal(t, iotago.BaseToken(17_000_000)-inputsSum, balance.BaseTokens)
+		require.Len(t, w.UnspentOutputs(), 3-len(pendingSpends))
+
+		_, err = w.Send(targetSlot, []iotago.Output{builder.NewBasicOutputBuilder(recipient, 11_000_000).MustBuild()})
+		require.ErrorIs(t, err, builder.ErrInputSelectionNotEnoughBaseTokens)
+
+		require.Contains(t, w.PendingTransactions(), transactionID)
+
+		require.NoError(t, w.ReleasePendingSpends(transactionID))
+		require.Empty(t, w.PendingSpends())
+		require.Empty(t, w.PendingTransactions())
+		require.Len(t, w.UnspentOutputs(), 3)
+
+		_, err = w.Send(targetSlot, []iotago.Output{builder.NewBasicOutputBuilder(recipient, 11_000_000).MustBuild()})
+		require.NoError(t, err)
+		require.NotEmpty(t, w.PendingSpends())
+	})
+
+	t.Run("ok - sync", func(t *testing.T) {
+		client := mockNodeClient(t)
+		indexer, err := client.Indexer(context.Background())
+		require.NoError(t, err)
+
+		remainderOutputIDs := mockUnlockableOutputs(t, internal, builder.NewBasicOutputBuilder(internal, 6_000_000).MustBuild())
+		mockNoUnlockableOutputs()
+
+		// an output created after the commitment of the indexer, e.g. reported by the event API, is kept
+		newerOutputID := tpkg.RandOutputIDWithCreationSlot(mockCommittedSlot+1, 0)
+		require.NoError(t, w.AddOutput(external, newerOutputID, builder.NewBasicOutputBuilder(external, 1_000_000).MustBuild()))
+
+		require.NoError(t, w.Sync(context.Background(), indexer))
+
+		outputIDs := make(iotago.OutputIDs, 0)
+		for _, output := range w.Outputs() {
+			outputIDs = append(outputIDs, output.InputID)
+		}
+		require.ElementsMatch(t, iotago.OutputIDs{remainderOutputIDs[0], newerOutputID}, outputIDs)
+		require.Empty(t, w.PendingSpends())
+		require.Empty(t, w.PendingTransactions())
+		require.Equal(t, mockCommittedSlot, w.SyncedSlot())
+
+		require.NoError(t, w.RemoveOutput(newerOutputID))
+	})
+	t.Run("ok - consolidate", func(t *testing.T) {
+		// the synced output was created in a random slot
+		targetSlot := w.Outputs()[0].InputID.CreationSlot() + 20
+		require.NoError(t, w.AddOutput(external, tpkg.RandOutputIDWithCreationSlot(creationSlot, 3), builder.NewBasicOutputBuilder(external, 3_000_000).Mana(500).MustBuild()))
+
+		inputSet := vm.InputSet{}
+		for _, output := range w.Outputs() {
+			inputSet[output.InputID] = output.Input
+		}
+
+		plan, err := w.Consolidate(targetSlot)
+		require.NoError(t, err)
+		require.Len(t, plan.Transactions, 1)
+
+		signedTx := plan.Transactions[0].SignedTransaction
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: inputSet})
+		require.NoError(t, err)
+
+		// the outputs are consolidated on the internal address
+		require.Len(t, signedTx.Transaction.Outputs, 1)
+		require.True(t, signedTx.Transaction.Outputs[0].(*iotago.BasicOutput).Owner().Equal(internal)) //nolint:forcetypeassert
+		require.Equal(t, iotago.BaseToken(9_000_000), signedTx.Transaction.Outputs[0].BaseTokenAmount())
+
+		require.Len(t, w.PendingSpends(), 2)
+		require.Contains(t, w.PendingTransactions(), plan.Transactions[0].TransactionID)
+
+		_, err = w.Consolidate(targetSlot)
+		require.ErrorIs(t, err, builder.ErrConsolidationNotNeeded)
+	})
+}
+
+func TestWalletSyncWithoutAddresses(t *testing.T) {
+	defer gock.Off()
+
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	store := wallet.NewMemoryStateStore()
+	require.NoError(t, store.Apply(wallet.SyncedSlotChange(mockCommittedSlot)))
+
+	w, err := wallet.LoadWallet(mockAPI, keyManager, store)
+	require.NoError(t, err)
+
+	client := mockNodeClient(t)
+	indexer, err := client.Indexer(context.Background())
+	require.NoError(t, err)
+
+	// the synced slot is not moved back, since no address was synced
+	require.NoError(t, w.Sync(context.Background(), indexer))
+	require.Equal(t, mockCommittedSlot, w.SyncedSlot())
+
+	state, err := store.Load()
+	require.NoError(t, err)
+	require.Equal(t, mockCommittedSlot, state.SyncedSlot)
+}
+
+func TestWalletSendExactAmount(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(mockAPI, keyManager)
+	addr, err := w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+
+	creationSlot := iotago.SlotIndex(100)
+	require.NoError(t, w.AddOutput(addr, tpkg.RandOutputIDWithCreationSlot(creationSlot, 0), builder.NewBasicOutputBuilder(addr, 1_000_000).Mana(1000).MustBuild()))
+
+	// no remainder is needed, so the mana of the input is stored in the payment
+	payment := builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000).MustBuild()
+	signedTx, err := w.Send(creationSlot+10, []iotago.Output{payment})
+	require.NoError(t, err)
+	require.Len(t, signedTx.Transaction.Outputs, 1)
+	require.GreaterOrEqual(t, signedTx.Transaction.Outputs[0].StoredMana(), iotago.Mana(1000))
+	require.Zero(t, payment.Mana)
+}
+
+func TestWalletSendExpiringOutput(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(keystoreTestMnemonic, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(mockAPI, keyManager)
+	addr, err := w.TrackAddress(iotago.AddressEd25519, 0, wallet.ChangeExternal, 0)
+	require.NoError(t, err)
+
+	creationSlot := iotago.SlotIndex(100)
+	targetSlot := creationSlot + 10
+	inputID := tpkg.RandOutputIDWithCreationSlot(creationSlot, 0)
+	input := builder.NewBasicOutputBuilder(addr, 1_000_000).Expiration(tpkg.RandEd25519Address(), targetSlot+1000).MustBuild()
+	require.NoError(t, w.AddOutput(addr, inputID, input))
+
+	payment := builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000).MustBuild()
+
+	t.Run("err - expiring output without a commitment", func(t *testing.T) {
+		_, err := w.Send(targetSlot, []iotago.Output{payment})
+		require.ErrorIs(t, err, builder.ErrInputSelectionNotEnoughBaseTokens)
+	})
+
+	t.Run("ok - expiring output with a commitment", func(t *testing.T) {
+		commitment := iotago.NewCommitment(mockAPI.Version(), targetSlot, iotago.NewCommitmentID(targetSlot-1, tpkg.RandIdentifier()), tpkg.RandIdentifier(), 0, 0)
+		commitmentID, err := commitment.ID()
+		require.NoError(t, err)
+
+		signedTx, err := w.Send(targetSlot, []iotago.Output{payment}, wallet.WithSendCommitmentID(commitmentID))
+		require.NoError(t, err)
+		require.Equal(t, iotago.TxEssenceContextInputs{&iotago.CommitmentInput{CommitmentID: commitmentID}}, signedTx.Transaction.TransactionEssence.ContextInputs)
+
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{
+			InputSet:        vm.InputSet{inputID: input},
+			CommitmentInput: commitment,
+		})
+		require.NoError(t, err)
+	})
+}